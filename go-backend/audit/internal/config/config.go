@@ -5,6 +5,9 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 )
 
 // Config holds the configuration for the audit & compliance service
@@ -16,6 +19,19 @@ type Config struct {
 	SIEMEnabled          bool
 	SIEMEndpoints        []string
 	ImmutableAuditTrails bool
+	RateLimitAPI         ratelimit.Limit // per calling service
+	TrustedProxies       []string        // proxy IPs or CIDRs whose X-Forwarded-For is believed
+
+	// Service-to-service TLS with certificates from the key management service's CA
+	TLSMode           string // "off", "tls" or "mtls"
+	TLSTrustDomain    string
+	TLSCAURL          string
+	TLSCARootFile     string // PEM roots trusted to bootstrap the first certificate
+	TLSBootstrapToken string
+
+	// Graceful shutdown
+	ShutdownTimeout int // in seconds, covering the whole shutdown including the drain delay
+	ShutdownDelay   int // in seconds, between turning not ready and closing listeners
 }
 
 // Load reads configuration from environment variables
@@ -37,6 +53,42 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid IMMUTABLE_AUDIT_TRAILS: %v", err)
 	}
 	
+	rateLimitAPI, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_API", "1200/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_API: %v", err)
+	}
+	
+	shutdownTimeout, err := strconv.Atoi(getEnv("SHUTDOWN_TIMEOUT", "30")) // 30 seconds default
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %v", err)
+	}
+	
+	shutdownDelay, err := strconv.Atoi(getEnv("SHUTDOWN_DELAY", "5")) // 5 seconds default
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_DELAY: %v", err)
+	}
+	if shutdownDelay < 0 || shutdownDelay >= shutdownTimeout {
+		return nil, fmt.Errorf("SHUTDOWN_DELAY must be at least 0 and less than SHUTDOWN_TIMEOUT")
+	}
+	
+	var trustedProxies []string
+	if proxies := getEnv("TRUSTED_PROXIES", ""); proxies != "" {
+		for _, proxy := range strings.Split(proxies, ",") {
+			trustedProxies = append(trustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	
+	tlsMode, err := mtls.ParseMode(getEnv("TLS_MODE", mtls.ModeOff))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS_MODE: %v", err)
+	}
+	
+	tlsCARootFile := os.Getenv("TLS_CA_ROOT_FILE")
+	tlsBootstrapToken := os.Getenv("TLS_BOOTSTRAP_TOKEN")
+	if tlsMode != mtls.ModeOff && (tlsCARootFile == "" || tlsBootstrapToken == "") {
+		return nil, fmt.Errorf("TLS_CA_ROOT_FILE and TLS_BOOTSTRAP_TOKEN are required when TLS_MODE is %s", tlsMode)
+	}
+	
 	// Parse compliance standards
 	standardsStr := getEnv("COMPLIANCE_STANDARDS", "GDPR,HIPAA,SOC2")
 	var standards []string
@@ -63,6 +115,17 @@ func Load() (*Config, error) {
 		SIEMEnabled:          siemEnabled,
 		SIEMEndpoints:        endpoints,
 		ImmutableAuditTrails: immutableAuditTrails,
+		RateLimitAPI:         rateLimitAPI,
+		TrustedProxies:       trustedProxies,
+
+		TLSMode:           tlsMode,
+		TLSTrustDomain:    getEnv("TLS_TRUST_DOMAIN", mtls.DefaultTrustDomain),
+		TLSCAURL:          getEnv("TLS_CA_URL", "https://localhost:8082"),
+		TLSCARootFile:     tlsCARootFile,
+		TLSBootstrapToken: tlsBootstrapToken,
+
+		ShutdownTimeout: shutdownTimeout,
+		ShutdownDelay:   shutdownDelay,
 	}, nil
 }
 
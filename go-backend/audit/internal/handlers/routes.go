@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"github.com/cryptofortress/backend/audit/internal/config"
 	"github.com/cryptofortress/backend/audit/internal/services"
 	"github.com/cryptofortress/backend/audit/internal/middleware"
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterRoutes sets up all the routes for the audit & compliance service
-func RegisterRoutes(router *gin.Engine, services *services.Services) {
+func RegisterRoutes(router *gin.Engine, services *services.Services, cfg *config.Config, limiter *ratelimit.Limiter) {
 	// Create handlers
 	auditHandler := NewAuditHandler(services.Audit)
 	complianceHandler := NewComplianceHandler(services.Compliance)
@@ -15,6 +18,10 @@ func RegisterRoutes(router *gin.Engine, services *services.Services) {
 
 	// Public routes (no authentication required for demo purposes)
 	public := router.Group("/api/v1/audit")
+	if cfg.TLSMode == mtls.ModeMTLS {
+		public.Use(mtls.RequirePeer())
+	}
+	public.Use(limiter.Limit("api", cfg.RateLimitAPI, ratelimit.ByPeer))
 	{
 		// Audit routes
 		public.POST("/events/log", auditHandler.LogEvent)
@@ -2,6 +2,9 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -9,19 +12,26 @@ import (
 	"github.com/cryptofortress/backend/audit/internal/handlers"
 	"github.com/cryptofortress/backend/audit/internal/middleware"
 	"github.com/cryptofortress/backend/audit/internal/services"
+	"github.com/cryptofortress/backend/pkg/lifecycle"
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 )
 
 // Server represents the audit & compliance service server
 type Server struct {
-	config   *config.Config
-	router   *gin.Engine
-	services *services.Services
+	config     *config.Config
+	router     *gin.Engine
+	httpServer *http.Server
+	services   *services.Services
+	certs      *mtls.CertManager // nil when TLS is off
+	readiness  *lifecycle.Readiness
+	workers    *lifecycle.Workers
 }
 
 // New creates a new audit & compliance server instance
-func New(cfg *config.Config) *Server {
+func New(cfg *config.Config) (*Server, error) {
 	// Initialize services
 	auditService := services.NewAuditService(cfg)
 	complianceService := services.NewComplianceService(cfg)
@@ -35,42 +45,82 @@ func New(cfg *config.Config) *Server {
 	
 	// Create router
 	router := gin.New()
+	// Client IPs, which most rate limits are keyed on, are only taken from X-Forwarded-For
+	// when a trusted proxy sent it
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logging())
 	
+	// Rate limits are kept in memory, so each instance enforces its own
+	limiter := ratelimit.New(ratelimit.NewMemoryStore())
+	
 	// Register routes
-	handlers.RegisterRoutes(router, services)
+	handlers.RegisterRoutes(router, services, cfg, limiter)
 	
-	return &Server{
-		config:   cfg,
-		router:   router,
-		services: services,
+	// Readiness turns false as soon as shutdown begins, so load balancers stop sending traffic
+	readiness := lifecycle.NewReadiness()
+	router.GET("/ready", readiness.Handler())
+	
+	// Service certificates are issued by the key management service's CA
+	var certs *mtls.CertManager
+	if cfg.TLSMode != mtls.ModeOff {
+		roots, err := mtls.LoadRoots(cfg.TLSCARootFile)
+		if err != nil {
+			return nil, err
+		}
+		certs = mtls.NewCertManager(mtls.ServiceID(cfg.TLSTrustDomain, mtls.ServiceAudit), roots)
 	}
+	
+	return &Server{
+		config: cfg,
+		router: router,
+		httpServer: &http.Server{
+			Addr:    ":" + cfg.Port,
+			Handler: router,
+		},
+		services:  services,
+		certs:     certs,
+		readiness: readiness,
+		workers:   lifecycle.NewWorkers(),
+	}, nil
 }
 
-// Start begins serving requests
+// Start begins serving requests and returns once the server is stopped
 func (s *Server) Start() error {
-	server := &http.Server{
-		Addr:    ":" + s.config.Port,
-		Handler: s.router,
+	// Purge audit logs past their retention period
+	s.workers.Every("retention", time.Hour, s.purgeExpiredLogs)
+	
+	if s.certs == nil {
+		return lifecycle.ServeError(s.httpServer.ListenAndServe())
 	}
 	
-	// Graceful shutdown
-	go func() {
-		<-context.Background().Done()
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		
-		if err := server.Shutdown(ctx); err != nil {
-			log.Error().Err(err).Msg("Server shutdown failed")
-		}
-	}()
+	// Obtain the first certificate before accepting connections; it is renewed in the background
+	keymgmtID := mtls.ServiceID(s.config.TLSTrustDomain, mtls.ServiceKeyManagement)
+	issuer := mtls.NewHTTPIssuer(s.config.TLSCAURL, s.config.TLSBootstrapToken, s.certs.HTTPClient(10*time.Second, keymgmtID))
+	if err := s.certs.Start(s.workers.Context(), issuer); err != nil {
+		return lifecycle.ServeError(err)
+	}
 	
-	return server.ListenAndServe()
+	s.httpServer.TLSConfig = s.certs.ServerTLSConfig(tls.VerifyClientCertIfGiven)
+	return lifecycle.ServeError(s.httpServer.ListenAndServeTLS("", ""))
 }
 
-// Stop gracefully shuts down the server
-func (s *Server) Stop() error {
-	// Cleanup resources if needed
-	return nil
-}
\ No newline at end of file
+// purgeExpiredLogs removes audit logs that have exceeded the retention period
+func (s *Server) purgeExpiredLogs(ctx context.Context) {
+	if err := s.services.Audit.PurgeExpiredLogs(); err != nil {
+		log.Error().Err(err).Msg("Failed to purge expired audit logs")
+	}
+}
+
+// Stop gracefully shuts down the server: it turns not ready, waits for load balancers
+// to notice, drains open connections and then stops the background workers
+func (s *Server) Stop(ctx context.Context) error {
+	s.readiness.Drain(ctx, time.Duration(s.config.ShutdownDelay)*time.Second)
+	
+	// Workers stop even if draining times out, with a timeout of their own, so the shutdown
+	// error reports both
+	httpErr := s.httpServer.Shutdown(ctx)
+	return errors.Join(httpErr, s.workers.StopWithin(lifecycle.WorkerStopTimeout))
+}
@@ -2,10 +2,11 @@ package main
 
 import (
 	"log"
-	"os"
+	"time"
 
 	"github.com/cryptofortress/backend/auth/internal/server"
 	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/cryptofortress/backend/pkg/lifecycle"
 )
 
 func main() {
@@ -16,12 +17,15 @@ func main() {
 	}
 
 	// Initialize server
-	srv := server.New(cfg)
+	srv, err := server.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize server: %v", err)
+	}
 
-	// Start server
+	// Start server; it runs until SIGINT or SIGTERM and then shuts down gracefully
 	log.Printf("Starting Authentication Service on port %s", cfg.Port)
-	if err := srv.Start(); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
-		os.Exit(1)
+	timeout := time.Duration(cfg.ShutdownTimeout) * time.Second
+	if err := lifecycle.Run(srv, timeout); err != nil {
+		log.Fatalf("Server failed: %v", err)
 	}
 }
\ No newline at end of file
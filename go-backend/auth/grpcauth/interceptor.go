@@ -0,0 +1,132 @@
+// Package grpcauth provides gRPC server interceptors that authenticate requests with
+// access tokens issued by the authentication service. Other Go services use them to
+// protect their own gRPC endpoints.
+package grpcauth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/cryptofortress/backend/auth/proto/authpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TokenValidator validates an access token and returns its claims
+type TokenValidator interface {
+	ValidateToken(ctx context.Context, accessToken string) (*authpb.TokenInfo, error)
+}
+
+// tokenInfoKey is the context key under which validated token claims are stored
+type tokenInfoKey struct{}
+
+// FromContext returns the validated token claims set by the interceptors
+func FromContext(ctx context.Context) (*authpb.TokenInfo, bool) {
+	info, ok := ctx.Value(tokenInfoKey{}).(*authpb.TokenInfo)
+	return info, ok
+}
+
+// NewContext returns a copy of ctx carrying validated token claims
+func NewContext(ctx context.Context, info *authpb.TokenInfo) context.Context {
+	return context.WithValue(ctx, tokenInfoKey{}, info)
+}
+
+// remoteValidator validates tokens by calling the authentication service
+type remoteValidator struct {
+	client authpb.AuthServiceClient
+}
+
+// NewRemoteValidator creates a token validator backed by the authentication service's
+// Validate RPC
+func NewRemoteValidator(client authpb.AuthServiceClient) TokenValidator {
+	return &remoteValidator{client: client}
+}
+
+// ValidateToken calls the authentication service to validate the token
+func (v *remoteValidator) ValidateToken(ctx context.Context, accessToken string) (*authpb.TokenInfo, error) {
+	resp, err := v.client.Validate(ctx, &authpb.ValidateRequest{AccessToken: accessToken})
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetToken(), nil
+}
+
+// UnaryServerInterceptor creates an interceptor that requires a valid bearer token in the
+// "authorization" metadata of every unary call, except for the given public methods
+func UnaryServerInterceptor(validator TokenValidator, publicMethods ...string) grpc.UnaryServerInterceptor {
+	public := methodSet(publicMethods)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if public[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		ctx, err := authenticate(ctx, validator)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor creates an interceptor that requires a valid bearer token in the
+// "authorization" metadata of every streaming call, except for the given public methods
+func StreamServerInterceptor(validator TokenValidator, publicMethods ...string) grpc.StreamServerInterceptor {
+	public := methodSet(publicMethods)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if public[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		ctx, err := authenticate(ss.Context(), validator)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream overrides the stream context with one carrying token claims
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the authenticated context
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate validates the bearer token in the incoming metadata
+func authenticate(ctx context.Context, validator TokenValidator) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+	}
+
+	tokenString, found := strings.CutPrefix(values[0], "Bearer ")
+	if !found || tokenString == "" {
+		return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+	}
+
+	info, err := validator.ValidateToken(ctx, tokenString)
+	if err != nil {
+		if status.Code(err) != codes.Unknown {
+			return nil, err
+		}
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	return NewContext(ctx, info), nil
+}
+
+// methodSet turns a list of full method names into a lookup set
+func methodSet(methods []string) map[string]bool {
+	set := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		set[method] = true
+	}
+	return set
+}
@@ -0,0 +1,200 @@
+// Package httpauth provides gin middleware that authenticates HTTP requests with access
+// tokens issued by the authentication service. Other Go services use it to protect their
+// own HTTP routes. Tokens are checked by the authentication service's Validate RPC, so
+// disabled accounts, revoked sessions and ended elevations are rejected there as well.
+package httpauth
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/proto/authpb"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// validateTimeout bounds each call to the authentication service
+const validateTimeout = 5 * time.Second
+
+// tokenInfoKey is the context key under which validated token claims are stored
+const tokenInfoKey = "tokenInfo"
+
+// DPoPHeader is the request header carrying an RFC 9449 DPoP proof
+const DPoPHeader = "DPoP"
+
+// Authenticator validates access tokens with the authentication service
+type Authenticator struct {
+	client authpb.AuthServiceClient
+}
+
+// New creates an authenticator backed by the authentication service's gRPC API
+func New(client authpb.AuthServiceClient) *Authenticator {
+	return &Authenticator{client: client}
+}
+
+// Authenticate creates a middleware that requires a valid access token. Bearer tokens
+// use the Bearer scheme; DPoP-bound tokens use the DPoP scheme and a proof for this
+// request, which the authentication service verifies. The validated claims are stored
+// in the context, where TokenInfo returns them, and the user, actor and elevation IDs
+// are set as "userID", "actorID" and "elevationID".
+func (a *Authenticator) Authenticate() gin.HandlerFunc {
+	return a.authenticate("")
+}
+
+// Require creates a middleware like Authenticate that also requires one of the token's
+// roles to grant the permission. Other tokens are refused with 403.
+func (a *Authenticator) Require(permission string) gin.HandlerFunc {
+	return a.authenticate(permission)
+}
+
+// authenticate validates the request's access token, and its permission when one is given
+func (a *Authenticator) authenticate(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, tokenString, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || tokenString == "" || (scheme != "Bearer" && scheme != "DPoP") {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			return
+		}
+
+		req := &authpb.ValidateRequest{AccessToken: tokenString, Permission: permission}
+		if scheme == "DPoP" {
+			proofs := c.Request.Header.Values(DPoPHeader)
+			if len(proofs) != 1 {
+				abortWithDPoPError(c, "exactly one DPoP header is required")
+				return
+			}
+			req.DpopProof = proofs[0]
+			req.HttpMethod = c.Request.Method
+			req.HttpUrl = RequestURL(c)
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), validateTimeout)
+		defer cancel()
+
+		resp, err := a.client.Validate(ctx, req)
+		if err != nil {
+			abortWithValidationError(c, err)
+			return
+		}
+
+		// Bound tokens without a proof are refused by Validate; the DPoP scheme is only
+		// meaningful for bound tokens
+		info := resp.GetToken()
+		if scheme == "DPoP" && info.GetJkt() == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "The DPoP scheme requires a DPoP-bound token"})
+			return
+		}
+
+		c.Set(tokenInfoKey, info)
+		c.Set("userID", info.GetUserId())
+		if info.GetActorId() != "" {
+			c.Set("actorID", info.GetActorId())
+		}
+		if info.GetElevationId() != "" {
+			c.Set("elevationID", info.GetElevationId())
+		}
+
+		c.Next()
+	}
+}
+
+// TokenInfo returns the claims validated by Authenticate
+func TokenInfo(c *gin.Context) (*authpb.TokenInfo, bool) {
+	value, _ := c.Get(tokenInfoKey)
+	info, ok := value.(*authpb.TokenInfo)
+	return info, ok
+}
+
+// abortWithValidationError maps a Validate error to an HTTP response. Only a missing
+// permission and an unreachable authentication service are reported as anything but
+// a bad token.
+func abortWithValidationError(c *gin.Context, err error) {
+	switch status.Code(err) {
+	case codes.PermissionDenied:
+		log.Info().Err(err).Str("path", c.Request.URL.Path).Msg("Token lacks permission")
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	case codes.Unavailable, codes.DeadlineExceeded:
+		log.Error().Err(err).Str("path", c.Request.URL.Path).Msg("Authentication service unavailable")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Authentication service unavailable"})
+	default:
+		log.Info().Err(err).Str("path", c.Request.URL.Path).Msg("Token validation failed")
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+	}
+}
+
+// abortWithDPoPError writes an invalid_dpop_proof error with its WWW-Authenticate challenge
+func abortWithDPoPError(c *gin.Context, description string) {
+	c.Header("WWW-Authenticate", `DPoP error="invalid_dpop_proof", algs="ES256 ES384 RS256 PS256 EdDSA"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid_dpop_proof", "error_description": description})
+}
+
+// forwardedKey is the context key that marks requests sent by a trusted proxy
+const forwardedKey = "trustedProxy"
+
+// TrustForwardedHeaders creates a middleware that lets RequestURL take the scheme and host
+// from the X-Forwarded-Proto and X-Forwarded-Host headers of requests sent by one of the
+// proxies. Proxies are IPs or CIDRs, as passed to the engine's SetTrustedProxies; every
+// server passes the same list to both.
+func TrustForwardedHeaders(proxies []string) (gin.HandlerFunc, error) {
+	var trusted []*net.IPNet
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, cidr, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		trusted = append(trusted, cidr)
+	}
+
+	return func(c *gin.Context) {
+		if ip := net.ParseIP(c.RemoteIP()); ip != nil {
+			for _, cidr := range trusted {
+				if cidr.Contains(ip) {
+					c.Set(forwardedKey, true)
+					break
+				}
+			}
+		}
+		c.Next()
+	}, nil
+}
+
+// RequestURL reconstructs the URL the client called, as it appears in a DPoP proof's htu claim.
+// The scheme and host set by a proxy trusted by TrustForwardedHeaders take precedence over the
+// local connection. Forwarding headers from anyone else are ignored.
+func RequestURL(c *gin.Context) string {
+	r := c.Request
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+
+	if c.GetBool(forwardedKey) {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			scheme = strings.TrimSpace(strings.Split(proto, ",")[0])
+		}
+		if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+			host = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+
+	return scheme + "://" + host + r.URL.Path
+}
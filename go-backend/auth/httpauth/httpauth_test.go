@@ -0,0 +1,58 @@
+package httpauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRequestURL tests that forwarding headers are only believed from trusted proxies
+func TestRequestURL(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	trustForwarded, err := TrustForwardedHeaders([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("Failed to parse trusted proxies: %v", err)
+	}
+
+	requestURL := func(remoteAddr string) string {
+		var url string
+		router := gin.New()
+		router.Use(trustForwarded)
+		router.GET("/resource", func(c *gin.Context) {
+			url = RequestURL(c)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "http://service.internal/resource", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-Proto", "https")
+		req.Header.Set("X-Forwarded-Host", "api.example.com")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		return url
+	}
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		expected   string
+	}{
+		{"TrustedCIDR", "10.1.2.3:4000", "https://api.example.com/resource"},
+		{"TrustedIP", "192.0.2.1:4000", "https://api.example.com/resource"},
+		{"Untrusted", "203.0.113.7:4000", "http://service.internal/resource"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if url := requestURL(tc.remoteAddr); url != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, url)
+			}
+		})
+	}
+
+	t.Run("InvalidProxy", func(t *testing.T) {
+		if _, err := TrustForwardedHeaders([]string{"not-an-ip"}); err == nil {
+			t.Error("Expected an invalid proxy to be rejected")
+		}
+	})
+}
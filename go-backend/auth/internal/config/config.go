@@ -1,14 +1,21 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 )
 
 // Config holds the configuration for the authentication service
 type Config struct {
 	Port              string
+	GRPCPort          string
 	JWTSecret         string
 	RefreshTokenTTL   int // in hours
 	AccessTokenTTL    int // in minutes
@@ -20,11 +27,88 @@ type Config struct {
 	SAMLEntityID      string
 	VaultAddr         string
 	VaultToken        string
+
+	// Initial administrator, created at startup and assigned the admin role
+	BootstrapAdminUsername string
+	BootstrapAdminEmail    string
+	BootstrapAdminPassword string
+
+	// Just-in-time role elevation
+	ElevationMaxDuration       int // in minutes
+	ElevationRequiredApprovals int
+
+	// Admin impersonation
+	ImpersonationTokenTTL int // in minutes
+
+	// Risk-based adaptive authentication
+	RiskScorer          string // "heuristic" or "http"
+	RiskServiceURL      string
+	RiskServiceTimeout  int // in milliseconds
+	RiskStepUpThreshold float64
+	RiskBlockThreshold  float64
+
+	// Step-up authentication
+	StepUpMaxAge int // in minutes
+
+	// DPoP sender-constrained tokens
+	DPoPRequiredClients []string // client IDs that may not use plain bearer tokens, "*" for all
+	DPoPProofMaxAge     int      // in seconds
+
+	// Passwordless login
+	PasswordlessTTL        int // in minutes
+	PasswordlessRateLimit  int // logins started per address per window
+	PasswordlessRateWindow int // in minutes
+	MagicLinkURL           string
+
+	// Device authorization grant
+	DeviceCodeTTL         int // in minutes
+	DevicePollInterval    int // in seconds
+	DeviceVerificationURL string
+
+	// User lifecycle
+	UserDeletionGracePeriod int // in days
+
+	// Break-glass emergency access
+	BreakGlassSessionTTL int // in minutes
+
+	// Access reviews
+	AccessReviewDuration   int    // in days
+	AccessReviewSigningKey string // base64 Ed25519 seed
+
+	// Security events
+	SecurityEventSink string // "log" or "siem"
+	AuditServiceURL   string
+
+	// Request rate limits
+	RateLimitLogin  ratelimit.Limit // credential endpoints, per client IP
+	RateLimitPublic ratelimit.Limit // other public endpoints, per client IP
+	RateLimitAPI    ratelimit.Limit // authenticated endpoints, per user
+	TrustedProxies  []string        // proxy IPs or CIDRs whose X-Forwarded-For is believed
+
+	// Service-to-service TLS with certificates from the key management service's CA
+	TLSMode           string // "off", "tls" or "mtls"
+	TLSTrustDomain    string
+	TLSCAURL          string
+	TLSCARootFile     string // PEM roots trusted to bootstrap the first certificate
+	TLSBootstrapToken string
+
+	// Email notifications
+	Notifier     string // "log" or "smtp"
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// Graceful shutdown
+	ShutdownTimeout int // in seconds, covering the whole shutdown including the drain delay
+	ShutdownDelay   int // in seconds, between turning not ready and closing listeners
 }
 
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
 	port := getEnv("AUTH_SERVICE_PORT", "8080")
+	grpcPort := getEnv("AUTH_GRPC_PORT", "9080")
 	
 	jwtSecret := os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
@@ -46,8 +130,189 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid LDAP_PORT: %v", err)
 	}
 	
+	bootstrapAdminUsername := os.Getenv("BOOTSTRAP_ADMIN_USERNAME")
+	bootstrapAdminEmail := os.Getenv("BOOTSTRAP_ADMIN_EMAIL")
+	bootstrapAdminPassword := os.Getenv("BOOTSTRAP_ADMIN_PASSWORD")
+	if bootstrapAdminUsername != "" || bootstrapAdminEmail != "" || bootstrapAdminPassword != "" {
+		if bootstrapAdminUsername == "" || bootstrapAdminEmail == "" || bootstrapAdminPassword == "" {
+			return nil, fmt.Errorf("BOOTSTRAP_ADMIN_USERNAME, BOOTSTRAP_ADMIN_EMAIL and BOOTSTRAP_ADMIN_PASSWORD must be set together")
+		}
+		if len(bootstrapAdminPassword) < 12 {
+			return nil, fmt.Errorf("BOOTSTRAP_ADMIN_PASSWORD must be at least 12 characters")
+		}
+	}
+	
+	elevationMaxDuration, err := strconv.Atoi(getEnv("ELEVATION_MAX_DURATION", "240")) // 4 hours default
+	if err != nil {
+		return nil, fmt.Errorf("invalid ELEVATION_MAX_DURATION: %v", err)
+	}
+	
+	elevationApprovals, err := strconv.Atoi(getEnv("ELEVATION_REQUIRED_APPROVALS", "1"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid ELEVATION_REQUIRED_APPROVALS: %v", err)
+	}
+	if elevationApprovals < 1 {
+		return nil, fmt.Errorf("ELEVATION_REQUIRED_APPROVALS must be at least 1")
+	}
+	
+	impersonationTTL, err := strconv.Atoi(getEnv("IMPERSONATION_TOKEN_TTL", "10")) // 10 minutes default
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMPERSONATION_TOKEN_TTL: %v", err)
+	}
+	
+	riskServiceTimeout, err := strconv.Atoi(getEnv("RISK_SERVICE_TIMEOUT", "500"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RISK_SERVICE_TIMEOUT: %v", err)
+	}
+	
+	riskStepUpThreshold, err := strconv.ParseFloat(getEnv("RISK_STEP_UP_THRESHOLD", "0.4"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RISK_STEP_UP_THRESHOLD: %v", err)
+	}
+	
+	riskBlockThreshold, err := strconv.ParseFloat(getEnv("RISK_BLOCK_THRESHOLD", "0.7"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RISK_BLOCK_THRESHOLD: %v", err)
+	}
+	if riskStepUpThreshold > riskBlockThreshold {
+		return nil, fmt.Errorf("RISK_STEP_UP_THRESHOLD cannot be greater than RISK_BLOCK_THRESHOLD")
+	}
+	
+	riskScorer := getEnv("RISK_SCORER", "heuristic")
+	if riskScorer != "heuristic" && riskScorer != "http" {
+		return nil, fmt.Errorf("invalid RISK_SCORER: %s", riskScorer)
+	}
+	
+	stepUpMaxAge, err := strconv.Atoi(getEnv("STEP_UP_MAX_AGE", "5")) // 5 minutes default
+	if err != nil {
+		return nil, fmt.Errorf("invalid STEP_UP_MAX_AGE: %v", err)
+	}
+	
+	dpopProofMaxAge, err := strconv.Atoi(getEnv("DPOP_PROOF_MAX_AGE", "60"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DPOP_PROOF_MAX_AGE: %v", err)
+	}
+	
+	var dpopRequiredClients []string
+	if clients := getEnv("DPOP_REQUIRED_CLIENTS", ""); clients != "" {
+		for _, clientID := range strings.Split(clients, ",") {
+			dpopRequiredClients = append(dpopRequiredClients, strings.TrimSpace(clientID))
+		}
+	}
+	
+	passwordlessTTL, err := strconv.Atoi(getEnv("PASSWORDLESS_TTL", "10")) // 10 minutes default
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORDLESS_TTL: %v", err)
+	}
+	
+	passwordlessRateLimit, err := strconv.Atoi(getEnv("PASSWORDLESS_RATE_LIMIT", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORDLESS_RATE_LIMIT: %v", err)
+	}
+	
+	passwordlessRateWindow, err := strconv.Atoi(getEnv("PASSWORDLESS_RATE_WINDOW", "15")) // 15 minutes default
+	if err != nil {
+		return nil, fmt.Errorf("invalid PASSWORDLESS_RATE_WINDOW: %v", err)
+	}
+	
+	deviceCodeTTL, err := strconv.Atoi(getEnv("DEVICE_CODE_TTL", "10")) // 10 minutes default
+	if err != nil {
+		return nil, fmt.Errorf("invalid DEVICE_CODE_TTL: %v", err)
+	}
+	
+	devicePollInterval, err := strconv.Atoi(getEnv("DEVICE_POLL_INTERVAL", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DEVICE_POLL_INTERVAL: %v", err)
+	}
+	
+	userDeletionGracePeriod, err := strconv.Atoi(getEnv("USER_DELETION_GRACE_PERIOD", "30")) // 30 days default
+	if err != nil {
+		return nil, fmt.Errorf("invalid USER_DELETION_GRACE_PERIOD: %v", err)
+	}
+	
+	breakGlassSessionTTL, err := strconv.Atoi(getEnv("BREAK_GLASS_SESSION_TTL", "60")) // 60 minutes default
+	if err != nil {
+		return nil, fmt.Errorf("invalid BREAK_GLASS_SESSION_TTL: %v", err)
+	}
+	
+	accessReviewDuration, err := strconv.Atoi(getEnv("ACCESS_REVIEW_DURATION", "14")) // 14 days default
+	if err != nil {
+		return nil, fmt.Errorf("invalid ACCESS_REVIEW_DURATION: %v", err)
+	}
+	
+	// Evidence must stay verifiable across restarts, so its key is never generated or derived
+	accessReviewSigningKey := os.Getenv("ACCESS_REVIEW_SIGNING_KEY")
+	if accessReviewSigningKey == "" {
+		return nil, fmt.Errorf("ACCESS_REVIEW_SIGNING_KEY environment variable is required")
+	}
+	if seed, err := base64.StdEncoding.DecodeString(accessReviewSigningKey); err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid ACCESS_REVIEW_SIGNING_KEY: must be a base64 %d-byte Ed25519 seed", ed25519.SeedSize)
+	}
+	
+	securityEventSink := getEnv("SECURITY_EVENT_SINK", "log")
+	if securityEventSink != "log" && securityEventSink != "siem" {
+		return nil, fmt.Errorf("invalid SECURITY_EVENT_SINK: %s", securityEventSink)
+	}
+	
+	rateLimitLogin, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_LOGIN", "10/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_LOGIN: %v", err)
+	}
+	
+	rateLimitPublic, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_PUBLIC", "60/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_PUBLIC: %v", err)
+	}
+	
+	rateLimitAPI, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_API", "600/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_API: %v", err)
+	}
+	
+	shutdownTimeout, err := strconv.Atoi(getEnv("SHUTDOWN_TIMEOUT", "30")) // 30 seconds default
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %v", err)
+	}
+	
+	shutdownDelay, err := strconv.Atoi(getEnv("SHUTDOWN_DELAY", "5")) // 5 seconds default
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_DELAY: %v", err)
+	}
+	if shutdownDelay < 0 || shutdownDelay >= shutdownTimeout {
+		return nil, fmt.Errorf("SHUTDOWN_DELAY must be at least 0 and less than SHUTDOWN_TIMEOUT")
+	}
+	
+	var trustedProxies []string
+	if proxies := getEnv("TRUSTED_PROXIES", ""); proxies != "" {
+		for _, proxy := range strings.Split(proxies, ",") {
+			trustedProxies = append(trustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	
+	tlsMode, err := mtls.ParseMode(getEnv("TLS_MODE", mtls.ModeOff))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS_MODE: %v", err)
+	}
+	
+	tlsCARootFile := os.Getenv("TLS_CA_ROOT_FILE")
+	tlsBootstrapToken := os.Getenv("TLS_BOOTSTRAP_TOKEN")
+	if tlsMode != mtls.ModeOff && (tlsCARootFile == "" || tlsBootstrapToken == "") {
+		return nil, fmt.Errorf("TLS_CA_ROOT_FILE and TLS_BOOTSTRAP_TOKEN are required when TLS_MODE is %s", tlsMode)
+	}
+	
+	notifier := getEnv("NOTIFIER", "log")
+	if notifier != "log" && notifier != "smtp" {
+		return nil, fmt.Errorf("invalid NOTIFIER: %s", notifier)
+	}
+	
+	smtpPort, err := strconv.Atoi(getEnv("SMTP_PORT", "587"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP_PORT: %v", err)
+	}
+	
 	return &Config{
 		Port:              port,
+		GRPCPort:          grpcPort,
 		JWTSecret:         jwtSecret,
 		RefreshTokenTTL:   refreshTokenTTL,
 		AccessTokenTTL:    accessTokenTTL,
@@ -59,6 +324,66 @@ func Load() (*Config, error) {
 		SAMLEntityID:      os.Getenv("SAML_ENTITY_ID"),
 		VaultAddr:         os.Getenv("VAULT_ADDR"),
 		VaultToken:        os.Getenv("VAULT_TOKEN"),
+
+		BootstrapAdminUsername: bootstrapAdminUsername,
+		BootstrapAdminEmail:    bootstrapAdminEmail,
+		BootstrapAdminPassword: bootstrapAdminPassword,
+
+		ElevationMaxDuration:       elevationMaxDuration,
+		ElevationRequiredApprovals: elevationApprovals,
+
+		ImpersonationTokenTTL: impersonationTTL,
+
+		RiskScorer:          riskScorer,
+		RiskServiceURL:      getEnv("RISK_SERVICE_URL", "http://localhost:5002"),
+		RiskServiceTimeout:  riskServiceTimeout,
+		RiskStepUpThreshold: riskStepUpThreshold,
+		RiskBlockThreshold:  riskBlockThreshold,
+
+		StepUpMaxAge: stepUpMaxAge,
+
+		DPoPRequiredClients: dpopRequiredClients,
+		DPoPProofMaxAge:     dpopProofMaxAge,
+
+		PasswordlessTTL:        passwordlessTTL,
+		PasswordlessRateLimit:  passwordlessRateLimit,
+		PasswordlessRateWindow: passwordlessRateWindow,
+		MagicLinkURL:           getEnv("MAGIC_LINK_URL", "http://localhost:3000/login/magic"),
+
+		DeviceCodeTTL:         deviceCodeTTL,
+		DevicePollInterval:    devicePollInterval,
+		DeviceVerificationURL: getEnv("DEVICE_VERIFICATION_URL", "http://localhost:3000/device"),
+
+		UserDeletionGracePeriod: userDeletionGracePeriod,
+
+		BreakGlassSessionTTL: breakGlassSessionTTL,
+
+		AccessReviewDuration:   accessReviewDuration,
+		AccessReviewSigningKey: accessReviewSigningKey,
+
+		SecurityEventSink: securityEventSink,
+		AuditServiceURL:   getEnv("AUDIT_SERVICE_URL", "http://localhost:8083"),
+
+		RateLimitLogin:  rateLimitLogin,
+		RateLimitPublic: rateLimitPublic,
+		RateLimitAPI:    rateLimitAPI,
+		TrustedProxies:  trustedProxies,
+
+		TLSMode:           tlsMode,
+		TLSTrustDomain:    getEnv("TLS_TRUST_DOMAIN", mtls.DefaultTrustDomain),
+		TLSCAURL:          getEnv("TLS_CA_URL", "https://localhost:8082"),
+		TLSCARootFile:     tlsCARootFile,
+		TLSBootstrapToken: tlsBootstrapToken,
+
+		ShutdownTimeout: shutdownTimeout,
+		ShutdownDelay:   shutdownDelay,
+
+		Notifier:     notifier,
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     smtpPort,
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:     getEnv("SMTP_FROM", "no-reply@cryptofortress.local"),
 	}, nil
 }
 
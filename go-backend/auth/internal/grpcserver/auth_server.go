@@ -0,0 +1,300 @@
+// Package grpcserver serves the authentication service's gRPC API, defined in proto/auth.proto,
+// on top of the same services used by the HTTP handlers.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/grpcauth"
+	"github.com/cryptofortress/backend/auth/internal/handlers"
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/cryptofortress/backend/auth/proto/authpb"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// publicMethods can be called without an access token
+var publicMethods = []string{
+	authpb.AuthService_Login_FullMethodName,
+	authpb.AuthService_Refresh_FullMethodName,
+	authpb.AuthService_Validate_FullMethodName,
+}
+
+// authServer implements the authpb.AuthServiceServer interface
+type authServer struct {
+	authpb.UnimplementedAuthServiceServer
+	services *services.Services
+}
+
+// New creates a gRPC server exposing the authentication service. Every method except
+// Login, Refresh and Validate requires a bearer token in the "authorization" metadata.
+// Extra options, such as transport credentials, are passed to the server.
+func New(svcs *services.Services, opts ...grpc.ServerOption) *grpc.Server {
+	validator := &localValidator{services: svcs}
+	server := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(logging, grpcauth.UnaryServerInterceptor(validator, publicMethods...)),
+		grpc.ChainStreamInterceptor(grpcauth.StreamServerInterceptor(validator, publicMethods...)),
+	}, opts...)...)
+	authpb.RegisterAuthServiceServer(server, &authServer{services: svcs})
+	return server
+}
+
+// Login authenticates a user with a password and optional MFA code
+func (s *authServer) Login(ctx context.Context, req *authpb.LoginRequest) (*authpb.LoginResponse, error) {
+	if req.GetUsername() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "username and password are required")
+	}
+
+	// gRPC has no way to carry a DPoP proof for the token endpoint
+	if s.services.DPoP.RequiresDPoP(req.GetClientId()) {
+		return nil, status.Error(codes.FailedPrecondition, "client must obtain DPoP-bound tokens over HTTP")
+	}
+
+	result, err := s.services.Login.PasswordLogin(services.PasswordLoginRequest{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+		MFACode:  req.GetMfaCode(),
+		Attempt:  loginAttempt(ctx, req),
+		Binding:  services.TokenBinding{ClientID: req.GetClientId()},
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, services.ErrUserDisabled):
+		return nil, status.Error(codes.PermissionDenied, "account disabled")
+	case errors.Is(err, services.ErrInvalidCredentials):
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	case errors.Is(err, services.ErrInvalidMFACode):
+		return nil, status.Error(codes.Unauthenticated, "invalid MFA code")
+	case errors.Is(err, services.ErrLoginBlocked):
+		return nil, status.Error(codes.PermissionDenied, "login_blocked")
+	case errors.Is(err, services.ErrMFARequired):
+		return nil, status.Error(codes.Unauthenticated, "mfa_required")
+	default:
+		log.Error().Err(err).Msg("gRPC login failed")
+		return nil, status.Error(codes.Internal, "login failed")
+	}
+
+	return &authpb.LoginResponse{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		TokenType:    "Bearer",
+		UserId:       result.User.ID,
+		Username:     result.User.Username,
+	}, nil
+}
+
+// Refresh issues a new access token from a refresh token
+func (s *authServer) Refresh(ctx context.Context, req *authpb.RefreshRequest) (*authpb.RefreshResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh token is required")
+	}
+	if s.services.DPoP.RequiresDPoP(req.GetClientId()) {
+		return nil, status.Error(codes.FailedPrecondition, "client must obtain DPoP-bound tokens over HTTP")
+	}
+
+	claims, err := s.services.Auth.ValidateRefreshToken(req.GetRefreshToken())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+	}
+
+	accessToken, err := s.services.Auth.GenerateAccessToken(claims.UserID, claims.Roles, services.TokenBinding{ClientID: req.GetClientId()})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to generate access token")
+	}
+
+	return &authpb.RefreshResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+	}, nil
+}
+
+// Validate checks an access token and returns its claims. DPoP-bound tokens are only
+// accepted together with a proof for the HTTP request being authorized. When a permission
+// is given, one of the token's roles must grant it.
+func (s *authServer) Validate(ctx context.Context, req *authpb.ValidateRequest) (*authpb.ValidateResponse, error) {
+	claims, err := validateToken(s.services, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if permission := req.GetPermission(); permission != "" {
+		allowed, err := s.services.RBAC.RolesGrant(claims.Roles, permission)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check permission")
+		}
+		if !allowed {
+			return nil, status.Errorf(codes.PermissionDenied, "token does not grant %s", permission)
+		}
+	}
+
+	return &authpb.ValidateResponse{Token: tokenInfo(claims)}, nil
+}
+
+// CheckPermission reports whether a user holds a permission
+func (s *authServer) CheckPermission(ctx context.Context, req *authpb.CheckPermissionRequest) (*authpb.CheckPermissionResponse, error) {
+	if req.GetUserId() == "" || req.GetPermission() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user ID and permission are required")
+	}
+
+	if err := s.authorizeUserQuery(ctx, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	allowed, err := s.services.RBAC.CheckPermission(req.GetUserId(), req.GetPermission())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check permission")
+	}
+
+	return &authpb.CheckPermissionResponse{Allowed: allowed}, nil
+}
+
+// GetUserRoles returns the roles assigned to a user
+func (s *authServer) GetUserRoles(ctx context.Context, req *authpb.GetUserRolesRequest) (*authpb.GetUserRolesResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user ID is required")
+	}
+
+	if err := s.authorizeUserQuery(ctx, req.GetUserId()); err != nil {
+		return nil, err
+	}
+
+	roles, err := s.services.RBAC.GetUserRoles(req.GetUserId())
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get user roles")
+	}
+
+	return &authpb.GetUserRolesResponse{Roles: roles}, nil
+}
+
+// authorizeUserQuery allows callers to look up their own roles and permissions. Looking up
+// another user's needs a token granting manage:roles, like the HTTP API.
+func (s *authServer) authorizeUserQuery(ctx context.Context, userID string) error {
+	info, ok := grpcauth.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "access token is required")
+	}
+	if info.GetUserId() == userID {
+		return nil
+	}
+
+	allowed, err := s.services.RBAC.RolesGrant(info.GetRoles(), handlers.PermissionManageRoles)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to check permission")
+	}
+	if !allowed {
+		return status.Error(codes.PermissionDenied, "looking up another user requires "+handlers.PermissionManageRoles)
+	}
+	return nil
+}
+
+// localValidator validates tokens in-process for the auth service's own interceptors
+type localValidator struct {
+	services *services.Services
+}
+
+// ValidateToken validates a bearer access token
+func (v *localValidator) ValidateToken(ctx context.Context, accessToken string) (*authpb.TokenInfo, error) {
+	claims, err := validateToken(v.services, &authpb.ValidateRequest{AccessToken: accessToken})
+	if err != nil {
+		return nil, err
+	}
+	return tokenInfo(claims), nil
+}
+
+// validateToken checks an access token and its sender constraint, mirroring the HTTP AuthMiddleware
+func validateToken(svcs *services.Services, req *authpb.ValidateRequest) (*services.TokenClaims, error) {
+	if req.GetAccessToken() == "" {
+		return nil, status.Error(codes.InvalidArgument, "access token is required")
+	}
+
+	claims, err := svcs.Auth.ValidateAccessToken(req.GetAccessToken())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired token")
+	}
+
+	if claims.Confirmation != nil {
+		if req.GetDpopProof() == "" {
+			return nil, status.Error(codes.Unauthenticated, "DPoP-bound token requires a DPoP proof")
+		}
+		jkt, err := svcs.DPoP.VerifyProof(req.GetDpopProof(), req.GetHttpMethod(), req.GetHttpUrl(), req.GetAccessToken())
+		if err == nil && jkt != claims.Confirmation.JKT {
+			err = errors.New("proof key does not match token binding")
+		}
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+	} else if svcs.DPoP.RequiresDPoP(claims.ClientID) {
+		return nil, status.Error(codes.Unauthenticated, "client must use DPoP-bound tokens")
+	}
+
+	return claims, nil
+}
+
+// tokenInfo converts validated claims into their protobuf representation
+func tokenInfo(claims *services.TokenClaims) *authpb.TokenInfo {
+	info := &authpb.TokenInfo{
+		UserId:      claims.UserID,
+		Username:    claims.Username,
+		Roles:       claims.Roles,
+		ElevationId: claims.ElevationID,
+		ClientId:    claims.ClientID,
+		Acr:         claims.ACR,
+		Amr:         claims.AMR,
+		AuthTime:    claims.AuthTime,
+		ExpiresAt:   claims.ExpiresAt,
+	}
+	if claims.Actor != nil {
+		info.ActorId = claims.Actor.Subject
+	}
+	if claims.Confirmation != nil {
+		info.Jkt = claims.Confirmation.JKT
+	}
+	return info
+}
+
+// loginAttempt collects the risk-relevant context of a gRPC login
+func loginAttempt(ctx context.Context, req *authpb.LoginRequest) services.LoginAttempt {
+	attempt := services.LoginAttempt{
+		Username:  req.GetUsername(),
+		DeviceID:  req.GetDeviceId(),
+		Timestamp: time.Now(),
+	}
+
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			attempt.IPAddress = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if userAgent := md.Get("user-agent"); len(userAgent) > 0 {
+			attempt.UserAgent = userAgent[0]
+		}
+	}
+
+	return attempt
+}
+
+// logging logs every unary call with its outcome and duration
+func logging(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	event := log.Info()
+	if err != nil {
+		event = log.Warn()
+	}
+	event.
+		Str("method", info.FullMethod).
+		Str("code", status.Code(err).String()).
+		Dur("latency", time.Since(start)).
+		Msg("gRPC request")
+
+	return resp, err
+}
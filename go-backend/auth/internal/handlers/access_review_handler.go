@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionManageAccessReviews is required to start and close access review campaigns
+// and to export their evidence
+const PermissionManageAccessReviews = "manage:access-reviews"
+
+// AccessReviewHandler handles access review campaign HTTP requests
+type AccessReviewHandler struct {
+	accessReviewService services.AccessReviewService
+	rbacService         services.RBACService
+}
+
+// NewAccessReviewHandler creates a new access review handler
+func NewAccessReviewHandler(accessReviewService services.AccessReviewService, rbacService services.RBACService) *AccessReviewHandler {
+	return &AccessReviewHandler{
+		accessReviewService: accessReviewService,
+		rbacService:         rbacService,
+	}
+}
+
+// CreateCampaignRequest represents the access review campaign creation payload
+type CreateCampaignRequest struct {
+	Name             string    `json:"name" binding:"required"`
+	Roles            []string  `json:"roles,omitempty"`
+	UserIDs          []string  `json:"user_ids,omitempty"`
+	Reviewers        []string  `json:"reviewers" binding:"required,min=1"`
+	DueAt            time.Time `json:"due_at,omitempty"`
+	RevokeUnreviewed bool      `json:"revoke_unreviewed"`
+}
+
+// CampaignRequest represents a request that targets an access review campaign
+type CampaignRequest struct {
+	CampaignID string `json:"campaign_id" binding:"required"`
+}
+
+// DecideItemRequest represents a reviewer's decision on an access review item
+type DecideItemRequest struct {
+	CampaignID string                        `json:"campaign_id" binding:"required"`
+	ItemID     string                        `json:"item_id" binding:"required"`
+	Decision   services.AccessReviewDecision `json:"decision" binding:"required,oneof=approve revoke"`
+	Comment    string                        `json:"comment,omitempty"`
+}
+
+// CreateCampaign handles starting an access review campaign
+func (h *AccessReviewHandler) CreateCampaign(c *gin.Context) {
+	var req CreateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if !requirePermission(c, h.rbacService, PermissionManageAccessReviews) {
+		return
+	}
+
+	scope := services.AccessReviewScope{Roles: req.Roles, UserIDs: req.UserIDs}
+	campaign, err := h.accessReviewService.CreateCampaign(req.Name, scope, req.Reviewers, req.DueAt, req.RevokeUnreviewed, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// ListCampaigns handles listing access review campaigns
+func (h *AccessReviewHandler) ListCampaigns(c *gin.Context) {
+	if !requirePermission(c, h.rbacService, PermissionManageAccessReviews) {
+		return
+	}
+
+	campaigns, err := h.accessReviewService.ListCampaigns()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list access review campaigns"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+// GetCampaign handles retrieving a campaign with all of its items
+func (h *AccessReviewHandler) GetCampaign(c *gin.Context) {
+	var req CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !requirePermission(c, h.rbacService, PermissionManageAccessReviews) {
+		return
+	}
+
+	campaign, err := h.accessReviewService.GetCampaign(req.CampaignID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, campaign)
+}
+
+// ListMyItems handles listing the open items assigned to the current reviewer
+func (h *AccessReviewHandler) ListMyItems(c *gin.Context) {
+	items, err := h.accessReviewService.ListReviewerItems(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list access review items"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// DecideItem handles a reviewer approving or revoking an item assigned to them
+func (h *AccessReviewHandler) DecideItem(c *gin.Context) {
+	var req DecideItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := h.accessReviewService.DecideItem(req.CampaignID, req.ItemID, c.GetString("userID"), req.Decision, req.Comment)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, item)
+}
+
+// CloseCampaign handles closing a campaign, which removes revoked assignments
+func (h *AccessReviewHandler) CloseCampaign(c *gin.Context) {
+	var req CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if !requirePermission(c, h.rbacService, PermissionManageAccessReviews) {
+		return
+	}
+
+	campaign, err := h.accessReviewService.CloseCampaign(req.CampaignID, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, campaign)
+}
+
+// ExportEvidence handles exporting the signed evidence record of a closed campaign
+func (h *AccessReviewHandler) ExportEvidence(c *gin.Context) {
+	var req CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !requirePermission(c, h.rbacService, PermissionManageAccessReviews) {
+		return
+	}
+
+	evidence, err := h.accessReviewService.ExportEvidence(req.CampaignID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Return response
+	c.Header("Content-Disposition", `attachment; filename="access-review-`+evidence.CampaignID+`.json"`)
+	c.JSON(http.StatusOK, evidence)
+}
+
+// EvidenceKey handles publishing the public key that verifies evidence records
+func (h *AccessReviewHandler) EvidenceKey(c *gin.Context) {
+	// Return response
+	c.JSON(http.StatusOK, gin.H{"keys": []services.AccessReviewEvidenceKey{h.accessReviewService.EvidenceKey()}})
+}
+
+// respondError maps access review errors to HTTP responses
+func (h *AccessReviewHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAccessReviewNotFound), errors.Is(err, services.ErrAccessReviewItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAccessReviewNotReviewer):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAccessReviewClosed), errors.Is(err, services.ErrAccessReviewOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -1,21 +1,39 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/cryptofortress/backend/auth/internal/middleware"
 	"github.com/cryptofortress/backend/auth/internal/services"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Headers set by the trusted edge proxy describing the client's network and location
+const (
+	headerClientASN       = "X-Client-ASN"
+	headerClientLatitude  = "X-Client-Latitude"
+	headerClientLongitude = "X-Client-Longitude"
 )
 
 // AuthHandler handles authentication-related HTTP requests
 type AuthHandler struct {
-	authService services.AuthService
+	authService  services.AuthService
+	mfaService   services.MFAService
+	loginService services.LoginService
+	dpopService  services.DPoPService
 }
 
 // NewAuthHandler creates a new authentication handler
-func NewAuthHandler(authService services.AuthService) *AuthHandler {
+func NewAuthHandler(authService services.AuthService, mfaService services.MFAService, loginService services.LoginService, dpopService services.DPoPService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:  authService,
+		mfaService:   mfaService,
+		loginService: loginService,
+		dpopService:  dpopService,
 	}
 }
 
@@ -23,11 +41,22 @@ func NewAuthHandler(authService services.AuthService) *AuthHandler {
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	DeviceID string `json:"device_id,omitempty"`
+	MFACode  string `json:"mfa_code,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+}
+
+// LoginChallengeResponse is returned when a login needs step-up MFA or is blocked
+type LoginChallengeResponse struct {
+	Error     string                `json:"error"`
+	RiskScore float64               `json:"risk_score"`
+	Decision  services.RiskDecision `json:"decision"`
 }
 
 // LoginResponse represents the login response payload
 type LoginResponse struct {
 	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
 	RefreshToken string `json:"refresh_token"`
 	UserID       string `json:"user_id"`
 	Username     string `json:"username"`
@@ -41,38 +70,113 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Authenticate user
-	user, err := h.authService.AuthenticateUser(req.Username, req.Password)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+	// Bind the tokens to the client's DPoP key before any credentials are checked
+	binding, ok := tokenBinding(c, h.dpopService, req.ClientID)
+	if !ok {
 		return
 	}
 
-	// Generate tokens
-	accessToken, err := h.authService.GenerateAccessToken(user.ID, user.Roles)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+	result, err := h.loginService.PasswordLogin(services.PasswordLoginRequest{
+		Username: req.Username,
+		Password: req.Password,
+		MFACode:  req.MFACode,
+		Attempt:  loginAttempt(c, req),
+		Binding:  binding,
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, services.ErrUserDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
 		return
-	}
-
-	refreshToken, err := h.authService.GenerateRefreshToken(user.ID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	case errors.Is(err, services.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid MFA code"})
+		return
+	case errors.Is(err, services.ErrLoginBlocked):
+		c.JSON(http.StatusForbidden, LoginChallengeResponse{
+			Error:     "login_blocked",
+			RiskScore: result.Assessment.Score,
+			Decision:  result.Assessment.Decision,
+		})
+		return
+	case errors.Is(err, services.ErrMFARequired):
+		c.JSON(http.StatusUnauthorized, LoginChallengeResponse{
+			Error:     "mfa_required",
+			RiskScore: result.Assessment.Score,
+			Decision:  result.Assessment.Decision,
+		})
+		return
+	default:
+		log.Error().Err(err).Msg("Login failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Login failed"})
 		return
 	}
 
 	// Return response
 	c.JSON(http.StatusOK, LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		UserID:       user.ID,
-		Username:     user.Username,
+		AccessToken:  result.AccessToken,
+		TokenType:    tokenType(binding),
+		RefreshToken: result.RefreshToken,
+		UserID:       result.User.ID,
+		Username:     result.User.Username,
 	})
 }
 
+// loginAttempt collects the risk-relevant context of a login request
+func loginAttempt(c *gin.Context, req LoginRequest) services.LoginAttempt {
+	attempt := services.LoginAttempt{
+		Username:  req.Username,
+		IPAddress: c.ClientIP(),
+		ASN:       c.GetHeader(headerClientASN),
+		DeviceID:  req.DeviceID,
+		UserAgent: c.Request.UserAgent(),
+		Timestamp: time.Now(),
+	}
+
+	lat, latErr := strconv.ParseFloat(c.GetHeader(headerClientLatitude), 64)
+	lon, lonErr := strconv.ParseFloat(c.GetHeader(headerClientLongitude), 64)
+	if latErr == nil && lonErr == nil {
+		attempt.Latitude = &lat
+		attempt.Longitude = &lon
+	}
+
+	return attempt
+}
+
+// tokenBinding verifies an optional DPoP proof at a token endpoint. It writes an error
+// response and returns false when the proof is invalid or the client requires DPoP but sent none.
+func tokenBinding(c *gin.Context, dpopService services.DPoPService, clientID string) (services.TokenBinding, bool) {
+	binding := services.TokenBinding{ClientID: clientID}
+
+	jkt, err := middleware.VerifyDPoP(c, dpopService, "")
+	if err != nil {
+		log.Warn().Err(err).Str("client_id", clientID).Msg("DPoP proof rejected at token endpoint")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_dpop_proof", "error_description": err.Error()})
+		return binding, false
+	}
+	if jkt == "" && dpopService.RequiresDPoP(clientID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_dpop_proof", "error_description": "client must present a DPoP proof"})
+		return binding, false
+	}
+
+	binding.JKT = jkt
+	return binding, true
+}
+
+// tokenType returns the OAuth token_type for a binding
+func tokenType(binding services.TokenBinding) string {
+	if binding.JKT != "" {
+		return "DPoP"
+	}
+	return "Bearer"
+}
+
 // RefreshRequest represents the refresh token request payload
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
+	ClientID     string `json:"client_id,omitempty"`
 }
 
 // Refresh handles token refresh requests
@@ -90,13 +194,70 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 		return
 	}
 
+	binding, ok := tokenBinding(c, h.dpopService, req.ClientID)
+	if !ok {
+		return
+	}
+
 	// Generate new access token
-	accessToken, err := h.authService.GenerateAccessToken(claims.UserID, claims.Roles)
+	accessToken, err := h.authService.GenerateAccessToken(claims.UserID, claims.Roles, binding)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
 		return
 	}
 
+	// Return response
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": accessToken,
+		"token_type":   tokenType(binding),
+	})
+}
+
+// ReauthenticateRequest represents the re-authentication request payload
+type ReauthenticateRequest struct {
+	Password string `json:"password" binding:"required"`
+	MFACode  string `json:"mfa_code" binding:"required"`
+}
+
+// Reauthenticate handles upgrading the current access token after a fresh
+// password and MFA check, satisfying step-up authentication challenges
+func (h *AuthHandler) Reauthenticate(c *gin.Context) {
+	var req ReauthenticateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	value, _ := c.Get("claims")
+	claims, ok := value.(*services.TokenClaims)
+	if !ok || claims.Username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token does not identify a user to re-authenticate"})
+		return
+	}
+
+	user, err := h.authService.AuthenticateUser(claims.Username, req.Password)
+	if err != nil || user.ID != claims.UserID {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	if !h.mfaService.VerifyTOTP(claims.UserID, req.MFACode) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid MFA code"})
+		return
+	}
+
+	accessToken, err := h.authService.UpgradeAccessToken(claims, services.AuthContext{
+		AuthTime: time.Now(),
+		ACR:      services.ACRMultiFactor,
+		AMR:      []string{"pwd", "otp"},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upgrade access token"})
+		return
+	}
+
+	log.Info().Str("user_id", claims.UserID).Msg("User re-authenticated for step-up")
+
 	// Return response
 	c.JSON(http.StatusOK, gin.H{
 		"access_token": accessToken,
@@ -120,6 +281,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 
 	// Register user
 	user, err := h.authService.RegisterUser(req.Username, req.Email, req.Password)
+	if errors.Is(err, services.ErrUserExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
 		return
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionManageBreakGlass is required to create, rotate and end break-glass accounts and sessions
+const PermissionManageBreakGlass = "manage:break-glass"
+
+// BreakGlassHandler handles break-glass emergency access HTTP requests
+type BreakGlassHandler struct {
+	breakGlassService services.BreakGlassService
+	rbacService       services.RBACService
+}
+
+// NewBreakGlassHandler creates a new break-glass handler
+func NewBreakGlassHandler(breakGlassService services.BreakGlassService, rbacService services.RBACService) *BreakGlassHandler {
+	return &BreakGlassHandler{
+		breakGlassService: breakGlassService,
+		rbacService:       rbacService,
+	}
+}
+
+// CreateBreakGlassAccountRequest represents the break-glass account creation payload
+type CreateBreakGlassAccountRequest struct {
+	Name       string   `json:"name" binding:"required"`
+	Roles      []string `json:"roles" binding:"required,min=1"`
+	Custodians []string `json:"custodians" binding:"required,min=1,dive,email"`
+	Threshold  int      `json:"threshold" binding:"required,min=1"`
+}
+
+// ActivateBreakGlassRequest represents the break-glass activation payload
+type ActivateBreakGlassRequest struct {
+	Account       string   `json:"account" binding:"required"`
+	Shares        []string `json:"shares" binding:"required,min=1"`
+	Justification string   `json:"justification" binding:"required,min=20"`
+}
+
+// ActivateBreakGlassResponse represents the break-glass activation response payload
+type ActivateBreakGlassResponse struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	SessionID   string    `json:"session_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// BreakGlassAccountRequest represents a request that targets a break-glass account
+type BreakGlassAccountRequest struct {
+	AccountID string `json:"account_id" binding:"required"`
+}
+
+// BreakGlassSessionRequest represents a request that targets a break-glass session
+type BreakGlassSessionRequest struct {
+	SessionID string `json:"session_id" binding:"required"`
+}
+
+// Activate handles opening an emergency session with a quorum of credential shares.
+// It is public so that it keeps working when the identity providers are down.
+func (h *BreakGlassHandler) Activate(c *gin.Context) {
+	var req ActivateBreakGlassRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, accessToken, err := h.breakGlassService.Activate(req.Account, req.Shares, req.Justification, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBreakGlassAccountNotFound), errors.Is(err, services.ErrBreakGlassInvalidShares):
+			// Do not reveal which account names exist
+			c.JSON(http.StatusUnauthorized, gin.H{"error": services.ErrBreakGlassInvalidShares.Error()})
+		case errors.Is(err, services.ErrBreakGlassSessionActive):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate break-glass account"})
+		}
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, ActivateBreakGlassResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		SessionID:   session.ID,
+		ExpiresAt:   session.ExpiresAt,
+	})
+}
+
+// CreateAccount handles creating a break-glass account
+func (h *BreakGlassHandler) CreateAccount(c *gin.Context) {
+	var req CreateBreakGlassAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if !requirePermission(c, h.rbacService, PermissionManageBreakGlass) {
+		return
+	}
+
+	account, err := h.breakGlassService.CreateAccount(req.Name, req.Roles, req.Custodians, req.Threshold, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusCreated, account)
+}
+
+// ListAccounts handles listing break-glass accounts
+func (h *BreakGlassHandler) ListAccounts(c *gin.Context) {
+	if !requirePermission(c, h.rbacService, PermissionManageBreakGlass) {
+		return
+	}
+
+	accounts, err := h.breakGlassService.ListAccounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list break-glass accounts"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts})
+}
+
+// RotateCredential handles replacing a break-glass account's credential
+func (h *BreakGlassHandler) RotateCredential(c *gin.Context) {
+	var req BreakGlassAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if !requirePermission(c, h.rbacService, PermissionManageBreakGlass) {
+		return
+	}
+
+	account, err := h.breakGlassService.RotateCredential(req.AccountID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrBreakGlassAccountNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate break-glass credential"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, account)
+}
+
+// EndSession handles closing a break-glass session, either from the session itself
+// or by an administrator
+func (h *BreakGlassHandler) EndSession(c *gin.Context) {
+	var req BreakGlassSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	session, err := h.breakGlassService.GetSession(req.SessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if session.UserID != userID && !requirePermission(c, h.rbacService, PermissionManageBreakGlass) {
+		return
+	}
+
+	session, err = h.breakGlassService.EndSession(req.SessionID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrBreakGlassSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end break-glass session"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, session)
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// DeviceHandler handles OAuth 2.0 device authorization grant (RFC 8628) HTTP requests
+type DeviceHandler struct {
+	deviceService   services.DeviceAuthorizationService
+	authService     services.AuthService
+	dpopService     services.DPoPService
+	verificationURL string
+}
+
+// NewDeviceHandler creates a new device authorization handler
+func NewDeviceHandler(deviceService services.DeviceAuthorizationService, authService services.AuthService, dpopService services.DPoPService, verificationURL string) *DeviceHandler {
+	return &DeviceHandler{
+		deviceService:   deviceService,
+		authService:     authService,
+		dpopService:     dpopService,
+		verificationURL: verificationURL,
+	}
+}
+
+// deviceCodeGrantType is the grant type a device polls the token endpoint with (RFC 8628 section 3.4)
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// DeviceCodeRequest represents the device authorization request payload. The scope is a
+// space-separated list of roles the device's tokens are limited to.
+type DeviceCodeRequest struct {
+	ClientID string `json:"client_id" form:"client_id" binding:"required"`
+	Scope    string `json:"scope,omitempty" form:"scope"`
+}
+
+// DeviceCodeResponse represents the device authorization response payload
+type DeviceCodeResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// RequestDeviceCode handles a device asking for a device code and user code. The request
+// is form encoded as in RFC 8628, or JSON.
+func (h *DeviceHandler) RequestDeviceCode(c *gin.Context) {
+	var req DeviceCodeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	auth, err := h.deviceService.RequestDeviceCode(req.ClientID, req.Scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create device code"})
+		return
+	}
+
+	userCode := services.FormatUserCode(auth.UserCode)
+
+	// Return response
+	c.JSON(http.StatusOK, DeviceCodeResponse{
+		DeviceCode:              auth.DeviceCode,
+		UserCode:                userCode,
+		VerificationURI:         h.verificationURL,
+		VerificationURIComplete: h.verificationURL + "?user_code=" + url.QueryEscape(userCode),
+		ExpiresIn:               int(time.Until(auth.ExpiresAt).Seconds()),
+		Interval:                int(auth.Interval.Seconds()),
+	})
+}
+
+// DeviceTokenRequest represents the form-encoded device access token request payload
+type DeviceTokenRequest struct {
+	GrantType  string `form:"grant_type" binding:"required"`
+	DeviceCode string `form:"device_code" binding:"required"`
+	ClientID   string `form:"client_id" binding:"required"`
+}
+
+// Token handles the OAuth 2.0 token endpoint, where a device polls for its tokens with the
+// device_code grant. Until the user decides, the response is an authorization_pending or
+// slow_down error.
+func (h *DeviceHandler) Token(c *gin.Context) {
+	// Token responses must never be cached (RFC 6749 section 5.1)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	if c.ContentType() != "application/x-www-form-urlencoded" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": "request must be form encoded"})
+		return
+	}
+	var req DeviceTokenRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+	if req.GrantType != deviceCodeGrantType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+
+	// Check the DPoP proof before the authorization is consumed
+	binding, ok := tokenBinding(c, h.dpopService, req.ClientID)
+	if !ok {
+		return
+	}
+
+	auth, err := h.deviceService.PollDeviceCode(req.DeviceCode, req.ClientID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAuthorizationPending), errors.Is(err, services.ErrSlowDown),
+			errors.Is(err, services.ErrAccessDenied), errors.Is(err, services.ErrExpiredToken),
+			errors.Is(err, services.ErrInvalidDeviceCode):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to poll device code"})
+		}
+		return
+	}
+
+	// The tokens carry only the user's roles named in the requested scope
+	binding.Scope = strings.Fields(auth.Scope)
+
+	user := &services.User{ID: auth.UserID, Username: auth.Username}
+	accessToken, err := h.authService.GenerateAuthenticatedAccessToken(user, services.AuthContext{
+		AuthTime: *auth.ApprovedAt,
+		ACR:      services.ACRDevice,
+	}, binding)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		return
+	}
+
+	refreshToken, err := h.authService.GenerateRefreshToken(user.ID, binding.Scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate refresh token"})
+		return
+	}
+
+	log.Info().
+		Str("user_id", user.ID).
+		Str("client_id", auth.ClientID).
+		Str("scope", auth.Scope).
+		Msg("Device authorization tokens issued")
+
+	// Return response
+	c.JSON(http.StatusOK, LoginResponse{
+		AccessToken:  accessToken,
+		TokenType:    tokenType(binding),
+		RefreshToken: refreshToken,
+		UserID:       user.ID,
+		Username:     user.Username,
+	})
+}
+
+// UserCodeRequest represents the user code verification payload
+type UserCodeRequest struct {
+	UserCode string `json:"user_code" binding:"required"`
+}
+
+// VerifyUserCode handles a signed-in user looking up the device request behind a user code
+func (h *DeviceHandler) VerifyUserCode(c *gin.Context) {
+	h.decide(c, func(userCode string) (*services.DeviceAuthorization, error) {
+		return h.deviceService.LookupUserCode(userCode)
+	}, "")
+}
+
+// ApproveUserCode handles a signed-in user approving a device request
+func (h *DeviceHandler) ApproveUserCode(c *gin.Context) {
+	h.decide(c, func(userCode string) (*services.DeviceAuthorization, error) {
+		return h.deviceService.ApproveUserCode(userCode, c.GetString("userID"), c.GetString("username"))
+	}, "Device authorization approved")
+}
+
+// DenyUserCode handles a signed-in user denying a device request
+func (h *DeviceHandler) DenyUserCode(c *gin.Context) {
+	h.decide(c, func(userCode string) (*services.DeviceAuthorization, error) {
+		return h.deviceService.DenyUserCode(userCode, c.GetString("userID"))
+	}, "Device authorization denied")
+}
+
+// decide runs a user-code operation and writes the resulting authorization
+func (h *DeviceHandler) decide(c *gin.Context, operation func(userCode string) (*services.DeviceAuthorization, error), logMessage string) {
+	var req UserCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	auth, err := operation(req.UserCode)
+	if err != nil {
+		if errors.Is(err, services.ErrUserCodeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process user code"})
+		return
+	}
+	auth.UserCode = services.FormatUserCode(auth.UserCode)
+
+	if logMessage != "" {
+		log.Info().
+			Str("user_id", c.GetString("userID")).
+			Str("client_id", auth.ClientID).
+			Str("status", string(auth.Status)).
+			Msg(logMessage)
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, auth)
+}
@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// PermissionApproveElevation is required to approve, deny or revoke another user's elevation
+const PermissionApproveElevation = "approve:elevation"
+
+// ElevationHandler handles just-in-time role elevation HTTP requests
+type ElevationHandler struct {
+	elevationService services.ElevationService
+	authService      services.AuthService
+	rbacService      services.RBACService
+}
+
+// NewElevationHandler creates a new elevation handler
+func NewElevationHandler(elevationService services.ElevationService, authService services.AuthService, rbacService services.RBACService) *ElevationHandler {
+	return &ElevationHandler{
+		elevationService: elevationService,
+		authService:      authService,
+		rbacService:      rbacService,
+	}
+}
+
+// RequestElevationRequest represents the elevation request payload
+type RequestElevationRequest struct {
+	RoleName        string `json:"role_name" binding:"required"`
+	Justification   string `json:"justification" binding:"required"`
+	DurationMinutes int    `json:"duration_minutes" binding:"required,min=1"`
+}
+
+// RequestElevation handles a user asking for a time-boxed role elevation
+func (h *ElevationHandler) RequestElevation(c *gin.Context) {
+	var req RequestElevationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The requester is always the authenticated caller
+	userID := c.GetString("userID")
+
+	elevation, err := h.elevationService.RequestElevation(userID, req.RoleName, req.Justification, time.Duration(req.DurationMinutes)*time.Minute)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRoleNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrRoleAlreadyHeld):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	log.Info().
+		Str("elevation_id", elevation.ID).
+		Str("user_id", userID).
+		Str("role", elevation.RoleName).
+		Str("justification", elevation.Justification).
+		Msg("Role elevation requested")
+
+	// Return response
+	c.JSON(http.StatusCreated, elevation)
+}
+
+// ElevationDecisionRequest represents the approve, deny and revoke request payload
+type ElevationDecisionRequest struct {
+	ElevationID string `json:"elevation_id" binding:"required"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// ApproveElevation handles an approver granting an elevation request
+func (h *ElevationHandler) ApproveElevation(c *gin.Context) {
+	var req ElevationDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	approverID := c.GetString("userID")
+	if !requirePermission(c, h.rbacService, PermissionApproveElevation) {
+		return
+	}
+
+	elevation, err := h.elevationService.ApproveElevation(req.ElevationID, approverID)
+	if err != nil {
+		h.respondError(c, err, "Failed to approve elevation")
+		return
+	}
+
+	log.Info().
+		Str("elevation_id", elevation.ID).
+		Str("approver_id", approverID).
+		Str("status", string(elevation.Status)).
+		Msg("Role elevation approved")
+
+	// Return response
+	c.JSON(http.StatusOK, elevation)
+}
+
+// DenyElevation handles an approver rejecting an elevation request
+func (h *ElevationHandler) DenyElevation(c *gin.Context) {
+	var req ElevationDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	approverID := c.GetString("userID")
+	if !requirePermission(c, h.rbacService, PermissionApproveElevation) {
+		return
+	}
+
+	elevation, err := h.elevationService.DenyElevation(req.ElevationID, approverID, req.Reason)
+	if err != nil {
+		h.respondError(c, err, "Failed to deny elevation")
+		return
+	}
+
+	log.Info().
+		Str("elevation_id", elevation.ID).
+		Str("approver_id", approverID).
+		Str("reason", req.Reason).
+		Msg("Role elevation denied")
+
+	// Return response
+	c.JSON(http.StatusOK, elevation)
+}
+
+// RevokeElevation handles ending an elevation early, either by its requester or an approver
+func (h *ElevationHandler) RevokeElevation(c *gin.Context) {
+	var req ElevationDecisionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	elevation, err := h.elevationService.GetElevation(req.ElevationID)
+	if err != nil {
+		h.respondError(c, err, "Failed to revoke elevation")
+		return
+	}
+	if elevation.UserID != userID && !requirePermission(c, h.rbacService, PermissionApproveElevation) {
+		return
+	}
+
+	elevation, err = h.elevationService.RevokeElevation(req.ElevationID, userID)
+	if err != nil {
+		h.respondError(c, err, "Failed to revoke elevation")
+		return
+	}
+
+	log.Info().
+		Str("elevation_id", elevation.ID).
+		Str("revoked_by", userID).
+		Msg("Role elevation revoked")
+
+	// Return response
+	c.JSON(http.StatusOK, elevation)
+}
+
+// GetElevationRequest represents the get elevation request payload
+type GetElevationRequest struct {
+	ElevationID string `json:"elevation_id" binding:"required"`
+}
+
+// GetElevation handles retrieving a single elevation
+func (h *ElevationHandler) GetElevation(c *gin.Context) {
+	var req GetElevationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	elevation, err := h.elevationService.GetElevation(req.ElevationID)
+	if err != nil {
+		h.respondError(c, err, "Failed to get elevation")
+		return
+	}
+	if elevation.UserID != userID && !requirePermission(c, h.rbacService, PermissionApproveElevation) {
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, elevation)
+}
+
+// ListPendingElevationsResponse represents the pending elevations response payload
+type ListPendingElevationsResponse struct {
+	Elevations []services.Elevation `json:"elevations"`
+}
+
+// ListPendingElevations handles listing elevation requests awaiting a decision
+func (h *ElevationHandler) ListPendingElevations(c *gin.Context) {
+	if !requirePermission(c, h.rbacService, PermissionApproveElevation) {
+		return
+	}
+
+	elevations, err := h.elevationService.ListPendingElevations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list pending elevations"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, ListPendingElevationsResponse{
+		Elevations: elevations,
+	})
+}
+
+// ElevatedTokenRequest represents the elevated token request payload
+type ElevatedTokenRequest struct {
+	ElevationID string `json:"elevation_id" binding:"required"`
+}
+
+// ElevatedTokenResponse represents the elevated token response payload
+type ElevatedTokenResponse struct {
+	AccessToken string    `json:"access_token"`
+	ElevationID string    `json:"elevation_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// IssueElevatedToken handles issuing an access token that carries an active elevation
+func (h *ElevationHandler) IssueElevatedToken(c *gin.Context) {
+	var req ElevatedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	elevation, err := h.elevationService.GetElevation(req.ElevationID)
+	if err != nil {
+		h.respondError(c, err, "Failed to issue elevated token")
+		return
+	}
+	if elevation.UserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Elevation belongs to another user"})
+		return
+	}
+	if elevation.Status != services.ElevationActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Elevation is " + string(elevation.Status)})
+		return
+	}
+
+	roles, err := h.rbacService.GetUserRoles(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user roles"})
+		return
+	}
+	roles = appendRole(roles, elevation.RoleName)
+
+	value, _ := c.Get("claims")
+	current, ok := value.(*services.TokenClaims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	accessToken, err := h.authService.GenerateElevatedAccessToken(current, roles, elevation.ID, *elevation.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate access token"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, ElevatedTokenResponse{
+		AccessToken: accessToken,
+		ElevationID: elevation.ID,
+		ExpiresAt:   *elevation.ExpiresAt,
+	})
+}
+
+// respondError maps elevation service errors to HTTP responses
+func (h *ElevationHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrElevationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrElevationNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSelfApproval):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// appendRole adds a role to a role list unless it is already present
+func appendRole(roles []string, role string) []string {
+	for _, existing := range roles {
+		if existing == role {
+			return roles
+		}
+	}
+	return append(roles, role)
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// PermissionImpersonateUsers is required to obtain an impersonation token
+const PermissionImpersonateUsers = "impersonate:users"
+
+// privilegedPermissions mark administrative accounts, which can never be impersonated
+var privilegedPermissions = []string{
+	PermissionManageRoles,
+	PermissionManageUsers,
+	PermissionImpersonateUsers,
+	PermissionApproveElevation,
+	PermissionManageBreakGlass,
+	PermissionManageAccessReviews,
+}
+
+// ImpersonationHandler handles admin impersonation HTTP requests
+type ImpersonationHandler struct {
+	authService services.AuthService
+	rbacService services.RBACService
+	tokenTTL    time.Duration
+}
+
+// NewImpersonationHandler creates a new impersonation handler
+func NewImpersonationHandler(authService services.AuthService, rbacService services.RBACService, tokenTTL time.Duration) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		authService: authService,
+		rbacService: rbacService,
+		tokenTTL:    tokenTTL,
+	}
+}
+
+// ImpersonateRequest represents the impersonation request payload
+type ImpersonateRequest struct {
+	TargetUserID string `json:"target_user_id" binding:"required"`
+	Reason       string `json:"reason" binding:"required"`
+}
+
+// ImpersonateResponse represents the impersonation response payload
+type ImpersonateResponse struct {
+	AccessToken  string    `json:"access_token"`
+	TargetUserID string    `json:"target_user_id"`
+	ActorID      string    `json:"actor_id"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// Impersonate handles issuing a short-lived token that acts as another user
+func (h *ImpersonationHandler) Impersonate(c *gin.Context) {
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actorID := c.GetString("userID")
+	if !requirePermission(c, h.rbacService, PermissionImpersonateUsers) {
+		return
+	}
+
+	target, err := h.authService.GetUser(req.TargetUserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target user not found"})
+		return
+	}
+	if target.Status != services.UserActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Target user is " + string(target.Status)})
+		return
+	}
+
+	roles, err := h.rbacService.GetUserRoles(req.TargetUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user roles"})
+		return
+	}
+
+	// The token carries the target's roles, so it must not give the actor anything new
+	reason, err := h.impersonationDenied(c.GetStringSlice("roles"), roles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+	if reason != "" {
+		log.Warn().
+			Str("actor_id", actorID).
+			Str("target_user_id", req.TargetUserID).
+			Str("reason", reason).
+			Msg("Impersonation refused")
+		c.JSON(http.StatusForbidden, gin.H{"error": reason})
+		return
+	}
+
+	accessToken, err := h.authService.GenerateImpersonationToken(actorID, req.TargetUserID, roles, req.Reason)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Warn().
+		Str("actor_id", actorID).
+		Str("target_user_id", req.TargetUserID).
+		Str("reason", req.Reason).
+		Str("client_ip", c.ClientIP()).
+		Msg("Impersonation token issued")
+
+	// Return response
+	c.JSON(http.StatusOK, ImpersonateResponse{
+		AccessToken:  accessToken,
+		TargetUserID: req.TargetUserID,
+		ActorID:      actorID,
+		ExpiresAt:    time.Now().Add(h.tokenTTL),
+	})
+}
+
+// impersonationDenied returns why an actor whose token carries actorRoles may not impersonate
+// a user with the given roles, or "" if they may. Privileged users cannot be impersonated at
+// all, and every permission of the target must already be held by the actor.
+func (h *ImpersonationHandler) impersonationDenied(actorRoles, targetRoles []string) (string, error) {
+	for _, role := range targetRoles {
+		permissions, err := h.rbacService.GetRolePermissions(role)
+		if err != nil {
+			return "", err
+		}
+		for _, permission := range permissions {
+			for _, privileged := range privilegedPermissions {
+				if permission == privileged {
+					return "Privileged users cannot be impersonated", nil
+				}
+			}
+
+			allowed, err := h.rbacService.RolesGrant(actorRoles, permission)
+			if err != nil {
+				return "", err
+			}
+			if !allowed {
+				return "Target user holds permissions the actor lacks", nil
+			}
+		}
+	}
+	return "", nil
+}
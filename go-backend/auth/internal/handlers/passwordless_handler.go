@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// passwordlessNonceCookie binds a passwordless login to the browser that started it
+const passwordlessNonceCookie = "cf_passwordless_nonce"
+
+// PasswordlessHandler handles magic-link and email one-time code login HTTP requests
+type PasswordlessHandler struct {
+	passwordlessService services.PasswordlessService
+	loginService        services.LoginService
+	dpopService         services.DPoPService
+	ttl                 time.Duration
+}
+
+// NewPasswordlessHandler creates a new passwordless login handler
+func NewPasswordlessHandler(passwordlessService services.PasswordlessService, loginService services.LoginService, dpopService services.DPoPService, ttl time.Duration) *PasswordlessHandler {
+	return &PasswordlessHandler{
+		passwordlessService: passwordlessService,
+		loginService:        loginService,
+		dpopService:         dpopService,
+		ttl:                 ttl,
+	}
+}
+
+// PasswordlessStartRequest represents the passwordless login start payload
+type PasswordlessStartRequest struct {
+	Email  string                      `json:"email" binding:"required,email"`
+	Method services.PasswordlessMethod `json:"method" binding:"required,oneof=magic_link code"`
+}
+
+// StartLogin handles sending a magic link or one-time code. The response is the same
+// whether or not the address belongs to an account.
+func (h *PasswordlessHandler) StartLogin(c *gin.Context) {
+	var req PasswordlessStartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	nonceBytes := make([]byte, 32)
+	if _, err := rand.Read(nonceBytes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passwordless login"})
+		return
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+
+	challenge, err := h.passwordlessService.StartLogin(req.Email, req.Method, nonce)
+	if err != nil {
+		if errors.Is(err, services.ErrPasswordlessRateLimited) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Err(err).Msg("Failed to start passwordless login")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passwordless login"})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(passwordlessNonceCookie, nonce, int(h.ttl.Seconds()), "/api/v1/auth/passwordless", "", true, true)
+
+	// Return response
+	c.JSON(http.StatusAccepted, challenge)
+}
+
+// PasswordlessVerifyRequest represents the passwordless login verification payload.
+// Either a magic-link token or a challenge ID and code must be given.
+type PasswordlessVerifyRequest struct {
+	Token       string `json:"token,omitempty"`
+	ChallengeID string `json:"challenge_id,omitempty"`
+	Code        string `json:"code,omitempty"`
+	MFACode     string `json:"mfa_code,omitempty"`
+	DeviceID    string `json:"device_id,omitempty"`
+	ClientID    string `json:"client_id,omitempty"`
+}
+
+// VerifyLogin handles exchanging a magic-link token or one-time code for tokens. Like a
+// password login, it is risk evaluated and may be blocked or require an MFA code.
+func (h *PasswordlessHandler) VerifyLogin(c *gin.Context) {
+	var req PasswordlessVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Token == "" && (req.ChallengeID == "" || req.Code == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token or challenge_id and code are required"})
+		return
+	}
+
+	binding, ok := tokenBinding(c, h.dpopService, req.ClientID)
+	if !ok {
+		return
+	}
+
+	nonce, _ := c.Cookie(passwordlessNonceCookie)
+
+	result, err := h.loginService.PasswordlessLogin(services.PasswordlessLoginRequest{
+		Token:        req.Token,
+		ChallengeID:  req.ChallengeID,
+		Code:         req.Code,
+		BrowserNonce: nonce,
+		MFACode:      req.MFACode,
+		Attempt:      loginAttempt(c, LoginRequest{DeviceID: req.DeviceID}),
+		Binding:      binding,
+	})
+
+	// The nonce is single-use along with the challenge
+	if !errors.Is(err, services.ErrPasswordlessInvalid) {
+		c.SetCookie(passwordlessNonceCookie, "", -1, "/api/v1/auth/passwordless", "", true, true)
+	}
+
+	switch {
+	case err == nil:
+	case errors.Is(err, services.ErrPasswordlessInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid MFA code"})
+		return
+	case errors.Is(err, services.ErrLoginBlocked):
+		c.JSON(http.StatusForbidden, LoginChallengeResponse{
+			Error:     "login_blocked",
+			RiskScore: result.Assessment.Score,
+			Decision:  result.Assessment.Decision,
+		})
+		return
+	case errors.Is(err, services.ErrMFARequired):
+		c.JSON(http.StatusUnauthorized, LoginChallengeResponse{
+			Error:     "mfa_required",
+			RiskScore: result.Assessment.Score,
+			Decision:  result.Assessment.Decision,
+		})
+		return
+	default:
+		log.Error().Err(err).Msg("Passwordless login failed")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Login failed"})
+		return
+	}
+
+	log.Info().Str("user_id", result.User.ID).Msg("Passwordless login succeeded")
+
+	// Return response
+	c.JSON(http.StatusOK, LoginResponse{
+		AccessToken:  result.AccessToken,
+		TokenType:    tokenType(binding),
+		RefreshToken: result.RefreshToken,
+		UserID:       result.User.ID,
+		Username:     result.User.Username,
+	})
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// requirePermission writes a 403 response and returns false unless one of the roles of
+// the caller's access token grants the permission. Elevated, scoped, break-glass and
+// impersonation tokens are judged by the roles they carry, not by every role of the user.
+func requirePermission(c *gin.Context, rbacService services.RBACService, permission string) bool {
+	allowed, err := rbacService.RolesGrant(c.GetStringSlice("roles"), permission)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permission"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+		return false
+	}
+	return true
+}
+
+// requireSelfOrPermission writes a 403 response and returns false unless the caller is the
+// user or holds the permission
+func requireSelfOrPermission(c *gin.Context, rbacService services.RBACService, userID, permission string) bool {
+	return c.GetString("userID") == userID || requirePermission(c, rbacService, permission)
+}
+
+// permissionRequired returns middleware that aborts the request unless the caller holds the permission
+func permissionRequired(rbacService services.RBACService, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requirePermission(c, rbacService, permission) {
+			c.Abort()
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// testServer is the service's router over in-memory services
+type testServer struct {
+	t        *testing.T
+	router   *gin.Engine
+	services *services.Services
+	mail     *recordingNotifier
+}
+
+// recordingNotifier keeps every email instead of sending it
+type recordingNotifier struct {
+	mu     sync.Mutex
+	bodies map[string][]string // recipient to message bodies
+}
+
+func (n *recordingNotifier) SendEmail(to, subject, body string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.bodies[to] = append(n.bodies[to], body)
+	return nil
+}
+
+// last returns the last message sent to a recipient
+func (n *recordingNotifier) last(to string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if bodies := n.bodies[to]; len(bodies) > 0 {
+		return bodies[len(bodies)-1]
+	}
+	return ""
+}
+
+// newTestServer registers every route with rate limits off
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{
+		JWTSecret:                  "test-secret",
+		AccessTokenTTL:             15,
+		RefreshTokenTTL:            24,
+		ElevationMaxDuration:       60,
+		ElevationRequiredApprovals: 1,
+		StepUpMaxAge:               5,
+		DeviceCodeTTL:              10,
+		DevicePollInterval:         5,
+		BreakGlassSessionTTL:       60,
+		AccessReviewSigningKey:     "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=",
+	}
+
+	rbacService := services.NewRBACService(cfg)
+	elevationService := services.NewElevationService(cfg, rbacService)
+	authService := services.NewAuthService(cfg, rbacService, elevationService)
+	mail := &recordingNotifier{bodies: make(map[string][]string)}
+	accessReviewService, err := services.NewAccessReviewService(cfg, rbacService)
+	if err != nil {
+		t.Fatalf("Failed to create access review service: %v", err)
+	}
+	svcs := &services.Services{
+		Auth:         authService,
+		MFA:          services.NewMFAService(cfg),
+		RBAC:         rbacService,
+		Elevation:    elevationService,
+		DPoP:         services.NewDPoPService(cfg),
+		Device:       services.NewDeviceAuthorizationService(cfg),
+		BreakGlass:   services.NewBreakGlassService(cfg, authService, mail, services.NewLogEventSink()),
+		AccessReview: accessReviewService,
+	}
+
+	router := gin.New()
+	RegisterRoutes(router, svcs, cfg, ratelimit.New(ratelimit.NewMemoryStore()))
+	return &testServer{t: t, router: router, services: svcs, mail: mail}
+}
+
+// user registers a user holding the given roles besides the default one
+func (s *testServer) user(username string, roles ...string) *services.User {
+	s.t.Helper()
+	user, err := s.services.Auth.RegisterUser(username, username+"@example.com", "correct horse battery staple")
+	if err != nil {
+		s.t.Fatalf("Failed to register %s: %v", username, err)
+	}
+	for _, role := range roles {
+		if err := s.services.RBAC.AssignRoleToUser(user.ID, role); err != nil {
+			s.t.Fatalf("Failed to assign %s to %s: %v", role, username, err)
+		}
+	}
+	return user
+}
+
+// token issues an access token after an MFA login
+func (s *testServer) token(user *services.User, binding services.TokenBinding) string {
+	s.t.Helper()
+	authCtx := services.AuthContext{AuthTime: time.Now(), ACR: services.ACRMultiFactor, AMR: []string{"pwd", "otp"}}
+	token, err := s.services.Auth.GenerateAuthenticatedAccessToken(user, authCtx, binding)
+	if err != nil {
+		s.t.Fatalf("Failed to generate access token: %v", err)
+	}
+	return token
+}
+
+// post sends a JSON request with a bearer token and decodes the JSON response into out
+func (s *testServer) post(path, token string, body, out interface{}) int {
+	s.t.Helper()
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	if out != nil {
+		json.Unmarshal(w.Body.Bytes(), out)
+	}
+	return w.Code
+}
+
+// form sends a form-encoded request and decodes the JSON response into out
+func (s *testServer) form(path string, values url.Values, out interface{}) int {
+	s.t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(values.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	if out != nil {
+		json.Unmarshal(w.Body.Bytes(), out)
+	}
+	return w.Code
+}
+
+// deviceToken runs the device authorization grant for a scope, approved by the user
+func (s *testServer) deviceToken(approverToken, scope string) string {
+	s.t.Helper()
+	var code DeviceCodeResponse
+	if status := s.form("/api/v1/auth/device/code", url.Values{"client_id": {"cli"}, "scope": {scope}}, &code); status != http.StatusOK {
+		s.t.Fatalf("Expected a device code, got %d", status)
+	}
+	if status := s.post("/api/v1/auth/device/approve", approverToken, UserCodeRequest{UserCode: code.UserCode}, nil); status != http.StatusOK {
+		s.t.Fatalf("Expected the device to be approved, got %d", status)
+	}
+
+	var tokens LoginResponse
+	poll := url.Values{"grant_type": {deviceCodeGrantType}, "device_code": {code.DeviceCode}, "client_id": {"cli"}}
+	if status := s.form("/api/v1/auth/token", poll, &tokens); status != http.StatusOK {
+		s.t.Fatalf("Expected device tokens, got %d", status)
+	}
+	return tokens.AccessToken
+}
+
+// TestPermissions tests that routes are authorized by the roles of the access token presented
+func TestPermissions(t *testing.T) {
+	getUser := "/api/v1/auth/users/get"
+
+	t.Run("ElevatedToken", func(t *testing.T) {
+		s := newTestServer(t)
+		admin := s.user("admin", services.AdminRole)
+		alice := s.user("alice")
+		adminToken := s.token(admin, services.TokenBinding{})
+		aliceToken := s.token(alice, services.TokenBinding{})
+		lookup := UserIDRequest{UserID: admin.ID}
+
+		elevation, err := s.services.Elevation.RequestElevation(alice.ID, "manager", "Incident 42", time.Hour)
+		if err != nil {
+			t.Fatalf("RequestElevation failed: %v", err)
+		}
+		if code := s.post("/api/v1/auth/elevation/approve", adminToken, ElevationDecisionRequest{ElevationID: elevation.ID}, nil); code != http.StatusOK {
+			t.Fatalf("Expected approval to succeed, got %d", code)
+		}
+
+		// Approval leaves RBAC alone, so only the elevated token holds the role
+		roles, _ := s.services.RBAC.GetUserRoles(alice.ID)
+		if len(roles) != 1 || roles[0] != services.DefaultUserRole {
+			t.Errorf("Expected approval not to assign roles, got %v", roles)
+		}
+		if code := s.post(getUser, aliceToken, lookup, nil); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for an ordinary token during the elevation, got %d", code)
+		}
+
+		var elevated ElevatedTokenResponse
+		if code := s.post("/api/v1/auth/elevation/token", aliceToken, ElevatedTokenRequest{ElevationID: elevation.ID}, &elevated); code != http.StatusOK {
+			t.Fatalf("Expected an elevated token, got %d", code)
+		}
+		if code := s.post(getUser, elevated.AccessToken, lookup, nil); code != http.StatusOK {
+			t.Errorf("Expected 200 for the elevated token, got %d", code)
+		}
+
+		if code := s.post("/api/v1/auth/elevation/revoke", aliceToken, ElevationDecisionRequest{ElevationID: elevation.ID}, nil); code != http.StatusOK {
+			t.Fatalf("Expected revocation to succeed, got %d", code)
+		}
+		if code := s.post(getUser, elevated.AccessToken, lookup, nil); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for the elevated token after revocation, got %d", code)
+		}
+	})
+
+	t.Run("RemovedRole", func(t *testing.T) {
+		s := newTestServer(t)
+		admin := s.user("admin", services.AdminRole)
+		adminToken := s.token(admin, services.TokenBinding{})
+		lookup := UserIDRequest{UserID: admin.ID}
+
+		if code := s.post(getUser, adminToken, lookup, nil); code != http.StatusOK {
+			t.Fatalf("Expected 200 for the administrator, got %d", code)
+		}
+
+		// A role taken away in RBAC stops counting in tokens issued before
+		if err := s.services.RBAC.RemoveRoleFromUser(admin.ID, services.AdminRole); err != nil {
+			t.Fatalf("RemoveRoleFromUser failed: %v", err)
+		}
+		if code := s.post(getUser, adminToken, lookup, nil); code != http.StatusForbidden {
+			t.Errorf("Expected 403 once the role is removed, got %d", code)
+		}
+	})
+	t.Run("ScopedDeviceToken", func(t *testing.T) {
+		s := newTestServer(t)
+		admin := s.user("admin", services.AdminRole)
+		lookup := UserIDRequest{UserID: admin.ID}
+
+		// A device limited to the user role gets none of the user's administrative rights
+		scoped := s.deviceToken(s.token(admin, services.TokenBinding{}), services.DefaultUserRole)
+		for _, path := range []string{getUser, "/api/v1/auth/users/disable", "/api/v1/auth/users/update"} {
+			if code := s.post(path, scoped, lookup, nil); code != http.StatusForbidden {
+				t.Errorf("Expected 403 for a scoped device token on %s, got %d", path, code)
+			}
+		}
+
+		unscoped := s.deviceToken(s.token(admin, services.TokenBinding{}), "")
+		if code := s.post(getUser, unscoped, lookup, nil); code != http.StatusOK {
+			t.Errorf("Expected 200 for an unscoped device token, got %d", code)
+		}
+	})
+	t.Run("BreakGlassSession", func(t *testing.T) {
+		s := newTestServer(t)
+		admin := s.user("admin", services.AdminRole)
+		custodians := []string{"alice@example.com", "bob@example.com"}
+		account, err := s.services.BreakGlass.CreateAccount("ops", []string{services.AdminRole}, custodians, 2, admin.ID)
+		if err != nil {
+			t.Fatalf("CreateAccount failed: %v", err)
+		}
+
+		share := regexp.MustCompile(`cfbg1\.[A-Za-z0-9_.-]+`)
+		var shares []string
+		for _, custodian := range custodians {
+			shares = append(shares, share.FindString(s.mail.last(custodian)))
+		}
+		// Tokens issued in the second the account's user was disabled count as revoked
+		time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+		var session ActivateBreakGlassResponse
+		activate := ActivateBreakGlassRequest{Account: "ops", Shares: shares, Justification: "Identity provider outage, incident 42"}
+		if code := s.post("/api/v1/auth/break-glass/activate", "", activate, &session); code != http.StatusOK {
+			t.Fatalf("Expected activation to succeed, got %d", code)
+		}
+
+		// The session holds the account's roles, which its user does not hold in RBAC, and
+		// passes step-up
+		if code := s.post(getUser, session.AccessToken, UserIDRequest{UserID: admin.ID}, nil); code != http.StatusOK {
+			t.Errorf("Expected a break-glass session to pass manage:users, got %d", code)
+		}
+		if code := s.post("/api/v1/auth/break-glass/rotate", session.AccessToken, BreakGlassAccountRequest{AccountID: account.ID}, nil); code != http.StatusOK {
+			t.Errorf("Expected a break-glass session to pass step-up, got %d", code)
+		}
+
+		if code := s.post("/api/v1/auth/break-glass/end", session.AccessToken, BreakGlassSessionRequest{SessionID: session.SessionID}, nil); code != http.StatusOK {
+			t.Fatalf("Expected the session to end, got %d", code)
+		}
+		if code := s.post(getUser, session.AccessToken, UserIDRequest{UserID: admin.ID}, nil); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 once the session ended, got %d", code)
+		}
+	})
+}
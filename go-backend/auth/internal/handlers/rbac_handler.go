@@ -5,8 +5,13 @@ import (
 
 	"github.com/cryptofortress/backend/auth/internal/services"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
 )
 
+// PermissionManageRoles is required to change roles, permissions and assignments and to
+// export, diff and apply RBAC bundles
+const PermissionManageRoles = "manage:roles"
+
 // RBACHandler handles role-based access control HTTP requests
 type RBACHandler struct {
 	rbacService services.RBACService
@@ -212,6 +217,11 @@ func (h *RBACHandler) CheckPermission(c *gin.Context) {
 		return
 	}
 
+	// Users may look up their own permissions; anyone else's need manage:roles
+	if !requireSelfOrPermission(c, h.rbacService, req.UserID, PermissionManageRoles) {
+		return
+	}
+
 	// Check permission
 	hasPermission, err := h.rbacService.CheckPermission(req.UserID, req.PermissionName)
 	if err != nil {
@@ -243,6 +253,11 @@ func (h *RBACHandler) GetUserRoles(c *gin.Context) {
 		return
 	}
 
+	// Users may look up their own roles; anyone else's need manage:roles
+	if !requireSelfOrPermission(c, h.rbacService, req.UserID, PermissionManageRoles) {
+		return
+	}
+
 	// Get user roles
 	roles, err := h.rbacService.GetUserRoles(req.UserID)
 	if err != nil {
@@ -285,4 +300,83 @@ func (h *RBACHandler) GetRolePermissions(c *gin.Context) {
 	c.JSON(http.StatusOK, GetRolePermissionsResponse{
 		Permissions: permissions,
 	})
-}
\ No newline at end of file
+}
+
+// ExportBundle handles exporting the current RBAC state as a bundle. The format is
+// selected with ?format=yaml or ?format=json (the default).
+func (h *RBACHandler) ExportBundle(c *gin.Context) {
+	format := c.DefaultQuery("format", services.BundleFormatJSON)
+	bundle, err := h.rbacService.ExportBundle()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export RBAC bundle"})
+		return
+	}
+
+	data, err := services.MarshalRBACBundle(bundle, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return response
+	contentType := "application/json"
+	if format == services.BundleFormatYAML {
+		contentType = "application/yaml"
+	}
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// DiffBundle handles a dry run that compares a bundle with the current RBAC state
+func (h *RBACHandler) DiffBundle(c *gin.Context) {
+	h.bundleOperation(c, h.rbacService.DiffBundle)
+}
+
+// ApplyBundle handles making the RBAC state match a bundle
+func (h *RBACHandler) ApplyBundle(c *gin.Context) {
+	h.bundleOperation(c, h.rbacService.ApplyBundle)
+}
+
+// bundleOperation reads a YAML or JSON bundle from the request body and runs a diff or
+// apply with the ?prune query parameter
+func (h *RBACHandler) bundleOperation(c *gin.Context, operation func(bundle *services.RBACBundle, prune bool) (*services.RBACDiff, error)) {
+	userID := c.GetString("userID")
+	prune := c.Query("prune") == "true"
+	data, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	bundle, err := services.ParseRBACBundle(data, bundleFormat(c.ContentType()))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	diff, err := operation(bundle, prune)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+
+	if diff.Applied {
+		log.Warn().
+			Str("user_id", userID).
+			Bool("prune", prune).
+			Int("changes", len(diff.Changes)).
+			Msg("RBAC bundle applied")
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, diff)
+}
+
+// bundleFormat maps a request content type to a bundle format
+func bundleFormat(contentType string) string {
+	switch contentType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return services.BundleFormatYAML
+	default:
+		return services.BundleFormatJSON
+	}
+}
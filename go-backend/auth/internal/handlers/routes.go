@@ -1,37 +1,70 @@
 package handlers
 
 import (
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
 	"github.com/cryptofortress/backend/auth/internal/services"
 	"github.com/cryptofortress/backend/auth/internal/middleware"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterRoutes sets up all the routes for the authentication service
-func RegisterRoutes(router *gin.Engine, services *services.Services) {
+func RegisterRoutes(router *gin.Engine, services *services.Services, cfg *config.Config, limiter *ratelimit.Limiter) {
 	// Create handlers
-	authHandler := NewAuthHandler(services.Auth)
+	authHandler := NewAuthHandler(services.Auth, services.MFA, services.Login, services.DPoP)
 	mfaHandler := NewMFAHandler(services.MFA)
 	rbacHandler := NewRBACHandler(services.RBAC)
+	elevationHandler := NewElevationHandler(services.Elevation, services.Auth, services.RBAC)
+	passwordlessHandler := NewPasswordlessHandler(services.Passwordless, services.Login, services.DPoP, time.Duration(cfg.PasswordlessTTL)*time.Minute)
+	deviceHandler := NewDeviceHandler(services.Device, services.Auth, services.DPoP, cfg.DeviceVerificationURL)
+	userHandler := NewUserHandler(services.Auth, services.RBAC)
+	breakGlassHandler := NewBreakGlassHandler(services.BreakGlass, services.RBAC)
+	accessReviewHandler := NewAccessReviewHandler(services.AccessReview, services.RBAC)
+	impersonationHandler := NewImpersonationHandler(services.Auth, services.RBAC, time.Duration(cfg.ImpersonationTokenTTL)*time.Minute)
+
+	stepUpMaxAge := time.Duration(cfg.StepUpMaxAge) * time.Minute
+
+	// Endpoints that check credentials share a tighter per-IP budget against guessing
+	loginLimit := limiter.Limit("login", cfg.RateLimitLogin, ratelimit.ByIP)
 
 	// Public routes (no authentication required)
 	public := router.Group("/api/v1/auth")
+	public.Use(limiter.Limit("public", cfg.RateLimitPublic, ratelimit.ByIP))
 	{
-		public.POST("/login", authHandler.Login)
+		public.POST("/login", loginLimit, authHandler.Login)
 		public.POST("/refresh", authHandler.Refresh)
-		public.POST("/register", authHandler.Register)
+		public.POST("/register", loginLimit, authHandler.Register)
 		public.POST("/logout", authHandler.Logout)
+		
+		// Passwordless login by magic link or emailed one-time code
+		public.POST("/passwordless/start", loginLimit, passwordlessHandler.StartLogin)
+		public.POST("/passwordless/verify", loginLimit, passwordlessHandler.VerifyLogin)
+		
+		// Device authorization grant for CLI tools (the device polls the /token endpoint)
+		public.POST("/device/code", deviceHandler.RequestDeviceCode)
+		public.POST("/token", deviceHandler.Token)
+		
+		// Break-glass activation must work while the identity providers are down
+		public.POST("/break-glass/activate", loginLimit, breakGlassHandler.Activate)
 	}
 
 	// Protected routes (authentication required)
 	protected := router.Group("/api/v1/auth")
-	protected.Use(middleware.AuthMiddleware(services.Auth))
+	protected.Use(middleware.AuthMiddleware(services.Auth, services.DPoP))
+	protected.Use(limiter.Limit("api", cfg.RateLimitAPI, ratelimit.ByUser))
 	{
-		// MFA routes
+		// Re-authentication upgrades the current token for step-up protected routes
+		protected.POST("/reauthenticate", middleware.RejectImpersonation(), authHandler.Reauthenticate)
+
+		// MFA routes (never available to impersonation tokens)
 		mfa := protected.Group("/mfa")
+		mfa.Use(middleware.RejectImpersonation())
 		{
 			mfa.POST("/totp/enable", mfaHandler.EnableTOTP)
 			mfa.POST("/totp/verify", mfaHandler.VerifyTOTP)
-			mfa.POST("/totp/disable", mfaHandler.DisableTOTP)
+			mfa.POST("/totp/disable", middleware.RequireRecentAuth(stepUpMaxAge), mfaHandler.DisableTOTP)
 			
 			mfa.POST("/webauthn/register", mfaHandler.RegisterWebAuthn)
 			mfa.POST("/webauthn/register/verify", mfaHandler.VerifyWebAuthnRegistration)
@@ -42,22 +75,107 @@ func RegisterRoutes(router *gin.Engine, services *services.Services) {
 		// RBAC routes
 		rbac := protected.Group("/rbac")
 		{
-			// Role management
-			rbac.POST("/roles", rbacHandler.CreateRole)
-			rbac.DELETE("/roles", rbacHandler.DeleteRole)
-			rbac.POST("/roles/assign", rbacHandler.AssignRole)
-			rbac.POST("/roles/remove", rbacHandler.RemoveRole)
-			
-			// Permission management
-			rbac.POST("/permissions", rbacHandler.CreatePermission)
-			rbac.POST("/permissions/assign", rbacHandler.AssignPermission)
-			rbac.POST("/permissions/remove", rbacHandler.RemovePermission)
-			
 			// Access control
 			rbac.POST("/permissions/check", rbacHandler.CheckPermission)
 			rbac.POST("/users/roles", rbacHandler.GetUserRoles)
 			rbac.POST("/roles/permissions", rbacHandler.GetRolePermissions)
 		}
+
+		// Every change to roles, permissions or assignments needs manage:roles and is never
+		// available to impersonation tokens
+		rbacAdmin := protected.Group("/rbac")
+		rbacAdmin.Use(middleware.RejectImpersonation(), permissionRequired(services.RBAC, PermissionManageRoles))
+		{
+			// Role management
+			rbacAdmin.POST("/roles", rbacHandler.CreateRole)
+			rbacAdmin.DELETE("/roles", rbacHandler.DeleteRole)
+			rbacAdmin.POST("/roles/assign", rbacHandler.AssignRole)
+			rbacAdmin.POST("/roles/remove", rbacHandler.RemoveRole)
+			
+			// Permission management
+			rbacAdmin.POST("/permissions", rbacHandler.CreatePermission)
+			rbacAdmin.POST("/permissions/assign", rbacHandler.AssignPermission)
+			rbacAdmin.POST("/permissions/remove", rbacHandler.RemovePermission)
+			
+			// Declarative policy bundles (YAML or JSON)
+			rbacAdmin.GET("/bundle", rbacHandler.ExportBundle)
+			rbacAdmin.POST("/bundle/diff", rbacHandler.DiffBundle)
+			rbacAdmin.POST("/bundle/apply", rbacHandler.ApplyBundle)
+		}
+
+		// Just-in-time role elevation routes
+		elevation := protected.Group("/elevation")
+		elevation.Use(middleware.RejectImpersonation())
+		{
+			elevation.POST("/request", elevationHandler.RequestElevation)
+			elevation.POST("/approve", elevationHandler.ApproveElevation)
+			elevation.POST("/deny", elevationHandler.DenyElevation)
+			elevation.POST("/revoke", elevationHandler.RevokeElevation)
+			elevation.POST("/get", elevationHandler.GetElevation)
+			elevation.GET("/pending", elevationHandler.ListPendingElevations)
+			elevation.POST("/token", elevationHandler.IssueElevatedToken)
+		}
+
+		// Device authorization routes, where a signed-in user approves a device
+		device := protected.Group("/device")
+		device.Use(middleware.RejectImpersonation())
+		{
+			device.POST("/verify", deviceHandler.VerifyUserCode)
+			device.POST("/approve", deviceHandler.ApproveUserCode)
+			device.POST("/deny", deviceHandler.DenyUserCode)
+		}
+
+		// Self-service account routes (impersonation tokens can only read the profile)
+		me := protected.Group("/users/me")
+		{
+			me.GET("", userHandler.GetProfile)
+			me.POST("/update", middleware.RejectImpersonation(), userHandler.UpdateProfile)
+			me.POST("/password", middleware.RejectImpersonation(), userHandler.ChangePassword)
+			me.POST("/delete", middleware.RejectImpersonation(), middleware.RequireRecentAuth(stepUpMaxAge), userHandler.DeleteAccount)
+		}
+
+		// User administration routes
+		users := protected.Group("/users")
+		users.Use(middleware.RejectImpersonation())
+		{
+			users.POST("/get", userHandler.GetUser)
+			users.POST("/update", userHandler.AdminUpdateProfile)
+			users.POST("/disable", userHandler.DisableUser)
+			users.POST("/enable", userHandler.EnableUser)
+			users.POST("/delete", middleware.RequireRecentAuth(stepUpMaxAge), userHandler.DeleteUser)
+			users.POST("/restore", userHandler.RestoreUser)
+		}
+
+		// Break-glass account management routes
+		breakGlass := protected.Group("/break-glass")
+		breakGlass.Use(middleware.RejectImpersonation())
+		{
+			breakGlass.POST("/accounts", middleware.RequireRecentAuth(stepUpMaxAge), breakGlassHandler.CreateAccount)
+			breakGlass.GET("/accounts", breakGlassHandler.ListAccounts)
+			breakGlass.POST("/rotate", middleware.RequireRecentAuth(stepUpMaxAge), breakGlassHandler.RotateCredential)
+			breakGlass.POST("/end", breakGlassHandler.EndSession)
+		}
+
+		// Access review campaigns (reviewers decide the items assigned to them)
+		accessReviews := protected.Group("/access-reviews")
+		accessReviews.Use(middleware.RejectImpersonation())
+		{
+			accessReviews.POST("/campaigns", accessReviewHandler.CreateCampaign)
+			accessReviews.GET("/campaigns", accessReviewHandler.ListCampaigns)
+			accessReviews.POST("/campaigns/get", accessReviewHandler.GetCampaign)
+			accessReviews.POST("/campaigns/close", accessReviewHandler.CloseCampaign)
+			accessReviews.GET("/items", accessReviewHandler.ListMyItems)
+			accessReviews.POST("/items/decide", accessReviewHandler.DecideItem)
+			accessReviews.POST("/evidence", accessReviewHandler.ExportEvidence)
+			accessReviews.GET("/evidence/keys", accessReviewHandler.EvidenceKey)
+		}
+
+		// Admin impersonation routes (an impersonation token cannot start another impersonation)
+		impersonation := protected.Group("/impersonation")
+		impersonation.Use(middleware.RejectImpersonation())
+		{
+			impersonation.POST("/start", impersonationHandler.Impersonate)
+		}
 	}
 
 	// Health check endpoint
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// PermissionManageUsers is required to view and change other users' accounts
+const PermissionManageUsers = "manage:users"
+
+// UserHandler handles user profile and account lifecycle HTTP requests
+type UserHandler struct {
+	authService services.AuthService
+	rbacService services.RBACService
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(authService services.AuthService, rbacService services.RBACService) *UserHandler {
+	return &UserHandler{
+		authService: authService,
+		rbacService: rbacService,
+	}
+}
+
+// UpdateProfileRequest represents the profile update request payload
+type UpdateProfileRequest struct {
+	UserID      string  `json:"user_id,omitempty"` // admin only
+	Email       *string `json:"email,omitempty" binding:"omitempty,email"`
+	DisplayName *string `json:"display_name,omitempty"`
+}
+
+// ChangePasswordRequest represents the change password request payload
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8"`
+}
+
+// UserIDRequest represents an admin request that targets a single user
+type UserIDRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// GetProfile handles a user reading their own profile
+func (h *UserHandler) GetProfile(c *gin.Context) {
+	user, err := h.authService.GetUser(c.GetString("userID"))
+	if err != nil {
+		h.respondError(c, err, "Failed to get profile")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateProfile handles a user changing their own profile
+func (h *UserHandler) UpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.updateProfile(c, c.GetString("userID"), req)
+}
+
+// ChangePassword handles a user replacing their password, which requires the current one
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if err := h.authService.ChangePassword(userID, req.CurrentPassword, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+		h.respondError(c, err, "Failed to change password")
+		return
+	}
+
+	log.Info().Str("user_id", userID).Msg("Password changed")
+
+	// Return response
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+// DeleteAccount handles a user deleting their own account. The account can be
+// restored by an administrator until the grace period ends.
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	userID := c.GetString("userID")
+	user, err := h.authService.DeleteUser(userID)
+	if err != nil {
+		h.respondError(c, err, "Failed to delete account")
+		return
+	}
+
+	log.Warn().
+		Str("user_id", userID).
+		Time("purge_at", *user.PurgeAt).
+		Msg("User deleted their account")
+
+	// Return response
+	c.JSON(http.StatusOK, user)
+}
+
+// GetUser handles an administrator reading a user's account
+func (h *UserHandler) GetUser(c *gin.Context) {
+	var req UserIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !requirePermission(c, h.rbacService, PermissionManageUsers) {
+		return
+	}
+
+	user, err := h.authService.GetUser(req.UserID)
+	if err != nil {
+		h.respondError(c, err, "Failed to get user")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, user)
+}
+
+// AdminUpdateProfile handles an administrator changing a user's profile
+func (h *UserHandler) AdminUpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.UserID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user_id is required"})
+		return
+	}
+
+	if !requirePermission(c, h.rbacService, PermissionManageUsers) {
+		return
+	}
+
+	h.updateProfile(c, req.UserID, req)
+}
+
+// DisableUser handles an administrator disabling an account, which revokes all of its sessions
+func (h *UserHandler) DisableUser(c *gin.Context) {
+	h.changeStatus(c, h.authService.DisableUser, "User disabled")
+}
+
+// EnableUser handles an administrator re-enabling a disabled account
+func (h *UserHandler) EnableUser(c *gin.Context) {
+	h.changeStatus(c, h.authService.EnableUser, "User enabled")
+}
+
+// DeleteUser handles an administrator soft-deleting an account
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	h.changeStatus(c, h.authService.DeleteUser, "User deleted")
+}
+
+// RestoreUser handles an administrator restoring a deleted account during its grace period
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	h.changeStatus(c, h.authService.RestoreUser, "User restored")
+}
+
+// updateProfile applies a profile update to a user
+func (h *UserHandler) updateProfile(c *gin.Context, userID string, req UpdateProfileRequest) {
+	user, err := h.authService.UpdateProfile(userID, services.ProfileUpdate{
+		Email:       req.Email,
+		DisplayName: req.DisplayName,
+	})
+	if err != nil {
+		h.respondError(c, err, "Failed to update profile")
+		return
+	}
+
+	log.Info().
+		Str("user_id", userID).
+		Str("updated_by", c.GetString("userID")).
+		Msg("User profile updated")
+
+	// Return response
+	c.JSON(http.StatusOK, user)
+}
+
+// changeStatus runs an administrative lifecycle operation on another user's account
+func (h *UserHandler) changeStatus(c *gin.Context, operation func(userID string) (*services.User, error), logMessage string) {
+	var req UserIDRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID := c.GetString("userID")
+	if !requirePermission(c, h.rbacService, PermissionManageUsers) {
+		return
+	}
+
+	// Administrators cannot lock themselves out
+	if req.UserID == adminID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Administrators cannot change the status of their own account"})
+		return
+	}
+
+	user, err := operation(req.UserID)
+	if err != nil {
+		h.respondError(c, err, "Failed to change user status")
+		return
+	}
+
+	log.Warn().
+		Str("user_id", user.ID).
+		Str("admin_id", adminID).
+		Str("status", string(user.Status)).
+		Msg(logMessage)
+
+	// Return response
+	c.JSON(http.StatusOK, user)
+}
+
+// respondError maps user service errors to HTTP responses
+func (h *UserHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUserExists), errors.Is(err, services.ErrUserDisabled),
+		errors.Is(err, services.ErrUserDeleted), errors.Is(err, services.ErrUserNotDeleted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
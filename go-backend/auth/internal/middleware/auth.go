@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
 
@@ -9,8 +10,9 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// AuthMiddleware creates a middleware for JWT authentication
-func AuthMiddleware(authService services.AuthService) gin.HandlerFunc {
+// AuthMiddleware creates a middleware for JWT authentication. Tokens bound to a key
+// through a cnf.jkt claim must be presented with the DPoP scheme and a matching proof.
+func AuthMiddleware(authService services.AuthService, dpopService services.DPoPService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -20,16 +22,14 @@ func AuthMiddleware(authService services.AuthService) gin.HandlerFunc {
 			return
 		}
 
-		// Check if the header starts with "Bearer "
-		if !strings.HasPrefix(authHeader, "Bearer ") {
+		// Check if the header uses the Bearer or DPoP scheme
+		scheme, tokenString, found := strings.Cut(authHeader, " ")
+		if !found || (scheme != "Bearer" && scheme != "DPoP") {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
 			c.Abort()
 			return
 		}
 
-		// Extract the token
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-
 		// Validate the token
 		claims, err := authService.ValidateAccessToken(tokenString)
 		if err != nil {
@@ -39,12 +39,62 @@ func AuthMiddleware(authService services.AuthService) gin.HandlerFunc {
 			return
 		}
 
+		// Check the sender constraint
+		if scheme == "DPoP" || claims.Confirmation != nil {
+			if claims.Confirmation == nil || scheme != "DPoP" {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "DPoP-bound tokens require the DPoP authorization scheme"})
+				c.Abort()
+				return
+			}
+
+			jkt, err := VerifyDPoP(c, dpopService, tokenString)
+			if err == nil && jkt != claims.Confirmation.JKT {
+				err = fmt.Errorf("%w: proof key does not match token binding", services.ErrInvalidDPoPProof)
+			}
+			if err != nil {
+				log.Warn().Err(err).Str("user_id", claims.UserID).Msg("DPoP proof rejected")
+				AbortWithDPoPError(c, err)
+				return
+			}
+		} else if dpopService.RequiresDPoP(claims.ClientID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Client must use DPoP-bound tokens"})
+			c.Abort()
+			return
+		}
+
 		// Set the user claims in the context
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("roles", claims.Roles)
+		c.Set("claims", claims)
+		if claims.ElevationID != "" {
+			c.Set("elevationID", claims.ElevationID)
+		}
+		if claims.Actor != nil {
+			c.Set("actorID", claims.Actor.Subject)
+			c.Set("impersonationReason", claims.ImpersonationReason)
+		}
 
 		// Continue with the next handler
+		c.Next()
+	}
+}
+
+// RejectImpersonation creates a middleware that blocks requests made with impersonation tokens.
+// It must run after AuthMiddleware.
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if actorID := c.GetString("actorID"); actorID != "" {
+			log.Warn().
+				Str("actor_id", actorID).
+				Str("user_id", c.GetString("userID")).
+				Str("path", c.Request.URL.Path).
+				Msg("Impersonation token rejected")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Operation not permitted with an impersonation token"})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
\ No newline at end of file
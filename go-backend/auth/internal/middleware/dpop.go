@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/cryptofortress/backend/auth/httpauth"
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyDPoP validates the request's DPoP proof and returns the thumbprint of the proof key.
+// It returns an empty thumbprint and no error when the request carries no proof. accessToken
+// is empty at the token endpoint and otherwise the token the proof must be bound to.
+func VerifyDPoP(c *gin.Context, dpopService services.DPoPService, accessToken string) (string, error) {
+	proofs := c.Request.Header.Values(httpauth.DPoPHeader)
+	switch len(proofs) {
+	case 0:
+		return "", nil
+	case 1:
+		return dpopService.VerifyProof(proofs[0], c.Request.Method, httpauth.RequestURL(c), accessToken)
+	default:
+		return "", fmt.Errorf("%w: multiple DPoP headers", services.ErrInvalidDPoPProof)
+	}
+}
+
+// AbortWithDPoPError writes an invalid_dpop_proof error with its WWW-Authenticate challenge
+func AbortWithDPoPError(c *gin.Context, err error) {
+	c.Header("WWW-Authenticate", `DPoP error="invalid_dpop_proof", algs="ES256 ES384 RS256 PS256 EdDSA"`)
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_dpop_proof", "error_description": err.Error()})
+	c.Abort()
+}
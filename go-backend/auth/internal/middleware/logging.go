@@ -19,12 +19,26 @@ func Logging() gin.HandlerFunc {
 		// Log request details
 		duration := time.Since(start)
 		
-		log.Info().
+		event := log.Info().
 			Str("method", c.Request.Method).
 			Str("path", c.Request.URL.Path).
 			Int("status", c.Writer.Status()).
 			Dur("duration", duration).
-			Str("client_ip", c.ClientIP()).
-			Msg("HTTP request")
+			Str("client_ip", c.ClientIP())
+		
+		// Tie actions taken with elevated or impersonation tokens back to their origin
+		elevationID := c.GetString("elevationID")
+		actorID := c.GetString("actorID")
+		if elevationID != "" || actorID != "" {
+			event = event.Str("user_id", c.GetString("userID"))
+		}
+		if elevationID != "" {
+			event = event.Str("elevation_id", elevationID)
+		}
+		if actorID != "" {
+			event = event.Str("actor_id", actorID).Bool("impersonated", true)
+		}
+		
+		event.Msg("HTTP request")
 	}
 }
\ No newline at end of file
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/cryptofortress/backend/pkg/stepup"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// RequireRecentAuth creates a middleware that demands multi-factor authentication within
+// the last maxAge, even when the access token itself is still valid. It must run after
+// AuthMiddleware.
+func RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("claims")
+		claims, ok := value.(*services.TokenClaims)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		if reason := stepup.Check(claims.ACR, claims.AuthTime, maxAge); reason != "" {
+			log.Info().
+				Str("user_id", claims.UserID).
+				Str("path", c.Request.URL.Path).
+				Str("reason", reason).
+				Msg("Step-up authentication required")
+			stepup.Abort(c, reason, maxAge)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -2,75 +2,272 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/cryptofortress/backend/auth/httpauth"
 	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/cryptofortress/backend/auth/internal/grpcserver"
 	"github.com/cryptofortress/backend/auth/internal/handlers"
 	"github.com/cryptofortress/backend/auth/internal/middleware"
 	"github.com/cryptofortress/backend/auth/internal/services"
+	"github.com/cryptofortress/backend/pkg/lifecycle"
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // Server represents the authentication service server
 type Server struct {
-	config   *config.Config
-	router   *gin.Engine
-	services *services.Services
+	config     *config.Config
+	router     *gin.Engine
+	httpServer *http.Server
+	grpcServer *grpc.Server
+	services   *services.Services
+	certs      *mtls.CertManager // nil when TLS is off
+	readiness  *lifecycle.Readiness
+	workers    *lifecycle.Workers
 }
 
 // New creates a new authentication server instance
-func New(cfg *config.Config) *Server {
+func New(cfg *config.Config) (*Server, error) {
+	// Service certificates are issued by the key management service's CA
+	var certs *mtls.CertManager
+	auditClient := &http.Client{Timeout: 5 * time.Second}
+	if cfg.TLSMode != mtls.ModeOff {
+		roots, err := mtls.LoadRoots(cfg.TLSCARootFile)
+		if err != nil {
+			return nil, err
+		}
+		certs = mtls.NewCertManager(mtls.ServiceID(cfg.TLSTrustDomain, mtls.ServiceAuth), roots)
+		auditClient = certs.HTTPClient(5*time.Second, mtls.ServiceID(cfg.TLSTrustDomain, mtls.ServiceAudit))
+	}
+	
 	// Initialize services
-	authService := services.NewAuthService(cfg)
-	mfaService := services.NewMFAService(cfg)
 	rbacService := services.NewRBACService(cfg)
+	elevationService := services.NewElevationService(cfg, rbacService)
+	authService := services.NewAuthService(cfg, rbacService, elevationService)
+	mfaService := services.NewMFAService(cfg)
+	riskService := services.NewRiskService(cfg)
+	dpopService := services.NewDPoPService(cfg)
+	notifier := services.NewNotifier(cfg)
+	passwordlessService := services.NewPasswordlessService(cfg, authService, notifier)
+	loginService := services.NewLoginService(authService, mfaService, riskService, passwordlessService)
+	deviceService := services.NewDeviceAuthorizationService(cfg)
+	breakGlassService := services.NewBreakGlassService(cfg, authService, notifier, services.NewSecurityEventSink(cfg, auditClient))
+	accessReviewService, err := services.NewAccessReviewService(cfg, rbacService)
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := bootstrapAdmin(cfg, authService, rbacService); err != nil {
+		return nil, err
+	}
 	
 	services := &services.Services{
-		Auth: authService,
-		MFA:  mfaService,
-		RBAC: rbacService,
+		Auth:         authService,
+		MFA:          mfaService,
+		RBAC:         rbacService,
+		Elevation:    elevationService,
+		Risk:         riskService,
+		Login:        loginService,
+		DPoP:         dpopService,
+		Passwordless: passwordlessService,
+		Device:       deviceService,
+		BreakGlass:   breakGlassService,
+		AccessReview: accessReviewService,
 	}
 	
 	// Create router
 	router := gin.New()
+	// Client IPs, which most rate limits are keyed on, are only taken from X-Forwarded-For
+	// when a trusted proxy sent it
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	// The same proxies set the scheme and host that DPoP proofs are checked against
+	trustForwarded, err := httpauth.TrustForwardedHeaders(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	router.Use(trustForwarded)
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logging())
 	
+	// Rate limits are kept in memory, so each instance enforces its own
+	limiter := ratelimit.New(ratelimit.NewMemoryStore())
+	
 	// Register routes
-	handlers.RegisterRoutes(router, services)
+	handlers.RegisterRoutes(router, services, cfg, limiter)
+	
+	// Readiness turns false as soon as shutdown begins, so load balancers stop sending traffic
+	readiness := lifecycle.NewReadiness()
+	router.GET("/ready", readiness.Handler())
+	
+	// gRPC clients must always present a certificate in mtls mode
+	var grpcOptions []grpc.ServerOption
+	if certs != nil {
+		clientAuth := tls.VerifyClientCertIfGiven
+		if cfg.TLSMode == mtls.ModeMTLS {
+			clientAuth = tls.RequireAndVerifyClientCert
+		}
+		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(certs.ServerTLSConfig(clientAuth))))
+	}
 	
 	return &Server{
-		config:   cfg,
-		router:   router,
-		services: services,
+		config: cfg,
+		router: router,
+		httpServer: &http.Server{
+			Addr:    ":" + cfg.Port,
+			Handler: router,
+		},
+		grpcServer: grpcserver.New(services, grpcOptions...),
+		services:   services,
+		certs:      certs,
+		readiness:  readiness,
+		workers:    lifecycle.NewWorkers(),
+	}, nil
+}
+
+// bootstrapAdmin creates the configured initial administrator. Every administrative route
+// requires a permission that only an administrator can grant, so without one they stay closed.
+func bootstrapAdmin(cfg *config.Config, authService services.AuthService, rbacService services.RBACService) error {
+	if cfg.BootstrapAdminUsername == "" {
+		log.Warn().Msg("No bootstrap administrator configured; administrative routes are unavailable")
+		return nil
 	}
+	
+	admin, err := authService.RegisterUser(cfg.BootstrapAdminUsername, cfg.BootstrapAdminEmail, cfg.BootstrapAdminPassword)
+	if err != nil {
+		return fmt.Errorf("failed to create bootstrap administrator: %w", err)
+	}
+	if err := rbacService.AssignRoleToUser(admin.ID, services.AdminRole); err != nil {
+		return fmt.Errorf("failed to assign admin role to bootstrap administrator: %w", err)
+	}
+	
+	log.Info().Str("user_id", admin.ID).Str("username", admin.Username).Msg("Bootstrap administrator created")
+	return nil
 }
 
-// Start begins serving requests
+// Start begins serving requests and returns once the server is stopped
 func (s *Server) Start() error {
-	server := &http.Server{
-		Addr:    ":" + s.config.Port,
-		Handler: s.router,
+	// Obtain the first certificate before accepting connections; it is renewed in the background
+	// until the workers stop
+	if s.certs != nil {
+		keymgmtID := mtls.ServiceID(s.config.TLSTrustDomain, mtls.ServiceKeyManagement)
+		issuer := mtls.NewHTTPIssuer(s.config.TLSCAURL, s.config.TLSBootstrapToken, s.certs.HTTPClient(10*time.Second, keymgmtID))
+		if err := s.certs.Start(s.workers.Context(), issuer); err != nil {
+			return lifecycle.ServeError(err)
+		}
+		s.httpServer.TLSConfig = s.certs.ServerTLSConfig(tls.VerifyClientCertIfGiven)
 	}
 	
-	// Graceful shutdown
+	// Serve the gRPC API next to HTTP
+	listener, err := net.Listen("tcp", ":"+s.config.GRPCPort)
+	if err != nil {
+		return err
+	}
 	go func() {
-		<-context.Background().Done()
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		
-		if err := server.Shutdown(ctx); err != nil {
-			log.Error().Err(err).Msg("Server shutdown failed")
+		log.Info().Str("port", s.config.GRPCPort).Msg("Starting gRPC server")
+		if err := s.grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			log.Error().Err(err).Msg("gRPC server failed")
 		}
 	}()
 	
-	return server.ListenAndServe()
+	// Mark elevations expired once their window has passed
+	s.workers.Every("elevations", time.Minute, s.expireElevations)
+	
+	// Close break-glass sessions once their time box has passed
+	s.workers.Every("break-glass", time.Minute, s.expireBreakGlassSessions)
+	
+	// Close access reviews that have reached their due date
+	s.workers.Every("access-reviews", time.Hour, s.closeOverdueAccessReviews)
+	
+	// Permanently remove deleted accounts once their grace period has passed
+	s.workers.Every("user-purge", time.Hour, s.purgeDeletedUsers)
+	
+	if s.certs != nil {
+		return lifecycle.ServeError(s.httpServer.ListenAndServeTLS("", ""))
+	}
+	return lifecycle.ServeError(s.httpServer.ListenAndServe())
 }
 
-// Stop gracefully shuts down the server
-func (s *Server) Stop() error {
-	// Cleanup resources if needed
-	return nil
-}
\ No newline at end of file
+// expireElevations expires just-in-time role elevations whose window has passed
+func (s *Server) expireElevations(ctx context.Context) {
+	expired, err := s.services.Elevation.ExpireElevations()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to expire role elevations")
+	}
+	if expired > 0 {
+		log.Info().Int("count", expired).Msg("Expired role elevations")
+	}
+}
+
+// expireBreakGlassSessions ends expired break-glass sessions
+func (s *Server) expireBreakGlassSessions(ctx context.Context) {
+	expired, err := s.services.BreakGlass.ExpireSessions()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to expire break-glass sessions")
+	}
+	if expired > 0 {
+		log.Info().Int("count", expired).Msg("Expired break-glass sessions")
+	}
+}
+
+// closeOverdueAccessReviews closes access review campaigns past their due date
+func (s *Server) closeOverdueAccessReviews(ctx context.Context) {
+	closed, err := s.services.AccessReview.CloseOverdueCampaigns()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to close overdue access reviews")
+	}
+	if closed > 0 {
+		log.Info().Int("count", closed).Msg("Closed overdue access reviews")
+	}
+}
+
+// purgeDeletedUsers hard-deletes soft-deleted accounts past their grace period
+func (s *Server) purgeDeletedUsers(ctx context.Context) {
+	purged, err := s.services.Auth.PurgeDeletedUsers()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to purge deleted users")
+	}
+	if purged > 0 {
+		log.Info().Int("count", purged).Msg("Purged deleted users")
+	}
+}
+
+// Stop gracefully shuts down the server: it turns not ready, waits for load balancers
+// to notice, drains open HTTP connections and gRPC calls and then stops the background
+// workers
+func (s *Server) Stop(ctx context.Context) error {
+	s.readiness.Drain(ctx, time.Duration(s.config.ShutdownDelay)*time.Second)
+	
+	// GracefulStop waits for in-flight calls without a deadline, so it is cut short
+	// once ctx is done
+	grpcStopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	
+	// Workers stop even if draining times out, with a timeout of their own, so the shutdown
+	// error reports everything
+	httpErr := s.httpServer.Shutdown(ctx)
+	
+	var grpcErr error
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		grpcErr = errors.New("gRPC calls did not finish in time")
+	}
+	
+	return errors.Join(httpErr, grpcErr, s.workers.StopWithin(lifecycle.WorkerStopTimeout))
+}
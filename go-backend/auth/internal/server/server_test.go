@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/cryptofortress/backend/pkg/lifecycle"
+	"google.golang.org/grpc"
+)
+
+// TestStop tests that the background workers still stop cleanly when draining HTTP
+// connections runs out of time
+func TestStop(t *testing.T) {
+	// A request that outlives the shutdown context keeps HTTP from draining
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	s := &Server{
+		config:     &config.Config{},
+		httpServer: &http.Server{Handler: handler},
+		grpcServer: grpc.NewServer(),
+		readiness:  lifecycle.NewReadiness(),
+		workers:    lifecycle.NewWorkers(),
+	}
+	go s.httpServer.Serve(listener)
+
+	// The worker's current run finishes after the shutdown context has expired
+	stopped := make(chan struct{})
+	s.workers.Go("slow", func(ctx context.Context) {
+		<-ctx.Done()
+		time.Sleep(200 * time.Millisecond)
+		close(stopped)
+	})
+
+	go http.Get("http://" + listener.Addr().String())
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = s.Stop(ctx)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the HTTP drain to time out, got %v", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "background workers did not finish") {
+		t.Errorf("Expected the workers to stop cleanly, got %v", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("Expected Stop to wait for the worker's current run")
+	}
+}
@@ -0,0 +1,483 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrAccessReviewNotFound is returned when an access review campaign does not exist
+	ErrAccessReviewNotFound = errors.New("access review campaign not found")
+	// ErrAccessReviewItemNotFound is returned when an item does not belong to the campaign
+	ErrAccessReviewItemNotFound = errors.New("access review item not found")
+	// ErrAccessReviewClosed is returned when deciding on or closing a closed campaign
+	ErrAccessReviewClosed = errors.New("access review campaign is closed")
+	// ErrAccessReviewOpen is returned when exporting evidence of a campaign that is still open
+	ErrAccessReviewOpen = errors.New("access review campaign is still open")
+	// ErrAccessReviewNotReviewer is returned when a user decides an item assigned to someone else
+	ErrAccessReviewNotReviewer = errors.New("item is assigned to a different reviewer")
+)
+
+// accessReviewEvidenceIssuer identifies the service in evidence records
+const accessReviewEvidenceIssuer = "CryptoFortress Auth Service"
+
+// accessReviewCampaign is the stored state of a campaign
+type accessReviewCampaign struct {
+	AccessReviewCampaign
+	evidence string // signed evidence record, set when the campaign closes
+}
+
+// accessReviewEvidenceClaims are the claims of a signed evidence record
+type accessReviewEvidenceClaims struct {
+	Campaign *AccessReviewCampaign `json:"campaign"`
+	jwt.StandardClaims
+}
+
+// accessReviewServiceImpl implements the AccessReviewService interface
+type accessReviewServiceImpl struct {
+	config      *config.Config
+	rbacService RBACService
+	signingKey  ed25519.PrivateKey
+	keyID       string
+
+	mu        sync.Mutex
+	campaigns map[string]*accessReviewCampaign
+	// In a real implementation, campaigns and evidence would be stored in the database
+}
+
+// NewAccessReviewService creates a new instance of the access review service
+func NewAccessReviewService(cfg *config.Config, rbacService RBACService) (AccessReviewService, error) {
+	// Evidence is signed with its own key, unrelated to any other secret of the service
+	seed, err := base64.StdEncoding.DecodeString(cfg.AccessReviewSigningKey)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("access review signing key must be a base64 %d-byte Ed25519 seed", ed25519.SeedSize)
+	}
+	signingKey := ed25519.NewKeyFromSeed(seed)
+
+	keyHash := sha256.Sum256(signingKey.Public().(ed25519.PublicKey))
+
+	return &accessReviewServiceImpl{
+		config:      cfg,
+		rbacService: rbacService,
+		signingKey:  signingKey,
+		keyID:       hex.EncodeToString(keyHash[:8]),
+		campaigns:   make(map[string]*accessReviewCampaign),
+	}, nil
+}
+
+// CreateCampaign snapshots the role assignments in scope and spreads them across the
+// reviewers. Nobody is asked to review their own access.
+func (s *accessReviewServiceImpl) CreateCampaign(name string, scope AccessReviewScope, reviewers []string, dueAt time.Time, revokeUnreviewed bool, createdBy string) (*AccessReviewCampaign, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("campaign name is required")
+	}
+	if len(reviewers) == 0 {
+		return nil, errors.New("at least one reviewer is required")
+	}
+
+	now := time.Now()
+	if dueAt.IsZero() {
+		dueAt = now.Add(time.Duration(s.config.AccessReviewDuration) * 24 * time.Hour)
+	}
+	if !dueAt.After(now) {
+		return nil, errors.New("due date must be in the future")
+	}
+
+	bundle, err := s.rbacService.ExportBundle()
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot role assignments: %w", err)
+	}
+
+	roles := make(map[string]bool, len(bundle.Roles))
+	for _, role := range bundle.Roles {
+		roles[role.Name] = true
+	}
+	for _, role := range scope.Roles {
+		if !roles[role] {
+			return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, role)
+		}
+	}
+
+	campaign := &accessReviewCampaign{
+		AccessReviewCampaign: AccessReviewCampaign{
+			ID:               uuid.New().String(),
+			Name:             name,
+			Scope:            scope,
+			Reviewers:        reviewers,
+			RevokeUnreviewed: revokeUnreviewed,
+			Status:           AccessReviewOpen,
+			CreatedBy:        createdBy,
+			CreatedAt:        now,
+			DueAt:            dueAt,
+		},
+	}
+
+	// Effective permissions are captured once per role so that every item shows
+	// what the assignment granted at the time of the snapshot
+	permissions := make(map[string][]string)
+	next := 0
+	for _, assignment := range bundle.Assignments {
+		if !inScope(scope.UserIDs, assignment.UserID) {
+			continue
+		}
+		for _, role := range assignment.Roles {
+			if !inScope(scope.Roles, role) {
+				continue
+			}
+
+			if _, ok := permissions[role]; !ok {
+				if permissions[role], err = s.rbacService.GetRolePermissions(role); err != nil {
+					return nil, fmt.Errorf("failed to snapshot permissions of role %s: %w", role, err)
+				}
+			}
+
+			reviewer, ok := pickReviewer(reviewers, assignment.UserID, &next)
+			if !ok {
+				return nil, fmt.Errorf("no reviewer other than %s can review their access", assignment.UserID)
+			}
+
+			campaign.Items = append(campaign.Items, AccessReviewItem{
+				ID:          uuid.New().String(),
+				CampaignID:  campaign.ID,
+				UserID:      assignment.UserID,
+				Role:        role,
+				Permissions: permissions[role],
+				ReviewerID:  reviewer,
+				Decision:    AccessReviewPending,
+			})
+		}
+	}
+	if len(campaign.Items) == 0 {
+		return nil, errors.New("no role assignments match the campaign scope")
+	}
+	campaign.Summary = summarizeAccessReview(campaign.Items)
+
+	s.mu.Lock()
+	s.campaigns[campaign.ID] = campaign
+	s.mu.Unlock()
+
+	log.Info().
+		Str("campaign_id", campaign.ID).
+		Str("created_by", createdBy).
+		Int("items", len(campaign.Items)).
+		Time("due_at", dueAt).
+		Msg("Access review campaign started")
+
+	result := campaign.AccessReviewCampaign
+	result.Items = append([]AccessReviewItem(nil), campaign.Items...)
+	return &result, nil
+}
+
+// GetCampaign retrieves a campaign with its items
+func (s *accessReviewServiceImpl) GetCampaign(campaignID string) (*AccessReviewCampaign, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, exists := s.campaigns[campaignID]
+	if !exists {
+		return nil, ErrAccessReviewNotFound
+	}
+
+	result := campaign.AccessReviewCampaign
+	result.Items = append([]AccessReviewItem(nil), campaign.Items...)
+	return &result, nil
+}
+
+// ListCampaigns returns all campaigns, newest first, without their items
+func (s *accessReviewServiceImpl) ListCampaigns() ([]AccessReviewCampaign, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaigns := make([]AccessReviewCampaign, 0, len(s.campaigns))
+	for _, campaign := range s.campaigns {
+		result := campaign.AccessReviewCampaign
+		result.Items = nil
+		campaigns = append(campaigns, result)
+	}
+	sort.Slice(campaigns, func(i, j int) bool {
+		return campaigns[i].CreatedAt.After(campaigns[j].CreatedAt)
+	})
+	return campaigns, nil
+}
+
+// ListReviewerItems returns the items of open campaigns assigned to a reviewer
+func (s *accessReviewServiceImpl) ListReviewerItems(reviewerID string) ([]AccessReviewItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := []AccessReviewItem{}
+	for _, campaign := range s.campaigns {
+		if campaign.Status != AccessReviewOpen {
+			continue
+		}
+		for _, item := range campaign.Items {
+			if item.ReviewerID == reviewerID {
+				items = append(items, item)
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].CampaignID != items[j].CampaignID {
+			return items[i].CampaignID < items[j].CampaignID
+		}
+		if items[i].UserID != items[j].UserID {
+			return items[i].UserID < items[j].UserID
+		}
+		return items[i].Role < items[j].Role
+	})
+	return items, nil
+}
+
+// DecideItem records a reviewer's decision. Decisions can be changed until the campaign closes.
+func (s *accessReviewServiceImpl) DecideItem(campaignID, itemID, reviewerID string, decision AccessReviewDecision, comment string) (*AccessReviewItem, error) {
+	if decision != AccessReviewApprove && decision != AccessReviewRevoke {
+		return nil, fmt.Errorf("invalid decision: %s", decision)
+	}
+	if decision == AccessReviewRevoke && strings.TrimSpace(comment) == "" {
+		return nil, errors.New("a comment is required to revoke access")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, exists := s.campaigns[campaignID]
+	if !exists {
+		return nil, ErrAccessReviewNotFound
+	}
+	if campaign.Status != AccessReviewOpen {
+		return nil, ErrAccessReviewClosed
+	}
+
+	for i := range campaign.Items {
+		item := &campaign.Items[i]
+		if item.ID != itemID {
+			continue
+		}
+		if item.ReviewerID != reviewerID {
+			return nil, ErrAccessReviewNotReviewer
+		}
+
+		now := time.Now()
+		item.Decision = decision
+		item.Comment = comment
+		item.DecidedAt = &now
+		campaign.Summary = summarizeAccessReview(campaign.Items)
+
+		log.Info().
+			Str("campaign_id", campaignID).
+			Str("item_id", itemID).
+			Str("reviewer_id", reviewerID).
+			Str("user_id", item.UserID).
+			Str("role", item.Role).
+			Str("decision", string(decision)).
+			Msg("Access review decision recorded")
+
+		result := *item
+		return &result, nil
+	}
+	return nil, ErrAccessReviewItemNotFound
+}
+
+// CloseCampaign applies the revocations and seals the evidence record
+func (s *accessReviewServiceImpl) CloseCampaign(campaignID, closedBy string) (*AccessReviewCampaign, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, exists := s.campaigns[campaignID]
+	if !exists {
+		return nil, ErrAccessReviewNotFound
+	}
+	if campaign.Status != AccessReviewOpen {
+		return nil, ErrAccessReviewClosed
+	}
+
+	if err := s.closeLocked(campaign, closedBy); err != nil {
+		return nil, err
+	}
+
+	result := campaign.AccessReviewCampaign
+	result.Items = append([]AccessReviewItem(nil), campaign.Items...)
+	return &result, nil
+}
+
+// CloseOverdueCampaigns closes every open campaign past its due date
+func (s *accessReviewServiceImpl) CloseOverdueCampaigns() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	closed := 0
+	for _, campaign := range s.campaigns {
+		if campaign.Status != AccessReviewOpen || now.Before(campaign.DueAt) {
+			continue
+		}
+		if err := s.closeLocked(campaign, "system"); err != nil {
+			return closed, err
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// closeLocked removes the revoked assignments and signs the campaign. A failed removal is
+// recorded on its item instead of aborting, so the evidence shows exactly what was applied.
+// The caller must hold s.mu.
+func (s *accessReviewServiceImpl) closeLocked(campaign *accessReviewCampaign, closedBy string) error {
+	now := time.Now()
+	for i := range campaign.Items {
+		item := &campaign.Items[i]
+		if item.Decision == AccessReviewPending {
+			item.Decision = AccessReviewUnreviewed
+		}
+		if item.Decision != AccessReviewRevoke && !(item.Decision == AccessReviewUnreviewed && campaign.RevokeUnreviewed) {
+			continue
+		}
+
+		if err := s.rbacService.RemoveRoleFromUser(item.UserID, item.Role); err != nil {
+			item.RemoveError = err.Error()
+			log.Error().Err(err).
+				Str("campaign_id", campaign.ID).
+				Str("user_id", item.UserID).
+				Str("role", item.Role).
+				Msg("Failed to remove revoked role assignment")
+			continue
+		}
+		removedAt := now
+		item.RemovedAt = &removedAt
+	}
+
+	campaign.Status = AccessReviewClosed
+	campaign.ClosedBy = closedBy
+	campaign.ClosedAt = &now
+	campaign.Summary = summarizeAccessReview(campaign.Items)
+
+	evidence, err := s.signEvidence(&campaign.AccessReviewCampaign)
+	if err != nil {
+		return fmt.Errorf("failed to sign access review evidence: %w", err)
+	}
+	campaign.evidence = evidence
+
+	log.Info().
+		Str("campaign_id", campaign.ID).
+		Str("closed_by", closedBy).
+		Int("approved", campaign.Summary.Approved).
+		Int("revoked", campaign.Summary.Revoked).
+		Int("unreviewed", campaign.Summary.Unreviewed).
+		Int("removed", campaign.Summary.Removed).
+		Msg("Access review campaign closed")
+
+	return nil
+}
+
+// ExportEvidence returns the signed evidence record of a closed campaign
+func (s *accessReviewServiceImpl) ExportEvidence(campaignID string) (*AccessReviewEvidence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	campaign, exists := s.campaigns[campaignID]
+	if !exists {
+		return nil, ErrAccessReviewNotFound
+	}
+	if campaign.Status != AccessReviewClosed {
+		return nil, ErrAccessReviewOpen
+	}
+
+	result := campaign.AccessReviewCampaign
+	result.Items = append([]AccessReviewItem(nil), campaign.Items...)
+	return &AccessReviewEvidence{
+		CampaignID: campaign.ID,
+		KeyID:      s.keyID,
+		Algorithm:  jwt.SigningMethodEdDSA.Alg(),
+		Token:      campaign.evidence,
+		Campaign:   &result,
+	}, nil
+}
+
+// EvidenceKey returns the public key that verifies evidence records
+func (s *accessReviewServiceImpl) EvidenceKey() AccessReviewEvidenceKey {
+	return AccessReviewEvidenceKey{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(s.signingKey.Public().(ed25519.PublicKey)),
+		KeyID:     s.keyID,
+		Algorithm: jwt.SigningMethodEdDSA.Alg(),
+		Use:       "sig",
+	}
+}
+
+// signEvidence signs a closed campaign as an EdDSA JWS
+func (s *accessReviewServiceImpl) signEvidence(campaign *AccessReviewCampaign) (string, error) {
+	claims := &accessReviewEvidenceClaims{
+		Campaign: campaign,
+		StandardClaims: jwt.StandardClaims{
+			Id:       uuid.New().String(),
+			Subject:  campaign.ID,
+			IssuedAt: time.Now().Unix(),
+			Issuer:   accessReviewEvidenceIssuer,
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = s.keyID
+	return token.SignedString(s.signingKey)
+}
+
+// pickReviewer assigns reviewers round-robin, skipping the user under review
+func pickReviewer(reviewers []string, userID string, next *int) (string, bool) {
+	for range reviewers {
+		reviewer := reviewers[*next%len(reviewers)]
+		*next++
+		if reviewer != userID {
+			return reviewer, true
+		}
+	}
+	return "", false
+}
+
+// inScope reports whether a value matches a scope filter, where an empty filter matches everything
+func inScope(filter []string, value string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, v := range filter {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// summarizeAccessReview counts items by decision
+func summarizeAccessReview(items []AccessReviewItem) AccessReviewSummary {
+	summary := AccessReviewSummary{Total: len(items)}
+	for _, item := range items {
+		switch item.Decision {
+		case AccessReviewPending:
+			summary.Pending++
+		case AccessReviewApprove:
+			summary.Approved++
+		case AccessReviewRevoke:
+			summary.Revoked++
+		case AccessReviewUnreviewed:
+			summary.Unreviewed++
+		}
+		if item.RemovedAt != nil {
+			summary.Removed++
+		}
+	}
+	return summary
+}
@@ -2,35 +2,95 @@ package services
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cryptofortress/backend/auth/internal/config"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	// ErrUserNotFound is returned when a user does not exist
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserExists is returned when a username or email address is already taken
+	ErrUserExists = errors.New("username or email already in use")
+	// ErrInvalidCredentials is returned when a username or password is wrong
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUserDisabled is returned when a disabled account is used
+	ErrUserDisabled = errors.New("user account is disabled")
+	// ErrUserDeleted is returned when a deleted account is used or changed
+	ErrUserDeleted = errors.New("user account is deleted")
+	// ErrUserNotDeleted is returned when restoring an account that is not deleted
+	ErrUserNotDeleted = errors.New("user account is not deleted")
+	// ErrSessionRevoked is returned for tokens issued before the user's sessions were revoked
+	ErrSessionRevoked = errors.New("session has been revoked")
+	// ErrElevationInactive is returned for elevated tokens whose elevation was revoked or has expired
+	ErrElevationInactive = errors.New("elevation is no longer active")
+)
+
+// dummyPasswordHash is compared against when a username is unknown, so that
+// unknown and known usernames take the same time to reject
+var dummyPasswordHash = []byte("$2a$10$N.zmdr9k7uOCQb0bta/OauRxaOKSr.QhqyD2R5FKvMQjmHoLkm5Sy")
+
+// userRecord is the stored state of a user account
+type userRecord struct {
+	User
+	passwordHash      []byte
+	sessionsRevokedAt time.Time
+}
+
+// refreshTokenRecord is the stored state of an issued refresh token
+type refreshTokenRecord struct {
+	userID    string
+	scope     []string
+	expiresAt time.Time
+}
+
 // authServiceImpl implements the AuthService interface
 type authServiceImpl struct {
-	config *config.Config
-	// In a real implementation, you would have database connections here
+	config           *config.Config
+	rbacService      RBACService
+	elevationService ElevationService
+
+	mu            sync.RWMutex
+	users         map[string]*userRecord         // keyed by user ID
+	usernames     map[string]string              // lower-cased username to user ID
+	emails        map[string]string              // lower-cased email to user ID
+	refreshTokens map[string]*refreshTokenRecord // keyed by token hash
+	// In a real implementation, users and refresh tokens would be stored in the database
 }
 
-// NewAuthService creates a new instance of the authentication service
-func NewAuthService(cfg *config.Config) AuthService {
+// NewAuthService creates a new instance of the authentication service. The roles in
+// issued tokens are those the RBAC service assigns to the user, apart from roles held
+// through an elevation, which only elevated tokens carry.
+func NewAuthService(cfg *config.Config, rbacService RBACService, elevationService ElevationService) AuthService {
 	return &authServiceImpl{
-		config: cfg,
+		config:           cfg,
+		rbacService:      rbacService,
+		elevationService: elevationService,
+		users:         make(map[string]*userRecord),
+		usernames:     make(map[string]string),
+		emails:        make(map[string]string),
+		refreshTokens: make(map[string]*refreshTokenRecord),
 	}
 }
 
 // GenerateAccessToken creates a new JWT access token
-func (s *authServiceImpl) GenerateAccessToken(userID string, roles []string) (string, error) {
+func (s *authServiceImpl) GenerateAccessToken(userID string, roles []string, binding TokenBinding) (string, error) {
 	claims := &TokenClaims{
-		UserID: userID,
-		Roles:  roles,
+		UserID:       userID,
+		Roles:        limitToScope(roles, binding.Scope),
+		ClientID:     binding.ClientID,
+		Confirmation: confirmationClaim(binding),
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(time.Minute * time.Duration(s.config.AccessTokenTTL)).Unix(),
 			IssuedAt:  time.Now().Unix(),
@@ -42,8 +102,154 @@ func (s *authServiceImpl) GenerateAccessToken(userID string, roles []string) (st
 	return token.SignedString([]byte(s.config.JWTSecret))
 }
 
-// GenerateRefreshToken creates a new refresh token
-func (s *authServiceImpl) GenerateRefreshToken(userID string) (string, error) {
+// GenerateAuthenticatedAccessToken creates an access token recording when and how the user authenticated
+func (s *authServiceImpl) GenerateAuthenticatedAccessToken(user *User, authCtx AuthContext, binding TokenBinding) (string, error) {
+	roles, err := s.rbacService.GetUserRoles(user.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	claims := &TokenClaims{
+		UserID:       user.ID,
+		Username:     user.Username,
+		Roles:        limitToScope(roles, binding.Scope),
+		AuthTime:     authCtx.AuthTime.Unix(),
+		ACR:          authCtx.ACR,
+		AMR:          authCtx.AMR,
+		ClientID:     binding.ClientID,
+		Confirmation: confirmationClaim(binding),
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Minute * time.Duration(s.config.AccessTokenTTL)).Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    "CryptoFortress Auth Service",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.config.JWTSecret))
+}
+
+// UpgradeAccessToken re-issues an existing token with a fresh authentication context.
+// Elevation, identity and DPoP binding claims are kept, and elevated tokens keep their original expiry
+// so they never outlive the elevation window.
+func (s *authServiceImpl) UpgradeAccessToken(claims *TokenClaims, authCtx AuthContext) (string, error) {
+	if claims.Actor != nil {
+		return "", errors.New("impersonation tokens cannot be upgraded")
+	}
+
+	upgraded := *claims
+	upgraded.AuthTime = authCtx.AuthTime.Unix()
+	upgraded.ACR = authCtx.ACR
+	upgraded.AMR = authCtx.AMR
+	upgraded.IssuedAt = time.Now().Unix()
+	if upgraded.ElevationID == "" {
+		upgraded.ExpiresAt = time.Now().Add(time.Minute * time.Duration(s.config.AccessTokenTTL)).Unix()
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &upgraded)
+	return token.SignedString([]byte(s.config.JWTSecret))
+}
+
+// GenerateElevatedAccessToken creates an access token tied to an approved role elevation.
+// Identity and authentication context are carried over from the caller's current token,
+// and the token never outlives the elevation window.
+func (s *authServiceImpl) GenerateElevatedAccessToken(current *TokenClaims, roles []string, elevationID string, expiresAt time.Time) (string, error) {
+	if elevationID == "" {
+		return "", errors.New("elevation ID is required")
+	}
+
+	tokenExpiry := time.Now().Add(time.Minute * time.Duration(s.config.AccessTokenTTL))
+	if expiresAt.Before(tokenExpiry) {
+		tokenExpiry = expiresAt
+	}
+
+	claims := &TokenClaims{
+		UserID:       current.UserID,
+		Username:     current.Username,
+		Roles:        roles,
+		ElevationID:  elevationID,
+		AuthTime:     current.AuthTime,
+		ACR:          current.ACR,
+		AMR:          current.AMR,
+		ClientID:     current.ClientID,
+		Confirmation: current.Confirmation,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: tokenExpiry.Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    "CryptoFortress Auth Service",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.config.JWTSecret))
+}
+
+// GenerateBreakGlassAccessToken creates the access token of an emergency access session.
+// It expires with the session and is never paired with a refresh token.
+func (s *authServiceImpl) GenerateBreakGlassAccessToken(user *User, sessionID string, expiresAt time.Time) (string, error) {
+	if sessionID == "" {
+		return "", errors.New("break-glass session ID is required")
+	}
+
+	now := time.Now()
+	claims := &TokenClaims{
+		UserID:              user.ID,
+		Username:            user.Username,
+		Roles:               user.Roles,
+		BreakGlassSessionID: sessionID,
+		AuthTime:            now.Unix(),
+		ACR:                 ACRBreakGlass,
+		AMR:                 []string{"shamir"},
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expiresAt.Unix(),
+			IssuedAt:  now.Unix(),
+			Issuer:    "CryptoFortress Auth Service",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.config.JWTSecret))
+}
+
+// confirmationClaim returns the cnf claim for a DPoP binding, or nil for a plain bearer token
+func confirmationClaim(binding TokenBinding) *ConfirmationClaim {
+	if binding.JKT == "" {
+		return nil
+	}
+	return &ConfirmationClaim{JKT: binding.JKT}
+}
+
+// GenerateImpersonationToken creates a short-lived access token for the target user
+// that records the impersonating actor in an RFC 8693 "act" claim.
+func (s *authServiceImpl) GenerateImpersonationToken(actorID, targetUserID string, roles []string, reason string) (string, error) {
+	if actorID == "" || targetUserID == "" {
+		return "", errors.New("actor and target user are required")
+	}
+	if actorID == targetUserID {
+		return "", errors.New("users cannot impersonate themselves")
+	}
+	if reason == "" {
+		return "", errors.New("impersonation reason is required")
+	}
+
+	claims := &TokenClaims{
+		UserID:              targetUserID,
+		Roles:               roles,
+		Actor:               &ActorClaim{Subject: actorID},
+		ImpersonationReason: reason,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Minute * time.Duration(s.config.ImpersonationTokenTTL)).Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    "CryptoFortress Auth Service",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(s.config.JWTSecret))
+}
+
+// GenerateRefreshToken creates a new refresh token. Its access tokens are limited to the scope, if any.
+func (s *authServiceImpl) GenerateRefreshToken(userID string, scope []string) (string, error) {
 	// Generate a random token
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
@@ -52,8 +258,19 @@ func (s *authServiceImpl) GenerateRefreshToken(userID string) (string, error) {
 	
 	token := base64.URLEncoding.EncodeToString(tokenBytes)
 	
-	// In a real implementation, you would store this token in a database
-	// with the user ID and expiration time for validation later
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if _, exists := s.users[userID]; !exists {
+		return "", ErrUserNotFound
+	}
+	
+	// Only a hash of the token is kept, so a leaked store cannot be replayed
+	s.refreshTokens[hashRefreshToken(token)] = &refreshTokenRecord{
+		userID:    userID,
+		scope:     scope,
+		expiresAt: time.Now().Add(time.Hour * time.Duration(s.config.RefreshTokenTTL)),
+	}
 	
 	return token, nil
 }
@@ -77,59 +294,160 @@ func (s *authServiceImpl) ValidateAccessToken(tokenString string) (*TokenClaims,
 		return nil, errors.New("invalid token")
 	}
 	
+	// Elevated roles end with their elevation, whether it expires or is revoked
+	var elevatedRole string
+	if claims.ElevationID != "" {
+		elevation, err := s.elevationService.GetElevation(claims.ElevationID)
+		if err != nil || elevation.UserID != claims.UserID || elevation.Status != ElevationActive {
+			return nil, ErrElevationInactive
+		}
+		elevatedRole = elevation.RoleName
+	}
+	
+	// Roles removed since the token was issued no longer count. A break-glass token's roles
+	// belong to its account, not to RBAC, and end with its session.
+	if claims.BreakGlassSessionID == "" {
+		held, err := s.rbacService.GetUserRoles(claims.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user roles: %w", err)
+		}
+		if elevatedRole != "" {
+			held = append(held, elevatedRole)
+		}
+		claims.Roles = heldRoles(claims.Roles, held)
+	}
+	
+	// Disabled and deleted accounts lose access immediately, not when their tokens expire
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	if err := s.checkSessionLocked(claims.UserID, claims.IssuedAt); err != nil {
+		return nil, err
+	}
+	if claims.Actor != nil {
+		if err := s.checkSessionLocked(claims.Actor.Subject, claims.IssuedAt); err != nil {
+			return nil, fmt.Errorf("impersonating actor: %w", err)
+		}
+	}
+	
 	return claims, nil
 }
 
-// ValidateRefreshToken validates a refresh token
+// checkSessionLocked verifies that a token issued at issuedAt still belongs to an active
+// session of the user. The caller must hold s.mu.
+func (s *authServiceImpl) checkSessionLocked(userID string, issuedAt int64) error {
+	record, ok := s.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	
+	switch record.Status {
+	case UserDisabled:
+		return ErrUserDisabled
+	case UserDeleted:
+		return ErrUserDeleted
+	}
+	
+	if issuedAt <= record.sessionsRevokedAt.Unix() {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// ValidateRefreshToken validates a refresh token and returns the claims of its user
 func (s *authServiceImpl) ValidateRefreshToken(tokenString string) (*TokenClaims, error) {
-	// In a real implementation, you would check the database to see if this token exists
-	// and hasn't been revoked, then return the associated user claims
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	key := hashRefreshToken(tokenString)
+	record, ok := s.refreshTokens[key]
+	if !ok {
+		return nil, errors.New("invalid refresh token")
+	}
+	if time.Now().After(record.expiresAt) {
+		delete(s.refreshTokens, key)
+		return nil, errors.New("refresh token has expired")
+	}
+	
+	user, ok := s.users[record.userID]
+	if !ok || user.Status != UserActive {
+		delete(s.refreshTokens, key)
+		return nil, errors.New("invalid refresh token")
+	}
+	
+	roles, err := s.rbacService.GetUserRoles(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
 	
-	// This is a simplified implementation
-	// In practice, you'd look up the token in storage and verify it hasn't expired or been revoked
 	return &TokenClaims{
-		UserID: "user-id-from-token-storage",
-		Roles:  []string{},
+		UserID:   user.ID,
+		Username: user.Username,
+		Roles:    limitToScope(roles, record.scope),
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(time.Hour * time.Duration(s.config.RefreshTokenTTL)).Unix(),
+			ExpiresAt: record.expiresAt.Unix(),
 		},
 	}, nil
 }
 
 // RevokeRefreshToken marks a refresh token as invalid
 func (s *authServiceImpl) RevokeRefreshToken(tokenString string) error {
-	// In a real implementation, you would mark this token as revoked in the database
-	// or add it to a blacklist
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	
+	delete(s.refreshTokens, hashRefreshToken(tokenString))
 	return nil
 }
 
-// AuthenticateUser verifies user credentials
+// AuthenticateUser verifies user credentials. The password is checked before the account
+// status so that ErrUserDisabled is only revealed to someone who knows the password.
 func (s *authServiceImpl) AuthenticateUser(username, password string) (*User, error) {
-	// In a real implementation, you would query the database for the user
-	// and compare the hashed password
+	s.mu.RLock()
+	record, ok := s.users[s.usernames[strings.ToLower(username)]]
+	var passwordHash []byte
+	var user *User
+	if ok {
+		passwordHash = record.passwordHash
+		user = s.copyUser(&record.User)
+	}
+	s.mu.RUnlock()
 	
-	// This is a simplified example
-	storedHash := "$2a$10$N.zmdr9k7uOCQb0bta/OauRxaOKSr.QhqyD2R5FKvMQjmHoLkm5Sy" // bcrypt hash for "password"
+	if !ok || user.Status == UserDeleted {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
 	
-	if err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(password)); err != nil {
-		return nil, errors.New("invalid credentials")
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
 	}
 	
-	return &User{
-		ID:       "user-123",
-		Username: username,
-		Email:    username + "@example.com",
-		Roles:    []string{"user"},
-	}, nil
+	if user.Status == UserDisabled {
+		return nil, ErrUserDisabled
+	}
+	
+	return user, nil
+}
+
+// GetUserByEmail looks up an active user by email address
+func (s *authServiceImpl) GetUserByEmail(email string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	record, ok := s.users[s.emails[normalizeEmail(email)]]
+	if !ok || record.Status != UserActive {
+		return nil, ErrUserNotFound
+	}
+	
+	return s.copyUser(&record.User), nil
 }
 
 // RegisterUser creates a new user account
 func (s *authServiceImpl) RegisterUser(username, email, password string) (*User, error) {
-	// In a real implementation, you would:
-	// 1. Check if username/email already exists
-	// 2. Hash the password
-	// 3. Store the user in the database
+	username = strings.TrimSpace(username)
+	email = normalizeEmail(email)
+	if username == "" || email == "" {
+		return nil, errors.New("username and email are required")
+	}
 	
 	// Generate a bcrypt hash of the password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -137,15 +455,308 @@ func (s *authServiceImpl) RegisterUser(username, email, password string) (*User,
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 	
-	fmt.Printf("Hashed password: %s\n", string(hashedPassword))
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	
-	// Return a new user object
-	return &User{
-		ID:       "new-user-id",
-		Username: username,
-		Email:    email,
-		Roles:    []string{"user"},
-	}, nil
+	// Deleted accounts keep their username and email until they are purged
+	if _, taken := s.usernames[strings.ToLower(username)]; taken {
+		return nil, ErrUserExists
+	}
+	if _, taken := s.emails[email]; taken {
+		return nil, ErrUserExists
+	}
+	
+	record := &userRecord{
+		User: User{
+			ID:        uuid.New().String(),
+			Username:  username,
+			Email:     email,
+			Status:    UserActive,
+			CreatedAt: time.Now(),
+		},
+		passwordHash: hashedPassword,
+	}
+	if err := s.rbacService.AssignRoleToUser(record.ID, DefaultUserRole); err != nil {
+		return nil, fmt.Errorf("failed to assign default role: %w", err)
+	}
+	s.users[record.ID] = record
+	s.usernames[strings.ToLower(username)] = record.ID
+	s.emails[email] = record.ID
+	
+	return s.copyUser(&record.User), nil
+}
+
+// GetUser returns a user in any lifecycle state
+func (s *authServiceImpl) GetUser(userID string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	record, ok := s.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	
+	return s.copyUser(&record.User), nil
+}
+
+// UpdateProfile changes the profile fields of an active user
+func (s *authServiceImpl) UpdateProfile(userID string, update ProfileUpdate) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	record, err := s.activeUserLocked(userID)
+	if err != nil {
+		return nil, err
+	}
+	
+	if update.Email != nil {
+		email := normalizeEmail(*update.Email)
+		if email == "" {
+			return nil, errors.New("email cannot be empty")
+		}
+		if ownerID, taken := s.emails[email]; taken && ownerID != userID {
+			return nil, ErrUserExists
+		}
+		delete(s.emails, record.Email)
+		s.emails[email] = userID
+		record.Email = email
+	}
+	if update.DisplayName != nil {
+		record.DisplayName = strings.TrimSpace(*update.DisplayName)
+	}
+	
+	return s.copyUser(&record.User), nil
+}
+
+// ChangePassword replaces a user's password after checking the current one.
+// Every refresh token of the user is revoked, so other devices have to log in again.
+func (s *authServiceImpl) ChangePassword(userID, currentPassword, newPassword string) error {
+	s.mu.RLock()
+	record, err := s.activeUserLocked(userID)
+	var passwordHash []byte
+	if err == nil {
+		passwordHash = record.passwordHash
+	}
+	s.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	
+	if err := bcrypt.CompareHashAndPassword(passwordHash, []byte(currentPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+	
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	record, err = s.activeUserLocked(userID)
+	if err != nil {
+		return err
+	}
+	record.passwordHash = hashedPassword
+	s.revokeRefreshTokensLocked(userID)
+	
+	return nil
+}
+
+// DisableUser blocks an account and revokes all of its sessions
+func (s *authServiceImpl) DisableUser(userID string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	record, ok := s.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if record.Status == UserDeleted {
+		return nil, ErrUserDeleted
+	}
+	
+	if record.Status == UserActive {
+		now := time.Now()
+		record.Status = UserDisabled
+		record.DisabledAt = &now
+		s.revokeSessionsLocked(record, now)
+	}
+	
+	return s.copyUser(&record.User), nil
+}
+
+// EnableUser reactivates a disabled account. Sessions revoked when it was disabled stay revoked.
+func (s *authServiceImpl) EnableUser(userID string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	record, ok := s.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if record.Status == UserDeleted {
+		return nil, ErrUserDeleted
+	}
+	
+	record.Status = UserActive
+	record.DisabledAt = nil
+	
+	return s.copyUser(&record.User), nil
+}
+
+// DeleteUser soft-deletes an account. It can be restored until the grace period ends,
+// after which PurgeDeletedUsers removes it permanently.
+func (s *authServiceImpl) DeleteUser(userID string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	record, ok := s.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	
+	if record.Status != UserDeleted {
+		now := time.Now()
+		purgeAt := now.AddDate(0, 0, s.config.UserDeletionGracePeriod)
+		record.Status = UserDeleted
+		record.DeletedAt = &now
+		record.PurgeAt = &purgeAt
+		s.revokeSessionsLocked(record, now)
+	}
+	
+	return s.copyUser(&record.User), nil
+}
+
+// RestoreUser undoes a soft delete during the grace period. An account that was disabled
+// before it was deleted is restored as disabled.
+func (s *authServiceImpl) RestoreUser(userID string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	record, ok := s.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	if record.Status != UserDeleted {
+		return nil, ErrUserNotDeleted
+	}
+	
+	record.Status = UserActive
+	if record.DisabledAt != nil {
+		record.Status = UserDisabled
+	}
+	record.DeletedAt = nil
+	record.PurgeAt = nil
+	
+	return s.copyUser(&record.User), nil
+}
+
+// PurgeDeletedUsers permanently removes deleted accounts whose grace period has ended
+func (s *authServiceImpl) PurgeDeletedUsers() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	now := time.Now()
+	purged := 0
+	for id, record := range s.users {
+		if record.Status != UserDeleted || record.PurgeAt == nil || now.Before(*record.PurgeAt) {
+			continue
+		}
+		s.revokeRefreshTokensLocked(id)
+		s.removeRoles(id)
+		delete(s.usernames, strings.ToLower(record.Username))
+		delete(s.emails, record.Email)
+		delete(s.users, id)
+		purged++
+	}
+	
+	return purged, nil
+}
+
+// activeUserLocked returns the record of an active user. The caller must hold s.mu.
+func (s *authServiceImpl) activeUserLocked(userID string) (*userRecord, error) {
+	record, ok := s.users[userID]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	switch record.Status {
+	case UserDisabled:
+		return nil, ErrUserDisabled
+	case UserDeleted:
+		return nil, ErrUserDeleted
+	}
+	return record, nil
+}
+
+// revokeSessionsLocked invalidates every access and refresh token issued to a user
+// up to now. The caller must hold s.mu.
+func (s *authServiceImpl) revokeSessionsLocked(record *userRecord, now time.Time) {
+	record.sessionsRevokedAt = now
+	s.revokeRefreshTokensLocked(record.ID)
+}
+
+// revokeRefreshTokensLocked deletes every refresh token of a user. The caller must hold s.mu.
+func (s *authServiceImpl) revokeRefreshTokensLocked(userID string) {
+	for key, token := range s.refreshTokens {
+		if token.userID == userID {
+			delete(s.refreshTokens, key)
+		}
+	}
+}
+
+// copyUser returns a snapshot of a user that is safe to hand out, with the roles
+// currently assigned to them
+func (s *authServiceImpl) copyUser(user *User) *User {
+	c := *user
+	c.Roles, _ = s.rbacService.GetUserRoles(user.ID)
+	return &c
+}
+
+// limitToScope keeps the roles named in a scope, or all roles when there is no scope
+func limitToScope(roles, scope []string) []string {
+	if len(scope) == 0 {
+		return roles
+	}
+	
+	limited := []string{}
+	for _, role := range roles {
+		if containsRole(scope, role) {
+			limited = append(limited, role)
+		}
+	}
+	return limited
+}
+
+// heldRoles keeps the roles of a token that are in held
+func heldRoles(roles, held []string) []string {
+	kept := []string{}
+	for _, role := range roles {
+		if containsRole(held, role) {
+			kept = append(kept, role)
+		}
+	}
+	return kept
+}
+
+// removeRoles drops every role assignment of a purged user
+func (s *authServiceImpl) removeRoles(userID string) {
+	roles, _ := s.rbacService.GetUserRoles(userID)
+	for _, role := range roles {
+		s.rbacService.RemoveRoleFromUser(userID, role)
+	}
+}
+
+// normalizeEmail lower-cases and trims an email address for lookups
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// hashRefreshToken hashes a refresh token for storage
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
 }
 
 // InitiateOAuthFlow starts the OAuth2 flow for the specified provider
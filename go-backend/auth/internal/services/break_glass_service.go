@@ -0,0 +1,560 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrBreakGlassAccountNotFound is returned when a break-glass account does not exist
+	ErrBreakGlassAccountNotFound = errors.New("break-glass account not found")
+	// ErrBreakGlassInvalidShares is returned when the presented shares do not recover the credential
+	ErrBreakGlassInvalidShares = errors.New("invalid break-glass credential shares")
+	// ErrBreakGlassSessionActive is returned when an account already has an open session
+	ErrBreakGlassSessionActive = errors.New("break-glass account already has an active session")
+	// ErrBreakGlassSessionNotFound is returned when a break-glass session does not exist or has ended
+	ErrBreakGlassSessionNotFound = errors.New("break-glass session not found or already ended")
+)
+
+const (
+	// breakGlassSharePrefix marks a break-glass credential share and its format version
+	breakGlassSharePrefix = "cfbg1."
+	// breakGlassSecretSize is the size of a break-glass credential in bytes
+	breakGlassSecretSize = 32
+)
+
+// breakGlassAccount is the stored state of a break-glass account
+type breakGlassAccount struct {
+	BreakGlassAccount
+	verifier [sha256.Size]byte // hash of the current credential
+
+	// rotating is held for a whole rotation, while shares are delivered without s.mu.
+	// The generation and verifier only change while it is held.
+	rotating sync.Mutex
+}
+
+// breakGlassRotation is a new credential whose shares must reach every custodian
+// before it replaces the current one
+type breakGlassRotation struct {
+	generation int
+	verifier   [sha256.Size]byte
+	messages   []string // one per custodian
+}
+
+// breakGlassServiceImpl implements the BreakGlassService interface
+type breakGlassServiceImpl struct {
+	config      *config.Config
+	authService AuthService
+	notifier    Notifier
+	events      SecurityEventSink
+
+	mu       sync.Mutex
+	accounts map[string]*breakGlassAccount // keyed by account ID
+	names    map[string]string             // account name to account ID
+	sessions map[string]*BreakGlassSession
+	// In a real implementation, accounts and sessions would be stored in the database
+}
+
+// NewBreakGlassService creates a new instance of the break-glass service
+func NewBreakGlassService(cfg *config.Config, authService AuthService, notifier Notifier, events SecurityEventSink) BreakGlassService {
+	return &breakGlassServiceImpl{
+		config:      cfg,
+		authService: authService,
+		notifier:    notifier,
+		events:      events,
+		accounts:    make(map[string]*breakGlassAccount),
+		names:       make(map[string]string),
+		sessions:    make(map[string]*BreakGlassSession),
+	}
+}
+
+// CreateAccount creates a break-glass account and sends each custodian their share of its
+// credential. The account's user stays disabled except during an emergency session.
+func (s *breakGlassServiceImpl) CreateAccount(name string, roles, custodians []string, threshold int, createdBy string) (*BreakGlassAccount, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("account name is required")
+	}
+	if len(roles) == 0 {
+		return nil, errors.New("at least one role is required")
+	}
+	if threshold < 1 || threshold > len(custodians) {
+		return nil, fmt.Errorf("threshold must be between 1 and the number of custodians (%d)", len(custodians))
+	}
+	normalized := make([]string, 0, len(custodians))
+	seen := make(map[string]bool, len(custodians))
+	for _, custodian := range custodians {
+		custodian = normalizeEmail(custodian)
+		if custodian == "" || seen[custodian] {
+			return nil, errors.New("custodians must be distinct email addresses")
+		}
+		seen[custodian] = true
+		normalized = append(normalized, custodian)
+	}
+
+	// The name is reserved while the shares are delivered, without holding s.mu
+	s.mu.Lock()
+	if _, exists := s.names[name]; exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("break-glass account %s already exists", name)
+	}
+	s.names[name] = ""
+	s.mu.Unlock()
+
+	account, err := s.newAccount(name, roles, normalized, threshold, createdBy)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		delete(s.names, name)
+		return nil, err
+	}
+	s.accounts[account.ID] = account
+	s.names[name] = account.ID
+
+	s.emit(SecurityEvent{
+		Type:     "break_glass_account_created",
+		Severity: SeverityMedium,
+		Message:  "Break-glass account created",
+		Data: map[string]string{
+			"account":    name,
+			"created_by": createdBy,
+			"threshold":  strconv.Itoa(threshold),
+			"custodians": strconv.Itoa(len(custodians)),
+		},
+	})
+
+	return copyBreakGlassAccount(&account.BreakGlassAccount), nil
+}
+
+// newAccount creates an account's disabled user and delivers the shares of its first credential
+func (s *breakGlassServiceImpl) newAccount(name string, roles, custodians []string, threshold int, createdBy string) (*breakGlassAccount, error) {
+	// The user's password is thrown away; only a quorum of shares can open a session
+	password := make([]byte, 32)
+	if _, err := rand.Read(password); err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	username := "break-glass-" + name
+	user, err := s.authService.RegisterUser(username, username+"@break-glass.invalid", base64.RawURLEncoding.EncodeToString(password))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create break-glass user: %w", err)
+	}
+	if _, err := s.authService.DisableUser(user.ID); err != nil {
+		return nil, fmt.Errorf("failed to disable break-glass user: %w", err)
+	}
+
+	now := time.Now()
+	account := &breakGlassAccount{
+		BreakGlassAccount: BreakGlassAccount{
+			ID:         uuid.New().String(),
+			Name:       name,
+			UserID:     user.ID,
+			Roles:      append([]string(nil), roles...),
+			Custodians: custodians,
+			Threshold:  threshold,
+			CreatedBy:  createdBy,
+			CreatedAt:  now,
+		},
+	}
+
+	// Nobody else can see the account yet, so it needs no locking
+	rotation, err := newBreakGlassRotation(account)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.deliverShares(account, rotation); err != nil {
+		return nil, err
+	}
+	rotation.apply(account)
+
+	return account, nil
+}
+
+// ListAccounts returns all break-glass accounts ordered by name
+func (s *breakGlassServiceImpl) ListAccounts() ([]BreakGlassAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts := make([]BreakGlassAccount, 0, len(s.accounts))
+	for _, account := range s.accounts {
+		accounts = append(accounts, *copyBreakGlassAccount(&account.BreakGlassAccount))
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Name < accounts[j].Name
+	})
+
+	return accounts, nil
+}
+
+// RotateCredential replaces an account's credential and sends custodians their new shares
+func (s *breakGlassServiceImpl) RotateCredential(accountID, rotatedBy string) (*BreakGlassAccount, error) {
+	s.mu.Lock()
+	account, ok := s.accounts[accountID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrBreakGlassAccountNotFound
+	}
+	if err := s.rotate(account); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.emit(SecurityEvent{
+		Type:     "break_glass_credential_rotated",
+		Severity: SeverityMedium,
+		Message:  "Break-glass credential rotated",
+		Data: map[string]string{
+			"account":    account.Name,
+			"rotated_by": rotatedBy,
+			"generation": strconv.Itoa(account.Generation),
+		},
+	})
+
+	return copyBreakGlassAccount(&account.BreakGlassAccount), nil
+}
+
+// Activate opens an emergency session. Every attempt, successful or not, is reported as a
+// high-severity security event, and a successful one rotates the credential.
+func (s *breakGlassServiceImpl) Activate(accountName string, shares []string, justification, clientIP string) (*BreakGlassSession, string, error) {
+	justification = strings.TrimSpace(justification)
+	if justification == "" {
+		return nil, "", errors.New("justification is required")
+	}
+
+	s.mu.Lock()
+	session, token, account, err := s.activateLocked(accountName, shares, justification, clientIP)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The shares that were just combined are no longer secret
+	if err := s.rotate(account); err != nil {
+		s.emit(SecurityEvent{
+			Type:     "break_glass_rotation_failed",
+			Severity: SeverityCritical,
+			Message:  "Break-glass credential could not be rotated after use",
+			Data: map[string]string{
+				"account": account.Name,
+				"error":   err.Error(),
+			},
+		})
+	}
+
+	return session, token, nil
+}
+
+// activateLocked checks an activation and opens its session. The caller must hold s.mu.
+func (s *breakGlassServiceImpl) activateLocked(accountName string, shares []string, justification, clientIP string) (*BreakGlassSession, string, *breakGlassAccount, error) {
+	account, ok := s.accounts[s.names[accountName]]
+	if !ok {
+		s.activationFailed(accountName, clientIP, justification, ErrBreakGlassAccountNotFound)
+		return nil, "", nil, ErrBreakGlassAccountNotFound
+	}
+
+	now := time.Now()
+	if active, ok := s.sessions[account.ActiveSessionID]; ok && active.EndedAt == nil && now.Before(active.ExpiresAt) {
+		s.activationFailed(accountName, clientIP, justification, ErrBreakGlassSessionActive)
+		return nil, "", nil, ErrBreakGlassSessionActive
+	}
+
+	if err := s.verifySharesLocked(account, shares); err != nil {
+		s.activationFailed(accountName, clientIP, justification, err)
+		return nil, "", nil, ErrBreakGlassInvalidShares
+	}
+
+	session := &BreakGlassSession{
+		ID:            uuid.New().String(),
+		AccountID:     account.ID,
+		AccountName:   account.Name,
+		UserID:        account.UserID,
+		Justification: justification,
+		ClientIP:      clientIP,
+		StartedAt:     now,
+		ExpiresAt:     now.Add(time.Duration(s.config.BreakGlassSessionTTL) * time.Minute),
+	}
+
+	user, err := s.authService.EnableUser(account.UserID)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to enable break-glass user: %w", err)
+	}
+	token, err := s.authService.GenerateBreakGlassAccessToken(&User{
+		ID:       user.ID,
+		Username: user.Username,
+		Roles:    account.Roles,
+	}, session.ID, session.ExpiresAt)
+	if err != nil {
+		s.disableUser(account)
+		return nil, "", nil, fmt.Errorf("failed to generate break-glass token: %w", err)
+	}
+
+	s.sessions[session.ID] = session
+	account.ActiveSessionID = session.ID
+	account.LastUsedAt = &now
+
+	s.emit(SecurityEvent{
+		Type:     "break_glass_activated",
+		Severity: SeverityHigh,
+		Message:  "Break-glass account activated",
+		Data: map[string]string{
+			"account":       account.Name,
+			"session_id":    session.ID,
+			"user_id":       account.UserID,
+			"roles":         strings.Join(account.Roles, ","),
+			"justification": justification,
+			"client_ip":     clientIP,
+			"expires_at":    session.ExpiresAt.Format(time.RFC3339),
+		},
+	})
+
+	result := *session
+	return &result, token, account, nil
+}
+
+// EndSession closes an emergency session before it expires
+func (s *breakGlassServiceImpl) EndSession(sessionID, endedBy string) (*BreakGlassSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok || session.EndedAt != nil {
+		return nil, ErrBreakGlassSessionNotFound
+	}
+	s.endSessionLocked(session, time.Now(), endedBy, "break_glass_session_ended")
+
+	result := *session
+	return &result, nil
+}
+
+// GetSession returns a break-glass session
+func (s *breakGlassServiceImpl) GetSession(sessionID string) (*BreakGlassSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok {
+		return nil, ErrBreakGlassSessionNotFound
+	}
+
+	result := *session
+	return &result, nil
+}
+
+// ExpireSessions ends every session whose time box has passed
+func (s *breakGlassServiceImpl) ExpireSessions() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	expired := 0
+	for _, session := range s.sessions {
+		if session.EndedAt == nil && !now.Before(session.ExpiresAt) {
+			s.endSessionLocked(session, now, "system", "break_glass_session_expired")
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// endSessionLocked closes a session and disables the account's user, which revokes
+// its access token at once: token validation here and through the gRPC Validate call,
+// which the other services use, rejects tokens of disabled users. The caller must hold s.mu.
+func (s *breakGlassServiceImpl) endSessionLocked(session *BreakGlassSession, now time.Time, endedBy, eventType string) {
+	session.EndedAt = &now
+	session.EndedBy = endedBy
+
+	if account, ok := s.accounts[session.AccountID]; ok {
+		if account.ActiveSessionID == session.ID {
+			account.ActiveSessionID = ""
+		}
+		s.disableUser(account)
+	}
+
+	s.emit(SecurityEvent{
+		Type:     eventType,
+		Severity: SeverityHigh,
+		Message:  "Break-glass session closed",
+		Data: map[string]string{
+			"account":    session.AccountName,
+			"session_id": session.ID,
+			"ended_by":   endedBy,
+			"duration":   now.Sub(session.StartedAt).Round(time.Second).String(),
+		},
+	})
+}
+
+// verifySharesLocked checks that the shares recover the account's current credential.
+// The caller must hold s.mu.
+func (s *breakGlassServiceImpl) verifySharesLocked(account *breakGlassAccount, encoded []string) error {
+	if len(encoded) < account.Threshold {
+		return fmt.Errorf("%d shares presented, %d required", len(encoded), account.Threshold)
+	}
+
+	shares := make([][]byte, 0, len(encoded))
+	for _, value := range encoded {
+		generation, share, err := decodeBreakGlassShare(value)
+		if err != nil {
+			return err
+		}
+		if generation != account.Generation {
+			return errors.New("share belongs to a rotated credential")
+		}
+		shares = append(shares, share)
+	}
+
+	secret, err := combineShares(shares)
+	if err != nil {
+		return err
+	}
+	verifier := sha256.Sum256(secret)
+	if subtle.ConstantTimeCompare(verifier[:], account.verifier[:]) != 1 {
+		return errors.New("shares do not recover the credential")
+	}
+	return nil
+}
+
+// rotate delivers a new credential's shares to the custodians and then replaces the
+// verifier. If delivery fails the current credential stays valid. Concurrent rotations
+// of an account wait for each other, and s.mu is not held while email is sent.
+func (s *breakGlassServiceImpl) rotate(account *breakGlassAccount) error {
+	account.rotating.Lock()
+	defer account.rotating.Unlock()
+
+	rotation, err := newBreakGlassRotation(account)
+	if err != nil {
+		return err
+	}
+	if err := s.deliverShares(account, rotation); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	rotation.apply(account)
+	s.mu.Unlock()
+	return nil
+}
+
+// newBreakGlassRotation generates the next credential of an account and one share message
+// per custodian. The caller must hold account.rotating unless the account is not shared yet.
+func newBreakGlassRotation(account *breakGlassAccount) (*breakGlassRotation, error) {
+	secret := make([]byte, breakGlassSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate credential: %w", err)
+	}
+	shares, err := splitSecret(secret, account.Threshold, len(account.Custodians))
+	if err != nil {
+		return nil, err
+	}
+
+	rotation := &breakGlassRotation{
+		generation: account.Generation + 1,
+		verifier:   sha256.Sum256(secret),
+	}
+	for i := range account.Custodians {
+		rotation.messages = append(rotation.messages, fmt.Sprintf("You are a custodian of the CryptoFortress break-glass account %q.\n\n"+
+			"Your credential share (generation %d, %d of %d shares are needed to activate the account):\n\n%s\n\n"+
+			"Store it offline and delete this message. Earlier shares for this account no longer work.\n",
+			account.Name, rotation.generation, account.Threshold, len(shares), encodeBreakGlassShare(rotation.generation, shares[i])))
+	}
+	return rotation, nil
+}
+
+// deliverShares sends each custodian their share of a new credential
+func (s *breakGlassServiceImpl) deliverShares(account *breakGlassAccount, rotation *breakGlassRotation) error {
+	for i, custodian := range account.Custodians {
+		if err := s.notifier.SendEmail(custodian, "CryptoFortress break-glass credential share", rotation.messages[i]); err != nil {
+			return fmt.Errorf("failed to deliver share to custodian %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// apply makes a delivered credential the account's current one. The caller must hold s.mu
+// unless the account is not shared yet.
+func (r *breakGlassRotation) apply(account *breakGlassAccount) {
+	account.verifier = r.verifier
+	account.Generation = r.generation
+	account.RotatedAt = time.Now()
+}
+
+// activationFailed reports a failed activation attempt
+func (s *breakGlassServiceImpl) activationFailed(accountName, clientIP, justification string, reason error) {
+	s.emit(SecurityEvent{
+		Type:     "break_glass_activation_failed",
+		Severity: SeverityHigh,
+		Message:  "Break-glass activation failed",
+		Data: map[string]string{
+			"account":       accountName,
+			"client_ip":     clientIP,
+			"justification": justification,
+			"reason":        reason.Error(),
+		},
+	})
+}
+
+// disableUser returns an account's user to its disabled resting state
+func (s *breakGlassServiceImpl) disableUser(account *breakGlassAccount) {
+	if _, err := s.authService.DisableUser(account.UserID); err != nil {
+		log.Error().Err(err).Str("account", account.Name).Msg("Failed to disable break-glass user")
+	}
+}
+
+// emit publishes a security event, logging delivery failures
+func (s *breakGlassServiceImpl) emit(event SecurityEvent) {
+	if err := s.events.Emit(event); err != nil {
+		log.Error().Err(err).Str("security_event", event.Type).Msg("Failed to publish security event")
+	}
+}
+
+// encodeBreakGlassShare formats a credential share for custodians
+func encodeBreakGlassShare(generation int, share []byte) string {
+	return breakGlassSharePrefix + strconv.Itoa(generation) + "." + base64.RawURLEncoding.EncodeToString(share)
+}
+
+// decodeBreakGlassShare parses a credential share
+func decodeBreakGlassShare(value string) (int, []byte, error) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(value), breakGlassSharePrefix)
+	if !found {
+		return 0, nil, errors.New("unrecognized share format")
+	}
+	generationPart, sharePart, found := strings.Cut(rest, ".")
+	if !found {
+		return 0, nil, errors.New("unrecognized share format")
+	}
+
+	generation, err := strconv.Atoi(generationPart)
+	if err != nil {
+		return 0, nil, errors.New("invalid share generation")
+	}
+	share, err := base64.RawURLEncoding.DecodeString(sharePart)
+	if err != nil || len(share) != breakGlassSecretSize+1 {
+		return 0, nil, errors.New("invalid share encoding")
+	}
+
+	return generation, share, nil
+}
+
+// copyBreakGlassAccount returns a snapshot of an account that is safe to hand out
+func copyBreakGlassAccount(account *BreakGlassAccount) *BreakGlassAccount {
+	c := *account
+	c.Roles = append([]string(nil), account.Roles...)
+	c.Custodians = append([]string(nil), account.Custodians...)
+	return &c
+}
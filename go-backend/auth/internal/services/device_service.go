@@ -0,0 +1,259 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+)
+
+// Device token endpoint errors, named after their RFC 8628 error codes
+var (
+	ErrAuthorizationPending = errors.New("authorization_pending")
+	ErrSlowDown             = errors.New("slow_down")
+	ErrAccessDenied         = errors.New("access_denied")
+	ErrExpiredToken         = errors.New("expired_token")
+	ErrInvalidDeviceCode    = errors.New("invalid_grant")
+)
+
+// ErrUserCodeNotFound is returned when a user code does not match a pending authorization
+var ErrUserCodeNotFound = errors.New("user code not found or expired")
+
+const (
+	// userCodeAlphabet avoids vowels and look-alike characters (RFC 8628 section 6.1)
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+	// slowDownIncrement is added to the polling interval each time a client polls too fast
+	slowDownIncrement = 5 * time.Second
+)
+
+// deviceAuthorization is the server-side state of a device authorization
+type deviceAuthorization struct {
+	DeviceAuthorization
+	lastPoll time.Time
+}
+
+// deviceServiceImpl implements the DeviceAuthorizationService interface
+type deviceServiceImpl struct {
+	config *config.Config
+
+	mu         sync.Mutex
+	byDevice   map[string]*deviceAuthorization // keyed by device code hash
+	byUserCode map[string]*deviceAuthorization
+	// In a real implementation, device authorizations would be persisted in the database
+}
+
+// NewDeviceAuthorizationService creates a new instance of the device authorization service
+func NewDeviceAuthorizationService(cfg *config.Config) DeviceAuthorizationService {
+	return &deviceServiceImpl{
+		config:     cfg,
+		byDevice:   make(map[string]*deviceAuthorization),
+		byUserCode: make(map[string]*deviceAuthorization),
+	}
+}
+
+// RequestDeviceCode starts a device authorization for a client
+func (s *deviceServiceImpl) RequestDeviceCode(clientID, scope string) (*DeviceAuthorization, error) {
+	if clientID == "" {
+		return nil, errors.New("client ID is required")
+	}
+
+	deviceCodeBytes := make([]byte, 32)
+	if _, err := rand.Read(deviceCodeBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate device code: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneLocked(now)
+
+	userCode, err := s.newUserCodeLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &deviceAuthorization{
+		DeviceAuthorization: DeviceAuthorization{
+			DeviceCode: base64.RawURLEncoding.EncodeToString(deviceCodeBytes),
+			UserCode:   userCode,
+			ClientID:   clientID,
+			Scope:      scope,
+			Status:     DeviceAuthorizationPending,
+			Interval:   time.Duration(s.config.DevicePollInterval) * time.Second,
+			CreatedAt:  now,
+			ExpiresAt:  now.Add(time.Duration(s.config.DeviceCodeTTL) * time.Minute),
+		},
+	}
+	s.byDevice[hashDeviceCode(auth.DeviceCode)] = auth
+	s.byUserCode[userCode] = auth
+
+	result := auth.DeviceAuthorization
+	return &result, nil
+}
+
+// PollDeviceCode checks a device authorization from the polling client. An approved
+// authorization is returned exactly once; every other state is reported as an error.
+func (s *deviceServiceImpl) PollDeviceCode(deviceCode, clientID string) (*DeviceAuthorization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	auth, ok := s.byDevice[hashDeviceCode(deviceCode)]
+	if !ok || auth.ClientID != clientID {
+		return nil, ErrInvalidDeviceCode
+	}
+
+	now := time.Now()
+	if now.After(auth.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+
+	// Clients polling faster than the interval must back off
+	if !auth.lastPoll.IsZero() && now.Sub(auth.lastPoll) < auth.Interval {
+		auth.lastPoll = now
+		auth.Interval += slowDownIncrement
+		return nil, ErrSlowDown
+	}
+	auth.lastPoll = now
+
+	switch auth.Status {
+	case DeviceAuthorizationPending:
+		return nil, ErrAuthorizationPending
+	case DeviceAuthorizationDenied:
+		return nil, ErrAccessDenied
+	case DeviceAuthorizationIssued:
+		return nil, ErrInvalidDeviceCode
+	}
+
+	auth.Status = DeviceAuthorizationIssued
+	delete(s.byUserCode, auth.UserCode)
+
+	result := auth.DeviceAuthorization
+	result.Status = DeviceAuthorizationApproved
+	return &result, nil
+}
+
+// LookupUserCode returns the pending authorization for a user code so the user can
+// confirm which client they are approving
+func (s *deviceServiceImpl) LookupUserCode(userCode string) (*DeviceAuthorization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	auth, err := s.pendingLocked(userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	result := auth.DeviceAuthorization
+	return &result, nil
+}
+
+// ApproveUserCode grants the device the approving user's identity
+func (s *deviceServiceImpl) ApproveUserCode(userCode, userID, username string) (*DeviceAuthorization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	auth, err := s.pendingLocked(userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	auth.Status = DeviceAuthorizationApproved
+	auth.UserID = userID
+	auth.Username = username
+	auth.ApprovedAt = &now
+
+	result := auth.DeviceAuthorization
+	return &result, nil
+}
+
+// DenyUserCode rejects a device authorization
+func (s *deviceServiceImpl) DenyUserCode(userCode, userID string) (*DeviceAuthorization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	auth, err := s.pendingLocked(userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	auth.Status = DeviceAuthorizationDenied
+	auth.UserID = userID
+
+	result := auth.DeviceAuthorization
+	return &result, nil
+}
+
+// pendingLocked finds a pending, unexpired authorization by user code.
+// The caller must hold s.mu.
+func (s *deviceServiceImpl) pendingLocked(userCode string) (*deviceAuthorization, error) {
+	auth, ok := s.byUserCode[normalizeUserCode(userCode)]
+	if !ok || auth.Status != DeviceAuthorizationPending || time.Now().After(auth.ExpiresAt) {
+		return nil, ErrUserCodeNotFound
+	}
+	return auth, nil
+}
+
+// newUserCodeLocked generates a user code that is not currently in use.
+// The caller must hold s.mu.
+func (s *deviceServiceImpl) newUserCodeLocked() (string, error) {
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	for {
+		var code strings.Builder
+		for i := 0; i < userCodeLength; i++ {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate user code: %w", err)
+			}
+			code.WriteByte(userCodeAlphabet[n.Int64()])
+		}
+		if _, exists := s.byUserCode[code.String()]; !exists {
+			return code.String(), nil
+		}
+	}
+}
+
+// pruneLocked drops expired authorizations. The caller must hold s.mu.
+func (s *deviceServiceImpl) pruneLocked(now time.Time) {
+	for key, auth := range s.byDevice {
+		if now.After(auth.ExpiresAt) {
+			delete(s.byDevice, key)
+			delete(s.byUserCode, auth.UserCode)
+		}
+	}
+}
+
+// FormatUserCode formats a user code for display as XXXX-XXXX
+func FormatUserCode(userCode string) string {
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+// normalizeUserCode accepts user codes typed in any case, with or without separators
+func normalizeUserCode(userCode string) string {
+	userCode = strings.ToUpper(userCode)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, userCode)
+}
+
+// hashDeviceCode hashes a device code for storage
+func hashDeviceCode(deviceCode string) string {
+	hash := sha256.Sum256([]byte(deviceCode))
+	return hex.EncodeToString(hash[:])
+}
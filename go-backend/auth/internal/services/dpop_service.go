@@ -0,0 +1,284 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidDPoPProof is returned for any DPoP proof that fails validation
+var ErrInvalidDPoPProof = errors.New("invalid DPoP proof")
+
+// dpopProofType is the required typ header of a DPoP proof JWT
+const dpopProofType = "dpop+jwt"
+
+// dpopSigningMethods are the asymmetric algorithms accepted for DPoP proofs
+var dpopSigningMethods = []string{"ES256", "ES384", "RS256", "PS256", "EdDSA"}
+
+// dpopServiceImpl implements the DPoPService interface
+type dpopServiceImpl struct {
+	config          *config.Config
+	maxAge          time.Duration
+	requiredClients map[string]bool
+
+	mu        sync.Mutex
+	seenJTIs  map[string]time.Time
+	lastSweep time.Time
+	// In a real implementation, the replay cache would be shared between instances (e.g. Redis)
+}
+
+// NewDPoPService creates a new instance of the DPoP service
+func NewDPoPService(cfg *config.Config) DPoPService {
+	required := make(map[string]bool)
+	for _, clientID := range cfg.DPoPRequiredClients {
+		required[clientID] = true
+	}
+
+	return &dpopServiceImpl{
+		config:          cfg,
+		maxAge:          time.Duration(cfg.DPoPProofMaxAge) * time.Second,
+		requiredClients: required,
+		seenJTIs:        make(map[string]time.Time),
+	}
+}
+
+// dpopHeader is the JOSE header of a DPoP proof
+type dpopHeader struct {
+	Type      string          `json:"typ"`
+	Algorithm string          `json:"alg"`
+	JWK       json.RawMessage `json:"jwk"`
+}
+
+// dpopJWK is the public key embedded in a DPoP proof header
+type dpopJWK struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+	Y       string `json:"y,omitempty"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+	D       string `json:"d,omitempty"`
+}
+
+// VerifyProof validates a DPoP proof JWT for the given request and returns the
+// JWK SHA-256 thumbprint of the proof key
+func (s *dpopServiceImpl) VerifyProof(proof, method, requestURL, accessToken string) (string, error) {
+	header, err := parseDPoPHeader(proof)
+	if err != nil {
+		return "", err
+	}
+
+	var jwk dpopJWK
+	if err := json.Unmarshal(header.JWK, &jwk); err != nil {
+		return "", fmt.Errorf("%w: malformed jwk header", ErrInvalidDPoPProof)
+	}
+	publicKey, err := jwk.publicKey()
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(proof, claims, func(token *jwt.Token) (interface{}, error) {
+		return publicKey, nil
+	}, jwt.WithValidMethods(dpopSigningMethods))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidDPoPProof, err)
+	}
+
+	jti, _ := claims["jti"].(string)
+	htm, _ := claims["htm"].(string)
+	htu, _ := claims["htu"].(string)
+	iat, _ := claims["iat"].(float64)
+	if jti == "" || htm == "" || htu == "" || iat == 0 {
+		return "", fmt.Errorf("%w: jti, htm, htu and iat claims are required", ErrInvalidDPoPProof)
+	}
+
+	if htm != method {
+		return "", fmt.Errorf("%w: htm does not match request method", ErrInvalidDPoPProof)
+	}
+	if !sameHTU(htu, requestURL) {
+		return "", fmt.Errorf("%w: htu does not match request URL", ErrInvalidDPoPProof)
+	}
+
+	issuedAt := time.Unix(int64(iat), 0)
+	if age := time.Since(issuedAt); age > s.maxAge || age < -s.maxAge {
+		return "", fmt.Errorf("%w: iat is outside the accepted window", ErrInvalidDPoPProof)
+	}
+
+	// A proof presented with an access token must be bound to that token
+	if accessToken != "" {
+		ath, _ := claims["ath"].(string)
+		hash := sha256.Sum256([]byte(accessToken))
+		if ath != base64.RawURLEncoding.EncodeToString(hash[:]) {
+			return "", fmt.Errorf("%w: ath does not match access token", ErrInvalidDPoPProof)
+		}
+	}
+
+	thumbprint, err := jwk.thumbprint()
+	if err != nil {
+		return "", err
+	}
+
+	// Each proof may only be used once
+	if !s.markJTISeen(thumbprint+":"+jti, issuedAt) {
+		return "", fmt.Errorf("%w: proof has already been used", ErrInvalidDPoPProof)
+	}
+
+	return thumbprint, nil
+}
+
+// RequiresDPoP reports whether a client must use DPoP instead of plain bearer tokens.
+// A client that omits its ID cannot be told apart from a required one, so it must use
+// DPoP as soon as any client is required to.
+func (s *dpopServiceImpl) RequiresDPoP(clientID string) bool {
+	if clientID == "" {
+		return len(s.requiredClients) > 0
+	}
+	return s.requiredClients["*"] || s.requiredClients[clientID]
+}
+
+// markJTISeen records a proof identifier and reports whether it was new.
+// Entries are kept until the proof could no longer pass the iat check; expired
+// entries are swept at most once per proof lifetime.
+func (s *dpopServiceImpl) markJTISeen(key string, issuedAt time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= s.maxAge {
+		for seen, expiry := range s.seenJTIs {
+			if now.After(expiry) {
+				delete(s.seenJTIs, seen)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if expiry, ok := s.seenJTIs[key]; ok && !now.After(expiry) {
+		return false
+	}
+	s.seenJTIs[key] = issuedAt.Add(s.maxAge)
+	return true
+}
+
+// parseDPoPHeader decodes and checks the JOSE header of a DPoP proof
+func parseDPoPHeader(proof string) (*dpopHeader, error) {
+	parts := strings.Split(proof, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a compact JWS", ErrInvalidDPoPProof)
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidDPoPProof)
+	}
+
+	var header dpopHeader
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidDPoPProof)
+	}
+	if header.Type != dpopProofType {
+		return nil, fmt.Errorf("%w: typ must be %s", ErrInvalidDPoPProof, dpopProofType)
+	}
+	if len(header.JWK) == 0 {
+		return nil, fmt.Errorf("%w: jwk header is required", ErrInvalidDPoPProof)
+	}
+
+	return &header, nil
+}
+
+// publicKey converts the JWK into a public key usable for signature verification
+func (k *dpopJWK) publicKey() (interface{}, error) {
+	if k.D != "" {
+		return nil, fmt.Errorf("%w: jwk must not contain a private key", ErrInvalidDPoPProof)
+	}
+
+	switch k.KeyType {
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("%w: unsupported curve %q", ErrInvalidDPoPProof, k.Curve)
+		}
+		x, errX := decodeBigInt(k.X)
+		y, errY := decodeBigInt(k.Y)
+		if errX != nil || errY != nil || !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("%w: invalid EC key", ErrInvalidDPoPProof)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "RSA":
+		n, errN := decodeBigInt(k.N)
+		e, errE := decodeBigInt(k.E)
+		if errN != nil || errE != nil || n.BitLen() < 2048 || !e.IsInt64() {
+			return nil, fmt.Errorf("%w: invalid RSA key", ErrInvalidDPoPProof)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if k.Curve != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: invalid OKP key", ErrInvalidDPoPProof)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %q", ErrInvalidDPoPProof, k.KeyType)
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK SHA-256 thumbprint, base64url encoded
+func (k *dpopJWK) thumbprint() (string, error) {
+	// Required members only, in lexicographic order
+	var canonical string
+	switch k.KeyType {
+	case "EC":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"EC","x":%q,"y":%q}`, k.Curve, k.X, k.Y)
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, k.Curve, k.X)
+	default:
+		return "", fmt.Errorf("%w: unsupported key type %q", ErrInvalidDPoPProof, k.KeyType)
+	}
+
+	hash := sha256.Sum256([]byte(canonical))
+	return base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
+
+// decodeBigInt decodes a base64url encoded big-endian integer
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.New("invalid integer")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// sameHTU compares a proof's htu with the request URL, ignoring query and fragment
+// and the case of the scheme and host
+func sameHTU(htu, requestURL string) bool {
+	a, errA := url.Parse(htu)
+	b, errB := url.Parse(requestURL)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return strings.EqualFold(a.Scheme, b.Scheme) &&
+		strings.EqualFold(a.Host, b.Host) &&
+		a.EscapedPath() == b.EscapedPath()
+}
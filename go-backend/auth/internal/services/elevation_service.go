@@ -0,0 +1,246 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrElevationNotFound is returned when an elevation ID is unknown
+	ErrElevationNotFound = errors.New("elevation not found")
+	// ErrElevationNotPending is returned when acting on an already decided elevation
+	ErrElevationNotPending = errors.New("elevation is not pending")
+	// ErrSelfApproval is returned when a requester tries to approve their own elevation
+	ErrSelfApproval = errors.New("requester cannot approve their own elevation")
+	// ErrRoleAlreadyHeld is returned when elevating to a role the user already holds or has requested
+	ErrRoleAlreadyHeld = errors.New("user already holds or has requested this role")
+)
+
+// elevationServiceImpl implements the ElevationService interface
+type elevationServiceImpl struct {
+	config      *config.Config
+	rbacService RBACService
+
+	mu         sync.Mutex
+	elevations map[string]*Elevation
+	// In a real implementation, elevations would be persisted in the database
+}
+
+// NewElevationService creates a new instance of the elevation service
+func NewElevationService(cfg *config.Config, rbacService RBACService) ElevationService {
+	return &elevationServiceImpl{
+		config:      cfg,
+		rbacService: rbacService,
+		elevations:  make(map[string]*Elevation),
+	}
+}
+
+// RequestElevation records a pending request for a time-boxed role elevation. The role must
+// exist, and the user may neither hold it already nor have another open request for it.
+func (s *elevationServiceImpl) RequestElevation(userID, roleName, justification string, duration time.Duration) (*Elevation, error) {
+	if justification == "" {
+		return nil, errors.New("justification is required")
+	}
+
+	maxDuration := time.Duration(s.config.ElevationMaxDuration) * time.Minute
+	if duration <= 0 || duration > maxDuration {
+		return nil, fmt.Errorf("duration must be positive and at most %v", maxDuration)
+	}
+
+	exists, err := s.rbacService.RoleExists(roleName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRoleNotFound
+	}
+	held, err := s.rbacService.GetUserRoles(userID)
+	if err != nil {
+		return nil, err
+	}
+	if containsRole(held, roleName) {
+		return nil, ErrRoleAlreadyHeld
+	}
+
+	elevation := &Elevation{
+		ID:            uuid.New().String(),
+		UserID:        userID,
+		RoleName:      roleName,
+		Justification: justification,
+		Duration:      duration,
+		Status:        ElevationPending,
+		RequestedAt:   time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.elevations {
+		open := existing.Status == ElevationPending || existing.Status == ElevationActive
+		if open && existing.UserID == userID && existing.RoleName == roleName {
+			return nil, ErrRoleAlreadyHeld
+		}
+	}
+	s.elevations[elevation.ID] = elevation
+
+	return copyElevation(elevation), nil
+}
+
+// ApproveElevation records an approval and activates the elevation once enough approvals are collected
+func (s *elevationServiceImpl) ApproveElevation(elevationID, approverID string) (*Elevation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elevation, ok := s.elevations[elevationID]
+	if !ok {
+		return nil, ErrElevationNotFound
+	}
+	if elevation.Status != ElevationPending {
+		return nil, ErrElevationNotPending
+	}
+	if elevation.UserID == approverID {
+		return nil, ErrSelfApproval
+	}
+	for _, existing := range elevation.Approvers {
+		if existing == approverID {
+			return nil, fmt.Errorf("approver %s has already approved this elevation", approverID)
+		}
+	}
+
+	elevation.Approvers = append(elevation.Approvers, approverID)
+	if len(elevation.Approvers) < s.config.ElevationRequiredApprovals {
+		return copyElevation(elevation), nil
+	}
+
+	// Enough approvals: open the requested window. The role is not assigned in RBAC; only
+	// elevated tokens, which carry the elevation ID, hold it.
+	now := time.Now()
+	expiresAt := now.Add(elevation.Duration)
+	elevation.Status = ElevationActive
+	elevation.ActivatedAt = &now
+	elevation.ExpiresAt = &expiresAt
+
+	return copyElevation(elevation), nil
+}
+
+// DenyElevation rejects a pending elevation request
+func (s *elevationServiceImpl) DenyElevation(elevationID, approverID, reason string) (*Elevation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elevation, ok := s.elevations[elevationID]
+	if !ok {
+		return nil, ErrElevationNotFound
+	}
+	if elevation.Status != ElevationPending {
+		return nil, ErrElevationNotPending
+	}
+
+	elevation.Status = ElevationDenied
+	elevation.DeniedBy = approverID
+	elevation.DenyReason = reason
+
+	return copyElevation(elevation), nil
+}
+
+// RevokeElevation ends an active elevation early, which invalidates its elevated tokens
+func (s *elevationServiceImpl) RevokeElevation(elevationID, revokedBy string) (*Elevation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elevation, ok := s.elevations[elevationID]
+	if !ok {
+		return nil, ErrElevationNotFound
+	}
+
+	switch elevation.Status {
+	case ElevationPending, ElevationActive:
+		elevation.Status = ElevationRevoked
+	default:
+		return nil, fmt.Errorf("elevation is already %s", elevation.Status)
+	}
+	elevation.RevokedBy = revokedBy
+
+	return copyElevation(elevation), nil
+}
+
+// GetElevation retrieves an elevation, expiring it first if its window has passed
+func (s *elevationServiceImpl) GetElevation(elevationID string) (*Elevation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elevation, ok := s.elevations[elevationID]
+	if !ok {
+		return nil, ErrElevationNotFound
+	}
+	s.expireLocked(elevation, time.Now())
+
+	return copyElevation(elevation), nil
+}
+
+// ListPendingElevations lists elevation requests awaiting a decision, oldest first
+func (s *elevationServiceImpl) ListPendingElevations() ([]Elevation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := []Elevation{}
+	for _, elevation := range s.elevations {
+		if elevation.Status == ElevationPending {
+			pending = append(pending, *copyElevation(elevation))
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].RequestedAt.Before(pending[j].RequestedAt)
+	})
+
+	return pending, nil
+}
+
+// ExpireElevations marks every active elevation whose window has passed as expired
+func (s *elevationServiceImpl) ExpireElevations() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	expired := 0
+	for _, elevation := range s.elevations {
+		if s.expireLocked(elevation, now) {
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// expireLocked transitions an active elevation to expired once its window has passed and
+// reports whether it did. The caller must hold s.mu.
+func (s *elevationServiceImpl) expireLocked(elevation *Elevation, now time.Time) bool {
+	if elevation.Status != ElevationActive || elevation.ExpiresAt == nil || now.Before(*elevation.ExpiresAt) {
+		return false
+	}
+	elevation.Status = ElevationExpired
+	return true
+}
+
+// containsRole reports whether a role list includes the role
+func containsRole(roles []string, role string) bool {
+	for _, existing := range roles {
+		if existing == role {
+			return true
+		}
+	}
+	return false
+}
+
+// copyElevation returns a snapshot of an elevation that is safe to hand out
+func copyElevation(elevation *Elevation) *Elevation {
+	c := *elevation
+	c.Approvers = append([]string(nil), elevation.Approvers...)
+	return &c
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrLoginBlocked is returned when the risk assessment blocks a login
+	ErrLoginBlocked = errors.New("login blocked")
+	// ErrMFARequired is returned when the risk assessment demands an MFA code the login lacks
+	ErrMFARequired = errors.New("mfa required")
+	// ErrInvalidMFACode is returned when a login's MFA code is wrong
+	ErrInvalidMFACode = errors.New("invalid MFA code")
+)
+
+// loginServiceImpl implements the LoginService interface
+type loginServiceImpl struct {
+	authService         AuthService
+	mfaService          MFAService
+	riskService         RiskService
+	passwordlessService PasswordlessService
+}
+
+// NewLoginService creates a new instance of the login service
+func NewLoginService(authService AuthService, mfaService MFAService, riskService RiskService, passwordlessService PasswordlessService) LoginService {
+	return &loginServiceImpl{
+		authService:         authService,
+		mfaService:          mfaService,
+		riskService:         riskService,
+		passwordlessService: passwordlessService,
+	}
+}
+
+// PasswordLogin checks the password, evaluates the login's risk, checks the MFA code when
+// one is given or the risk demands it, records the outcome in the login history and issues
+// tokens. On ErrLoginBlocked and ErrMFARequired the result carries only the assessment.
+func (s *loginServiceImpl) PasswordLogin(req PasswordLoginRequest) (*LoginResult, error) {
+	attempt := req.Attempt
+	attempt.Username = req.Username
+
+	user, err := s.authService.AuthenticateUser(req.Username, req.Password)
+	if errors.Is(err, ErrUserDisabled) {
+		return nil, err
+	}
+	if err != nil {
+		s.recordFailure(attempt)
+		return nil, ErrInvalidCredentials
+	}
+	attempt.UserID = user.ID
+
+	return s.completeLogin(user, attempt, AuthContext{
+		AuthTime: attempt.Timestamp,
+		ACR:      ACRPassword,
+		AMR:      []string{"pwd"},
+	}, req.MFACode, req.Binding)
+}
+
+// PasswordlessLogin consumes a magic link or one-time code and then finishes the login like
+// PasswordLogin: the risk assessment may block it or demand an MFA code, and the outcome is
+// recorded in the login history. A wrong code or link is recorded as a failed login of the
+// account it was sent to.
+func (s *loginServiceImpl) PasswordlessLogin(req PasswordlessLoginRequest) (*LoginResult, error) {
+	authCtx := AuthContext{
+		AuthTime: req.Attempt.Timestamp,
+		ACR:      ACREmail,
+		AMR:      []string{"email"},
+	}
+
+	var user *User
+	var err error
+	if req.Token != "" {
+		user, err = s.passwordlessService.VerifyMagicLink(req.Token, req.BrowserNonce)
+	} else {
+		user, err = s.passwordlessService.VerifyCode(req.ChallengeID, req.Code, req.BrowserNonce)
+		authCtx.AMR = append(authCtx.AMR, "otp")
+	}
+
+	attempt := req.Attempt
+	if user != nil {
+		attempt.UserID = user.ID
+		attempt.Username = user.Username
+	}
+	if err != nil {
+		if user != nil {
+			s.recordFailure(attempt)
+		}
+		return nil, ErrPasswordlessInvalid
+	}
+
+	return s.completeLogin(user, attempt, authCtx, req.MFACode, req.Binding)
+}
+
+// completeLogin evaluates the risk of a login whose first factor has been verified, checks
+// the MFA code when one is given or the risk demands it, records the outcome in the login
+// history and issues tokens
+func (s *loginServiceImpl) completeLogin(user *User, attempt LoginAttempt, authCtx AuthContext, mfaCode string, binding TokenBinding) (*LoginResult, error) {
+	// Decide whether the login needs step-up MFA or must be blocked
+	assessment, err := s.riskService.EvaluateLogin(attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	logEvent := log.Info()
+	if assessment.Decision != RiskDecisionAllow {
+		logEvent = log.Warn()
+	}
+	logEvent.
+		Str("user_id", user.ID).
+		Str("client_ip", attempt.IPAddress).
+		Strs("amr", authCtx.AMR).
+		Float64("risk_score", assessment.Score).
+		Str("decision", string(assessment.Decision)).
+		Str("scorer", assessment.Scorer).
+		Interface("signals", assessment.Signals).
+		Msg("Login risk evaluated")
+
+	switch assessment.Decision {
+	case RiskDecisionBlock:
+		return &LoginResult{Assessment: assessment}, ErrLoginBlocked
+	case RiskDecisionStepUp:
+		if mfaCode == "" {
+			return &LoginResult{Assessment: assessment}, ErrMFARequired
+		}
+	}
+
+	// A verified MFA code raises the token's authentication context class
+	if mfaCode != "" {
+		if !s.mfaService.VerifyTOTP(user.ID, mfaCode) {
+			s.recordFailure(attempt)
+			return nil, ErrInvalidMFACode
+		}
+		authCtx.ACR = ACRMultiFactor
+		if !slices.Contains(authCtx.AMR, "otp") {
+			authCtx.AMR = append(authCtx.AMR, "otp")
+		}
+	}
+
+	if err := s.riskService.RecordLoginSuccess(attempt); err != nil {
+		log.Error().Err(err).Str("user_id", user.ID).Msg("Failed to record login history")
+	}
+
+	accessToken, err := s.authService.GenerateAuthenticatedAccessToken(user, authCtx, binding)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := s.authService.GenerateRefreshToken(user.ID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		Assessment:   assessment,
+	}, nil
+}
+
+// recordFailure adds a failed login to the risk history
+func (s *loginServiceImpl) recordFailure(attempt LoginAttempt) {
+	if err := s.riskService.RecordLoginFailure(attempt); err != nil {
+		log.Error().Err(err).Str("username", attempt.Username).Msg("Failed to record login failure")
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// NewNotifier creates the configured notifier
+func NewNotifier(cfg *config.Config) Notifier {
+	if cfg.Notifier == "smtp" {
+		return NewSMTPNotifier(cfg)
+	}
+	return NewLogNotifier()
+}
+
+// logNotifier writes messages to the service log instead of delivering them.
+// It is meant for local development only.
+type logNotifier struct{}
+
+// NewLogNotifier creates a notifier that only logs messages
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+// SendEmail logs the email; the body is only logged at debug level
+func (n *logNotifier) SendEmail(to, subject, body string) error {
+	log.Info().Str("to", to).Str("subject", subject).Msg("Email notification (not delivered)")
+	log.Debug().Str("to", to).Str("body", body).Msg("Email notification body")
+	return nil
+}
+
+// smtpNotifier delivers email through an SMTP relay
+type smtpNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPNotifier creates a notifier that sends email through the configured SMTP relay
+func NewSMTPNotifier(cfg *config.Config) Notifier {
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	return &smtpNotifier{
+		addr: net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+		from: cfg.SMTPFrom,
+		auth: auth,
+	}
+}
+
+// SendEmail sends a plain-text email
+func (n *smtpNotifier) SendEmail(to, subject, body string) error {
+	// Reject header injection through the recipient or subject
+	if strings.ContainsAny(to, "\r\n") || strings.ContainsAny(subject, "\r\n") {
+		return fmt.Errorf("invalid email header value")
+	}
+
+	message := "From: " + n.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+
+	if err := smtp.SendMail(n.addr, n.auth, n.from, []string{to}, []byte(message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
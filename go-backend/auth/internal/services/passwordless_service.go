@@ -0,0 +1,289 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrPasswordlessRateLimited is returned when an address has started too many logins,
+	// or too many logins are pending
+	ErrPasswordlessRateLimited = errors.New("too many passwordless logins, try again later")
+	// ErrPasswordlessInvalid is returned for any link or code that cannot be used to log in
+	ErrPasswordlessInvalid = errors.New("invalid or expired login link or code")
+)
+
+const (
+	// passwordlessAudience separates magic-link tokens from every other token the service signs
+	passwordlessAudience = "cryptofortress:magic-link"
+	// maxCodeAttempts is how many wrong codes a challenge accepts before it is burned
+	maxCodeAttempts = 5
+	// maxPendingChallenges bounds the unexpired challenges, which anyone can create
+	maxPendingChallenges = 10000
+)
+
+// passwordlessChallenge is the server-side state of a pending passwordless login
+type passwordlessChallenge struct {
+	id        string
+	email     string
+	user      *User // nil for unknown addresses
+	method    PasswordlessMethod
+	nonceHash string
+	codeMAC   []byte
+	attempts  int
+	expiresAt time.Time
+	used      bool
+}
+
+// passwordlessServiceImpl implements the PasswordlessService interface
+type passwordlessServiceImpl struct {
+	config      *config.Config
+	authService AuthService
+	notifier    Notifier
+	signingKey  []byte
+
+	mu         sync.Mutex
+	challenges map[string]*passwordlessChallenge
+	starts     map[string][]time.Time
+	lastSweep  time.Time
+	// In a real implementation, challenges and rate limits would live in a shared store
+}
+
+// NewPasswordlessService creates a new instance of the passwordless login service
+func NewPasswordlessService(cfg *config.Config, authService AuthService, notifier Notifier) PasswordlessService {
+	// Derive a dedicated key so magic-link tokens can never validate as access tokens
+	mac := hmac.New(sha256.New, []byte(cfg.JWTSecret))
+	mac.Write([]byte("cryptofortress passwordless login"))
+
+	return &passwordlessServiceImpl{
+		config:      cfg,
+		authService: authService,
+		notifier:    notifier,
+		signingKey:  mac.Sum(nil),
+		challenges:  make(map[string]*passwordlessChallenge),
+		starts:      make(map[string][]time.Time),
+	}
+}
+
+// magicLinkClaims are the claims of a signed magic-link token
+type magicLinkClaims struct {
+	jwt.StandardClaims
+}
+
+// StartLogin emails a magic link or one-time code bound to the browser nonce
+func (s *passwordlessServiceImpl) StartLogin(email string, method PasswordlessMethod, browserNonce string) (*PasswordlessChallenge, error) {
+	if method != PasswordlessMagicLink && method != PasswordlessCode {
+		return nil, fmt.Errorf("unsupported passwordless method: %s", method)
+	}
+	if browserNonce == "" {
+		return nil, errors.New("browser nonce is required")
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	now := time.Now()
+	if !s.allowStart(email, now) {
+		return nil, ErrPasswordlessRateLimited
+	}
+
+	challenge := &passwordlessChallenge{
+		id:        uuid.New().String(),
+		email:     email,
+		method:    method,
+		nonceHash: hashNonce(browserNonce),
+		expiresAt: now.Add(time.Duration(s.config.PasswordlessTTL) * time.Minute),
+	}
+
+	// Unknown addresses get an identical-looking challenge that can never succeed
+	user, err := s.authService.GetUserByEmail(email)
+	if err == nil {
+		challenge.user = user
+	}
+
+	var subject, body string
+	switch method {
+	case PasswordlessMagicLink:
+		token, err := s.signMagicLink(challenge)
+		if err != nil {
+			return nil, err
+		}
+		subject = "Your CryptoFortress sign-in link"
+		body = fmt.Sprintf("Use this link to sign in. It expires in %d minutes and works only once, in the browser that requested it.\n\n%s?token=%s\n",
+			s.config.PasswordlessTTL, s.config.MagicLinkURL, url.QueryEscape(token))
+	case PasswordlessCode:
+		code, err := randomCode()
+		if err != nil {
+			return nil, err
+		}
+		challenge.codeMAC = s.codeMAC(challenge.id, code)
+		subject = "Your CryptoFortress sign-in code"
+		body = fmt.Sprintf("Your sign-in code is %s. It expires in %d minutes and works only once.\n", code, s.config.PasswordlessTTL)
+	}
+
+	s.mu.Lock()
+	s.challenges[challenge.id] = challenge
+	s.mu.Unlock()
+
+	// Delivery happens in the background: waiting for the relay would make the response
+	// slower for existing accounts than for unknown addresses
+	if challenge.user != nil {
+		go func() {
+			if err := s.notifier.SendEmail(email, subject, body); err != nil {
+				log.Error().Err(err).Str("challenge_id", challenge.id).Msg("Failed to deliver passwordless login")
+			}
+		}()
+	}
+
+	return &PasswordlessChallenge{
+		ID:        challenge.id,
+		Method:    method,
+		ExpiresAt: challenge.expiresAt,
+	}, nil
+}
+
+// VerifyMagicLink checks a magic-link token and consumes its challenge
+func (s *passwordlessServiceImpl) VerifyMagicLink(token, browserNonce string) (*User, error) {
+	claims := &magicLinkClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.signingKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(passwordlessAudience), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, ErrPasswordlessInvalid
+	}
+
+	return s.consume(claims.Id, PasswordlessMagicLink, browserNonce, nil)
+}
+
+// VerifyCode checks a one-time code and consumes its challenge
+func (s *passwordlessServiceImpl) VerifyCode(challengeID, code, browserNonce string) (*User, error) {
+	return s.consume(challengeID, PasswordlessCode, browserNonce, func(challenge *passwordlessChallenge) bool {
+		return hmac.Equal(challenge.codeMAC, s.codeMAC(challenge.id, code))
+	})
+}
+
+// consume marks a challenge as used if it is valid for the browser and passes check
+func (s *passwordlessServiceImpl) consume(challengeID string, method PasswordlessMethod, browserNonce string, check func(*passwordlessChallenge) bool) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, ok := s.challenges[challengeID]
+	if !ok || challenge.used || challenge.method != method || time.Now().After(challenge.expiresAt) {
+		return nil, ErrPasswordlessInvalid
+	}
+	if subtle.ConstantTimeCompare([]byte(challenge.nonceHash), []byte(hashNonce(browserNonce))) != 1 {
+		log.Warn().Str("challenge_id", challengeID).Msg("Passwordless login attempted from a different browser")
+		return challenge.user, ErrPasswordlessInvalid
+	}
+
+	if check != nil && !check(challenge) {
+		challenge.attempts++
+		if challenge.attempts >= maxCodeAttempts {
+			challenge.used = true
+		}
+		return challenge.user, ErrPasswordlessInvalid
+	}
+
+	challenge.used = true
+	if challenge.user == nil {
+		return nil, ErrPasswordlessInvalid
+	}
+	return challenge.user, nil
+}
+
+// allowStart applies the per-address rate limit and the bound on pending challenges
+func (s *passwordlessServiceImpl) allowStart(email string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	window := time.Duration(s.config.PasswordlessRateWindow) * time.Minute
+	s.sweepLocked(now, window)
+	if len(s.challenges) >= maxPendingChallenges {
+		return false
+	}
+
+	recent := s.starts[email][:0]
+	for _, started := range s.starts[email] {
+		if now.Sub(started) < window {
+			recent = append(recent, started)
+		}
+	}
+
+	if len(recent) >= s.config.PasswordlessRateLimit {
+		s.starts[email] = recent
+		return false
+	}
+	s.starts[email] = append(recent, now)
+	return true
+}
+
+// sweepLocked drops expired challenges and addresses without a start in the rate window,
+// at most once a minute. The caller must hold s.mu.
+func (s *passwordlessServiceImpl) sweepLocked(now time.Time, window time.Duration) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for id, challenge := range s.challenges {
+		if now.After(challenge.expiresAt) {
+			delete(s.challenges, id)
+		}
+	}
+	for email, starts := range s.starts {
+		if len(starts) == 0 || now.Sub(starts[len(starts)-1]) >= window {
+			delete(s.starts, email)
+		}
+	}
+}
+
+// signMagicLink creates the signed token embedded in a magic link
+func (s *passwordlessServiceImpl) signMagicLink(challenge *passwordlessChallenge) (string, error) {
+	claims := &magicLinkClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        challenge.id,
+			Audience:  passwordlessAudience,
+			ExpiresAt: challenge.expiresAt.Unix(),
+			IssuedAt:  time.Now().Unix(),
+			Issuer:    "CryptoFortress Auth Service",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(s.signingKey)
+}
+
+// codeMAC signs a one-time code together with its challenge
+func (s *passwordlessServiceImpl) codeMAC(challengeID, code string) []byte {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(challengeID + ":" + code))
+	return mac.Sum(nil)
+}
+
+// randomCode returns a uniformly random 6-digit code
+func randomCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashNonce hashes a browser nonce for storage
+func hashNonce(nonce string) string {
+	hash := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle formats accepted by ParseRBACBundle and MarshalRBACBundle
+const (
+	BundleFormatJSON = "json"
+	BundleFormatYAML = "yaml"
+)
+
+// ParseRBACBundle decodes a bundle. Unknown fields are rejected so that typos in a
+// bundle do not silently drop configuration.
+func ParseRBACBundle(data []byte, format string) (*RBACBundle, error) {
+	var bundle RBACBundle
+	switch format {
+	case BundleFormatYAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&bundle); err != nil {
+			return nil, fmt.Errorf("invalid YAML bundle: %w", err)
+		}
+	case BundleFormatJSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&bundle); err != nil {
+			return nil, fmt.Errorf("invalid JSON bundle: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported bundle format: %s", format)
+	}
+	return &bundle, nil
+}
+
+// MarshalRBACBundle encodes a bundle
+func MarshalRBACBundle(bundle *RBACBundle, format string) ([]byte, error) {
+	switch format {
+	case BundleFormatYAML:
+		return yaml.Marshal(bundle)
+	case BundleFormatJSON:
+		return json.MarshalIndent(bundle, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported bundle format: %s", format)
+	}
+}
+
+// ExportBundle returns the current RBAC state as a bundle
+func (s *rbacServiceImpl) ExportBundle() (*RBACBundle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bundle := &RBACBundle{Version: RBACBundleVersion}
+	for _, name := range sortedKeys(s.state.permissions) {
+		bundle.Permissions = append(bundle.Permissions, BundlePermission{
+			Name:        name,
+			Description: s.state.permissions[name],
+		})
+	}
+	for _, name := range sortedKeys(s.state.roles) {
+		role := s.state.roles[name]
+		bundle.Roles = append(bundle.Roles, BundleRole{
+			Name:        name,
+			Description: role.description,
+			Inherits:    sortedKeys(role.inherits),
+			Permissions: sortedKeys(role.permissions),
+		})
+	}
+	for _, userID := range sortedKeys(s.state.assignments) {
+		bundle.Assignments = append(bundle.Assignments, BundleAssignment{
+			UserID: userID,
+			Roles:  sortedKeys(s.state.assignments[userID]),
+		})
+	}
+
+	return bundle, nil
+}
+
+// DiffBundle reports what applying the bundle would change, without changing anything
+func (s *rbacServiceImpl) DiffBundle(bundle *RBACBundle, prune bool) (*RBACDiff, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, diff, err := s.plan(bundle, prune)
+	return diff, err
+}
+
+// ApplyBundle makes the RBAC state match the bundle. The bundle is validated in full
+// before anything changes, so it is applied either completely or not at all.
+func (s *rbacServiceImpl) ApplyBundle(bundle *RBACBundle, prune bool) (*RBACDiff, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target, diff, err := s.plan(bundle, prune)
+	if err != nil {
+		return nil, err
+	}
+
+	s.state = target
+	diff.Applied = true
+	return diff, nil
+}
+
+// plan builds the state that results from applying a bundle and the changes that lead
+// there. The caller must hold s.mu.
+func (s *rbacServiceImpl) plan(bundle *RBACBundle, prune bool) (*rbacState, *RBACDiff, error) {
+	if err := validateBundle(bundle); err != nil {
+		return nil, nil, err
+	}
+
+	current := s.state
+	target := newRBACState()
+	if !prune {
+		target = current.clone()
+	}
+
+	for _, permission := range bundle.Permissions {
+		target.permissions[permission.Name] = permission.Description
+	}
+	for _, role := range bundle.Roles {
+		target.addRole(role.Name, role.Description, role.Permissions...)
+		for _, parent := range role.Inherits {
+			target.roles[role.Name].inherits[parent] = true
+		}
+	}
+	for _, assignment := range bundle.Assignments {
+		delete(target.assignments, assignment.UserID)
+		for _, roleName := range assignment.Roles {
+			if target.assignments[assignment.UserID] == nil {
+				target.assignments[assignment.UserID] = make(map[string]bool)
+			}
+			target.assignments[assignment.UserID][roleName] = true
+		}
+	}
+
+	if err := target.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	diff := diffStates(current, target)
+	if !prune {
+		diff.Changes = append(diff.Changes, unmanagedChanges(current, bundle)...)
+	}
+	sortChanges(diff.Changes)
+	diff.InSync = len(diff.Changes) == 0
+
+	return target, diff, nil
+}
+
+// validateBundle checks a bundle for an unknown version, missing names and duplicates
+func validateBundle(bundle *RBACBundle) error {
+	if bundle == nil {
+		return errors.New("bundle is required")
+	}
+	if bundle.Version != RBACBundleVersion {
+		return fmt.Errorf("unsupported bundle version %d, expected %d", bundle.Version, RBACBundleVersion)
+	}
+
+	seen := make(map[string]bool)
+	unique := func(kind, name string) error {
+		if name == "" {
+			return fmt.Errorf("%s name is required", kind)
+		}
+		if seen[kind+"/"+name] {
+			return fmt.Errorf("duplicate %s %s", kind, name)
+		}
+		seen[kind+"/"+name] = true
+		return nil
+	}
+
+	for _, permission := range bundle.Permissions {
+		if err := unique("permission", permission.Name); err != nil {
+			return err
+		}
+	}
+	for _, role := range bundle.Roles {
+		if err := unique("role", role.Name); err != nil {
+			return err
+		}
+	}
+	for _, assignment := range bundle.Assignments {
+		if err := unique("assignment", assignment.UserID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks that every reference resolves and that role inheritance has no cycles
+func (st *rbacState) validate() error {
+	for _, name := range sortedKeys(st.roles) {
+		role := st.roles[name]
+		for _, permission := range sortedKeys(role.permissions) {
+			if _, exists := st.permissions[permission]; !exists {
+				return fmt.Errorf("role %s references unknown permission %s", name, permission)
+			}
+		}
+		for _, parent := range sortedKeys(role.inherits) {
+			if _, exists := st.roles[parent]; !exists {
+				return fmt.Errorf("role %s inherits from unknown role %s", name, parent)
+			}
+		}
+	}
+	for _, userID := range sortedKeys(st.assignments) {
+		for _, roleName := range sortedKeys(st.assignments[userID]) {
+			if _, exists := st.roles[roleName]; !exists {
+				return fmt.Errorf("user %s is assigned unknown role %s", userID, roleName)
+			}
+		}
+	}
+
+	// Depth-first search for inheritance cycles
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	marks := make(map[string]int)
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch marks[name] {
+		case visiting:
+			return fmt.Errorf("role inheritance cycle through %s", name)
+		case done:
+			return nil
+		}
+		marks[name] = visiting
+		for _, parent := range sortedKeys(st.roles[name].inherits) {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		marks[name] = done
+		return nil
+	}
+	for _, name := range sortedKeys(st.roles) {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clone returns a deep copy of the state
+func (st *rbacState) clone() *rbacState {
+	c := newRBACState()
+	for name, description := range st.permissions {
+		c.permissions[name] = description
+	}
+	for name, role := range st.roles {
+		c.addRole(name, role.description, sortedKeys(role.permissions)...)
+		for parent := range role.inherits {
+			c.roles[name].inherits[parent] = true
+		}
+	}
+	for userID, roles := range st.assignments {
+		c.assignments[userID] = make(map[string]bool, len(roles))
+		for roleName := range roles {
+			c.assignments[userID][roleName] = true
+		}
+	}
+	return c
+}
+
+// diffStates lists the create, update and delete changes that turn current into target
+func diffStates(current, target *rbacState) *RBACDiff {
+	diff := &RBACDiff{Changes: []RBACChange{}}
+	add := func(action RBACChangeAction, kind, name, detail string) {
+		diff.Changes = append(diff.Changes, RBACChange{Action: action, Kind: kind, Name: name, Detail: detail})
+	}
+
+	for name, description := range target.permissions {
+		existing, exists := current.permissions[name]
+		switch {
+		case !exists:
+			add(RBACChangeCreate, "permission", name, "")
+		case existing != description:
+			add(RBACChangeUpdate, "permission", name, "description")
+		}
+	}
+	for name := range current.permissions {
+		if _, exists := target.permissions[name]; !exists {
+			add(RBACChangeDelete, "permission", name, "")
+		}
+	}
+
+	for name, role := range target.roles {
+		existing, exists := current.roles[name]
+		if !exists {
+			add(RBACChangeCreate, "role", name, setDetail("permissions", nil, role.permissions, "inherits", nil, role.inherits))
+			continue
+		}
+		detail := setDetail("permissions", existing.permissions, role.permissions, "inherits", existing.inherits, role.inherits)
+		if existing.description != role.description {
+			detail = strings.TrimSpace("description " + detail)
+		}
+		if detail != "" {
+			add(RBACChangeUpdate, "role", name, detail)
+		}
+	}
+	for name := range current.roles {
+		if _, exists := target.roles[name]; !exists {
+			add(RBACChangeDelete, "role", name, "")
+		}
+	}
+
+	for userID, roles := range target.assignments {
+		existing, exists := current.assignments[userID]
+		detail := setDetail("roles", existing, roles, "", nil, nil)
+		switch {
+		case !exists:
+			add(RBACChangeCreate, "assignment", userID, detail)
+		case detail != "":
+			add(RBACChangeUpdate, "assignment", userID, detail)
+		}
+	}
+	for userID := range current.assignments {
+		if _, exists := target.assignments[userID]; !exists {
+			add(RBACChangeDelete, "assignment", userID, "")
+		}
+	}
+
+	return diff
+}
+
+// unmanagedChanges lists objects in the current state that the bundle does not mention
+func unmanagedChanges(current *rbacState, bundle *RBACBundle) []RBACChange {
+	listed := make(map[string]bool)
+	for _, permission := range bundle.Permissions {
+		listed["permission/"+permission.Name] = true
+	}
+	for _, role := range bundle.Roles {
+		listed["role/"+role.Name] = true
+	}
+	for _, assignment := range bundle.Assignments {
+		listed["assignment/"+assignment.UserID] = true
+	}
+
+	var changes []RBACChange
+	unmanaged := func(kind, name string) {
+		if !listed[kind+"/"+name] {
+			changes = append(changes, RBACChange{Action: RBACChangeUnmanaged, Kind: kind, Name: name})
+		}
+	}
+	for name := range current.permissions {
+		unmanaged("permission", name)
+	}
+	for name := range current.roles {
+		unmanaged("role", name)
+	}
+	for userID := range current.assignments {
+		unmanaged("assignment", userID)
+	}
+	return changes
+}
+
+// setDetail describes the additions and removals between pairs of sets, e.g.
+// "permissions +read:data -write:data"
+func setDetail(label string, before, after map[string]bool, otherLabel string, otherBefore, otherAfter map[string]bool) string {
+	describe := func(label string, before, after map[string]bool) string {
+		var parts []string
+		for _, item := range sortedKeys(after) {
+			if !before[item] {
+				parts = append(parts, "+"+item)
+			}
+		}
+		for _, item := range sortedKeys(before) {
+			if !after[item] {
+				parts = append(parts, "-"+item)
+			}
+		}
+		if len(parts) == 0 {
+			return ""
+		}
+		return label + " " + strings.Join(parts, " ")
+	}
+
+	details := []string{}
+	if detail := describe(label, before, after); detail != "" {
+		details = append(details, detail)
+	}
+	if otherLabel != "" {
+		if detail := describe(otherLabel, otherBefore, otherAfter); detail != "" {
+			details = append(details, detail)
+		}
+	}
+	return strings.Join(details, "; ")
+}
+
+// sortChanges orders changes by kind, then name, then action
+func sortChanges(changes []RBACChange) {
+	kindOrder := map[string]int{"permission": 0, "role": 1, "assignment": 2}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return kindOrder[changes[i].Kind] < kindOrder[changes[j].Kind]
+		}
+		if changes[i].Name != changes[j].Name {
+			return changes[i].Name < changes[j].Name
+		}
+		return changes[i].Action < changes[j].Action
+	})
+}
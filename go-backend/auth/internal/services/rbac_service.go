@@ -1,139 +1,310 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"sync"
 
 	"github.com/cryptofortress/backend/auth/internal/config"
 )
 
+var (
+	// ErrRoleNotFound is returned when a role does not exist
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrPermissionNotFound is returned when a permission does not exist
+	ErrPermissionNotFound = errors.New("permission not found")
+)
+
+const (
+	// DefaultUserRole is assigned to every new user
+	DefaultUserRole = "user"
+	// AdminRole holds every built-in permission and is assigned to the bootstrap administrator
+	AdminRole = "admin"
+)
+
+// rbacRole is a role with its direct permissions and the roles it inherits from
+type rbacRole struct {
+	description string
+	permissions map[string]bool
+	inherits    map[string]bool
+}
+
+// rbacState is the complete RBAC configuration: permissions, roles and user assignments
+type rbacState struct {
+	permissions map[string]string          // permission name to description
+	roles       map[string]*rbacRole       // keyed by role name
+	assignments map[string]map[string]bool // user ID to role names
+}
+
 // rbacServiceImpl implements the RBACService interface
 type rbacServiceImpl struct {
 	config *config.Config
-	// In a real implementation, you would have database connections here
+
+	mu    sync.RWMutex
+	state *rbacState
+	// In a real implementation, the RBAC state would be stored in the database
 }
 
 // NewRBACService creates a new instance of the RBAC service
 func NewRBACService(cfg *config.Config) RBACService {
 	return &rbacServiceImpl{
 		config: cfg,
+		state:  defaultRBACState(),
 	}
 }
 
+// defaultRBACState returns the built-in roles and permissions. No user holds a role until
+// they register or an administrator assigns one.
+func defaultRBACState() *rbacState {
+	state := newRBACState()
+	for name, description := range map[string]string{
+		"read:data":             "Read data",
+		"write:data":            "Write any data",
+		"write:own_data":        "Write the user's own data",
+		"delete:data":           "Delete data",
+		"manage:users":          "Manage user accounts",
+		"manage:roles":          "Manage roles, permissions and assignments",
+		"approve:elevation":     "Approve just-in-time role elevations",
+		"impersonate:users":     "Impersonate other users",
+		"manage:break-glass":    "Manage break-glass accounts",
+		"manage:access-reviews": "Run access review campaigns",
+		"manage:keys":           "Create and rotate keys in the Key Management Service",
+		"sign:data":             "Sign data with keys held by the Key Management Service",
+		"generate:mac":          "Compute MACs with keys held by the Key Management Service",
+		"encrypt:data":          "Encrypt data under keys held by the Key Management Service",
+		"decrypt:data":          "Decrypt data encrypted under keys held by the Key Management Service",
+	} {
+		state.permissions[name] = description
+	}
+
+	state.addRole(DefaultUserRole, "Standard user", "read:data", "write:own_data")
+	state.addRole("manager", "Team manager", "read:data", "write:data", "manage:users")
+	state.addRole(AdminRole, "Administrator", "read:data", "write:data", "delete:data", "manage:users", "manage:roles",
+		"approve:elevation", "impersonate:users", "manage:break-glass", "manage:access-reviews", "manage:keys", "sign:data", "generate:mac",
+		"encrypt:data", "decrypt:data")
+
+	return state
+}
+
 // CreateRole creates a new role
 func (s *rbacServiceImpl) CreateRole(name, description string) error {
-	// In a real implementation, you would store the role in the database
-	fmt.Printf("Creating role: %s with description: %s\n", name, description)
+	if name == "" {
+		return errors.New("role name is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.state.roles[name]; exists {
+		return fmt.Errorf("role %s already exists", name)
+	}
+	s.state.addRole(name, description)
 	return nil
 }
 
-// DeleteRole removes a role
+// DeleteRole removes a role, its assignments and any inheritance from it
 func (s *rbacServiceImpl) DeleteRole(name string) error {
-	// In a real implementation, you would remove the role from the database
-	fmt.Printf("Deleting role: %s\n", name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.state.roles[name]; !exists {
+		return ErrRoleNotFound
+	}
+	delete(s.state.roles, name)
+	for _, role := range s.state.roles {
+		delete(role.inherits, name)
+	}
+	for userID, roles := range s.state.assignments {
+		delete(roles, name)
+		if len(roles) == 0 {
+			delete(s.state.assignments, userID)
+		}
+	}
 	return nil
 }
 
 // AssignRoleToUser assigns a role to a user
 func (s *rbacServiceImpl) AssignRoleToUser(userID, roleName string) error {
-	// In a real implementation, you would create a relationship between the user and role in the database
-	fmt.Printf("Assigning role: %s to user: %s\n", roleName, userID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.state.roles[roleName]; !exists {
+		return ErrRoleNotFound
+	}
+	if s.state.assignments[userID] == nil {
+		s.state.assignments[userID] = make(map[string]bool)
+	}
+	s.state.assignments[userID][roleName] = true
 	return nil
 }
 
 // RemoveRoleFromUser removes a role from a user
 func (s *rbacServiceImpl) RemoveRoleFromUser(userID, roleName string) error {
-	// In a real implementation, you would remove the relationship between the user and role in the database
-	fmt.Printf("Removing role: %s from user: %s\n", roleName, userID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	roles := s.state.assignments[userID]
+	delete(roles, roleName)
+	if len(roles) == 0 {
+		delete(s.state.assignments, userID)
+	}
 	return nil
 }
 
 // CreatePermission creates a new permission
 func (s *rbacServiceImpl) CreatePermission(name, description string) error {
-	// In a real implementation, you would store the permission in the database
-	fmt.Printf("Creating permission: %s with description: %s\n", name, description)
+	if name == "" {
+		return errors.New("permission name is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.state.permissions[name]; exists {
+		return fmt.Errorf("permission %s already exists", name)
+	}
+	s.state.permissions[name] = description
 	return nil
 }
 
 // AssignPermissionToRole assigns a permission to a role
 func (s *rbacServiceImpl) AssignPermissionToRole(roleName, permissionName string) error {
-	// In a real implementation, you would create a relationship between the role and permission in the database
-	fmt.Printf("Assigning permission: %s to role: %s\n", permissionName, roleName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role, exists := s.state.roles[roleName]
+	if !exists {
+		return ErrRoleNotFound
+	}
+	if _, exists := s.state.permissions[permissionName]; !exists {
+		return ErrPermissionNotFound
+	}
+	role.permissions[permissionName] = true
 	return nil
 }
 
 // RemovePermissionFromRole removes a permission from a role
 func (s *rbacServiceImpl) RemovePermissionFromRole(roleName, permissionName string) error {
-	// In a real implementation, you would remove the relationship between the role and permission in the database
-	fmt.Printf("Removing permission: %s from role: %s\n", permissionName, roleName)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	role, exists := s.state.roles[roleName]
+	if !exists {
+		return ErrRoleNotFound
+	}
+	delete(role.permissions, permissionName)
 	return nil
 }
 
-// CheckPermission verifies if a user has a specific permission
+// CheckPermission verifies if a user has a specific permission through any of their
+// roles, including inherited ones
 func (s *rbacServiceImpl) CheckPermission(userID, permissionName string) (bool, error) {
-	// In a real implementation, you would:
-	// 1. Get the user's roles
-	// 2. Check if any of those roles have the specified permission
-	
-	// Placeholder implementation - in reality this would check the database
-	fmt.Printf("Checking if user: %s has permission: %s\n", userID, permissionName)
-	
-	// For demo purposes, let's say user "admin" has all permissions
-	if userID == "admin" {
-		return true, nil
-	}
-	
-	// For other users, let's simulate some permissions
-	permissions := map[string][]string{
-		"user-123": {"read:data", "write:own_data"},
-		"manager":  {"read:data", "write:data", "manage:users"},
-	}
-	
-	userPerms, exists := permissions[userID]
-	if !exists {
-		return false, nil
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for roleName := range s.state.assignments[userID] {
+		if s.state.effectivePermissions(roleName)[permissionName] {
+			return true, nil
+		}
 	}
-	
-	for _, perm := range userPerms {
-		if perm == permissionName {
+	return false, nil
+}
+
+// RolesGrant reports whether any of the roles grants a permission, including through
+// inherited roles. Access tokens are checked this way, by the roles they carry.
+func (s *rbacServiceImpl) RolesGrant(roles []string, permissionName string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, roleName := range roles {
+		if s.state.effectivePermissions(roleName)[permissionName] {
 			return true, nil
 		}
 	}
-	
 	return false, nil
 }
 
+// RoleExists reports whether a role is defined
+func (s *rbacServiceImpl) RoleExists(roleName string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists := s.state.roles[roleName]
+	return exists, nil
+}
+
 // GetUserRoles retrieves all roles assigned to a user
 func (s *rbacServiceImpl) GetUserRoles(userID string) ([]string, error) {
-	// In a real implementation, you would query the database for the user's roles
-	
-	// Placeholder implementation
-	roles := map[string][]string{
-		"admin":    {"admin", "user", "manager"},
-		"user-123": {"user"},
-		"manager":  {"user", "manager"},
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sortedKeys(s.state.assignments[userID]), nil
+}
+
+// GetRolePermissions retrieves all permissions of a role, including inherited ones
+func (s *rbacServiceImpl) GetRolePermissions(roleName string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, exists := s.state.roles[roleName]; !exists {
+		return []string{}, nil
 	}
-	
-	if userRoles, exists := roles[userID]; exists {
-		return userRoles, nil
+	return sortedKeys(s.state.effectivePermissions(roleName)), nil
+}
+
+// newRBACState returns an empty RBAC state
+func newRBACState() *rbacState {
+	return &rbacState{
+		permissions: make(map[string]string),
+		roles:       make(map[string]*rbacRole),
+		assignments: make(map[string]map[string]bool),
 	}
-	
-	return []string{}, nil
 }
 
-// GetRolePermissions retrieves all permissions assigned to a role
-func (s *rbacServiceImpl) GetRolePermissions(roleName string) ([]string, error) {
-	// In a real implementation, you would query the database for the role's permissions
-	
-	// Placeholder implementation
-	permissions := map[string][]string{
-		"admin":    {"read:data", "write:data", "delete:data", "manage:users", "manage:roles"},
-		"user":     {"read:data", "write:own_data"},
-		"manager":  {"read:data", "write:data", "manage:users"},
-	}
-	
-	if rolePerms, exists := permissions[roleName]; exists {
-		return rolePerms, nil
-	}
-	
-	return []string{}, nil
-}
\ No newline at end of file
+// addRole adds or replaces a role with the given direct permissions
+func (st *rbacState) addRole(name, description string, permissions ...string) {
+	role := &rbacRole{
+		description: description,
+		permissions: make(map[string]bool),
+		inherits:    make(map[string]bool),
+	}
+	for _, permission := range permissions {
+		role.permissions[permission] = true
+	}
+	st.roles[name] = role
+}
+
+// effectivePermissions returns a role's permissions together with those of every role it inherits from
+func (st *rbacState) effectivePermissions(roleName string) map[string]bool {
+	permissions := make(map[string]bool)
+	visited := make(map[string]bool)
+
+	var visit func(name string)
+	visit = func(name string) {
+		role, exists := st.roles[name]
+		if !exists || visited[name] {
+			return
+		}
+		visited[name] = true
+		for permission := range role.permissions {
+			permissions[permission] = true
+		}
+		for parent := range role.inherits {
+			visit(parent)
+		}
+	}
+	visit(roleName)
+
+	return permissions
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
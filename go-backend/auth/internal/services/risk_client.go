@@ -0,0 +1,92 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// httpRiskScorer scores login risk by calling the python-ai risk assessment service
+type httpRiskScorer struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPRiskScorer creates a risk scorer backed by the risk assessment service's
+// POST /score/realtime endpoint
+func NewHTTPRiskScorer(baseURL string, timeout time.Duration) RiskScorer {
+	return &httpRiskScorer{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// riskScoreRequest is the request body for POST /score/realtime
+type riskScoreRequest struct {
+	RiskFactors map[string]float64 `json:"risk_factors"`
+}
+
+// riskScoreResponse is the subset of the POST /score/realtime response used here
+type riskScoreResponse struct {
+	RiskScore *float64 `json:"risk_score"`
+	RiskLevel string   `json:"risk_level"`
+}
+
+// Score maps login signals onto the service's risk factors and returns its score
+func (s *httpRiskScorer) Score(signals RiskSignals) (float64, error) {
+	body, err := json.Marshal(riskScoreRequest{RiskFactors: riskFactors(signals)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode risk request: %w", err)
+	}
+
+	resp, err := s.client.Post(s.baseURL+"/score/realtime", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("risk service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("risk service returned status %d", resp.StatusCode)
+	}
+
+	var result riskScoreResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("failed to decode risk response: %w", err)
+	}
+	if result.RiskScore == nil {
+		return 0, fmt.Errorf("risk response is missing risk_score")
+	}
+
+	return *result.RiskScore, nil
+}
+
+// riskFactors converts login signals into the 0..1 factors expected by the risk service.
+// Higher values mean higher risk, except for device_trust_score, which is 1 for a known
+// device and 0 for a new one.
+func riskFactors(signals RiskSignals) map[string]float64 {
+	boolFactor := func(b bool) float64 {
+		if b {
+			return 1
+		}
+		return 0
+	}
+
+	network := 0.0
+	if signals.NewIP {
+		network = 0.5
+	}
+	if signals.NewASN {
+		network = 1
+	}
+
+	return map[string]float64{
+		"user_behavior_score":  math.Min(float64(signals.RecentFailures)/5, 1),
+		"network_threat_level": network,
+		"geographical_risk":    boolFactor(signals.ImpossibleTravel),
+		"device_trust_score":   1 - boolFactor(signals.NewDevice),
+	}
+}
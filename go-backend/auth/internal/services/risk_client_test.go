@@ -0,0 +1,96 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+)
+
+// TestHTTPRiskScorer tests the risk service client against a local stub
+func TestHTTPRiskScorer(t *testing.T) {
+	cfg := &config.Config{RiskStepUpThreshold: 0.4, RiskBlockThreshold: 0.7}
+
+	t.Run("DecisionFollowsRemoteScore", func(t *testing.T) {
+		cases := []struct {
+			score    float64
+			decision RiskDecision
+		}{
+			{0.1, RiskDecisionAllow},
+			{0.5, RiskDecisionStepUp},
+			{0.9, RiskDecisionBlock},
+		}
+
+		for _, tc := range cases {
+			var received riskScoreRequest
+			stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/score/realtime" {
+					t.Errorf("Unexpected path %s", r.URL.Path)
+				}
+				json.NewDecoder(r.Body).Decode(&received)
+				json.NewEncoder(w).Encode(map[string]interface{}{"risk_score": tc.score, "risk_level": "STUB"})
+			}))
+
+			service := NewRiskServiceWithScorer(cfg, NewHTTPRiskScorer(stub.URL, time.Second))
+			assessment, err := service.EvaluateLogin(LoginAttempt{Username: "alice", IPAddress: "203.0.113.7", Timestamp: time.Now()})
+			stub.Close()
+			if err != nil {
+				t.Fatalf("EvaluateLogin failed: %v", err)
+			}
+
+			if assessment.Decision != tc.decision {
+				t.Errorf("Score %.1f: got decision %s, want %s", tc.score, assessment.Decision, tc.decision)
+			}
+			if assessment.Scorer != "http" {
+				t.Errorf("Expected http scorer, got %s", assessment.Scorer)
+			}
+			if _, ok := received.RiskFactors["device_trust_score"]; !ok {
+				t.Error("Risk request is missing device_trust_score")
+			}
+		}
+	})
+
+	t.Run("FallsBackToHeuristicOnError", func(t *testing.T) {
+		stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer stub.Close()
+
+		service := NewRiskServiceWithScorer(cfg, NewHTTPRiskScorer(stub.URL, time.Second))
+		assessment, err := service.EvaluateLogin(LoginAttempt{Username: "bob", Timestamp: time.Now()})
+		if err != nil {
+			t.Fatalf("EvaluateLogin failed: %v", err)
+		}
+
+		if assessment.Scorer != "heuristic" {
+			t.Errorf("Expected heuristic fallback, got %s", assessment.Scorer)
+		}
+		if assessment.Decision != RiskDecisionAllow {
+			t.Errorf("Expected first login to be allowed, got %s", assessment.Decision)
+		}
+	})
+
+	t.Run("ImpossibleTravelIsBlocked", func(t *testing.T) {
+		service := NewRiskServiceWithScorer(cfg, NewHeuristicRiskScorer())
+		now := time.Now()
+		london, londonLon := 51.5, -0.12
+		sydney, sydneyLon := -33.87, 151.21
+
+		service.RecordLoginSuccess(LoginAttempt{Username: "carol", IPAddress: "198.51.100.1", ASN: "AS1", DeviceID: "laptop", Latitude: &london, Longitude: &londonLon, Timestamp: now})
+
+		assessment, err := service.EvaluateLogin(LoginAttempt{Username: "carol", IPAddress: "192.0.2.9", ASN: "AS2", DeviceID: "unknown", Latitude: &sydney, Longitude: &sydneyLon, Timestamp: now.Add(time.Hour)})
+		if err != nil {
+			t.Fatalf("EvaluateLogin failed: %v", err)
+		}
+
+		if !assessment.Signals.ImpossibleTravel {
+			t.Error("Expected impossible travel to be detected")
+		}
+		if assessment.Decision != RiskDecisionBlock {
+			t.Errorf("Expected block, got %s", assessment.Decision)
+		}
+	})
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// failureWindow is how long failed logins count towards the risk score
+	failureWindow = 24 * time.Hour
+	// maxTravelSpeedKmh is the fastest plausible travel speed between two logins
+	maxTravelSpeedKmh = 900.0
+	// earthRadiusKm is used for great-circle distance between login locations
+	earthRadiusKm = 6371.0
+	// maxRecordedFailures is how many recent failures are kept per user; scorers saturate at 5
+	maxRecordedFailures = 5
+	// maxUnverifiedHistories bounds the histories of usernames that never logged in
+	// successfully, which anyone can create by failing a login
+	maxUnverifiedHistories = 10000
+)
+
+// loginHistory holds what is known about a user's previous logins
+type loginHistory struct {
+	devices      map[string]bool
+	ips          map[string]bool
+	asns         map[string]bool
+	lastLogin    time.Time
+	lastLat      *float64
+	lastLon      *float64
+	failures     []time.Time
+	successCount int
+}
+
+// riskServiceImpl implements the RiskService interface
+type riskServiceImpl struct {
+	config   *config.Config
+	scorer   RiskScorer
+	fallback RiskScorer
+
+	mu         sync.Mutex
+	history    map[string]*loginHistory
+	unverified []string // keys of histories created by failures, oldest first
+	// In a real implementation, login history would be persisted in the database
+}
+
+// NewRiskService creates a new instance of the risk service using the configured scorer
+func NewRiskService(cfg *config.Config) RiskService {
+	var scorer RiskScorer = NewHeuristicRiskScorer()
+	if cfg.RiskScorer == "http" {
+		scorer = NewHTTPRiskScorer(cfg.RiskServiceURL, time.Duration(cfg.RiskServiceTimeout)*time.Millisecond)
+	}
+	return NewRiskServiceWithScorer(cfg, scorer)
+}
+
+// NewRiskServiceWithScorer creates a new instance of the risk service with an explicit scorer.
+// The heuristic scorer is used whenever the given scorer fails.
+func NewRiskServiceWithScorer(cfg *config.Config, scorer RiskScorer) RiskService {
+	return &riskServiceImpl{
+		config:   cfg,
+		scorer:   scorer,
+		fallback: NewHeuristicRiskScorer(),
+		history:  make(map[string]*loginHistory),
+	}
+}
+
+// EvaluateLogin scores a login attempt and decides whether to allow, step up or block it
+func (s *riskServiceImpl) EvaluateLogin(attempt LoginAttempt) (*RiskAssessment, error) {
+	signals := s.deriveSignals(attempt)
+
+	usedScorer := scorerName(s.scorer)
+	score, err := s.scorer.Score(signals)
+	if err != nil {
+		log.Warn().Err(err).Str("scorer", usedScorer).Msg("Risk scorer failed, falling back to heuristic scorer")
+		usedScorer = scorerName(s.fallback)
+		score, err = s.fallback.Score(signals)
+		if err != nil {
+			return nil, fmt.Errorf("failed to score login risk: %w", err)
+		}
+	}
+
+	score = math.Max(0, math.Min(1, score))
+
+	decision := RiskDecisionAllow
+	switch {
+	case score >= s.config.RiskBlockThreshold:
+		decision = RiskDecisionBlock
+	case score >= s.config.RiskStepUpThreshold:
+		decision = RiskDecisionStepUp
+	}
+
+	return &RiskAssessment{
+		Score:    score,
+		Decision: decision,
+		Signals:  signals,
+		Scorer:   usedScorer,
+	}, nil
+}
+
+// RecordLoginSuccess adds the attempt's device, network and location to the user's known history
+func (s *riskServiceImpl) RecordLoginSuccess(attempt LoginAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := s.historyLocked(attempt.Username)
+	if attempt.DeviceID != "" {
+		h.devices[attempt.DeviceID] = true
+	}
+	if attempt.IPAddress != "" {
+		h.ips[attempt.IPAddress] = true
+	}
+	if attempt.ASN != "" {
+		h.asns[attempt.ASN] = true
+	}
+	if attempt.Latitude != nil && attempt.Longitude != nil {
+		h.lastLat = attempt.Latitude
+		h.lastLon = attempt.Longitude
+	}
+	h.lastLogin = attempt.Timestamp
+	h.failures = nil
+	h.successCount++
+
+	return nil
+}
+
+// RecordLoginFailure counts a failed login against the user's recent failure history
+func (s *riskServiceImpl) RecordLoginFailure(attempt LoginAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(attempt.Username)
+	h, ok := s.history[key]
+	if !ok {
+		h = newLoginHistory()
+		s.history[key] = h
+		s.trackUnverifiedLocked(key)
+	}
+	h.failures = append(recentFailures(h.failures, attempt.Timestamp), attempt.Timestamp)
+	if len(h.failures) > maxRecordedFailures {
+		h.failures = h.failures[len(h.failures)-maxRecordedFailures:]
+	}
+
+	return nil
+}
+
+// deriveSignals compares a login attempt against the user's history
+func (s *riskServiceImpl) deriveSignals(attempt LoginAttempt) RiskSignals {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Looking a user up must not create a history, or every attempted username would be kept
+	h, ok := s.history[strings.ToLower(attempt.Username)]
+	if !ok {
+		h = newLoginHistory()
+	}
+	signals := RiskSignals{
+		FirstLogin:     h.successCount == 0,
+		RecentFailures: len(recentFailures(h.failures, attempt.Timestamp)),
+	}
+
+	// Novelty only means something once there is a baseline to compare against
+	if signals.FirstLogin {
+		return signals
+	}
+
+	signals.NewDevice = attempt.DeviceID == "" || !h.devices[attempt.DeviceID]
+	signals.NewIP = attempt.IPAddress != "" && !h.ips[attempt.IPAddress]
+	signals.NewASN = attempt.ASN != "" && len(h.asns) > 0 && !h.asns[attempt.ASN]
+
+	if attempt.Latitude != nil && attempt.Longitude != nil && h.lastLat != nil && h.lastLon != nil {
+		distance := haversineKm(*h.lastLat, *h.lastLon, *attempt.Latitude, *attempt.Longitude)
+		elapsed := attempt.Timestamp.Sub(h.lastLogin).Hours()
+		if elapsed <= 0 {
+			elapsed = 1.0 / 3600 // treat simultaneous logins as one second apart
+		}
+		signals.TravelSpeedKmh = distance / elapsed
+		signals.ImpossibleTravel = signals.TravelSpeedKmh > maxTravelSpeedKmh
+	}
+
+	return signals
+}
+
+// historyLocked returns the history for a username, creating it if needed.
+// The caller must hold s.mu.
+func (s *riskServiceImpl) historyLocked(username string) *loginHistory {
+	key := strings.ToLower(username)
+	h, ok := s.history[key]
+	if !ok {
+		h = newLoginHistory()
+		s.history[key] = h
+	}
+	return h
+}
+
+// trackUnverifiedLocked records a history created by a failed login and evicts the oldest
+// such histories that still have no successful login once there are too many. The caller
+// must hold s.mu.
+func (s *riskServiceImpl) trackUnverifiedLocked(key string) {
+	s.unverified = append(s.unverified, key)
+	for len(s.unverified) > maxUnverifiedHistories {
+		oldest := s.unverified[0]
+		s.unverified = s.unverified[1:]
+		if h, ok := s.history[oldest]; ok && h.successCount == 0 {
+			delete(s.history, oldest)
+		}
+	}
+}
+
+// newLoginHistory returns an empty login history
+func newLoginHistory() *loginHistory {
+	return &loginHistory{
+		devices: make(map[string]bool),
+		ips:     make(map[string]bool),
+		asns:    make(map[string]bool),
+	}
+}
+
+// recentFailures drops failures that fall outside the failure window
+func recentFailures(failures []time.Time, now time.Time) []time.Time {
+	recent := failures[:0]
+	for _, failure := range failures {
+		if now.Sub(failure) < failureWindow {
+			recent = append(recent, failure)
+		}
+	}
+	return recent
+}
+
+// haversineKm returns the great-circle distance between two coordinates in kilometres
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// scorerName returns a short name for a scorer, used in assessments and logs
+func scorerName(scorer RiskScorer) string {
+	switch scorer.(type) {
+	case *heuristicRiskScorer:
+		return "heuristic"
+	case *httpRiskScorer:
+		return "http"
+	default:
+		return fmt.Sprintf("%T", scorer)
+	}
+}
+
+// heuristicRiskScorer scores login risk with fixed weights and no external dependencies
+type heuristicRiskScorer struct{}
+
+// NewHeuristicRiskScorer creates the built-in rule-based risk scorer
+func NewHeuristicRiskScorer() RiskScorer {
+	return &heuristicRiskScorer{}
+}
+
+// Score adds up weighted risk signals, capped at 1
+func (s *heuristicRiskScorer) Score(signals RiskSignals) (float64, error) {
+	score := 0.0
+	if signals.NewDevice {
+		score += 0.25
+	}
+	if signals.NewIP {
+		score += 0.1
+	}
+	if signals.NewASN {
+		score += 0.2
+	}
+	if signals.ImpossibleTravel {
+		score += 0.5
+	}
+	score += 0.08 * float64(min(signals.RecentFailures, 5))
+
+	return math.Min(score, 1), nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cryptofortress/backend/auth/internal/config"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// securityEventSource identifies this service in SIEM events
+const securityEventSource = "auth-service"
+
+// NewSecurityEventSink creates the configured security event sink. The client is used
+// to reach the audit service.
+func NewSecurityEventSink(cfg *config.Config, client *http.Client) SecurityEventSink {
+	if cfg.SecurityEventSink == "siem" {
+		return NewSIEMEventSink(cfg.AuditServiceURL, client)
+	}
+	return NewLogEventSink()
+}
+
+// logEventSink writes security events to the service log
+type logEventSink struct{}
+
+// NewLogEventSink creates a sink that only logs security events
+func NewLogEventSink() SecurityEventSink {
+	return &logEventSink{}
+}
+
+// Emit logs the event at a level matching its severity
+func (s *logEventSink) Emit(event SecurityEvent) error {
+	level := zerolog.WarnLevel
+	if event.Severity == SeverityHigh || event.Severity == SeverityCritical {
+		level = zerolog.ErrorLevel
+	}
+
+	logEvent := log.WithLevel(level).
+		Str("security_event", event.Type).
+		Str("severity", event.Severity)
+	for key, value := range event.Data {
+		logEvent = logEvent.Str(key, value)
+	}
+	logEvent.Msg(event.Message)
+
+	return nil
+}
+
+// siemEventSink forwards security events to the audit service's SIEM endpoint
+type siemEventSink struct {
+	baseURL string
+	client  *http.Client
+	local   SecurityEventSink
+}
+
+// NewSIEMEventSink creates a sink that posts events to the audit service's
+// POST /api/v1/audit/siem/event endpoint. Events are also logged locally, so they
+// are not lost when the audit service is unreachable.
+func NewSIEMEventSink(baseURL string, client *http.Client) SecurityEventSink {
+	return &siemEventSink{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+		local:   NewLogEventSink(),
+	}
+}
+
+// siemEventRequest is the request body for POST /api/v1/audit/siem/event
+type siemEventRequest struct {
+	Source   string            `json:"source"`
+	Type     string            `json:"type"`
+	Severity string            `json:"severity"`
+	Message  string            `json:"message"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+// Emit logs the event and sends it to the SIEM
+func (s *siemEventSink) Emit(event SecurityEvent) error {
+	s.local.Emit(event)
+
+	body, err := json.Marshal(siemEventRequest{
+		Source:   securityEventSource,
+		Type:     event.Type,
+		Severity: event.Severity,
+		Message:  event.Message,
+		Data:     event.Data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode security event: %w", err)
+	}
+
+	resp, err := s.client.Post(s.baseURL+"/api/v1/audit/siem/event", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("SIEM request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("SIEM returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -1,22 +1,37 @@
 package services
 
 import (
+	"time"
+
 	"github.com/cryptofortress/backend/auth/internal/config"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 // Services holds references to all authentication services
 type Services struct {
-	Auth AuthService
-	MFA  MFAService
-	RBAC RBACService
+	Auth         AuthService
+	MFA          MFAService
+	RBAC         RBACService
+	Elevation    ElevationService
+	Risk         RiskService
+	Login        LoginService
+	DPoP         DPoPService
+	Passwordless PasswordlessService
+	Device       DeviceAuthorizationService
+	BreakGlass   BreakGlassService
+	AccessReview AccessReviewService
 }
 
 // AuthService defines the interface for authentication operations
 type AuthService interface {
 	// JWT operations
-	GenerateAccessToken(userID string, roles []string) (string, error)
-	GenerateRefreshToken(userID string) (string, error)
+	GenerateAccessToken(userID string, roles []string, binding TokenBinding) (string, error)
+	GenerateRefreshToken(userID string, scope []string) (string, error)
+	GenerateElevatedAccessToken(current *TokenClaims, roles []string, elevationID string, expiresAt time.Time) (string, error)
+	GenerateImpersonationToken(actorID, targetUserID string, roles []string, reason string) (string, error)
+	GenerateAuthenticatedAccessToken(user *User, authCtx AuthContext, binding TokenBinding) (string, error)
+	GenerateBreakGlassAccessToken(user *User, sessionID string, expiresAt time.Time) (string, error)
+	UpgradeAccessToken(claims *TokenClaims, authCtx AuthContext) (string, error)
 	ValidateAccessToken(tokenString string) (*TokenClaims, error)
 	ValidateRefreshToken(tokenString string) (*TokenClaims, error)
 	RevokeRefreshToken(tokenString string) error
@@ -24,6 +39,17 @@ type AuthService interface {
 	// User authentication
 	AuthenticateUser(username, password string) (*User, error)
 	RegisterUser(username, email, password string) (*User, error)
+	GetUserByEmail(email string) (*User, error)
+	
+	// User lifecycle
+	GetUser(userID string) (*User, error)
+	UpdateProfile(userID string, update ProfileUpdate) (*User, error)
+	ChangePassword(userID, currentPassword, newPassword string) error
+	DisableUser(userID string) (*User, error)
+	EnableUser(userID string) (*User, error)
+	DeleteUser(userID string) (*User, error)
+	RestoreUser(userID string) (*User, error)
+	PurgeDeletedUsers() (int, error)
 	
 	// OAuth2 operations
 	InitiateOAuthFlow(provider string) (string, error)
@@ -66,22 +92,555 @@ type RBACService interface {
 	
 	// Access control
 	CheckPermission(userID, permissionName string) (bool, error)
+	RolesGrant(roles []string, permissionName string) (bool, error)
+	RoleExists(roleName string) (bool, error)
 	GetUserRoles(userID string) ([]string, error)
 	GetRolePermissions(roleName string) ([]string, error)
+	
+	// Declarative policy bundles. Roles and users listed in a bundle are replaced by
+	// their bundle definition; with prune, everything the bundle does not list is removed.
+	ExportBundle() (*RBACBundle, error)
+	DiffBundle(bundle *RBACBundle, prune bool) (*RBACDiff, error)
+	ApplyBundle(bundle *RBACBundle, prune bool) (*RBACDiff, error)
+}
+
+// RBACBundleVersion is the current version of the RBAC bundle format
+const RBACBundleVersion = 1
+
+// RBACBundle is a declarative description of permissions, roles, role inheritance and user assignments
+type RBACBundle struct {
+	Version     int                `json:"version" yaml:"version"`
+	Permissions []BundlePermission `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+	Roles       []BundleRole       `json:"roles,omitempty" yaml:"roles,omitempty"`
+	Assignments []BundleAssignment `json:"assignments,omitempty" yaml:"assignments,omitempty"`
+}
+
+// BundlePermission declares a permission
+type BundlePermission struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// BundleRole declares a role, its direct permissions and the roles it inherits from
+type BundleRole struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"`
+	Inherits    []string `json:"inherits,omitempty" yaml:"inherits,omitempty"`
+	Permissions []string `json:"permissions,omitempty" yaml:"permissions,omitempty"`
+}
+
+// BundleAssignment declares the complete set of roles assigned to a user
+type BundleAssignment struct {
+	UserID string   `json:"user_id" yaml:"user_id"`
+	Roles  []string `json:"roles" yaml:"roles"`
+}
+
+// RBACChangeAction describes how applying a bundle changes an object
+type RBACChangeAction string
+
+const (
+	RBACChangeCreate RBACChangeAction = "create"
+	RBACChangeUpdate RBACChangeAction = "update"
+	RBACChangeDelete RBACChangeAction = "delete"
+	// RBACChangeUnmanaged marks an object the bundle does not list and that is kept
+	// because pruning is off. Unmanaged objects usually come from manual changes.
+	RBACChangeUnmanaged RBACChangeAction = "unmanaged"
+)
+
+// RBACChange is a single difference between the current RBAC state and a bundle
+type RBACChange struct {
+	Action RBACChangeAction `json:"action"`
+	Kind   string           `json:"kind"` // "permission", "role" or "assignment"
+	Name   string           `json:"name"`
+	Detail string           `json:"detail,omitempty"`
+}
+
+// RBACDiff is the result of comparing a bundle with the current RBAC state
+type RBACDiff struct {
+	InSync  bool         `json:"in_sync"`
+	Applied bool         `json:"applied"`
+	Changes []RBACChange `json:"changes"`
+}
+
+// ElevationService defines the interface for just-in-time privileged role elevation
+type ElevationService interface {
+	// Elevation requests
+	RequestElevation(userID, roleName, justification string, duration time.Duration) (*Elevation, error)
+	ApproveElevation(elevationID, approverID string) (*Elevation, error)
+	DenyElevation(elevationID, approverID, reason string) (*Elevation, error)
+	RevokeElevation(elevationID, revokedBy string) (*Elevation, error)
+	
+	// Elevation queries
+	GetElevation(elevationID string) (*Elevation, error)
+	ListPendingElevations() ([]Elevation, error)
+	
+	// Expiry
+	ExpireElevations() (int, error)
+}
+
+// ElevationStatus represents the lifecycle state of an elevation request
+type ElevationStatus string
+
+const (
+	ElevationPending ElevationStatus = "pending"
+	ElevationActive  ElevationStatus = "active"
+	ElevationDenied  ElevationStatus = "denied"
+	ElevationRevoked ElevationStatus = "revoked"
+	ElevationExpired ElevationStatus = "expired"
+)
+
+// Elevation represents a time-boxed role elevation request
+type Elevation struct {
+	ID            string          `json:"id"`
+	UserID        string          `json:"user_id"`
+	RoleName      string          `json:"role_name"`
+	Justification string          `json:"justification"`
+	Duration      time.Duration   `json:"duration"`
+	Status        ElevationStatus `json:"status"`
+	Approvers     []string        `json:"approvers,omitempty"`
+	DeniedBy      string          `json:"denied_by,omitempty"`
+	DenyReason    string          `json:"deny_reason,omitempty"`
+	RevokedBy     string          `json:"revoked_by,omitempty"`
+	RequestedAt   time.Time       `json:"requested_at"`
+	ActivatedAt   *time.Time      `json:"activated_at,omitempty"`
+	ExpiresAt     *time.Time      `json:"expires_at,omitempty"`
+}
+
+// RiskService defines the interface for risk-based adaptive authentication
+type RiskService interface {
+	// Risk evaluation
+	EvaluateLogin(attempt LoginAttempt) (*RiskAssessment, error)
+	
+	// Login history
+	RecordLoginSuccess(attempt LoginAttempt) error
+	RecordLoginFailure(attempt LoginAttempt) error
+}
+
+// LoginService defines the interface for logins, shared by the HTTP and gRPC APIs. Every
+// login is risk evaluated and recorded in the login history.
+type LoginService interface {
+	PasswordLogin(req PasswordLoginRequest) (*LoginResult, error)
+	PasswordlessLogin(req PasswordlessLoginRequest) (*LoginResult, error)
+}
+
+// PasswordLoginRequest is a password login with its risk context and token binding
+type PasswordLoginRequest struct {
+	Username string
+	Password string
+	MFACode  string
+	Attempt  LoginAttempt // network and device context; Username and UserID are filled in
+	Binding  TokenBinding
+}
+
+// PasswordlessLoginRequest is a magic-link token, or a challenge ID and one-time code, with
+// the browser nonce, an optional MFA code, the login's risk context and its token binding
+type PasswordlessLoginRequest struct {
+	Token        string
+	ChallengeID  string
+	Code         string
+	BrowserNonce string
+	MFACode      string
+	Attempt      LoginAttempt // network and device context; Username and UserID are filled in
+	Binding      TokenBinding
+}
+
+// LoginResult holds the tokens issued by a successful login and its risk assessment
+type LoginResult struct {
+	User         *User
+	AccessToken  string
+	RefreshToken string
+	Assessment   *RiskAssessment
+}
+
+// RiskScorer turns login risk signals into a score between 0 (safe) and 1 (hostile)
+type RiskScorer interface {
+	Score(signals RiskSignals) (float64, error)
+}
+
+// RiskDecision is the outcome of evaluating a login attempt
+type RiskDecision string
+
+const (
+	RiskDecisionAllow  RiskDecision = "allow"
+	RiskDecisionStepUp RiskDecision = "step_up"
+	RiskDecisionBlock  RiskDecision = "block"
+)
+
+// LoginAttempt describes the context of a single login attempt
+type LoginAttempt struct {
+	UserID    string    `json:"user_id,omitempty"`
+	Username  string    `json:"username"`
+	IPAddress string    `json:"ip_address"`
+	ASN       string    `json:"asn,omitempty"`
+	DeviceID  string    `json:"device_id,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Latitude  *float64  `json:"latitude,omitempty"`
+	Longitude *float64  `json:"longitude,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RiskSignals are the features derived from a login attempt and the user's history
+type RiskSignals struct {
+	FirstLogin       bool    `json:"first_login"`
+	NewDevice        bool    `json:"new_device"`
+	NewIP            bool    `json:"new_ip"`
+	NewASN           bool    `json:"new_asn"`
+	ImpossibleTravel bool    `json:"impossible_travel"`
+	TravelSpeedKmh   float64 `json:"travel_speed_kmh,omitempty"`
+	RecentFailures   int     `json:"recent_failures"`
+}
+
+// RiskAssessment is the scored result of evaluating a login attempt
+type RiskAssessment struct {
+	Score    float64      `json:"score"`
+	Decision RiskDecision `json:"decision"`
+	Signals  RiskSignals  `json:"signals"`
+	Scorer   string       `json:"scorer"`
+}
+
+// DPoPService defines the interface for RFC 9449 DPoP sender-constrained tokens
+type DPoPService interface {
+	// VerifyProof validates a DPoP proof JWT for the given request and returns the
+	// JWK SHA-256 thumbprint of the proof key. accessToken is empty at the token endpoint.
+	VerifyProof(proof, method, url, accessToken string) (string, error)
+	
+	// RequiresDPoP reports whether a client must use DPoP instead of plain bearer tokens
+	RequiresDPoP(clientID string) bool
+}
+
+// PasswordlessService defines the interface for magic-link and email one-time code login
+type PasswordlessService interface {
+	// StartLogin emails a magic link or one-time code bound to the browser nonce.
+	// Unknown addresses get a challenge too, so callers cannot probe for accounts.
+	StartLogin(email string, method PasswordlessMethod, browserNonce string) (*PasswordlessChallenge, error)
+	
+	// Verification consumes the challenge and returns the authenticated user. When a
+	// challenge for an account is found but fails, its user is returned with the error
+	// so the failure can be recorded.
+	VerifyMagicLink(token, browserNonce string) (*User, error)
+	VerifyCode(challengeID, code, browserNonce string) (*User, error)
+}
+
+// PasswordlessMethod selects how a passwordless login is delivered
+type PasswordlessMethod string
+
+const (
+	PasswordlessMagicLink PasswordlessMethod = "magic_link"
+	PasswordlessCode      PasswordlessMethod = "code"
+)
+
+// PasswordlessChallenge is a pending passwordless login
+type PasswordlessChallenge struct {
+	ID        string             `json:"challenge_id"`
+	Method    PasswordlessMethod `json:"method"`
+	ExpiresAt time.Time          `json:"expires_at"`
+}
+
+// DeviceAuthorizationService defines the interface for the RFC 8628 device authorization grant
+type DeviceAuthorizationService interface {
+	// Device side
+	RequestDeviceCode(clientID, scope string) (*DeviceAuthorization, error)
+	PollDeviceCode(deviceCode, clientID string) (*DeviceAuthorization, error)
+	
+	// User side, from an authenticated session
+	LookupUserCode(userCode string) (*DeviceAuthorization, error)
+	ApproveUserCode(userCode, userID, username string) (*DeviceAuthorization, error)
+	DenyUserCode(userCode, userID string) (*DeviceAuthorization, error)
+}
+
+// DeviceAuthorizationStatus represents the lifecycle state of a device authorization
+type DeviceAuthorizationStatus string
+
+const (
+	DeviceAuthorizationPending  DeviceAuthorizationStatus = "pending"
+	DeviceAuthorizationApproved DeviceAuthorizationStatus = "approved"
+	DeviceAuthorizationDenied   DeviceAuthorizationStatus = "denied"
+	DeviceAuthorizationIssued   DeviceAuthorizationStatus = "issued"
+)
+
+// DeviceAuthorization is a pending or completed device authorization request
+type DeviceAuthorization struct {
+	DeviceCode string                    `json:"-"`
+	UserCode   string                    `json:"user_code"`
+	ClientID   string                    `json:"client_id"`
+	Scope      string                    `json:"scope,omitempty"`
+	Status     DeviceAuthorizationStatus `json:"status"`
+	UserID     string                    `json:"user_id,omitempty"`
+	Username   string                    `json:"-"`
+	Interval   time.Duration             `json:"-"`
+	CreatedAt  time.Time                 `json:"created_at"`
+	ApprovedAt *time.Time                `json:"approved_at,omitempty"`
+	ExpiresAt  time.Time                 `json:"expires_at"`
+}
+
+// BreakGlassService defines the interface for emergency accounts used when the regular
+// identity providers are unavailable
+type BreakGlassService interface {
+	// Account management
+	CreateAccount(name string, roles, custodians []string, threshold int, createdBy string) (*BreakGlassAccount, error)
+	ListAccounts() ([]BreakGlassAccount, error)
+	RotateCredential(accountID, rotatedBy string) (*BreakGlassAccount, error)
+	
+	// Activate opens an emergency session from a quorum of credential shares and returns
+	// the session and its access token. The credential is rotated after every use.
+	Activate(accountName string, shares []string, justification, clientIP string) (*BreakGlassSession, string, error)
+	EndSession(sessionID, endedBy string) (*BreakGlassSession, error)
+	GetSession(sessionID string) (*BreakGlassSession, error)
+	ExpireSessions() (int, error)
+}
+
+// BreakGlassAccount is an emergency account whose credential is split between custodians.
+// An account with a threshold of 1 and a single custodian is a sealed credential.
+type BreakGlassAccount struct {
+	ID              string     `json:"id"`
+	Name            string     `json:"name"`
+	UserID          string     `json:"user_id"`
+	Roles           []string   `json:"roles"`
+	Custodians      []string   `json:"custodians"`
+	Threshold       int        `json:"threshold"`
+	Generation      int        `json:"generation"` // incremented on every credential rotation
+	CreatedBy       string     `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	RotatedAt       time.Time  `json:"rotated_at"`
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"`
+	ActiveSessionID string     `json:"active_session_id,omitempty"`
+}
+
+// BreakGlassSession is a time-boxed session opened with a break-glass account
+type BreakGlassSession struct {
+	ID            string     `json:"id"`
+	AccountID     string     `json:"account_id"`
+	AccountName   string     `json:"account_name"`
+	UserID        string     `json:"user_id"`
+	Justification string     `json:"justification"`
+	ClientIP      string     `json:"client_ip"`
+	StartedAt     time.Time  `json:"started_at"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	EndedAt       *time.Time `json:"ended_at,omitempty"`
+	EndedBy       string     `json:"ended_by,omitempty"`
+}
+
+// AccessReviewService defines the interface for access review campaigns, which
+// periodically certify that users still need the roles assigned to them
+type AccessReviewService interface {
+	// Campaign management
+	CreateCampaign(name string, scope AccessReviewScope, reviewers []string, dueAt time.Time, revokeUnreviewed bool, createdBy string) (*AccessReviewCampaign, error)
+	GetCampaign(campaignID string) (*AccessReviewCampaign, error)
+	ListCampaigns() ([]AccessReviewCampaign, error)
+	
+	// CloseCampaign removes every revoked role assignment and seals the campaign's
+	// signed evidence record. Overdue campaigns are closed automatically.
+	CloseCampaign(campaignID, closedBy string) (*AccessReviewCampaign, error)
+	CloseOverdueCampaigns() (int, error)
+	
+	// Reviews
+	ListReviewerItems(reviewerID string) ([]AccessReviewItem, error)
+	DecideItem(campaignID, itemID, reviewerID string, decision AccessReviewDecision, comment string) (*AccessReviewItem, error)
+	
+	// Evidence
+	ExportEvidence(campaignID string) (*AccessReviewEvidence, error)
+	EvidenceKey() AccessReviewEvidenceKey
+}
+
+// AccessReviewScope selects the role assignments a campaign reviews. An empty list
+// matches everything, so an empty scope reviews every assignment.
+type AccessReviewScope struct {
+	Roles   []string `json:"roles,omitempty"`
+	UserIDs []string `json:"user_ids,omitempty"`
+}
+
+// AccessReviewStatus represents the state of an access review campaign
+type AccessReviewStatus string
+
+const (
+	AccessReviewOpen   AccessReviewStatus = "open"
+	AccessReviewClosed AccessReviewStatus = "closed"
+)
+
+// AccessReviewDecision is a reviewer's decision on a single role assignment
+type AccessReviewDecision string
+
+const (
+	AccessReviewPending    AccessReviewDecision = "pending"
+	AccessReviewApprove    AccessReviewDecision = "approve"
+	AccessReviewRevoke     AccessReviewDecision = "revoke"
+	AccessReviewUnreviewed AccessReviewDecision = "unreviewed" // still pending when the campaign closed
+)
+
+// AccessReviewCampaign is a snapshot of role assignments under review
+type AccessReviewCampaign struct {
+	ID               string              `json:"id"`
+	Name             string              `json:"name"`
+	Scope            AccessReviewScope   `json:"scope"`
+	Reviewers        []string            `json:"reviewers"`
+	RevokeUnreviewed bool                `json:"revoke_unreviewed"`
+	Status           AccessReviewStatus  `json:"status"`
+	CreatedBy        string              `json:"created_by"`
+	CreatedAt        time.Time           `json:"created_at"`
+	DueAt            time.Time           `json:"due_at"`
+	ClosedBy         string              `json:"closed_by,omitempty"`
+	ClosedAt         *time.Time          `json:"closed_at,omitempty"`
+	Summary          AccessReviewSummary `json:"summary"`
+	Items            []AccessReviewItem  `json:"items,omitempty"`
+}
+
+// AccessReviewSummary counts a campaign's items by decision
+type AccessReviewSummary struct {
+	Total      int `json:"total"`
+	Pending    int `json:"pending"`
+	Approved   int `json:"approved"`
+	Revoked    int `json:"revoked"`
+	Unreviewed int `json:"unreviewed"`
+	Removed    int `json:"removed"` // assignments actually removed when the campaign closed
+}
+
+// AccessReviewItem is one user's role assignment, with the effective permissions the
+// role granted when the campaign started
+type AccessReviewItem struct {
+	ID          string               `json:"id"`
+	CampaignID  string               `json:"campaign_id"`
+	UserID      string               `json:"user_id"`
+	Role        string               `json:"role"`
+	Permissions []string             `json:"permissions"`
+	ReviewerID  string               `json:"reviewer_id"`
+	Decision    AccessReviewDecision `json:"decision"`
+	Comment     string               `json:"comment,omitempty"`
+	DecidedAt   *time.Time           `json:"decided_at,omitempty"`
+	RemovedAt   *time.Time           `json:"removed_at,omitempty"`
+	RemoveError string               `json:"remove_error,omitempty"`
+}
+
+// AccessReviewEvidence is the sealed record of a closed campaign. Token is a JWS
+// (EdDSA) over the campaign that can be verified with the evidence key.
+type AccessReviewEvidence struct {
+	CampaignID string                `json:"campaign_id"`
+	KeyID      string                `json:"kid"`
+	Algorithm  string                `json:"alg"`
+	Token      string                `json:"token"`
+	Campaign   *AccessReviewCampaign `json:"campaign"`
+}
+
+// AccessReviewEvidenceKey is the public JWK that verifies access review evidence
+type AccessReviewEvidenceKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// SecurityEventSink publishes security events to monitoring
+type SecurityEventSink interface {
+	Emit(event SecurityEvent) error
+}
+
+// Security event severities
+const (
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// SecurityEvent is a security-relevant occurrence reported to the SIEM
+type SecurityEvent struct {
+	Type     string            `json:"type"`
+	Severity string            `json:"severity"`
+	Message  string            `json:"message"`
+	Data     map[string]string `json:"data,omitempty"`
+}
+
+// Notifier delivers messages to users. Email flows send through it so that delivery
+// can be swapped between providers.
+type Notifier interface {
+	SendEmail(to, subject, body string) error
+}
+
+// TokenBinding identifies the client an access token is issued to and, for DPoP,
+// the thumbprint of the key the token is bound to. A scope limits the token to the
+// user's roles it names; without one the token carries all of them.
+type TokenBinding struct {
+	ClientID string
+	JKT      string
+	Scope    []string
 }
 
 // User represents a user in the system
 type User struct {
-	ID       string   `json:"id"`
-	Username string   `json:"username"`
-	Email    string   `json:"email"`
-	Roles    []string `json:"roles"`
+	ID          string     `json:"id"`
+	Username    string     `json:"username"`
+	Email       string     `json:"email"`
+	DisplayName string     `json:"display_name,omitempty"`
+	Roles       []string   `json:"roles"`
+	Status      UserStatus `json:"status,omitempty"`
+	CreatedAt   time.Time  `json:"created_at,omitempty"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	PurgeAt     *time.Time `json:"purge_at,omitempty"` // when a deleted account is permanently removed
+}
+
+// UserStatus represents the lifecycle state of a user account
+type UserStatus string
+
+const (
+	UserActive   UserStatus = "active"
+	UserDisabled UserStatus = "disabled"
+	UserDeleted  UserStatus = "deleted"
+)
+
+// ProfileUpdate holds the profile fields a user can change. Nil fields are left as they are.
+type ProfileUpdate struct {
+	Email       *string
+	DisplayName *string
+}
+
+// Authentication context class references carried in the acr claim
+const (
+	ACRPassword    = "urn:cryptofortress:acr:password"
+	ACREmail       = "urn:cryptofortress:acr:email"
+	ACRDevice      = "urn:cryptofortress:acr:device"
+	ACRMultiFactor = "urn:cryptofortress:acr:mfa"
+	ACRBreakGlass  = "urn:cryptofortress:acr:break-glass"
+)
+
+// AuthContext describes how and when the user last authenticated
+type AuthContext struct {
+	AuthTime time.Time
+	ACR      string
+	AMR      []string // authentication methods, e.g. "pwd", "otp", "email"
 }
 
 // TokenClaims represents the claims in a JWT token
 type TokenClaims struct {
-	UserID   string   `json:"user_id"`
-	Username string   `json:"username"`
-	Roles    []string `json:"roles"`
+	UserID      string   `json:"user_id"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles"`
+	ElevationID string   `json:"elevation_id,omitempty"`
+	
+	// Emergency access session the token belongs to
+	BreakGlassSessionID string `json:"break_glass_session_id,omitempty"`
+	
+	// Authentication context (OpenID Connect auth_time, acr and amr)
+	AuthTime int64    `json:"auth_time,omitempty"`
+	ACR      string   `json:"acr,omitempty"`
+	AMR      []string `json:"amr,omitempty"`
+	
+	// Client and sender constraint (RFC 9449 cnf.jkt)
+	ClientID     string             `json:"client_id,omitempty"`
+	Confirmation *ConfirmationClaim `json:"cnf,omitempty"`
+	
+	// Impersonation (RFC 8693 actor claim)
+	Actor               *ActorClaim `json:"act,omitempty"`
+	ImpersonationReason string      `json:"impersonation_reason,omitempty"`
 	jwt.StandardClaims
+}
+
+// ConfirmationClaim carries the JWK SHA-256 thumbprint of the key a DPoP-bound token is bound to
+type ConfirmationClaim struct {
+	JKT string `json:"jkt"`
+}
+
+// ActorClaim identifies the party acting on behalf of the token subject
+type ActorClaim struct {
+	Subject string `json:"sub"`
 }
\ No newline at end of file
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Shamir's secret sharing over GF(2^8). Each share is the secret's length in
+// polynomial evaluations followed by a single byte holding the share's x coordinate.
+
+// splitSecret splits a secret into n shares, any threshold of which recover it
+func splitSecret(secret []byte, threshold, n int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("secret cannot be empty")
+	}
+	if threshold < 1 || n < threshold {
+		return nil, fmt.Errorf("invalid threshold %d for %d shares", threshold, n)
+	}
+	if n > 255 {
+		return nil, errors.New("at most 255 shares are supported")
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+
+	// One random polynomial per secret byte, with the secret byte as its constant term
+	coefficients := make([]byte, threshold)
+	for b, value := range secret {
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, fmt.Errorf("failed to generate polynomial: %w", err)
+		}
+		coefficients[0] = value
+
+		for i := range shares {
+			shares[i][b] = evaluatePolynomial(coefficients, byte(i+1))
+		}
+	}
+
+	return shares, nil
+}
+
+// combineShares recovers a secret from shares by Lagrange interpolation at x = 0.
+// Too few shares produce a wrong secret rather than an error.
+func combineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, errors.New("no shares provided")
+	}
+
+	length := len(shares[0])
+	if length < 2 {
+		return nil, errors.New("share is too short")
+	}
+	seen := make(map[byte]bool, len(shares))
+	xs := make([]byte, len(shares))
+	for i, share := range shares {
+		if len(share) != length {
+			return nil, errors.New("shares have different lengths")
+		}
+		x := share[length-1]
+		if x == 0 || seen[x] {
+			return nil, errors.New("duplicate or invalid share")
+		}
+		seen[x] = true
+		xs[i] = x
+	}
+
+	secret := make([]byte, length-1)
+	for i := range shares {
+		// Lagrange basis polynomial for share i, evaluated at 0
+		basis := byte(1)
+		for j := range shares {
+			if i == j {
+				continue
+			}
+			basis = gfMul(basis, gfMul(xs[j], gfInverse(xs[i]^xs[j])))
+		}
+		for b := range secret {
+			secret[b] ^= gfMul(shares[i][b], basis)
+		}
+	}
+
+	return secret, nil
+}
+
+// evaluatePolynomial evaluates a polynomial at x using Horner's method
+func evaluatePolynomial(coefficients []byte, x byte) byte {
+	result := byte(0)
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		result = gfMul(result, x) ^ coefficients[i]
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) with the AES polynomial, without data-dependent branches
+func gfMul(a, b byte) byte {
+	var product byte
+	for i := 0; i < 8; i++ {
+		product ^= -(b & 1) & a
+		a = (a << 1) ^ (-(a >> 7) & 0x1b)
+		b >>= 1
+	}
+	return product
+}
+
+// gfInverse returns the multiplicative inverse in GF(2^8) as a^254
+func gfInverse(a byte) byte {
+	result := byte(1)
+	for i := 0; i < 7; i++ {
+		a = gfMul(a, a)
+		result = gfMul(result, a)
+	}
+	return result
+}
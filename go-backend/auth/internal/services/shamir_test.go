@@ -0,0 +1,156 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+// TestShamir tests secret sharing over GF(2^8)
+func TestShamir(t *testing.T) {
+	secret := make([]byte, breakGlassSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatalf("Failed to generate secret: %v", err)
+	}
+
+	t.Run("FieldInverse", func(t *testing.T) {
+		for a := 1; a < 256; a++ {
+			if product := gfMul(byte(a), gfInverse(byte(a))); product != 1 {
+				t.Fatalf("%d * inverse(%d) = %d, expected 1", a, a, product)
+			}
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		cases := []struct {
+			threshold int
+			n         int
+		}{
+			{1, 1},
+			{2, 3},
+			{3, 5},
+			{5, 5},
+		}
+
+		for _, tc := range cases {
+			shares, err := splitSecret(secret, tc.threshold, tc.n)
+			if err != nil {
+				t.Fatalf("Failed to split %d of %d: %v", tc.threshold, tc.n, err)
+			}
+			if len(shares) != tc.n {
+				t.Fatalf("Expected %d shares, got %d", tc.n, len(shares))
+			}
+
+			// Every window of threshold shares, in either order, recovers the secret
+			for start := 0; start+tc.threshold <= tc.n; start++ {
+				subset := shares[start : start+tc.threshold]
+				reversed := make([][]byte, len(subset))
+				for i, share := range subset {
+					reversed[len(subset)-1-i] = share
+				}
+
+				for _, combination := range [][][]byte{subset, reversed} {
+					recovered, err := combineShares(combination)
+					if err != nil {
+						t.Fatalf("Failed to combine %d of %d: %v", tc.threshold, tc.n, err)
+					}
+					if !bytes.Equal(recovered, secret) {
+						t.Errorf("Shares %d..%d of %d-of-%d did not recover the secret", start, start+tc.threshold-1, tc.threshold, tc.n)
+					}
+				}
+			}
+
+			// More shares than the threshold work too
+			recovered, err := combineShares(shares)
+			if err != nil {
+				t.Fatalf("Failed to combine all shares: %v", err)
+			}
+			if !bytes.Equal(recovered, secret) {
+				t.Errorf("All %d shares of %d-of-%d did not recover the secret", tc.n, tc.threshold, tc.n)
+			}
+		}
+	})
+
+	t.Run("BelowThreshold", func(t *testing.T) {
+		shares, err := splitSecret(secret, 3, 5)
+		if err != nil {
+			t.Fatalf("Failed to split: %v", err)
+		}
+
+		recovered, err := combineShares(shares[:2])
+		if err != nil {
+			t.Fatalf("Failed to combine: %v", err)
+		}
+		if bytes.Equal(recovered, secret) {
+			t.Error("Two shares of a 3-of-5 split recovered the secret")
+		}
+	})
+
+	t.Run("TamperedShare", func(t *testing.T) {
+		shares, err := splitSecret(secret, 2, 3)
+		if err != nil {
+			t.Fatalf("Failed to split: %v", err)
+		}
+
+		tampered := bytes.Clone(shares[0])
+		tampered[0] ^= 0x01
+		recovered, err := combineShares([][]byte{tampered, shares[1]})
+		if err != nil {
+			t.Fatalf("Failed to combine: %v", err)
+		}
+		if bytes.Equal(recovered, secret) {
+			t.Error("A tampered share recovered the secret")
+		}
+
+		// Changing a share's x coordinate breaks it too
+		moved := bytes.Clone(shares[0])
+		moved[len(moved)-1] = 9
+		recovered, err = combineShares([][]byte{moved, shares[1]})
+		if err != nil {
+			t.Fatalf("Failed to combine: %v", err)
+		}
+		if bytes.Equal(recovered, secret) {
+			t.Error("A share with a changed x coordinate recovered the secret")
+		}
+	})
+
+	t.Run("InvalidShares", func(t *testing.T) {
+		shares, err := splitSecret(secret, 2, 3)
+		if err != nil {
+			t.Fatalf("Failed to split: %v", err)
+		}
+
+		zeroX := bytes.Clone(shares[1])
+		zeroX[len(zeroX)-1] = 0
+		invalid := map[string][][]byte{
+			"none":            nil,
+			"duplicate":       {shares[0], shares[0]},
+			"different sizes": {shares[0], shares[1][1:]},
+			"x of zero":       {shares[0], zeroX},
+			"too short":       {{1}},
+		}
+		for name, combination := range invalid {
+			if _, err := combineShares(combination); err == nil {
+				t.Errorf("Expected an error combining %s", name)
+			}
+		}
+	})
+
+	t.Run("InvalidSplit", func(t *testing.T) {
+		invalid := []struct {
+			secret    []byte
+			threshold int
+			n         int
+		}{
+			{nil, 1, 1},
+			{secret, 0, 3},
+			{secret, 4, 3},
+			{secret, 2, 256},
+		}
+		for _, tc := range invalid {
+			if _, err := splitSecret(tc.secret, tc.threshold, tc.n); err == nil {
+				t.Errorf("Expected an error splitting %d bytes %d of %d", len(tc.secret), tc.threshold, tc.n)
+			}
+		}
+	})
+}
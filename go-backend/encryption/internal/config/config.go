@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 )
 
 // Config holds the configuration for the encryption service
@@ -18,6 +22,23 @@ type Config struct {
 	AESKeySize        int // in bits
 	RSAKeySize        int // in bits
 	DefaultAlgorithm  string
+	KeyManagementURL  string // key management service base URL, which holds the key-encryption keys
+	AuthGRPCURL       string // address of the auth service's gRPC API, which validates access tokens
+	AuditSink         string // "log" or "audit"
+	AuditServiceURL   string
+	RateLimitAPI      ratelimit.Limit // per calling service
+	TrustedProxies    []string        // proxy IPs or CIDRs whose X-Forwarded-For is believed
+
+	// Service-to-service TLS with certificates from the key management service's CA
+	TLSMode           string // "off", "tls" or "mtls"
+	TLSTrustDomain    string
+	TLSCAURL          string
+	TLSCARootFile     string // PEM roots trusted to bootstrap the first certificate
+	TLSBootstrapToken string
+
+	// Graceful shutdown
+	ShutdownTimeout int // in seconds, covering the whole shutdown including the drain delay
+	ShutdownDelay   int // in seconds, between turning not ready and closing listeners
 }
 
 // Load reads configuration from environment variables
@@ -33,12 +54,56 @@ func Load() (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid RSA_KEY_SIZE: %v", err)
 	}
+	if rsaKeySize < 2048 || rsaKeySize%8 != 0 {
+		return nil, fmt.Errorf("RSA_KEY_SIZE must be a multiple of 8 and at least 2048")
+	}
 	
 	hsmEnabled, err := strconv.ParseBool(getEnv("HSM_ENABLED", "false"))
 	if err != nil {
 		return nil, fmt.Errorf("invalid HSM_ENABLED: %v", err)
 	}
 	
+	auditSink := getEnv("AUDIT_SINK", "log")
+	if auditSink != "log" && auditSink != "audit" {
+		return nil, fmt.Errorf("invalid AUDIT_SINK: %s", auditSink)
+	}
+	
+	rateLimitAPI, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_API", "600/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_API: %v", err)
+	}
+	
+	shutdownTimeout, err := strconv.Atoi(getEnv("SHUTDOWN_TIMEOUT", "30")) // 30 seconds default
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %v", err)
+	}
+	
+	shutdownDelay, err := strconv.Atoi(getEnv("SHUTDOWN_DELAY", "5")) // 5 seconds default
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_DELAY: %v", err)
+	}
+	if shutdownDelay < 0 || shutdownDelay >= shutdownTimeout {
+		return nil, fmt.Errorf("SHUTDOWN_DELAY must be at least 0 and less than SHUTDOWN_TIMEOUT")
+	}
+	
+	var trustedProxies []string
+	if proxies := getEnv("TRUSTED_PROXIES", ""); proxies != "" {
+		for _, proxy := range strings.Split(proxies, ",") {
+			trustedProxies = append(trustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	
+	tlsMode, err := mtls.ParseMode(getEnv("TLS_MODE", mtls.ModeOff))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS_MODE: %v", err)
+	}
+	
+	tlsCARootFile := os.Getenv("TLS_CA_ROOT_FILE")
+	tlsBootstrapToken := os.Getenv("TLS_BOOTSTRAP_TOKEN")
+	if tlsMode != mtls.ModeOff && (tlsCARootFile == "" || tlsBootstrapToken == "") {
+		return nil, fmt.Errorf("TLS_CA_ROOT_FILE and TLS_BOOTSTRAP_TOKEN are required when TLS_MODE is %s", tlsMode)
+	}
+	
 	return &Config{
 		Port:             port,
 		DatabaseURL:      os.Getenv("DATABASE_URL"),
@@ -50,6 +115,21 @@ func Load() (*Config, error) {
 		AESKeySize:       aesKeySize,
 		RSAKeySize:       rsaKeySize,
 		DefaultAlgorithm: getEnv("DEFAULT_ALGORITHM", "AES-256-GCM"),
+		KeyManagementURL: getEnv("KEYMGMT_SERVICE_URL", "http://localhost:8082"),
+		AuthGRPCURL:      getEnv("AUTH_GRPC_URL", "localhost:9080"),
+		AuditSink:        auditSink,
+		AuditServiceURL:  getEnv("AUDIT_SERVICE_URL", "http://localhost:8083"),
+		RateLimitAPI:     rateLimitAPI,
+		TrustedProxies:   trustedProxies,
+
+		TLSMode:           tlsMode,
+		TLSTrustDomain:    getEnv("TLS_TRUST_DOMAIN", mtls.DefaultTrustDomain),
+		TLSCAURL:          getEnv("TLS_CA_URL", "https://localhost:8082"),
+		TLSCARootFile:     tlsCARootFile,
+		TLSBootstrapToken: tlsBootstrapToken,
+
+		ShutdownTimeout: shutdownTimeout,
+		ShutdownDelay:   shutdownDelay,
 	}, nil
 }
 
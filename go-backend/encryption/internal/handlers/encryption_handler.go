@@ -1,36 +1,64 @@
 package handlers
 
 import (
+	"context"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
+	"github.com/cryptofortress/backend/encryption/internal/middleware"
 	"github.com/cryptofortress/backend/encryption/internal/services"
+	"github.com/cryptofortress/backend/pkg/mtls"
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// PermissionEncrypt is required to encrypt under a key-encryption key held by the Key Management Service
+	PermissionEncrypt = "encrypt:data"
+	// PermissionDecrypt is required to decrypt envelopes whose data key the Key Management Service unwraps
+	PermissionDecrypt = "decrypt:data"
 )
 
 // EncryptionHandler handles encryption-related HTTP requests
 type EncryptionHandler struct {
 	encryptionService services.EncryptionService
+	envelopeService   services.EnvelopeService
+	audit             services.AuditRecorder
 }
 
 // NewEncryptionHandler creates a new encryption handler
-func NewEncryptionHandler(encryptionService services.EncryptionService) *EncryptionHandler {
+func NewEncryptionHandler(encryptionService services.EncryptionService, envelopeService services.EnvelopeService, audit services.AuditRecorder) *EncryptionHandler {
 	return &EncryptionHandler{
 		encryptionService: encryptionService,
+		envelopeService:   envelopeService,
+		audit:             audit,
 	}
 }
 
-// EncryptRequest represents the encryption request payload
+// EncryptRequest represents the encryption request payload. The payload is encrypted
+// under a fresh data key that is wrapped by the key-encryption key KeyID. AAD (base64)
+// and EncryptionContext are authenticated but not stored, so decryption must supply
+// them again.
 type EncryptRequest struct {
-	Plaintext string `json:"plaintext" binding:"required"`
-	Key       string `json:"key" binding:"required"`
-	Algorithm string `json:"algorithm" binding:"required"`
+	Plaintext         string            `json:"plaintext" binding:"required"`
+	KeyID             string            `json:"key_id" binding:"required"`
+	Algorithm         string            `json:"algorithm"`
+	AAD               string            `json:"aad"`
+	EncryptionContext map[string]string `json:"encryption_context"`
 }
 
-// EncryptResponse represents the encryption response payload
+// EncryptResponse represents the encryption response payload. Ciphertext is a base64
+// envelope that carries everything needed to decrypt it; the other fields are
+// informational.
 type EncryptResponse struct {
 	Ciphertext string `json:"ciphertext"`
-	Nonce      string `json:"nonce,omitempty"`
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Algorithm  string `json:"algorithm"`
 }
 
 // Encrypt handles encryption requests
@@ -41,71 +69,44 @@ func (h *EncryptionHandler) Encrypt(c *gin.Context) {
 		return
 	}
 
-	// Decode key from base64
-	key, err := base64.StdEncoding.DecodeString(req.Key)
+	// Decode associated data from base64
+	ad, err := associatedData(req.AAD, req.EncryptionContext)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid aad format"})
 		return
 	}
 
-	// Convert plaintext to bytes
-	plaintext := []byte(req.Plaintext)
-
-	// Encrypt based on algorithm
-	var ciphertext []byte
-	var nonce []byte
-
-	switch req.Algorithm {
-	case "AES-256-GCM":
-		// Generate nonce
-		nonce, err = h.encryptionService.GenerateNonce(12) // 12 bytes for GCM
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate nonce"})
-			return
-		}
-
-		// Encrypt
-		ciphertext, err = h.encryptionService.EncryptAES256GCM(plaintext, key, nonce)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Encryption failed"})
-			return
-		}
-	case "ChaCha20-Poly1305":
-		// Generate nonce
-		nonce, err = h.encryptionService.GenerateNonce(12) // 12 bytes for ChaCha20-Poly1305
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate nonce"})
-			return
-		}
-
-		// Encrypt
-		ciphertext, err = h.encryptionService.EncryptChaCha20Poly1305(plaintext, key, nonce)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Encryption failed"})
-			return
-		}
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported algorithm"})
+	// Encrypt under a fresh data key
+	envelope, err := h.envelopeService.Encrypt(c.Request.Context(), req.KeyID, req.Algorithm, []byte(req.Plaintext), ad)
+	h.record(c, "encrypt", req.KeyID, envelope, req.EncryptionContext, err)
+	if err != nil {
+		respondEnvelopeError(c, err, "Encryption failed")
 		return
 	}
 
-	// Encode ciphertext and nonce to base64
-	ciphertextB64 := base64.StdEncoding.EncodeToString(ciphertext)
-	nonceB64 := base64.StdEncoding.EncodeToString(nonce)
+	// Encode the envelope
+	ciphertext, err := services.EncodeEnvelope(envelope)
+	if err != nil {
+		respondEnvelopeError(c, err, "Encryption failed")
+		return
+	}
 
 	// Return response
 	c.JSON(http.StatusOK, EncryptResponse{
-		Ciphertext: ciphertextB64,
-		Nonce:      nonceB64,
+		Ciphertext: ciphertext,
+		KeyID:      envelope.KeyID,
+		KeyVersion: envelope.KeyVersion,
+		Algorithm:  envelope.Algorithm,
 	})
 }
 
-// DecryptRequest represents the decryption request payload
+// DecryptRequest represents the decryption request payload. The algorithm and key
+// are read from the envelope; AAD and EncryptionContext must match those given to
+// encryption.
 type DecryptRequest struct {
-	Ciphertext string `json:"ciphertext" binding:"required"`
-	Key        string `json:"key" binding:"required"`
-	Nonce      string `json:"nonce" binding:"required"`
-	Algorithm  string `json:"algorithm" binding:"required"`
+	Ciphertext        string            `json:"ciphertext" binding:"required"`
+	AAD               string            `json:"aad"`
+	EncryptionContext map[string]string `json:"encryption_context"`
 }
 
 // DecryptResponse represents the decryption response payload
@@ -121,13 +122,6 @@ func (h *EncryptionHandler) Decrypt(c *gin.Context) {
 		return
 	}
 
-	// Decode key from base64
-	key, err := base64.StdEncoding.DecodeString(req.Key)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key format"})
-		return
-	}
-
 	// Decode ciphertext from base64
 	ciphertext, err := base64.StdEncoding.DecodeString(req.Ciphertext)
 	if err != nil {
@@ -135,31 +129,18 @@ func (h *EncryptionHandler) Decrypt(c *gin.Context) {
 		return
 	}
 
-	// Decode nonce from base64
-	nonce, err := base64.StdEncoding.DecodeString(req.Nonce)
+	// Decode associated data from base64
+	ad, err := associatedData(req.AAD, req.EncryptionContext)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid nonce format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid aad format"})
 		return
 	}
 
-	// Decrypt based on algorithm
-	var plaintext []byte
-
-	switch req.Algorithm {
-	case "AES-256-GCM":
-		plaintext, err = h.encryptionService.DecryptAES256GCM(ciphertext, key, nonce)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Decryption failed"})
-			return
-		}
-	case "ChaCha20-Poly1305":
-		plaintext, err = h.encryptionService.DecryptChaCha20Poly1305(ciphertext, key, nonce)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Decryption failed"})
-			return
-		}
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported algorithm"})
+	// Unwrap the data key and decrypt
+	plaintext, envelope, err := h.envelopeService.Decrypt(c.Request.Context(), ciphertext, ad)
+	h.record(c, "decrypt", "", envelope, req.EncryptionContext, err)
+	if err != nil {
+		respondEnvelopeError(c, err, "Decryption failed")
 		return
 	}
 
@@ -169,6 +150,214 @@ func (h *EncryptionHandler) Decrypt(c *gin.Context) {
 	})
 }
 
+// Headers that carry the associated data of streaming requests, whose body is the data
+// itself
+const (
+	headerEncryptionContext = "X-Encryption-Context" // a JSON object of strings
+	headerAAD               = "X-Encryption-AAD"     // base64
+)
+
+// EncryptStream handles streaming encryption requests. The request body is the
+// plaintext as application/octet-stream, and the response body is a streaming envelope.
+// key_id and algorithm are query parameters.
+func (h *EncryptionHandler) EncryptStream(c *gin.Context) {
+	if c.ContentType() != "application/octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/octet-stream"})
+		return
+	}
+	keyID := c.Query("key_id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key_id is required"})
+		return
+	}
+	encryptionContext, ad, ok := streamAssociatedData(c)
+	if !ok {
+		return
+	}
+
+	// Encrypt while the upload is still being read
+	output := newStreamWriter(c)
+	envelope, err := h.envelopeService.EncryptStream(c.Request.Context(), keyID, c.Query("algorithm"), output, c.Request.Body, ad)
+	h.record(c, "encrypt", keyID, envelope, encryptionContext, err)
+	if err != nil {
+		output.fail(err, "Encryption failed")
+		return
+	}
+
+	// Return response
+	output.start()
+}
+
+// DecryptStream handles streaming decryption requests. The request body is a streaming
+// envelope as application/octet-stream, and the response body is the plaintext. If
+// decryption fails after the plaintext has started, the response is aborted, so
+// clients must treat an incomplete response as a failure.
+func (h *EncryptionHandler) DecryptStream(c *gin.Context) {
+	if c.ContentType() != "application/octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/octet-stream"})
+		return
+	}
+	encryptionContext, ad, ok := streamAssociatedData(c)
+	if !ok {
+		return
+	}
+
+	// Decrypt while the upload is still being read
+	output := newStreamWriter(c)
+	envelope, err := h.envelopeService.DecryptStream(c.Request.Context(), output, c.Request.Body, ad)
+	h.record(c, "decrypt", "", envelope, encryptionContext, err)
+	if err != nil {
+		output.fail(err, "Decryption failed")
+		return
+	}
+
+	// Return response
+	output.start()
+}
+
+// streamAssociatedData reads the associated data of a streaming request from its
+// headers. It responds with an error and returns false if they are invalid.
+func streamAssociatedData(c *gin.Context) (map[string]string, services.AssociatedData, bool) {
+	var encryptionContext map[string]string
+	if value := c.GetHeader(headerEncryptionContext); value != "" {
+		if err := json.Unmarshal([]byte(value), &encryptionContext); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + headerEncryptionContext + " header"})
+			return nil, services.AssociatedData{}, false
+		}
+	}
+
+	ad, err := associatedData(c.GetHeader(headerAAD), encryptionContext)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + headerAAD + " header"})
+		return nil, services.AssociatedData{}, false
+	}
+	return encryptionContext, ad, true
+}
+
+// streamWriter writes a streamed response body. Until the body starts, errors can still
+// be reported as JSON.
+type streamWriter struct {
+	c       *gin.Context
+	started bool
+}
+
+// newStreamWriter prepares a streamed response. The request body stays readable while
+// the response is written.
+func newStreamWriter(c *gin.Context) *streamWriter {
+	// HTTP/2 is always full duplex, so an error here only means HTTP/1 cannot be made so
+	_ = http.NewResponseController(c.Writer).EnableFullDuplex()
+	return &streamWriter{c: c}
+}
+
+// start sends the response headers if they have not been sent
+func (w *streamWriter) start() {
+	if !w.started {
+		w.started = true
+		w.c.Header("Content-Type", "application/octet-stream")
+		w.c.Status(http.StatusOK)
+		w.c.Writer.WriteHeaderNow()
+	}
+}
+
+// Write writes to the response body
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.start()
+	return w.c.Writer.Write(p)
+}
+
+// fail reports an error as JSON if the body has not started, or aborts the response
+func (w *streamWriter) fail(err error, message string) {
+	if !w.started {
+		respondEnvelopeError(w.c, err, message)
+		return
+	}
+	log.Warn().Err(err).Msg(message + " after the response started")
+	middleware.AbortResponse(w.c)
+}
+
+// associatedData builds the associated data of a request from base64 aad and an
+// encryption context
+func associatedData(aad string, encryptionContext map[string]string) (services.AssociatedData, error) {
+	data, err := base64.StdEncoding.DecodeString(aad)
+	if err != nil {
+		return services.AssociatedData{}, err
+	}
+	return services.AssociatedData{Context: encryptionContext, Data: data}, nil
+}
+
+// record writes an envelope operation to the audit trail. The encryption context is
+// recorded in full, so the trail shows which record or tenant each ciphertext belongs
+// to; the aad is only recorded as the hash stored in the envelope. Recording failures
+// are logged and do not fail the request.
+func (h *EncryptionHandler) record(c *gin.Context, action, keyID string, envelope *services.Envelope, encryptionContext map[string]string, err error) {
+	metadata := make(map[string]string, len(encryptionContext)+4)
+	for key, value := range encryptionContext {
+		metadata["encryption_context."+key] = value
+	}
+	if envelope != nil {
+		keyID = envelope.KeyID
+		metadata["key_version"] = strconv.Itoa(envelope.KeyVersion)
+		metadata["algorithm"] = envelope.Algorithm
+		if envelope.Streaming {
+			metadata["streaming"] = "true"
+		}
+		if len(envelope.AADHash) > 0 {
+			metadata["aad_hash"] = hex.EncodeToString(envelope.AADHash)
+		}
+	}
+	if keyID == "" {
+		keyID = "unknown"
+	}
+
+	// Callers are identified by their access token; an impersonating admin and the calling
+	// service are recorded alongside
+	if actorID := c.GetString("actorID"); actorID != "" {
+		metadata["actor_id"] = actorID
+	}
+	if peerID := mtls.RequestPeerID(c.Request); peerID != "" {
+		metadata["peer_id"] = peerID
+	}
+
+	event := services.AuditEvent{
+		UserID:      c.GetString("userID"),
+		Action:      action,
+		Resource:    "kek:" + keyID,
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Success:     err == nil,
+		Description: "Envelope " + action,
+		Metadata:    metadata,
+	}
+	if err != nil {
+		event.Description += " failed: " + err.Error()
+	}
+
+	// The event is recorded even if the client has gone away
+	if err := h.audit.Record(context.WithoutCancel(c.Request.Context()), event); err != nil {
+		log.Error().Err(err).Str("audit_action", action).Msg("Failed to record audit event")
+	}
+}
+
+// respondEnvelopeError maps envelope encryption errors to HTTP responses
+func respondEnvelopeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUnsupportedAlgorithm):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported algorithm"})
+	case errors.Is(err, services.ErrKeyManagementUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidEnvelope), errors.Is(err, services.ErrInvalidEncryptionContext),
+		errors.Is(err, services.ErrAssociatedDataMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUnwrapFailed), errors.Is(err, services.ErrDecryptionFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Decryption failed"})
+	default:
+		log.Error().Err(err).Msg(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
 // GenerateKeyRequest represents the key generation request payload
 type GenerateKeyRequest struct {
 	Algorithm string `json:"algorithm" binding:"required"`
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/base64"
+	"errors"
 	"net/http"
 
 	"github.com/cryptofortress/backend/encryption/internal/services"
@@ -20,11 +21,14 @@ func NewFPEHandler(fpeService services.FPEService) *FPEHandler {
 	}
 }
 
-// FPEEncryptRequest represents the FPE encryption request payload
+// FPEEncryptRequest represents the FPE encryption request payload. Algorithm is FF1
+// (the default) or FF3-1, and Alphabet defaults to the decimal digits.
 type FPEEncryptRequest struct {
 	Plaintext string `json:"plaintext" binding:"required"`
 	Key       string `json:"key" binding:"required"`
 	Tweak     string `json:"tweak,omitempty"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Alphabet  string `json:"alphabet,omitempty"`
 }
 
 // FPEEncryptResponse represents the FPE encryption response payload
@@ -58,9 +62,12 @@ func (h *FPEHandler) FPEEncrypt(c *gin.Context) {
 	}
 
 	// Encrypt
-	ciphertext, err := h.fpeService.EncryptFPE(req.Plaintext, key, tweak)
+	ciphertext, err := h.fpeService.EncryptFPE(req.Plaintext, key, tweak, services.FPEParams{
+		Algorithm: req.Algorithm,
+		Alphabet:  req.Alphabet,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "FPE encryption failed"})
+		respondFPEError(c, err, "FPE encryption failed")
 		return
 	}
 
@@ -75,6 +82,8 @@ type FPEDecryptRequest struct {
 	Ciphertext string `json:"ciphertext" binding:"required"`
 	Key        string `json:"key" binding:"required"`
 	Tweak      string `json:"tweak,omitempty"`
+	Algorithm  string `json:"algorithm,omitempty"`
+	Alphabet   string `json:"alphabet,omitempty"`
 }
 
 // FPEDecryptResponse represents the FPE decryption response payload
@@ -108,9 +117,12 @@ func (h *FPEHandler) FPEDecrypt(c *gin.Context) {
 	}
 
 	// Decrypt
-	plaintext, err := h.fpeService.DecryptFPE(req.Ciphertext, key, tweak)
+	plaintext, err := h.fpeService.DecryptFPE(req.Ciphertext, key, tweak, services.FPEParams{
+		Algorithm: req.Algorithm,
+		Alphabet:  req.Alphabet,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "FPE decryption failed"})
+		respondFPEError(c, err, "FPE decryption failed")
 		return
 	}
 
@@ -120,6 +132,138 @@ func (h *FPEHandler) FPEDecrypt(c *gin.Context) {
 	})
 }
 
+// CreateFPETemplateRequest represents the FPE template registration request payload
+type CreateFPETemplateRequest struct {
+	Name        string                `json:"name" binding:"required"`
+	Pattern     string                `json:"pattern" binding:"required"`
+	Algorithm   string                `json:"algorithm,omitempty"`
+	Alphabet    string                `json:"alphabet,omitempty"`
+	Segments    []services.FPESegment `json:"segments" binding:"required"`
+	PostProcess string                `json:"post_process,omitempty"`
+}
+
+// CreateTemplate handles FPE template registration requests
+func (h *FPEHandler) CreateTemplate(c *gin.Context) {
+	var req CreateFPETemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Register template
+	template := services.FPETemplate{
+		Name:        req.Name,
+		Pattern:     req.Pattern,
+		Algorithm:   req.Algorithm,
+		Alphabet:    req.Alphabet,
+		Segments:    req.Segments,
+		PostProcess: req.PostProcess,
+	}
+	if err := h.fpeService.RegisterTemplate(template); err != nil {
+		respondFPEError(c, err, "Failed to register FPE template")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, template)
+}
+
+// ListTemplates handles FPE template listing requests
+func (h *FPEHandler) ListTemplates(c *gin.Context) {
+	// Return response
+	c.JSON(http.StatusOK, gin.H{"templates": h.fpeService.ListTemplates()})
+}
+
+// FPETemplateEncryptRequest represents the FPE template encryption request payload
+type FPETemplateEncryptRequest struct {
+	Template  string `json:"template" binding:"required"`
+	Plaintext string `json:"plaintext" binding:"required"`
+	Key       string `json:"key" binding:"required"`
+	Tweak     string `json:"tweak,omitempty"`
+}
+
+// EncryptTemplate handles FPE template encryption requests
+func (h *FPEHandler) EncryptTemplate(c *gin.Context) {
+	var req FPETemplateEncryptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode key and tweak from base64
+	key, tweak, ok := decodeFPEKeyAndTweak(c, req.Key, req.Tweak)
+	if !ok {
+		return
+	}
+
+	// Encrypt
+	ciphertext, err := h.fpeService.EncryptTemplate(req.Template, req.Plaintext, key, tweak)
+	if err != nil {
+		respondFPEError(c, err, "FPE template encryption failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, FPEEncryptResponse{
+		Ciphertext: ciphertext,
+	})
+}
+
+// FPETemplateDecryptRequest represents the FPE template decryption request payload
+type FPETemplateDecryptRequest struct {
+	Template   string `json:"template" binding:"required"`
+	Ciphertext string `json:"ciphertext" binding:"required"`
+	Key        string `json:"key" binding:"required"`
+	Tweak      string `json:"tweak,omitempty"`
+}
+
+// DecryptTemplate handles FPE template decryption requests
+func (h *FPEHandler) DecryptTemplate(c *gin.Context) {
+	var req FPETemplateDecryptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode key and tweak from base64
+	key, tweak, ok := decodeFPEKeyAndTweak(c, req.Key, req.Tweak)
+	if !ok {
+		return
+	}
+
+	// Decrypt
+	plaintext, err := h.fpeService.DecryptTemplate(req.Template, req.Ciphertext, key, tweak)
+	if err != nil {
+		respondFPEError(c, err, "FPE template decryption failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, FPEDecryptResponse{
+		Plaintext: plaintext,
+	})
+}
+
+// decodeFPEKeyAndTweak decodes a base64 key and optional tweak, and responds with 400 if
+// either is invalid
+func decodeFPEKeyAndTweak(c *gin.Context, encodedKey, encodedTweak string) ([]byte, []byte, bool) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key format"})
+		return nil, nil, false
+	}
+
+	var tweak []byte
+	if encodedTweak != "" {
+		tweak, err = base64.StdEncoding.DecodeString(encodedTweak)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tweak format"})
+			return nil, nil, false
+		}
+	}
+	return key, tweak, true
+}
+
 // CreditCardEncryptRequest represents the credit card encryption request payload
 type CreditCardEncryptRequest struct {
 	CardNumber string `json:"card_number" binding:"required"`
@@ -149,7 +293,7 @@ func (h *FPEHandler) EncryptCreditCard(c *gin.Context) {
 	// Encrypt credit card
 	encryptedCard, err := h.fpeService.EncryptCreditCard(req.CardNumber, key)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Credit card encryption failed"})
+		respondFPEError(c, err, "Credit card encryption failed")
 		return
 	}
 
@@ -188,7 +332,7 @@ func (h *FPEHandler) DecryptCreditCard(c *gin.Context) {
 	// Decrypt credit card
 	cardNumber, err := h.fpeService.DecryptCreditCard(req.EncryptedCard, key)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Credit card decryption failed"})
+		respondFPEError(c, err, "Credit card decryption failed")
 		return
 	}
 
@@ -227,7 +371,7 @@ func (h *FPEHandler) EncryptSSN(c *gin.Context) {
 	// Encrypt SSN
 	encryptedSSN, err := h.fpeService.EncryptSSN(req.SSN, key)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "SSN encryption failed"})
+		respondFPEError(c, err, "SSN encryption failed")
 		return
 	}
 
@@ -266,7 +410,7 @@ func (h *FPEHandler) DecryptSSN(c *gin.Context) {
 	// Decrypt SSN
 	ssn, err := h.fpeService.DecryptSSN(req.EncryptedSSN, key)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "SSN decryption failed"})
+		respondFPEError(c, err, "SSN decryption failed")
 		return
 	}
 
@@ -274,4 +418,21 @@ func (h *FPEHandler) DecryptSSN(c *gin.Context) {
 	c.JSON(http.StatusOK, SSNDecryptResponse{
 		SSN: ssn,
 	})
-}
\ No newline at end of file
+}
+
+// respondFPEError maps FPE errors to HTTP responses
+func respondFPEError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUnsupportedFPEAlgorithm), errors.Is(err, services.ErrInvalidAlphabet),
+		errors.Is(err, services.ErrInvalidFPEInput), errors.Is(err, services.ErrFPEDomainTooSmall),
+		errors.Is(err, services.ErrInvalidTweak), errors.Is(err, services.ErrInvalidFPEKey),
+		errors.Is(err, services.ErrInvalidFPETemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFPETemplateNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrFPETemplateExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/cryptofortress/backend/encryption/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// KEMHandler handles ML-KEM and hybrid X25519-ML-KEM HTTP requests. Keys, ciphertexts
+// and shared keys are base64.
+type KEMHandler struct {
+	encryptionService services.EncryptionService
+}
+
+// NewKEMHandler creates a new KEM handler
+func NewKEMHandler(encryptionService services.EncryptionService) *KEMHandler {
+	return &KEMHandler{
+		encryptionService: encryptionService,
+	}
+}
+
+// GenerateKEMKeyPairRequest represents the KEM key generation request payload.
+// Algorithm is ML-KEM-768, ML-KEM-1024, X25519-ML-KEM-768 (the default) or
+// X25519-ML-KEM-1024.
+type GenerateKEMKeyPairRequest struct {
+	Algorithm string `json:"algorithm"`
+}
+
+// GenerateKEMKeyPairResponse represents the KEM key generation response payload
+type GenerateKEMKeyPairResponse struct {
+	Algorithm  string `json:"algorithm"`
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+}
+
+// GenerateKeyPair handles KEM key pair generation requests
+func (h *KEMHandler) GenerateKeyPair(c *gin.Context) {
+	var req GenerateKEMKeyPairRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Algorithm == "" {
+		req.Algorithm = services.KEMAlgorithmX25519MLKEM768
+	}
+
+	privateKey, publicKey, err := h.encryptionService.GenerateKyberKeyPair(req.Algorithm)
+	if err != nil {
+		respondKEMError(c, err, "Failed to generate key pair")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, GenerateKEMKeyPairResponse{
+		Algorithm:  req.Algorithm,
+		PrivateKey: base64.StdEncoding.EncodeToString(privateKey),
+		PublicKey:  base64.StdEncoding.EncodeToString(publicKey),
+	})
+}
+
+// EncapsulateRequest represents the KEM encapsulation request payload
+type EncapsulateRequest struct {
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+// EncapsulateResponse represents the KEM encapsulation response payload
+type EncapsulateResponse struct {
+	SharedKey  string `json:"shared_key"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Encapsulate handles KEM encapsulation requests
+func (h *KEMHandler) Encapsulate(c *gin.Context) {
+	var req EncapsulateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode public key from base64
+	publicKey, err := base64.StdEncoding.DecodeString(req.PublicKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key format"})
+		return
+	}
+
+	// Encapsulate
+	sharedKey, ciphertext, err := h.encryptionService.EncapsulateKyber(publicKey)
+	if err != nil {
+		respondKEMError(c, err, "Encapsulation failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, EncapsulateResponse{
+		SharedKey:  base64.StdEncoding.EncodeToString(sharedKey),
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	})
+}
+
+// DecapsulateRequest represents the KEM decapsulation request payload
+type DecapsulateRequest struct {
+	Ciphertext string `json:"ciphertext" binding:"required"`
+	PrivateKey string `json:"private_key" binding:"required"`
+}
+
+// DecapsulateResponse represents the KEM decapsulation response payload
+type DecapsulateResponse struct {
+	SharedKey string `json:"shared_key"`
+}
+
+// Decapsulate handles KEM decapsulation requests
+func (h *KEMHandler) Decapsulate(c *gin.Context) {
+	var req DecapsulateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode ciphertext and private key from base64
+	ciphertext, err := base64.StdEncoding.DecodeString(req.Ciphertext)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ciphertext format"})
+		return
+	}
+	privateKey, err := base64.StdEncoding.DecodeString(req.PrivateKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key format"})
+		return
+	}
+
+	// Decapsulate
+	sharedKey, err := h.encryptionService.DecapsulateKyber(ciphertext, privateKey)
+	if err != nil {
+		respondKEMError(c, err, "Decapsulation failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, DecapsulateResponse{
+		SharedKey: base64.StdEncoding.EncodeToString(sharedKey),
+	})
+}
+
+// KEMEncryptRequest represents the hybrid KEM encryption request payload
+type KEMEncryptRequest struct {
+	Plaintext string `json:"plaintext" binding:"required"`
+	PublicKey string `json:"public_key" binding:"required"`
+}
+
+// KEMEncryptResponse represents the hybrid KEM encryption response payload
+type KEMEncryptResponse struct {
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Encrypt handles hybrid KEM encryption requests
+func (h *KEMHandler) Encrypt(c *gin.Context) {
+	var req KEMEncryptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode public key from base64
+	publicKey, err := base64.StdEncoding.DecodeString(req.PublicKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key format"})
+		return
+	}
+
+	// Encrypt
+	ciphertext, err := h.encryptionService.EncryptKyber([]byte(req.Plaintext), publicKey)
+	if err != nil {
+		respondKEMError(c, err, "Encryption failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, KEMEncryptResponse{
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	})
+}
+
+// KEMDecryptRequest represents the hybrid KEM decryption request payload
+type KEMDecryptRequest struct {
+	Ciphertext string `json:"ciphertext" binding:"required"`
+	PrivateKey string `json:"private_key" binding:"required"`
+}
+
+// Decrypt handles hybrid KEM decryption requests
+func (h *KEMHandler) Decrypt(c *gin.Context) {
+	var req KEMDecryptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode ciphertext and private key from base64
+	ciphertext, err := base64.StdEncoding.DecodeString(req.Ciphertext)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ciphertext format"})
+		return
+	}
+	privateKey, err := base64.StdEncoding.DecodeString(req.PrivateKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key format"})
+		return
+	}
+
+	// Decrypt
+	plaintext, err := h.encryptionService.DecryptKyber(ciphertext, privateKey)
+	if err != nil {
+		respondKEMError(c, err, "Decryption failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, DecryptResponse{
+		Plaintext: string(plaintext),
+	})
+}
+
+// respondKEMError maps KEM errors to HTTP responses
+func respondKEMError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidKEMKey), errors.Is(err, services.ErrInvalidKEMCiphertext),
+		errors.Is(err, services.ErrInvalidEnvelope), errors.Is(err, services.ErrUnsupportedAlgorithm):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDecryptionFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Decryption failed"})
+	default:
+		log.Error().Err(err).Msg(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/cryptofortress/backend/encryption/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// PermissionGenerateMAC is required to compute tags with a key held by the Key Management Service
+const PermissionGenerateMAC = "generate:mac"
+
+// MACHandler handles message authentication HTTP requests. Tags are base64.
+type MACHandler struct {
+	macService services.MACService
+}
+
+// NewMACHandler creates a new MAC handler
+func NewMACHandler(macService services.MACService) *MACHandler {
+	return &MACHandler{
+		macService: macService,
+	}
+}
+
+// GenerateMACRequest represents the MAC generation request payload
+type GenerateMACRequest struct {
+	KeyID   string `json:"key_id" binding:"required"`
+	Message string `json:"message"`
+}
+
+// GenerateMACResponse represents the MAC generation response payload
+type GenerateMACResponse struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Algorithm  string `json:"algorithm"`
+	Tag        string `json:"tag"`
+}
+
+// GenerateMAC handles MAC generation requests
+func (h *MACHandler) GenerateMAC(c *gin.Context) {
+	var req GenerateMACRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Generate MAC
+	mac, err := h.macService.GenerateMAC(c.Request.Context(), req.KeyID, []byte(req.Message))
+	if err != nil {
+		respondMACError(c, err, "MAC generation failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, GenerateMACResponse{
+		KeyID:      mac.KeyID,
+		KeyVersion: mac.KeyVersion,
+		Algorithm:  mac.Algorithm,
+		Tag:        base64.StdEncoding.EncodeToString(mac.Tag),
+	})
+}
+
+// VerifyMACRequest represents the MAC verification request payload. KeyVersion is the
+// version that computed the tag.
+type VerifyMACRequest struct {
+	KeyID      string `json:"key_id" binding:"required"`
+	KeyVersion int    `json:"key_version" binding:"required"`
+	Message    string `json:"message"`
+	Tag        string `json:"tag" binding:"required"`
+}
+
+// VerifyMAC handles MAC verification requests
+func (h *MACHandler) VerifyMAC(c *gin.Context) {
+	var req VerifyMACRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode tag from base64
+	tag, err := base64.StdEncoding.DecodeString(req.Tag)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag format"})
+		return
+	}
+
+	// Verify MAC
+	valid, err := h.macService.VerifyMAC(c.Request.Context(), req.KeyID, req.KeyVersion, []byte(req.Message), tag)
+	if err != nil {
+		respondMACError(c, err, "MAC verification failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, VerifyResponse{Valid: valid})
+}
+
+// respondMACError maps MAC errors to HTTP responses
+func respondMACError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrKeyManagementUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMACKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTagLength):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Error().Err(err).Msg(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -1,27 +1,75 @@
 package handlers
 
 import (
+	"github.com/cryptofortress/backend/auth/httpauth"
+	"github.com/cryptofortress/backend/encryption/internal/config"
 	"github.com/cryptofortress/backend/encryption/internal/services"
 	"github.com/cryptofortress/backend/encryption/internal/middleware"
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterRoutes sets up all the routes for the encryption service
-func RegisterRoutes(router *gin.Engine, services *services.Services) {
+func RegisterRoutes(router *gin.Engine, services *services.Services, cfg *config.Config, limiter *ratelimit.Limiter, authenticator *httpauth.Authenticator) {
 	// Create handlers
-	encryptionHandler := NewEncryptionHandler(services.Encryption)
+	encryptionHandler := NewEncryptionHandler(services.Encryption, services.Envelope, services.Audit)
 	fpeHandler := NewFPEHandler(services.FPE)
+	rsaHandler := NewRSAHandler(services.Encryption)
+	kemHandler := NewKEMHandler(services.Encryption)
+	signingHandler := NewSigningHandler(services.Signing)
+	macHandler := NewMACHandler(services.MAC)
+
+	// Operations with keys held by the Key Management Service need a caller whose roles,
+	// checked by the auth service, grant the operation
+	encrypt := authenticator.Require(PermissionEncrypt)
+	decrypt := authenticator.Require(PermissionDecrypt)
 
 	// Public routes (no authentication required for demo purposes)
 	public := router.Group("/api/v1/encryption")
+	if cfg.TLSMode == mtls.ModeMTLS {
+		public.Use(mtls.RequirePeer())
+	}
+	public.Use(limiter.Limit("api", cfg.RateLimitAPI, ratelimit.ByPeer))
 	{
-		public.POST("/encrypt", encryptionHandler.Encrypt)
-		public.POST("/decrypt", encryptionHandler.Decrypt)
+		public.POST("/encrypt", encrypt, encryptionHandler.Encrypt)
+		public.POST("/decrypt", decrypt, encryptionHandler.Decrypt)
 		public.POST("/generate-key", encryptionHandler.GenerateKey)
 		
+		// RSA-OAEP routes
+		public.POST("/rsa/generate", rsaHandler.GenerateKeyPair)
+		public.POST("/rsa/convert", rsaHandler.ConvertKey)
+		public.POST("/rsa/encrypt", rsaHandler.Encrypt)
+		public.POST("/rsa/decrypt", rsaHandler.Decrypt)
+		
+		// ML-KEM and hybrid KEM routes
+		public.POST("/kem/generate", kemHandler.GenerateKeyPair)
+		public.POST("/kem/encapsulate", kemHandler.Encapsulate)
+		public.POST("/kem/decapsulate", kemHandler.Decapsulate)
+		public.POST("/kem/encrypt", kemHandler.Encrypt)
+		public.POST("/kem/decrypt", kemHandler.Decrypt)
+		
+		// Digital signature routes
+		public.POST("/sign", authenticator.Require(PermissionSign), signingHandler.Sign)
+		public.POST("/verify", signingHandler.Verify)
+		public.POST("/sign/stream", authenticator.Require(PermissionSign), signingHandler.SignStream)
+		public.POST("/verify/stream", signingHandler.VerifyStream)
+		
+		// Message authentication routes
+		public.POST("/mac/generate", authenticator.Require(PermissionGenerateMAC), macHandler.GenerateMAC)
+		public.POST("/mac/verify", macHandler.VerifyMAC)
+		
+		// Streaming routes
+		public.POST("/stream/encrypt", encrypt, encryptionHandler.EncryptStream)
+		public.POST("/stream/decrypt", decrypt, encryptionHandler.DecryptStream)
+		
 		// FPE routes
 		public.POST("/fpe/encrypt", fpeHandler.FPEEncrypt)
 		public.POST("/fpe/decrypt", fpeHandler.FPEDecrypt)
+		public.POST("/fpe/templates/create", fpeHandler.CreateTemplate)
+		public.GET("/fpe/templates", fpeHandler.ListTemplates)
+		public.POST("/fpe/templates/encrypt", fpeHandler.EncryptTemplate)
+		public.POST("/fpe/templates/decrypt", fpeHandler.DecryptTemplate)
 		
 		// Credit card routes
 		public.POST("/credit-card/encrypt", fpeHandler.EncryptCreditCard)
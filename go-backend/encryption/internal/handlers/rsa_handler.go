@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/cryptofortress/backend/encryption/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// RSAHandler handles RSA-OAEP HTTP requests. Keys are sent as a JSON string holding
+// PEM or base64 DER, or as a JWK object.
+type RSAHandler struct {
+	encryptionService services.EncryptionService
+}
+
+// NewRSAHandler creates a new RSA handler
+func NewRSAHandler(encryptionService services.EncryptionService) *RSAHandler {
+	return &RSAHandler{
+		encryptionService: encryptionService,
+	}
+}
+
+// GenerateRSAKeyPairResponse represents the RSA key generation response payload
+type GenerateRSAKeyPairResponse struct {
+	PrivateKey string `json:"private_key"` // PKCS#8 PEM
+	PublicKey  string `json:"public_key"`  // SPKI PEM
+}
+
+// GenerateKeyPair handles RSA key pair generation requests
+func (h *RSAHandler) GenerateKeyPair(c *gin.Context) {
+	privateKey, publicKey, err := h.encryptionService.GenerateRSAKeyPair()
+	if err != nil {
+		respondRSAError(c, err, "Failed to generate key pair")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, GenerateRSAKeyPairResponse{
+		PrivateKey: string(privateKey),
+		PublicKey:  string(publicKey),
+	})
+}
+
+// ConvertRSAKeyRequest represents the RSA key conversion request payload. Format is
+// pkcs1, pkcs8, spki or jwk; Encoding is pem (the default) or der.
+type ConvertRSAKeyRequest struct {
+	Key      json.RawMessage `json:"key" binding:"required"`
+	Format   string          `json:"format" binding:"required"`
+	Encoding string          `json:"encoding"`
+}
+
+// ConvertRSAKeyResponse represents the RSA key conversion response payload. Key is a
+// JWK object, or a string holding PEM or base64 DER.
+type ConvertRSAKeyResponse struct {
+	Key json.RawMessage `json:"key"`
+}
+
+// ConvertKey handles RSA key import and export requests
+func (h *RSAHandler) ConvertKey(c *gin.Context) {
+	var req ConvertRSAKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode key
+	key, err := decodeRSAKey(req.Key)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key format"})
+		return
+	}
+
+	// Convert key
+	converted, err := h.encryptionService.ConvertRSAKey(key, req.Format, req.Encoding)
+	if err != nil {
+		respondRSAError(c, err, "Failed to convert key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, ConvertRSAKeyResponse{
+		Key: encodeRSAKey(converted, req.Format, req.Encoding),
+	})
+}
+
+// RSAEncryptRequest represents the RSA-OAEP encryption request payload. Hash is
+// SHA-256 (the default), SHA-384 or SHA-512.
+type RSAEncryptRequest struct {
+	Plaintext string          `json:"plaintext" binding:"required"`
+	PublicKey json.RawMessage `json:"public_key" binding:"required"`
+	Hash      string          `json:"hash"`
+}
+
+// RSAEncryptResponse represents the RSA-OAEP encryption response payload
+type RSAEncryptResponse struct {
+	Ciphertext string `json:"ciphertext"`
+}
+
+// Encrypt handles RSA-OAEP encryption requests
+func (h *RSAHandler) Encrypt(c *gin.Context) {
+	var req RSAEncryptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode public key
+	publicKey, err := decodeRSAKey(req.PublicKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key format"})
+		return
+	}
+
+	// Encrypt
+	ciphertext, err := h.encryptionService.EncryptRSAOAEP([]byte(req.Plaintext), publicKey, req.Hash)
+	if err != nil {
+		respondRSAError(c, err, "Encryption failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, RSAEncryptResponse{
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	})
+}
+
+// RSADecryptRequest represents the RSA-OAEP decryption request payload
+type RSADecryptRequest struct {
+	Ciphertext string          `json:"ciphertext" binding:"required"`
+	PrivateKey json.RawMessage `json:"private_key" binding:"required"`
+	Hash       string          `json:"hash"`
+}
+
+// Decrypt handles RSA-OAEP decryption requests
+func (h *RSAHandler) Decrypt(c *gin.Context) {
+	var req RSADecryptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode ciphertext from base64
+	ciphertext, err := base64.StdEncoding.DecodeString(req.Ciphertext)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ciphertext format"})
+		return
+	}
+
+	// Decode private key
+	privateKey, err := decodeRSAKey(req.PrivateKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid key format"})
+		return
+	}
+
+	// Decrypt
+	plaintext, err := h.encryptionService.DecryptRSAOAEP(ciphertext, privateKey, req.Hash)
+	if err != nil {
+		respondRSAError(c, err, "Decryption failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, DecryptResponse{
+		Plaintext: string(plaintext),
+	})
+}
+
+// decodeRSAKey extracts a key from its JSON form: a JWK object, or a string holding PEM
+// or base64 DER
+func decodeRSAKey(raw json.RawMessage) ([]byte, error) {
+	raw = bytes.TrimSpace(raw)
+	if bytes.HasPrefix(raw, []byte("{")) {
+		return raw, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return nil, err
+	}
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "-----BEGIN") || strings.HasPrefix(text, "{") {
+		return []byte(text), nil
+	}
+	return base64.StdEncoding.DecodeString(text)
+}
+
+// encodeRSAKey returns the JSON form of a converted key
+func encodeRSAKey(key []byte, format, encoding string) json.RawMessage {
+	var encoded []byte
+	switch {
+	case format == services.RSAKeyFormatJWK:
+		return key
+	case encoding == services.KeyEncodingDER:
+		encoded, _ = json.Marshal(base64.StdEncoding.EncodeToString(key))
+	default:
+		encoded, _ = json.Marshal(string(key))
+	}
+	return encoded
+}
+
+// respondRSAError maps RSA errors to HTTP responses
+func respondRSAError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRSAKey), errors.Is(err, services.ErrUnsupportedKeyFormat),
+		errors.Is(err, services.ErrUnsupportedHash), errors.Is(err, services.ErrRSAMessageTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrDecryptionFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Decryption failed"})
+	default:
+		log.Error().Err(err).Msg(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
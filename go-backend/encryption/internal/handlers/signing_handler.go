@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/cryptofortress/backend/encryption/internal/services"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// headerSignature carries the base64 signature of streaming verification requests
+const headerSignature = "X-Signature"
+
+// PermissionSign is required to sign with a key held by the Key Management Service
+const PermissionSign = "sign:data"
+
+// SigningHandler handles digital signature HTTP requests. Signatures and digests are
+// base64.
+type SigningHandler struct {
+	signingService services.SigningService
+}
+
+// NewSigningHandler creates a new signing handler
+func NewSigningHandler(signingService services.SigningService) *SigningHandler {
+	return &SigningHandler{
+		signingService: signingService,
+	}
+}
+
+// SignRequest represents the signing request payload. Exactly one of Message and Digest
+// is set; a digest must be computed with the signing key's hash.
+type SignRequest struct {
+	KeyID   string `json:"key_id" binding:"required"`
+	Message string `json:"message"`
+	Digest  string `json:"digest"`
+}
+
+// SignResponse represents the signing response payload
+type SignResponse struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Algorithm  string `json:"algorithm"`
+	Hash       string `json:"hash"`
+	Signature  string `json:"signature"`
+}
+
+// Sign handles signing requests
+func (h *SigningHandler) Sign(c *gin.Context) {
+	var req SignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.Message == "") == (req.Digest == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of message and digest is required"})
+		return
+	}
+
+	// Sign the message or digest
+	var signature *services.Signature
+	var err error
+	if req.Digest != "" {
+		digest, decodeErr := base64.StdEncoding.DecodeString(req.Digest)
+		if decodeErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid digest format"})
+			return
+		}
+		signature, err = h.signingService.SignDigest(c.Request.Context(), req.KeyID, digest)
+	} else {
+		signature, err = h.signingService.SignMessage(c.Request.Context(), req.KeyID, bytes.NewReader([]byte(req.Message)))
+	}
+	if err != nil {
+		respondSigningError(c, err, "Signing failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, newSignResponse(signature))
+}
+
+// VerifyRequest represents the signature verification request payload. Exactly one of
+// Message and Digest is set, and KeyVersion is the version that made the signature.
+type VerifyRequest struct {
+	KeyID      string `json:"key_id" binding:"required"`
+	KeyVersion int    `json:"key_version" binding:"required"`
+	Message    string `json:"message"`
+	Digest     string `json:"digest"`
+	Signature  string `json:"signature" binding:"required"`
+}
+
+// VerifyResponse represents the signature verification response payload
+type VerifyResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// Verify handles signature verification requests
+func (h *SigningHandler) Verify(c *gin.Context) {
+	var req VerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.Message == "") == (req.Digest == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of message and digest is required"})
+		return
+	}
+
+	// Decode signature from base64
+	signature, err := base64.StdEncoding.DecodeString(req.Signature)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signature format"})
+		return
+	}
+
+	// Verify the signature of the message or digest
+	var valid bool
+	if req.Digest != "" {
+		digest, decodeErr := base64.StdEncoding.DecodeString(req.Digest)
+		if decodeErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid digest format"})
+			return
+		}
+		valid, err = h.signingService.VerifyDigest(c.Request.Context(), req.KeyID, req.KeyVersion, digest, signature)
+	} else {
+		valid, err = h.signingService.VerifyMessage(c.Request.Context(), req.KeyID, req.KeyVersion, bytes.NewReader([]byte(req.Message)), signature)
+	}
+	if err != nil {
+		respondSigningError(c, err, "Verification failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, VerifyResponse{Valid: valid})
+}
+
+// SignStream handles streaming signing requests. The request body is the message as
+// application/octet-stream, which is hashed as it is read, and key_id is a query
+// parameter.
+func (h *SigningHandler) SignStream(c *gin.Context) {
+	if c.ContentType() != "application/octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/octet-stream"})
+		return
+	}
+	keyID := c.Query("key_id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key_id is required"})
+		return
+	}
+
+	// Sign while the upload is still being read
+	signature, err := h.signingService.SignMessage(c.Request.Context(), keyID, c.Request.Body)
+	if err != nil {
+		respondSigningError(c, err, "Signing failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, newSignResponse(signature))
+}
+
+// VerifyStream handles streaming signature verification requests. The request body is
+// the message as application/octet-stream, key_id and key_version are query parameters,
+// and the signature is in the X-Signature header.
+func (h *SigningHandler) VerifyStream(c *gin.Context) {
+	if c.ContentType() != "application/octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be application/octet-stream"})
+		return
+	}
+	keyID := c.Query("key_id")
+	if keyID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key_id is required"})
+		return
+	}
+	keyVersion, err := strconv.Atoi(c.Query("key_version"))
+	if err != nil || keyVersion < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key_version must be a positive integer"})
+		return
+	}
+	signature, err := base64.StdEncoding.DecodeString(c.GetHeader(headerSignature))
+	if err != nil || len(signature) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + headerSignature + " header"})
+		return
+	}
+
+	// Verify while the upload is still being read
+	valid, err := h.signingService.VerifyMessage(c.Request.Context(), keyID, keyVersion, c.Request.Body, signature)
+	if err != nil {
+		respondSigningError(c, err, "Verification failed")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, VerifyResponse{Valid: valid})
+}
+
+// newSignResponse encodes a signature as a response payload
+func newSignResponse(signature *services.Signature) SignResponse {
+	return SignResponse{
+		KeyID:      signature.KeyID,
+		KeyVersion: signature.KeyVersion,
+		Algorithm:  signature.Algorithm,
+		Hash:       signature.Hash,
+		Signature:  base64.StdEncoding.EncodeToString(signature.Signature),
+	}
+}
+
+// respondSigningError maps signing errors to HTTP responses
+func respondSigningError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrKeyManagementUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSigningKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidDigest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Error().Err(err).Msg(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// abortResponseKey marks a response that must be aborted rather than completed
+const abortResponseKey = "abortResponse"
+
+// AbortResponse makes the server abort the current response once the handler returns:
+// the connection is closed, or the HTTP/2 stream reset, before the body is complete.
+// Streaming handlers use it when they fail after the body has started, so the client
+// sees an error instead of a response that looks complete.
+func AbortResponse(c *gin.Context) {
+	c.Set(abortResponseKey, true)
+}
+
+// AbortIncomplete carries out AbortResponse. It must be registered before gin.Recovery,
+// which would otherwise recover the abort.
+func AbortIncomplete() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		
+		if c.GetBool(abortResponseKey) {
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
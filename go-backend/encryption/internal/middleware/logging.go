@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Logging creates a middleware for request logging
+func Logging() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Start timer
+		start := time.Now()
+		
+		// Process request
+		c.Next()
+		
+		// Log request details
+		duration := time.Since(start)
+		
+		log.Info().
+			Str("method", c.Request.Method).
+			Str("path", c.Request.URL.Path).
+			Int("status", c.Writer.Status()).
+			Dur("duration", duration).
+			Str("client_ip", c.ClientIP()).
+			Msg("HTTP request")
+	}
+}
\ No newline at end of file
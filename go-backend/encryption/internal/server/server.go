@@ -2,73 +2,155 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/cryptofortress/backend/auth/httpauth"
+	"github.com/cryptofortress/backend/auth/proto/authpb"
 	"github.com/cryptofortress/backend/encryption/internal/config"
 	"github.com/cryptofortress/backend/encryption/internal/handlers"
 	"github.com/cryptofortress/backend/encryption/internal/middleware"
 	"github.com/cryptofortress/backend/encryption/internal/services"
+	"github.com/cryptofortress/backend/pkg/lifecycle"
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
-	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 // Server represents the encryption service server
 type Server struct {
-	config   *config.Config
-	router   *gin.Engine
-	services *services.Services
+	config     *config.Config
+	router     *gin.Engine
+	httpServer *http.Server
+	services   *services.Services
+	authConn   *grpc.ClientConn
+	certs      *mtls.CertManager // nil when TLS is off
+	readiness  *lifecycle.Readiness
+	workers    *lifecycle.Workers
 }
 
 // New creates a new encryption server instance
-func New(cfg *config.Config) *Server {
+func New(cfg *config.Config) (*Server, error) {
+	// Service certificates are issued by the key management service's CA, which also
+	// authenticates this service when it wraps data keys and records audit events. Without
+	// TLS there is no key management client, so envelope encryption, signing and MACs
+	// answer 503.
+	var certs *mtls.CertManager
+	var keymgmtClient *http.Client
+	auditClient := &http.Client{Timeout: 5 * time.Second}
+	if cfg.TLSMode != mtls.ModeOff {
+		roots, err := mtls.LoadRoots(cfg.TLSCARootFile)
+		if err != nil {
+			return nil, err
+		}
+		certs = mtls.NewCertManager(mtls.ServiceID(cfg.TLSTrustDomain, mtls.ServiceEncryption), roots)
+		keymgmtClient = certs.HTTPClient(10*time.Second, mtls.ServiceID(cfg.TLSTrustDomain, mtls.ServiceKeyManagement))
+		auditClient = certs.HTTPClient(5*time.Second, mtls.ServiceID(cfg.TLSTrustDomain, mtls.ServiceAudit))
+	}
+	
 	// Initialize services
 	encryptionService := services.NewEncryptionService(cfg)
 	fpeService := services.NewFPEService(cfg)
+	keyWrapper := services.NewKeyManagementWrapper(cfg.KeyManagementURL, keymgmtClient)
+	envelopeService := services.NewEnvelopeService(cfg, encryptionService, keyWrapper)
+	keySigner := services.NewKeyManagementSigner(cfg.KeyManagementURL, keymgmtClient)
+	signingService := services.NewSigningService(cfg, keySigner)
+	macService := services.NewKeyManagementMACService(cfg.KeyManagementURL, keymgmtClient)
+	auditRecorder := services.NewAuditRecorder(cfg, auditClient)
 	
 	services := &services.Services{
 		Encryption: encryptionService,
 		FPE:        fpeService,
+		Envelope:   envelopeService,
+		Signing:    signingService,
+		MAC:        macService,
+		Audit:      auditRecorder,
+	}
+	
+	// Callers are authenticated by the auth service, over the same service TLS when it is on
+	authCreds := insecure.NewCredentials()
+	if certs != nil {
+		authCreds = credentials.NewTLS(certs.ClientTLSConfig(mtls.ServiceID(cfg.TLSTrustDomain, mtls.ServiceAuth)))
+	}
+	authConn, err := grpc.Dial(cfg.AuthGRPCURL, grpc.WithTransportCredentials(authCreds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to auth service: %w", err)
 	}
+	authenticator := httpauth.New(authpb.NewAuthServiceClient(authConn))
 	
 	// Create router
 	router := gin.New()
+	// Client IPs, which most rate limits are keyed on, are only taken from X-Forwarded-For
+	// when a trusted proxy sent it
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	// The same proxies set the scheme and host that DPoP proofs are checked against
+	trustForwarded, err := httpauth.TrustForwardedHeaders(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	router.Use(trustForwarded)
+	router.Use(middleware.AbortIncomplete())
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logging())
 	
+	// Rate limits are kept in memory, so each instance enforces its own
+	limiter := ratelimit.New(ratelimit.NewMemoryStore())
+	
 	// Register routes
-	handlers.RegisterRoutes(router, services)
+	handlers.RegisterRoutes(router, services, cfg, limiter, authenticator)
+	
+	// Readiness turns false as soon as shutdown begins, so load balancers stop sending traffic
+	readiness := lifecycle.NewReadiness()
+	router.GET("/ready", readiness.Handler())
 	
 	return &Server{
-		config:   cfg,
-		router:   router,
-		services: services,
-	}
+		config: cfg,
+		router: router,
+		httpServer: &http.Server{
+			Addr:    ":" + cfg.Port,
+			Handler: router,
+		},
+		services:  services,
+		authConn:  authConn,
+		certs:     certs,
+		readiness: readiness,
+		workers:   lifecycle.NewWorkers(),
+	}, nil
 }
 
-// Start begins serving requests
+// Start begins serving requests and returns once the server is stopped
 func (s *Server) Start() error {
-	server := &http.Server{
-		Addr:    ":" + s.config.Port,
-		Handler: s.router,
+	if s.certs == nil {
+		return lifecycle.ServeError(s.httpServer.ListenAndServe())
 	}
 	
-	// Graceful shutdown
-	go func() {
-		<-context.Background().Done()
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		
-		if err := server.Shutdown(ctx); err != nil {
-			log.Error().Err(err).Msg("Server shutdown failed")
-		}
-	}()
+	// Obtain the first certificate before accepting connections; it is renewed in the background
+	// until the workers stop
+	keymgmtID := mtls.ServiceID(s.config.TLSTrustDomain, mtls.ServiceKeyManagement)
+	issuer := mtls.NewHTTPIssuer(s.config.TLSCAURL, s.config.TLSBootstrapToken, s.certs.HTTPClient(10*time.Second, keymgmtID))
+	if err := s.certs.Start(s.workers.Context(), issuer); err != nil {
+		return lifecycle.ServeError(err)
+	}
 	
-	return server.ListenAndServe()
+	s.httpServer.TLSConfig = s.certs.ServerTLSConfig(tls.VerifyClientCertIfGiven)
+	return lifecycle.ServeError(s.httpServer.ListenAndServeTLS("", ""))
 }
 
-// Stop gracefully shuts down the server
-func (s *Server) Stop() error {
-	// Cleanup resources if needed
-	return nil
-}
\ No newline at end of file
+// Stop gracefully shuts down the server: it turns not ready, waits for load balancers
+// to notice, drains open connections and then stops the background workers
+func (s *Server) Stop(ctx context.Context) error {
+	s.readiness.Drain(ctx, time.Duration(s.config.ShutdownDelay)*time.Second)
+	
+	// Workers stop even if draining times out, with a timeout of their own, so the shutdown
+	// error reports everything
+	httpErr := s.httpServer.Shutdown(ctx)
+	return errors.Join(httpErr, s.authConn.Close(), s.workers.StopWithin(lifecycle.WorkerStopTimeout))
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+var (
+	// ErrInvalidEncryptionContext is returned for encryption contexts that cannot be canonicalized
+	ErrInvalidEncryptionContext = errors.New("invalid encryption context")
+	// ErrAssociatedDataMismatch is returned when decryption is given different associated data
+	// or encryption context than encryption was
+	ErrAssociatedDataMismatch = errors.New("associated data or encryption context does not match")
+)
+
+// canonical returns the bytes authenticated for this associated data, or nil if there is
+// none. The encryption context is sorted by key and every key and value is
+// length-prefixed, so equal contexts always produce the same bytes and different
+// contexts never do:
+//
+//	pair count   2 bytes
+//	key          2-byte length, then UTF-8 bytes   (for each pair, in key order)
+//	value        2-byte length, then UTF-8 bytes
+//	data         the remaining bytes
+func (a AssociatedData) canonical() ([]byte, error) {
+	if len(a.Context) == 0 && len(a.Data) == 0 {
+		return nil, nil
+	}
+	if len(a.Context) > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: too many entries", ErrInvalidEncryptionContext)
+	}
+
+	keys := make([]string, 0, len(a.Context))
+	size := 2 + len(a.Data)
+	for key, value := range a.Context {
+		switch {
+		case key == "":
+			return nil, fmt.Errorf("%w: empty key", ErrInvalidEncryptionContext)
+		case len(key) > math.MaxUint16 || len(value) > math.MaxUint16:
+			return nil, fmt.Errorf("%w: entry %q is too long", ErrInvalidEncryptionContext, key)
+		}
+		keys = append(keys, key)
+		size += 4 + len(key) + len(value)
+	}
+	sort.Strings(keys)
+
+	canonical := make([]byte, 0, size)
+	canonical = binary.BigEndian.AppendUint16(canonical, uint16(len(keys)))
+	for _, key := range keys {
+		canonical = binary.BigEndian.AppendUint16(canonical, uint16(len(key)))
+		canonical = append(canonical, key...)
+		canonical = binary.BigEndian.AppendUint16(canonical, uint16(len(a.Context[key])))
+		canonical = append(canonical, a.Context[key]...)
+	}
+	return append(canonical, a.Data...), nil
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cryptofortress/backend/encryption/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// NewAuditRecorder creates the configured audit recorder. The client is used to reach
+// the audit service.
+func NewAuditRecorder(cfg *config.Config, client *http.Client) AuditRecorder {
+	if cfg.AuditSink == "audit" {
+		return NewAuditServiceRecorder(cfg.AuditServiceURL, client)
+	}
+	return NewLogAuditRecorder()
+}
+
+// logAuditRecorder writes audit events to the service log
+type logAuditRecorder struct{}
+
+// NewLogAuditRecorder creates a recorder that only logs audit events
+func NewLogAuditRecorder() AuditRecorder {
+	return &logAuditRecorder{}
+}
+
+// Record logs the event
+func (r *logAuditRecorder) Record(ctx context.Context, event AuditEvent) error {
+	logEvent := log.Info().
+		Str("audit_action", event.Action).
+		Str("resource", event.Resource).
+		Str("user_id", event.UserID).
+		Bool("success", event.Success)
+	for key, value := range event.Metadata {
+		logEvent = logEvent.Str(key, value)
+	}
+	logEvent.Msg(event.Description)
+
+	return nil
+}
+
+// auditServiceRecorder sends audit events to the audit service
+type auditServiceRecorder struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewAuditServiceRecorder creates a recorder that posts events to the audit service's
+// POST /api/v1/audit/events/log endpoint
+func NewAuditServiceRecorder(baseURL string, client *http.Client) AuditRecorder {
+	return &auditServiceRecorder{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// logEventRequest is the request body for POST /api/v1/audit/events/log
+type logEventRequest struct {
+	UserID      string            `json:"user_id"`
+	Action      string            `json:"action"`
+	Resource    string            `json:"resource"`
+	IPAddress   string            `json:"ip_address"`
+	UserAgent   string            `json:"user_agent"`
+	Success     bool              `json:"success"`
+	Description string            `json:"description"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// Record sends the event to the audit service
+func (r *auditServiceRecorder) Record(ctx context.Context, event AuditEvent) error {
+	body, err := json.Marshal(logEventRequest(event))
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+"/api/v1/audit/events/log", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("audit request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("audit service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -4,13 +4,25 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 
 	"github.com/cryptofortress/backend/encryption/internal/config"
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
+var (
+	// ErrUnsupportedHash is returned for OAEP hashes other than SHA-256, SHA-384 and SHA-512
+	ErrUnsupportedHash = errors.New("unsupported hash")
+	// ErrRSAMessageTooLong is returned when a plaintext does not fit in one RSA-OAEP block
+	ErrRSAMessageTooLong = errors.New("plaintext too long for RSA-OAEP")
+)
+
 // encryptionServiceImpl implements the EncryptionService interface
 type encryptionServiceImpl struct {
 	config *config.Config
@@ -24,7 +36,7 @@ func NewEncryptionService(cfg *config.Config) EncryptionService {
 }
 
 // EncryptAES256GCM encrypts data using AES-256-GCM
-func (s *encryptionServiceImpl) EncryptAES256GCM(plaintext, key, nonce []byte) ([]byte, error) {
+func (s *encryptionServiceImpl) EncryptAES256GCM(plaintext, key, nonce, additionalData []byte) ([]byte, error) {
 	// Create cipher
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -38,12 +50,12 @@ func (s *encryptionServiceImpl) EncryptAES256GCM(plaintext, key, nonce []byte) (
 	}
 
 	// Encrypt
-	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
+	ciphertext := gcm.Seal(nil, nonce, plaintext, additionalData)
 	return ciphertext, nil
 }
 
 // DecryptAES256GCM decrypts data using AES-256-GCM
-func (s *encryptionServiceImpl) DecryptAES256GCM(ciphertext, key, nonce []byte) ([]byte, error) {
+func (s *encryptionServiceImpl) DecryptAES256GCM(ciphertext, key, nonce, additionalData []byte) ([]byte, error) {
 	// Create cipher
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -57,7 +69,7 @@ func (s *encryptionServiceImpl) DecryptAES256GCM(ciphertext, key, nonce []byte)
 	}
 
 	// Decrypt
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, err
 	}
@@ -66,7 +78,7 @@ func (s *encryptionServiceImpl) DecryptAES256GCM(ciphertext, key, nonce []byte)
 }
 
 // EncryptChaCha20Poly1305 encrypts data using ChaCha20-Poly1305
-func (s *encryptionServiceImpl) EncryptChaCha20Poly1305(plaintext, key, nonce []byte) ([]byte, error) {
+func (s *encryptionServiceImpl) EncryptChaCha20Poly1305(plaintext, key, nonce, additionalData []byte) ([]byte, error) {
 	// Create cipher
 	aead, err := chacha20poly1305.New(key)
 	if err != nil {
@@ -74,12 +86,12 @@ func (s *encryptionServiceImpl) EncryptChaCha20Poly1305(plaintext, key, nonce []
 	}
 
 	// Encrypt
-	ciphertext := aead.Seal(nil, nonce, plaintext, nil)
+	ciphertext := aead.Seal(nil, nonce, plaintext, additionalData)
 	return ciphertext, nil
 }
 
 // DecryptChaCha20Poly1305 decrypts data using ChaCha20-Poly1305
-func (s *encryptionServiceImpl) DecryptChaCha20Poly1305(ciphertext, key, nonce []byte) ([]byte, error) {
+func (s *encryptionServiceImpl) DecryptChaCha20Poly1305(ciphertext, key, nonce, additionalData []byte) ([]byte, error) {
 	// Create cipher
 	aead, err := chacha20poly1305.New(key)
 	if err != nil {
@@ -87,7 +99,7 @@ func (s *encryptionServiceImpl) DecryptChaCha20Poly1305(ciphertext, key, nonce [
 	}
 
 	// Decrypt
-	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, err
 	}
@@ -113,31 +125,215 @@ func (s *encryptionServiceImpl) GenerateNonce(size int) ([]byte, error) {
 	return nonce, nil
 }
 
-// Placeholder implementations for other methods
-func (s *encryptionServiceImpl) EncryptRSAOAEP(plaintext, publicKey []byte) ([]byte, error) {
-	return nil, fmt.Errorf("RSA-OAEP not implemented")
+// EncryptRSAOAEP encrypts a short message, such as a symmetric key, with an RSA public
+// key using OAEP. The message must fit in one RSA block.
+func (s *encryptionServiceImpl) EncryptRSAOAEP(plaintext, publicKey []byte, hash string) ([]byte, error) {
+	_, pub, err := parseRSAKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	h, err := oaepHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// OAEP padding takes two hash outputs and two bytes of each block
+	if limit := pub.Size() - 2*h.Size() - 2; len(plaintext) > limit {
+		return nil, fmt.Errorf("%w: a %d-bit key with %s fits at most %d bytes, but the plaintext is %d; "+
+			"use hybrid encryption for larger payloads, encrypting the data with a symmetric key and only that key with RSA",
+			ErrRSAMessageTooLong, pub.N.BitLen(), oaepHashName(hash), limit, len(plaintext))
+	}
+
+	return rsa.EncryptOAEP(h, rand.Reader, pub, plaintext, nil)
+}
+
+// DecryptRSAOAEP decrypts an RSA-OAEP ciphertext with an RSA private key
+func (s *encryptionServiceImpl) DecryptRSAOAEP(ciphertext, privateKey []byte, hash string) ([]byte, error) {
+	priv, _, err := parseRSAKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if priv == nil {
+		return nil, fmt.Errorf("%w: decryption needs a private key", ErrInvalidRSAKey)
+	}
+	h, err := oaepHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := rsa.DecryptOAEP(h, rand.Reader, priv, ciphertext, nil)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+// GenerateRSAKeyPair generates an RSA key pair of the configured size, returning the
+// private key as PKCS#8 PEM and the public key as SPKI PEM
+func (s *encryptionServiceImpl) GenerateRSAKeyPair() (privateKey, publicKey []byte, err error) {
+	priv, err := rsa.GenerateKey(rand.Reader, s.config.RSAKeySize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	privateKey, err = encodeRSAKey(priv, &priv.PublicKey, RSAKeyFormatPKCS8, KeyEncodingPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err = encodeRSAKey(nil, &priv.PublicKey, RSAKeyFormatSPKI, KeyEncodingPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	return privateKey, publicKey, nil
+}
+
+// ConvertRSAKey re-encodes an RSA key given as PEM, DER or JWK in another format
+func (s *encryptionServiceImpl) ConvertRSAKey(key []byte, format, encoding string) ([]byte, error) {
+	priv, pub, err := parseRSAKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return encodeRSAKey(priv, pub, format, encoding)
+}
+
+// oaepHash returns the hash for an OAEP hash name, SHA-256 by default. MGF1 uses the
+// same hash.
+func oaepHash(name string) (hash.Hash, error) {
+	switch oaepHashName(name) {
+	case "SHA-256":
+		return sha256.New(), nil
+	case "SHA-384":
+		return sha512.New384(), nil
+	case "SHA-512":
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedHash, name)
 }
 
-func (s *encryptionServiceImpl) DecryptRSAOAEP(ciphertext, privateKey []byte) ([]byte, error) {
-	return nil, fmt.Errorf("RSA-OAEP not implemented")
+// oaepHashName applies the default OAEP hash
+func oaepHashName(name string) string {
+	if name == "" {
+		return "SHA-256"
+	}
+	return name
 }
 
+// EncryptKyber encrypts a message to a hybrid X25519-ML-KEM public key. The data key is
+// the KEM's shared key, and the result is an envelope whose wrapped-key field holds the
+// KEM ciphertext and whose key ID identifies the public key.
 func (s *encryptionServiceImpl) EncryptKyber(plaintext, publicKey []byte) ([]byte, error) {
-	return nil, fmt.Errorf("Kyber encryption not implemented")
+	pub, err := parseKEMPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !pub.algorithm.hybrid {
+		return nil, fmt.Errorf("%w: encryption needs an X25519-ML-KEM public key; %s keys only establish shared keys",
+			ErrInvalidKEMKey, pub.algorithm.name)
+	}
+
+	dataKey, kemCiphertext, err := pub.encapsulate()
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dataKey)
+
+	nonce, err := s.GenerateNonce(envelopeNonceSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	envelope := &Envelope{
+		KeyID:      pub.keyID(),
+		Algorithm:  pub.algorithm.name,
+		WrappedKey: kemCiphertext,
+		Nonce:      nonce,
+	}
+	header, err := envelope.header()
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := s.EncryptAES256GCM(plaintext, dataKey, nonce, header)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, ciphertext...), nil
 }
 
+// DecryptKyber decrypts an envelope produced by EncryptKyber with the matching private key
 func (s *encryptionServiceImpl) DecryptKyber(ciphertext, privateKey []byte) ([]byte, error) {
-	return nil, fmt.Errorf("Kyber decryption not implemented")
+	envelope, header, err := ParseEnvelope(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := kemAlgorithmIDs[envelope.Algorithm]; !ok {
+		return nil, fmt.Errorf("%w: not a KEM envelope", ErrInvalidEnvelope)
+	}
+	if len(envelope.Nonce) != envelopeNonceSize {
+		return nil, fmt.Errorf("%w: invalid nonce size", ErrInvalidEnvelope)
+	}
+
+	priv, err := parseKEMPrivateKey(kemAlgorithmByName(envelope.Algorithm), privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if priv.publicKey().keyID() != envelope.KeyID {
+		return nil, fmt.Errorf("%w: the envelope was encrypted to another key", ErrInvalidKEMKey)
+	}
+
+	dataKey, err := priv.decapsulate(envelope.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dataKey)
+
+	plaintext, err := s.DecryptAES256GCM(envelope.Ciphertext, dataKey, envelope.Nonce, header)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
 }
 
-func (s *encryptionServiceImpl) GenerateRSAKeyPair() (privateKey, publicKey []byte, err error) {
-	return nil, nil, fmt.Errorf("RSA key generation not implemented")
+// EncapsulateKyber returns a fresh shared key and its ciphertext for a public key of any
+// KEM algorithm
+func (s *encryptionServiceImpl) EncapsulateKyber(publicKey []byte) (sharedKey, ciphertext []byte, err error) {
+	pub, err := parseKEMPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub.encapsulate()
 }
 
-func (s *encryptionServiceImpl) GenerateKyberKeyPair() (privateKey, publicKey []byte, err error) {
-	return nil, nil, fmt.Errorf("Kyber key generation not implemented")
+// DecapsulateKyber recovers the shared key from a ciphertext made by EncapsulateKyber
+func (s *encryptionServiceImpl) DecapsulateKyber(ciphertext, privateKey []byte) ([]byte, error) {
+	algorithm := kemAlgorithmByCiphertext(ciphertext)
+	if algorithm == nil {
+		return nil, fmt.Errorf("%w: a %d-byte ciphertext matches no KEM algorithm", ErrInvalidKEMCiphertext, len(ciphertext))
+	}
+	priv, err := parseKEMPrivateKey(algorithm, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return priv.decapsulate(ciphertext)
+}
+
+// GenerateKyberKeyPair generates a KEM key pair, X25519-ML-KEM-768 by default
+func (s *encryptionServiceImpl) GenerateKyberKeyPair(algorithm string) (privateKey, publicKey []byte, err error) {
+	if algorithm == "" {
+		algorithm = defaultKEMAlgorithm
+	}
+	kem := kemAlgorithmByName(algorithm)
+	if kem == nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	priv, err := generateKEMKey(kem)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv.Bytes(), priv.publicKey().Bytes(), nil
 }
 
+// Placeholder implementations for other methods
 func (s *encryptionServiceImpl) EncryptWithHSM(plaintext []byte, keyID string) ([]byte, error) {
 	return nil, fmt.Errorf("HSM encryption not implemented")
 }
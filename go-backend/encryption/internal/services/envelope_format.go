@@ -0,0 +1,244 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Envelope wire format, version 1. Integers are big-endian.
+//
+//	magic        4 bytes  "CFEN"
+//	version      1 byte   1
+//	algorithm    1 byte   see the algorithm IDs below
+//	key ID       2-byte length, then UTF-8 bytes
+//	key version  4 bytes
+//	nonce        1-byte length, then bytes
+//	wrapped DEK  2-byte length, then bytes
+//	AAD hash     1-byte length, then bytes (0 or 32, SHA-256)
+//	ciphertext   the remaining bytes, including the AEAD tag
+//
+// Everything before the ciphertext is the header. The header is authenticated as the
+// AEAD's associated data, so it cannot be changed without decryption failing.
+//
+// Streaming algorithms split the ciphertext into STREAM segments of
+// streamSegmentSize plaintext bytes each (see pkg/stream). Their nonce field holds the
+// stream's nonce prefix.
+//
+// KEM algorithms encrypt to a public key instead of a key-encryption key. Their key ID
+// identifies the public key, their wrapped DEK field holds the KEM ciphertext, and the
+// data key is the KEM's shared key. They encrypt with AES-256-GCM.
+const (
+	envelopeMagic     = "CFEN"
+	envelopeVersion   = 1
+	envelopeFixedSize = len(envelopeMagic) + 2
+)
+
+// Algorithm IDs used in the envelope header. IDs are never reused.
+const (
+	algorithmIDAES256GCM              byte = 1
+	algorithmIDChaCha20Poly1305       byte = 2
+	algorithmIDAES256GCMStream        byte = 3
+	algorithmIDChaCha20Poly1305Stream byte = 4
+	algorithmIDX25519MLKEM768         byte = 5
+	algorithmIDX25519MLKEM1024        byte = 6
+)
+
+// streamSegmentSize is the segment size of the streaming algorithms. It is part of
+// their definition, so it cannot change without new algorithm IDs.
+const streamSegmentSize = 64 * 1024
+
+// ErrInvalidEnvelope is returned when a ciphertext is not a well-formed envelope
+var ErrInvalidEnvelope = errors.New("invalid ciphertext envelope")
+
+// envelopeAlgorithmIDs maps algorithm names to their envelope IDs
+var envelopeAlgorithmIDs = map[string]byte{
+	"AES-256-GCM":       algorithmIDAES256GCM,
+	"ChaCha20-Poly1305": algorithmIDChaCha20Poly1305,
+}
+
+// streamAlgorithmIDs maps algorithm names to the envelope IDs of their streaming forms
+var streamAlgorithmIDs = map[string]byte{
+	"AES-256-GCM":       algorithmIDAES256GCMStream,
+	"ChaCha20-Poly1305": algorithmIDChaCha20Poly1305Stream,
+}
+
+// kemAlgorithmIDs maps KEM algorithm names to their envelope IDs
+var kemAlgorithmIDs = map[string]byte{
+	KEMAlgorithmX25519MLKEM768:  algorithmIDX25519MLKEM768,
+	KEMAlgorithmX25519MLKEM1024: algorithmIDX25519MLKEM1024,
+}
+
+// MarshalBinary encodes the envelope in the wire format
+func (e *Envelope) MarshalBinary() ([]byte, error) {
+	header, err := e.header()
+	if err != nil {
+		return nil, err
+	}
+	return append(header, e.Ciphertext...), nil
+}
+
+// header encodes everything before the ciphertext
+func (e *Envelope) header() ([]byte, error) {
+	algorithmIDs := envelopeAlgorithmIDs
+	switch {
+	case e.Streaming:
+		algorithmIDs = streamAlgorithmIDs
+	case kemAlgorithmIDs[e.Algorithm] != 0:
+		algorithmIDs = kemAlgorithmIDs
+	}
+	algorithmID, ok := algorithmIDs[e.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, e.Algorithm)
+	}
+	switch {
+	case len(e.KeyID) > math.MaxUint16:
+		return nil, errors.New("key ID too long for envelope")
+	case e.KeyVersion < 0 || int64(e.KeyVersion) > math.MaxUint32:
+		return nil, errors.New("key version out of range for envelope")
+	case len(e.Nonce) > math.MaxUint8:
+		return nil, errors.New("nonce too long for envelope")
+	case len(e.WrappedKey) > math.MaxUint16:
+		return nil, errors.New("wrapped key too long for envelope")
+	case len(e.AADHash) > math.MaxUint8:
+		return nil, errors.New("AAD hash too long for envelope")
+	}
+
+	header := make([]byte, 0, envelopeFixedSize+2+len(e.KeyID)+4+1+len(e.Nonce)+2+len(e.WrappedKey)+1+len(e.AADHash))
+	header = append(header, envelopeMagic...)
+	header = append(header, envelopeVersion, algorithmID)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(e.KeyID)))
+	header = append(header, e.KeyID...)
+	header = binary.BigEndian.AppendUint32(header, uint32(e.KeyVersion))
+	header = append(header, byte(len(e.Nonce)))
+	header = append(header, e.Nonce...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(e.WrappedKey)))
+	header = append(header, e.WrappedKey...)
+	header = append(header, byte(len(e.AADHash)))
+	header = append(header, e.AADHash...)
+	return header, nil
+}
+
+// ParseEnvelope decodes an envelope from the wire format. It also returns the raw
+// header, which decryption authenticates.
+func ParseEnvelope(data []byte) (*Envelope, []byte, error) {
+	envelope, header, err := ReadEnvelopeHeader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	envelope.Ciphertext = data[len(header):]
+	return envelope, header, nil
+}
+
+// ReadEnvelopeHeader reads an envelope header from r, leaving r at the start of the
+// ciphertext. It returns the envelope without its ciphertext, and the raw header.
+func ReadEnvelopeHeader(r io.Reader) (*Envelope, []byte, error) {
+	er := envelopeReader{r: r}
+	fixed := er.next(envelopeFixedSize)
+	if er.err != nil {
+		return nil, nil, er.err
+	}
+	if fixed == nil || string(fixed[:len(envelopeMagic)]) != envelopeMagic {
+		return nil, nil, fmt.Errorf("%w: missing magic", ErrInvalidEnvelope)
+	}
+	if version := fixed[len(envelopeMagic)]; version != envelopeVersion {
+		return nil, nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidEnvelope, version)
+	}
+
+	envelope := &Envelope{}
+	algorithmID := fixed[len(envelopeMagic)+1]
+	for name, id := range envelopeAlgorithmIDs {
+		if id == algorithmID {
+			envelope.Algorithm = name
+		}
+	}
+	for name, id := range streamAlgorithmIDs {
+		if id == algorithmID {
+			envelope.Algorithm = name
+			envelope.Streaming = true
+		}
+	}
+	for name, id := range kemAlgorithmIDs {
+		if id == algorithmID {
+			envelope.Algorithm = name
+		}
+	}
+	if envelope.Algorithm == "" {
+		return nil, nil, fmt.Errorf("%w: unknown algorithm %d", ErrInvalidEnvelope, algorithmID)
+	}
+
+	envelope.KeyID = string(er.next(er.uint16()))
+	envelope.KeyVersion = int(er.uint32())
+	envelope.Nonce = er.next(int(er.uint8()))
+	envelope.WrappedKey = er.next(er.uint16())
+	envelope.AADHash = er.next(int(er.uint8()))
+	if er.err != nil {
+		return nil, nil, er.err
+	}
+	if er.short {
+		return nil, nil, fmt.Errorf("%w: truncated header", ErrInvalidEnvelope)
+	}
+	return envelope, er.header, nil
+}
+
+// EncodeEnvelope encodes an envelope as base64 of its wire format
+func EncodeEnvelope(e *Envelope) (string, error) {
+	data, err := e.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// envelopeReader reads length-prefixed fields, keeping the raw bytes it has read. It
+// remembers whether it ran out of data or failed to read.
+type envelopeReader struct {
+	r      io.Reader
+	header []byte
+	short  bool
+	err    error
+}
+
+// next returns a copy of the next n bytes, or nil if they cannot be read
+func (r *envelopeReader) next(n int) []byte {
+	if r.short || r.err != nil {
+		return nil
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r.r, b); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			r.short = true
+		} else {
+			r.err = err
+		}
+		return nil
+	}
+	r.header = append(r.header, b...)
+	return b
+}
+
+func (r *envelopeReader) uint8() byte {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *envelopeReader) uint16() int {
+	if b := r.next(2); b != nil {
+		return int(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (r *envelopeReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
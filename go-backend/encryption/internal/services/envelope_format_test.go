@@ -0,0 +1,170 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// testEnvelope returns an envelope with every header field set
+func testEnvelope() *Envelope {
+	return &Envelope{
+		KeyID:      "kek-payments",
+		KeyVersion: 3,
+		Algorithm:  "AES-256-GCM",
+		WrappedKey: sequence(0x40, 40),
+		Nonce:      sequence(0x10, 12),
+		AADHash:    sequence(0x80, 32),
+		Ciphertext: []byte("ciphertext and tag"),
+	}
+}
+
+// TestEnvelopeFormat tests the envelope wire format and the parser's rejection of
+// malformed and truncated headers
+func TestEnvelopeFormat(t *testing.T) {
+	envelope := testEnvelope()
+	data, err := envelope.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal envelope: %v", err)
+	}
+	headerSize := len(data) - len(envelope.Ciphertext)
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		cases := []*Envelope{envelope}
+
+		streaming := testEnvelope()
+		streaming.Streaming = true
+		streaming.Algorithm = "ChaCha20-Poly1305"
+		streaming.Nonce = sequence(0x10, 7)
+		cases = append(cases, streaming)
+
+		kem := testEnvelope()
+		kem.Algorithm = KEMAlgorithmX25519MLKEM768
+		kem.AADHash = nil
+		cases = append(cases, kem)
+
+		for _, want := range cases {
+			encoded, err := want.MarshalBinary()
+			if err != nil {
+				t.Fatalf("%s: failed to marshal: %v", want.Algorithm, err)
+			}
+			got, header, err := ParseEnvelope(encoded)
+			if err != nil {
+				t.Fatalf("%s: failed to parse: %v", want.Algorithm, err)
+			}
+			if !bytes.Equal(header, encoded[:len(encoded)-len(want.Ciphertext)]) {
+				t.Errorf("%s: raw header does not match the encoded header", want.Algorithm)
+			}
+
+			// Empty fields parse as empty slices
+			if len(want.AADHash) == 0 && len(got.AADHash) == 0 {
+				got.AADHash = want.AADHash
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: round trip mismatch:\n got %+v\nwant %+v", want.Algorithm, got, want)
+			}
+		}
+	})
+
+	t.Run("ReadHeader", func(t *testing.T) {
+		r := bytes.NewReader(data)
+		got, header, err := ReadEnvelopeHeader(iotest.OneByteReader(r))
+		if err != nil {
+			t.Fatalf("Failed to read header: %v", err)
+		}
+		if len(header) != headerSize {
+			t.Errorf("Expected a %d-byte header, got %d", headerSize, len(header))
+		}
+		if got.Ciphertext != nil {
+			t.Error("Expected the header alone to have no ciphertext")
+		}
+
+		// The reader is left at the start of the ciphertext
+		rest, _ := io.ReadAll(r)
+		if !bytes.Equal(rest, envelope.Ciphertext) {
+			t.Errorf("Expected the reader at the ciphertext, %q remains", rest)
+		}
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		modify := func(change func([]byte)) []byte {
+			b := bytes.Clone(data)
+			change(b)
+			return b
+		}
+		cases := map[string][]byte{
+			"empty":             nil,
+			"wrong magic":       modify(func(b []byte) { b[0] = 'X' }),
+			"base64 input":      []byte("Q0ZFTgEBAAxrZWstcGF5bWVudHM="),
+			"version 0":         modify(func(b []byte) { b[4] = 0 }),
+			"version 2":         modify(func(b []byte) { b[4] = 2 }),
+			"algorithm 0":       modify(func(b []byte) { b[5] = 0 }),
+			"unknown algorithm": modify(func(b []byte) { b[5] = 0xff }),
+			"oversized key ID":  modify(func(b []byte) { b[6], b[7] = 0xff, 0xff }),
+			"plaintext":         []byte(strings.Repeat("not an envelope ", 4)),
+		}
+
+		for name, input := range cases {
+			if _, _, err := ParseEnvelope(input); !errors.Is(err, ErrInvalidEnvelope) {
+				t.Errorf("%s: expected ErrInvalidEnvelope from ParseEnvelope, got %v", name, err)
+			}
+			if _, _, err := ReadEnvelopeHeader(bytes.NewReader(input)); !errors.Is(err, ErrInvalidEnvelope) {
+				t.Errorf("%s: expected ErrInvalidEnvelope from ReadEnvelopeHeader, got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		// Every cut inside the header is detected, whichever field it falls in
+		for n := 0; n < headerSize; n++ {
+			if _, _, err := ParseEnvelope(data[:n]); !errors.Is(err, ErrInvalidEnvelope) {
+				t.Errorf("Header cut at %d of %d bytes: expected ErrInvalidEnvelope from ParseEnvelope, got %v", n, headerSize, err)
+			}
+			if _, _, err := ReadEnvelopeHeader(iotest.HalfReader(bytes.NewReader(data[:n]))); !errors.Is(err, ErrInvalidEnvelope) {
+				t.Errorf("Header cut at %d of %d bytes: expected ErrInvalidEnvelope from ReadEnvelopeHeader, got %v", n, headerSize, err)
+			}
+		}
+
+		// A complete header with no ciphertext is a valid, empty envelope
+		got, _, err := ParseEnvelope(data[:headerSize])
+		if err != nil {
+			t.Fatalf("Failed to parse a header without ciphertext: %v", err)
+		}
+		if len(got.Ciphertext) != 0 {
+			t.Errorf("Expected no ciphertext, got %d bytes", len(got.Ciphertext))
+		}
+	})
+
+	t.Run("ReadError", func(t *testing.T) {
+		// Errors other than running out of data are returned as they are
+		failure := errors.New("connection reset")
+		r := io.MultiReader(bytes.NewReader(data[:10]), iotest.ErrReader(failure))
+		if _, _, err := ReadEnvelopeHeader(r); !errors.Is(err, failure) || errors.Is(err, ErrInvalidEnvelope) {
+			t.Errorf("Expected the read error, got %v", err)
+		}
+	})
+
+	t.Run("MarshalLimits", func(t *testing.T) {
+		cases := map[string]func(*Envelope){
+			"unknown algorithm":      func(e *Envelope) { e.Algorithm = "DES" },
+			"KEM streaming":          func(e *Envelope) { e.Algorithm = KEMAlgorithmX25519MLKEM768; e.Streaming = true },
+			"key ID too long":        func(e *Envelope) { e.KeyID = strings.Repeat("k", 1<<16) },
+			"negative key version":   func(e *Envelope) { e.KeyVersion = -1 },
+			"nonce too long":         func(e *Envelope) { e.Nonce = make([]byte, 256) },
+			"wrapped key too long":   func(e *Envelope) { e.WrappedKey = make([]byte, 1<<16) },
+			"associated data digest": func(e *Envelope) { e.AADHash = make([]byte, 256) },
+		}
+
+		for name, change := range cases {
+			e := testEnvelope()
+			change(e)
+			if _, err := e.MarshalBinary(); err == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cryptofortress/backend/encryption/internal/config"
+	"github.com/cryptofortress/backend/pkg/stream"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+var (
+	// ErrUnsupportedAlgorithm is returned for algorithms envelope encryption does not support
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	// ErrDecryptionFailed is returned when a ciphertext does not authenticate under its data key
+	ErrDecryptionFailed = errors.New("decryption failed")
+)
+
+// envelopeNonceSize is the nonce size of the supported AEADs
+const envelopeNonceSize = 12
+
+// envelopeServiceImpl implements the EnvelopeService interface
+type envelopeServiceImpl struct {
+	config     *config.Config
+	encryption EncryptionService
+	wrapper    KeyWrapper
+}
+
+// NewEnvelopeService creates a new instance of the envelope encryption service
+func NewEnvelopeService(cfg *config.Config, encryption EncryptionService, wrapper KeyWrapper) EnvelopeService {
+	return &envelopeServiceImpl{
+		config:     cfg,
+		encryption: encryption,
+		wrapper:    wrapper,
+	}
+}
+
+// envelopeKeys holds what encrypting or decrypting an envelope needs besides the
+// envelope itself
+type envelopeKeys struct {
+	dataKey        []byte
+	header         []byte
+	additionalData []byte // the header, then the canonical associated data
+}
+
+// clear erases the data key
+func (k *envelopeKeys) clear() {
+	clear(k.dataKey)
+}
+
+// Encrypt encrypts plaintext under a fresh data key and wraps the data key with the
+// key-encryption key keyID. An empty algorithm selects the configured default.
+func (s *envelopeServiceImpl) Encrypt(ctx context.Context, keyID, algorithm string, plaintext []byte, ad AssociatedData) (*Envelope, error) {
+	envelope, keys, err := s.newEnvelope(ctx, keyID, algorithm, false, ad)
+	if err != nil {
+		return nil, err
+	}
+	defer keys.clear()
+
+	// The header and the caller's associated data are authenticated together, so neither
+	// can be altered without detection
+	switch envelope.Algorithm {
+	case "AES-256-GCM":
+		envelope.Ciphertext, err = s.encryption.EncryptAES256GCM(plaintext, keys.dataKey, envelope.Nonce, keys.additionalData)
+	case "ChaCha20-Poly1305":
+		envelope.Ciphertext, err = s.encryption.EncryptChaCha20Poly1305(plaintext, keys.dataKey, envelope.Nonce, keys.additionalData)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return envelope, nil
+}
+
+// Decrypt parses an envelope, unwraps its data key and decrypts its ciphertext.
+// Streaming envelopes are accepted too.
+func (s *envelopeServiceImpl) Decrypt(ctx context.Context, ciphertext []byte, ad AssociatedData) ([]byte, *Envelope, error) {
+	envelope, header, err := ParseEnvelope(ciphertext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys, err := s.openEnvelope(ctx, envelope, header, ad)
+	if err != nil {
+		return nil, envelope, err
+	}
+	defer keys.clear()
+
+	var plaintext []byte
+	switch {
+	case envelope.Streaming:
+		var reader io.Reader
+		reader, err = newEnvelopeStreamReader(envelope, keys, bytes.NewReader(envelope.Ciphertext))
+		if err == nil {
+			plaintext, err = io.ReadAll(reader)
+		}
+	case envelope.Algorithm == "AES-256-GCM":
+		plaintext, err = s.encryption.DecryptAES256GCM(envelope.Ciphertext, keys.dataKey, envelope.Nonce, keys.additionalData)
+	case envelope.Algorithm == "ChaCha20-Poly1305":
+		plaintext, err = s.encryption.DecryptChaCha20Poly1305(envelope.Ciphertext, keys.dataKey, envelope.Nonce, keys.additionalData)
+	}
+	if err != nil {
+		return nil, envelope, ErrDecryptionFailed
+	}
+	return plaintext, envelope, nil
+}
+
+// EncryptStream writes the envelope header to dst, then encrypts src in segments
+func (s *envelopeServiceImpl) EncryptStream(ctx context.Context, keyID, algorithm string, dst io.Writer, src io.Reader, ad AssociatedData) (*Envelope, error) {
+	envelope, keys, err := s.newEnvelope(ctx, keyID, algorithm, true, ad)
+	if err != nil {
+		return nil, err
+	}
+	defer keys.clear()
+
+	aead, err := newEnvelopeAEAD(envelope.Algorithm, keys.dataKey)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := stream.NewWriter(dst, aead, envelope.Nonce, keys.additionalData, streamSegmentSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dst.Write(keys.header); err != nil {
+		return envelope, err
+	}
+	if _, err := io.Copy(writer, src); err != nil {
+		return envelope, err
+	}
+	return envelope, writer.Close()
+}
+
+// DecryptStream reads a streaming envelope's header from src, then decrypts the
+// segments that follow it to dst
+func (s *envelopeServiceImpl) DecryptStream(ctx context.Context, dst io.Writer, src io.Reader, ad AssociatedData) (*Envelope, error) {
+	envelope, header, err := ReadEnvelopeHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	if !envelope.Streaming {
+		return envelope, fmt.Errorf("%w: not a streaming envelope", ErrInvalidEnvelope)
+	}
+
+	keys, err := s.openEnvelope(ctx, envelope, header, ad)
+	if err != nil {
+		return envelope, err
+	}
+	defer keys.clear()
+
+	reader, err := newEnvelopeStreamReader(envelope, keys, src)
+	if err != nil {
+		return envelope, ErrDecryptionFailed
+	}
+	if _, err := io.Copy(dst, reader); err != nil {
+		if errors.Is(err, stream.ErrAuthentication) {
+			return envelope, ErrDecryptionFailed
+		}
+		return envelope, err
+	}
+	return envelope, nil
+}
+
+// newEnvelope generates and wraps a data key, and builds the envelope header. The
+// caller must clear the keys.
+func (s *envelopeServiceImpl) newEnvelope(ctx context.Context, keyID, algorithm string, streaming bool, ad AssociatedData) (*Envelope, *envelopeKeys, error) {
+	if algorithm == "" {
+		algorithm = s.config.DefaultAlgorithm
+	}
+	if _, ok := envelopeAlgorithmIDs[algorithm]; !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+	}
+
+	aad, err := ad.canonical()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce, err := s.encryption.GenerateNonce(envelopeNonceLength(streaming))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	dataKey, err := s.encryption.GenerateAESKey()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	keys := &envelopeKeys{dataKey: dataKey}
+
+	wrapped, err := s.wrapper.WrapKey(ctx, keyID, dataKey)
+	if err != nil {
+		keys.clear()
+		return nil, nil, err
+	}
+
+	envelope := &Envelope{
+		KeyID:      wrapped.KeyID,
+		KeyVersion: wrapped.KeyVersion,
+		Algorithm:  algorithm,
+		Streaming:  streaming,
+		WrappedKey: wrapped.WrappedKey,
+		Nonce:      nonce,
+	}
+	if aad != nil {
+		hash := sha256.Sum256(aad)
+		envelope.AADHash = hash[:]
+	}
+	keys.header, err = envelope.header()
+	if err != nil {
+		keys.clear()
+		return nil, nil, err
+	}
+	keys.additionalData = append(keys.header[:len(keys.header):len(keys.header)], aad...)
+	return envelope, keys, nil
+}
+
+// openEnvelope checks a parsed envelope against the caller's associated data and unwraps
+// its data key. The caller must clear the keys.
+func (s *envelopeServiceImpl) openEnvelope(ctx context.Context, envelope *Envelope, header []byte, ad AssociatedData) (*envelopeKeys, error) {
+	if _, ok := kemAlgorithmIDs[envelope.Algorithm]; ok {
+		return nil, fmt.Errorf("%w: %s envelopes are decrypted with the recipient's private key", ErrUnsupportedAlgorithm, envelope.Algorithm)
+	}
+	if len(envelope.Nonce) != envelopeNonceLength(envelope.Streaming) {
+		return nil, fmt.Errorf("%w: invalid nonce size", ErrInvalidEnvelope)
+	}
+
+	// A mismatch is caught by the AEAD as well; checking the hash first avoids asking the
+	// key management service to unwrap a data key that cannot be used
+	aad, err := ad.canonical()
+	if err != nil {
+		return nil, err
+	}
+	var aadHash []byte
+	if aad != nil {
+		hash := sha256.Sum256(aad)
+		aadHash = hash[:]
+	}
+	if subtle.ConstantTimeCompare(aadHash, envelope.AADHash) != 1 {
+		return nil, ErrAssociatedDataMismatch
+	}
+
+	dataKey, err := s.wrapper.UnwrapKey(ctx, envelope.KeyID, envelope.KeyVersion, envelope.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return &envelopeKeys{
+		dataKey:        dataKey,
+		header:         header,
+		additionalData: append(header[:len(header):len(header)], aad...),
+	}, nil
+}
+
+// envelopeNonceLength returns the length of an envelope's nonce field. Streaming
+// envelopes store a nonce prefix, and the segment index fills the rest of each nonce.
+func envelopeNonceLength(streaming bool) int {
+	if streaming {
+		return envelopeNonceSize - stream.NonceOverhead
+	}
+	return envelopeNonceSize
+}
+
+// newEnvelopeStreamReader decrypts the segments of a streaming envelope read from src
+func newEnvelopeStreamReader(envelope *Envelope, keys *envelopeKeys, src io.Reader) (io.Reader, error) {
+	aead, err := newEnvelopeAEAD(envelope.Algorithm, keys.dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return stream.NewReader(src, aead, envelope.Nonce, keys.additionalData, streamSegmentSize)
+}
+
+// newEnvelopeAEAD creates the AEAD for an envelope algorithm
+func newEnvelopeAEAD(algorithm string, key []byte) (cipher.AEAD, error) {
+	switch algorithm {
+	case "AES-256-GCM":
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case "ChaCha20-Poly1305":
+		return chacha20poly1305.New(key)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algorithm)
+}
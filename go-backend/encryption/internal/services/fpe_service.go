@@ -1,69 +1,210 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
 
 	"github.com/cryptofortress/backend/encryption/internal/config"
 )
 
+var (
+	// ErrUnsupportedFPEAlgorithm is returned for FPE algorithms other than FF1 and FF3-1
+	ErrUnsupportedFPEAlgorithm = errors.New("unsupported FPE algorithm")
+	// ErrInvalidAlphabet is returned for alphabets with fewer than 2 or more than 65536
+	// characters, or with repeated characters
+	ErrInvalidAlphabet = errors.New("invalid alphabet")
+	// ErrInvalidFPEInput is returned for inputs with characters outside the alphabet or
+	// with a length the algorithm does not allow
+	ErrInvalidFPEInput = errors.New("invalid FPE input")
+	// ErrFPEDomainTooSmall is returned when an input's length and alphabet allow fewer
+	// than a million values, which FF1 and FF3-1 cannot encrypt securely
+	ErrFPEDomainTooSmall = errors.New("FPE domain too small")
+	// ErrInvalidTweak is returned for tweaks of a length the algorithm does not allow
+	ErrInvalidTweak = errors.New("invalid tweak")
+	// ErrInvalidFPEKey is returned for keys that are not AES-128, AES-192 or AES-256 keys
+	ErrInvalidFPEKey = errors.New("invalid FPE key")
+)
+
+// FPE algorithms
+const (
+	FPEAlgorithmFF1  = "FF1"
+	FPEAlgorithmFF31 = "FF3-1"
+)
+
+// fpeDigits is the default alphabet
+const fpeDigits = "0123456789"
+
+// fpeCipher encrypts and decrypts numeral strings with a tweak
+type fpeCipher interface {
+	encrypt(x []uint16, tweak []byte) []uint16
+	decrypt(x []uint16, tweak []byte) []uint16
+}
+
+// fpeAlphabet maps the characters of an alphabet to numerals, in order
+type fpeAlphabet struct {
+	characters []rune
+	numerals   map[rune]uint16
+}
+
+// parseAlphabet parses an alphabet, whose radix is its number of characters
+func parseAlphabet(alphabet string) (*fpeAlphabet, error) {
+	if alphabet == "" {
+		alphabet = fpeDigits
+	}
+	characters := []rune(alphabet)
+	if len(characters) < 2 || len(characters) > 1<<16 {
+		return nil, fmt.Errorf("%w: must have 2 to 65536 characters", ErrInvalidAlphabet)
+	}
+
+	numerals := make(map[rune]uint16, len(characters))
+	for i, character := range characters {
+		if _, ok := numerals[character]; ok {
+			return nil, fmt.Errorf("%w: %q appears more than once", ErrInvalidAlphabet, character)
+		}
+		numerals[character] = uint16(i)
+	}
+	return &fpeAlphabet{characters: characters, numerals: numerals}, nil
+}
+
+// toNumerals converts a string to numerals
+func (a *fpeAlphabet) toNumerals(s string) ([]uint16, error) {
+	x := make([]uint16, 0, len(s))
+	for _, character := range s {
+		numeral, ok := a.numerals[character]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not in the alphabet", ErrInvalidFPEInput, character)
+		}
+		x = append(x, numeral)
+	}
+	return x, nil
+}
+
+// toString converts numerals to a string
+func (a *fpeAlphabet) toString(x []uint16) string {
+	var b strings.Builder
+	for _, numeral := range x {
+		b.WriteRune(a.characters[numeral])
+	}
+	return b.String()
+}
+
 // fpeServiceImpl implements the FPEService interface
 type fpeServiceImpl struct {
-	config *config.Config
+	config    *config.Config
+	mu        sync.RWMutex
+	templates map[string]*fpeTemplate
 }
 
-// NewFPEService creates a new instance of the FPE service
+// NewFPEService creates a new instance of the FPE service with the built-in templates
 func NewFPEService(cfg *config.Config) FPEService {
+	templates := make(map[string]*fpeTemplate, len(builtInFPETemplates))
+	for _, template := range builtInFPETemplates {
+		template.BuiltIn = true
+		t, err := compileFPETemplate(template)
+		if err != nil {
+			panic(fmt.Sprintf("built-in FPE template %s: %v", template.Name, err))
+		}
+		templates[t.Name] = t
+	}
+
 	return &fpeServiceImpl{
-		config: cfg,
+		config:    cfg,
+		templates: templates,
 	}
 }
 
-// EncryptFPE encrypts data using format-preserving encryption
-func (s *fpeServiceImpl) EncryptFPE(plaintext string, key []byte, tweak []byte) (string, error) {
-	// In a real implementation, you would use an FPE algorithm like FF1 or FF3
-	// This is a placeholder implementation
-	return "", fmt.Errorf("FPE encryption not implemented")
+// EncryptFPE encrypts a string with FF1 or FF3-1, keeping its length and alphabet
+func (s *fpeServiceImpl) EncryptFPE(plaintext string, key []byte, tweak []byte, params FPEParams) (string, error) {
+	c, alphabet, x, err := s.prepare(plaintext, key, tweak, params)
+	if err != nil {
+		return "", err
+	}
+	return alphabet.toString(c.encrypt(x, tweak)), nil
 }
 
-// DecryptFPE decrypts data using format-preserving encryption
-func (s *fpeServiceImpl) DecryptFPE(ciphertext string, key []byte, tweak []byte) (string, error) {
-	// In a real implementation, you would use an FPE algorithm like FF1 or FF3
-	// This is a placeholder implementation
-	return "", fmt.Errorf("FPE decryption not implemented")
+// DecryptFPE decrypts a string encrypted with EncryptFPE
+func (s *fpeServiceImpl) DecryptFPE(ciphertext string, key []byte, tweak []byte, params FPEParams) (string, error) {
+	c, alphabet, x, err := s.prepare(ciphertext, key, tweak, params)
+	if err != nil {
+		return "", err
+	}
+	return alphabet.toString(c.decrypt(x, tweak)), nil
 }
 
-// EncryptCreditCard encrypts a credit card number
+// prepare checks an input, tweak and key against the limits of the algorithm, and
+// returns the cipher, the alphabet and the input as numerals
+func (s *fpeServiceImpl) prepare(input string, key, tweak []byte, params FPEParams) (fpeCipher, *fpeAlphabet, []uint16, error) {
+	alphabet, err := parseAlphabet(params.Alphabet)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	x, err := alphabet.toNumerals(input)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	radix := len(alphabet.characters)
+
+	// radix^n must be at least a million (SP 800-38G Rev. 1, section 5.2)
+	if pow(big.NewInt(int64(radix)), len(x)).Cmp(big.NewInt(fpeMinDomainSize)) < 0 {
+		return nil, nil, nil, fmt.Errorf("%w: %d characters of a %d-character alphabet", ErrFPEDomainTooSmall, len(x), radix)
+	}
+
+	var c fpeCipher
+	switch params.Algorithm {
+	case FPEAlgorithmFF1, "":
+		if uint64(len(x)) > math.MaxUint32 {
+			return nil, nil, nil, fmt.Errorf("%w: FF1 inputs are at most 2^32 characters", ErrInvalidFPEInput)
+		}
+		if len(tweak) > ff1MaxTweakLength {
+			return nil, nil, nil, fmt.Errorf("%w: FF1 tweaks are at most %d bytes", ErrInvalidTweak, ff1MaxTweakLength)
+		}
+		c, err = newFF1(key, radix)
+	case FPEAlgorithmFF31:
+		if maxLength := ff3MaxLength(radix); len(x) > maxLength {
+			return nil, nil, nil, fmt.Errorf("%w: FF3-1 inputs of a %d-character alphabet are at most %d characters", ErrInvalidFPEInput, radix, maxLength)
+		}
+		if len(tweak) != ff3TweakLength {
+			return nil, nil, nil, fmt.Errorf("%w: FF3-1 tweaks are %d bytes", ErrInvalidTweak, ff3TweakLength)
+		}
+		c, err = newFF3(key, radix)
+	default:
+		return nil, nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedFPEAlgorithm, params.Algorithm)
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%w: %v", ErrInvalidFPEKey, err)
+	}
+	return c, alphabet, x, nil
+}
+
+// EncryptCreditCard encrypts a card number with the credit-card template, which keeps
+// the last four digits, or the credit-card-bin template for cards shorter than 16 digits
 func (s *fpeServiceImpl) EncryptCreditCard(cardNumber string, key []byte) (string, error) {
-	// In a real implementation, you would:
-	// 1. Validate the credit card number format
-	// 2. Apply FPE to preserve the format
-	// This is a placeholder implementation
-	return "", fmt.Errorf("credit card encryption not implemented")
+	return s.EncryptTemplate(creditCardTemplate(cardNumber), cardNumber, key, nil)
 }
 
-// DecryptCreditCard decrypts a credit card number
+// DecryptCreditCard decrypts a card number encrypted with EncryptCreditCard
 func (s *fpeServiceImpl) DecryptCreditCard(encryptedCard string, key []byte) (string, error) {
-	// In a real implementation, you would:
-	// 1. Validate the encrypted format
-	// 2. Apply FPE decryption
-	// This is a placeholder implementation
-	return "", fmt.Errorf("credit card decryption not implemented")
+	return s.DecryptTemplate(creditCardTemplate(encryptedCard), encryptedCard, key, nil)
 }
 
-// EncryptSSN encrypts a social security number
+// creditCardTemplate picks the card template by the number of digits, which encryption keeps
+func creditCardTemplate(cardNumber string) string {
+	if len(digitPositions(cardNumber)) < 16 {
+		return FPETemplateCreditCardBIN
+	}
+	return FPETemplateCreditCard
+}
+
+// EncryptSSN encrypts a social security number with the ssn template
 func (s *fpeServiceImpl) EncryptSSN(ssn string, key []byte) (string, error) {
-	// In a real implementation, you would:
-	// 1. Validate the SSN format
-	// 2. Apply FPE to preserve the format
-	// This is a placeholder implementation
-	return "", fmt.Errorf("SSN encryption not implemented")
+	return s.EncryptTemplate(FPETemplateSSN, ssn, key, nil)
 }
 
-// DecryptSSN decrypts a social security number
+// DecryptSSN decrypts a social security number encrypted with EncryptSSN
 func (s *fpeServiceImpl) DecryptSSN(encryptedSSN string, key []byte) (string, error) {
-	// In a real implementation, you would:
-	// 1. Validate the encrypted format
-	// 2. Apply FPE decryption
-	// This is a placeholder implementation
-	return "", fmt.Errorf("SSN decryption not implemented")
-}
\ No newline at end of file
+	return s.DecryptTemplate(FPETemplateSSN, encryptedSSN, key, nil)
+}
@@ -0,0 +1,460 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// ErrFPETemplateNotFound is returned for template names that are not registered
+	ErrFPETemplateNotFound = errors.New("FPE template not found")
+	// ErrFPETemplateExists is returned when registering a template under a name in use
+	ErrFPETemplateExists = errors.New("FPE template already exists")
+	// ErrInvalidFPETemplate is returned for templates with an invalid name, pattern,
+	// segment or post-processing step, and for templates whose pattern does not accept
+	// their own ciphertexts
+	ErrInvalidFPETemplate = errors.New("invalid FPE template")
+)
+
+// FPE template segment actions
+const (
+	FPESegmentEncrypt  = "encrypt"
+	FPESegmentPreserve = "preserve"
+)
+
+// FPE template post-processing steps
+const (
+	// FPEPostProcessLuhn recomputes the last digit as a Luhn check digit
+	FPEPostProcessLuhn = "luhn"
+	// FPEPostProcessLuhnWalk keeps a value passing the Luhn check without changing its
+	// check digit, by encrypting again until it passes (cycle walking). It suits
+	// templates that preserve the check digit.
+	FPEPostProcessLuhnWalk = "luhn-walk"
+	// FPEPostProcessIBAN recomputes the two ISO 7064 MOD 97-10 check digits of an IBAN
+	FPEPostProcessIBAN = "iban"
+)
+
+// Built-in FPE templates
+const (
+	FPETemplateCreditCard    = "credit-card"
+	FPETemplateCreditCardBIN = "credit-card-bin"
+	FPETemplateSSN           = "ssn"
+)
+
+// fpeMaxCycleWalk bounds the re-encryptions of a cycle-walking template. Each one passes
+// the Luhn check with probability 1/10, so the bound is never reached in practice.
+const fpeMaxCycleWalk = 1000
+
+// builtInFPETemplates are registered by NewFPEService. A card keeps its BIN, so it stays
+// routable and its network is still recognisable, and still passes the Luhn check.
+// credit-card also keeps the last four digits, as receipts and support staff show them,
+// and cycle walks to keep the check digit valid. That leaves too few digits to encrypt
+// in cards shorter than 16 digits, so credit-card-bin keeps only the BIN and gets a new
+// check digit instead. An SSN is encrypted whole, because its last four digits alone are
+// too small a domain.
+var builtInFPETemplates = []FPETemplate{
+	{
+		Name:    FPETemplateCreditCard,
+		Pattern: `(?P<bin>\d{4}[ -]?\d{2})(?P<account>(?:[ -]?\d){6,9})(?P<last4>(?:[ -]?\d){4})`,
+		Segments: []FPESegment{
+			{Group: "bin", Action: FPESegmentPreserve},
+			{Group: "account", Action: FPESegmentEncrypt},
+			{Group: "last4", Action: FPESegmentPreserve},
+		},
+		PostProcess: FPEPostProcessLuhnWalk,
+	},
+	{
+		Name:    FPETemplateCreditCardBIN,
+		Pattern: `(?P<bin>\d{4}[ -]?\d{2})(?P<account>(?:[ -]?\d){6,12})(?P<check>\d)`,
+		Segments: []FPESegment{
+			{Group: "bin", Action: FPESegmentPreserve},
+			{Group: "account", Action: FPESegmentEncrypt},
+			{Group: "check", Action: FPESegmentPreserve},
+		},
+		PostProcess: FPEPostProcessLuhn,
+	},
+	{
+		Name:    FPETemplateSSN,
+		Pattern: `(?P<ssn>\d{3}-?\d{2}-?\d{4})`,
+		Segments: []FPESegment{
+			{Group: "ssn", Action: FPESegmentEncrypt},
+		},
+	},
+}
+
+// fpeTemplateName is the form of template names
+var fpeTemplateName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// fpeTemplate is a registered template with its pattern compiled
+type fpeTemplate struct {
+	FPETemplate
+	pattern     *regexp.Regexp
+	alphabet    *fpeAlphabet
+	encrypted   []int
+	postProcess fpePostProcessor
+}
+
+// compileFPETemplate validates a template and compiles its pattern
+func compileFPETemplate(template FPETemplate) (*fpeTemplate, error) {
+	if !fpeTemplateName.MatchString(template.Name) {
+		return nil, fmt.Errorf("%w: names are up to 64 lowercase letters, digits, '-' and '_'", ErrInvalidFPETemplate)
+	}
+	if template.Algorithm != "" && template.Algorithm != FPEAlgorithmFF1 && template.Algorithm != FPEAlgorithmFF31 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFPEAlgorithm, template.Algorithm)
+	}
+	alphabet, err := parseAlphabet(template.Alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pattern must match the whole value
+	pattern, err := regexp.Compile(`^(?:` + template.Pattern + `)$`)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFPETemplate, err)
+	}
+
+	t := &fpeTemplate{FPETemplate: template, pattern: pattern, alphabet: alphabet}
+	seen := make(map[string]bool, len(template.Segments))
+	for _, segment := range template.Segments {
+		group := pattern.SubexpIndex(segment.Group)
+		if group < 0 {
+			return nil, fmt.Errorf("%w: the pattern has no group named %q", ErrInvalidFPETemplate, segment.Group)
+		}
+		if seen[segment.Group] {
+			return nil, fmt.Errorf("%w: group %q has more than one segment", ErrInvalidFPETemplate, segment.Group)
+		}
+		seen[segment.Group] = true
+
+		switch segment.Action {
+		case FPESegmentEncrypt:
+			t.encrypted = append(t.encrypted, group)
+		case FPESegmentPreserve:
+		default:
+			return nil, fmt.Errorf("%w: segment actions are %q or %q", ErrInvalidFPETemplate, FPESegmentEncrypt, FPESegmentPreserve)
+		}
+	}
+	if len(t.encrypted) == 0 {
+		return nil, fmt.Errorf("%w: at least one segment must be encrypted", ErrInvalidFPETemplate)
+	}
+
+	if template.PostProcess != "" {
+		t.postProcess = fpePostProcessors[template.PostProcess]
+		if t.postProcess == nil {
+			return nil, fmt.Errorf("%w: unknown post-processing step %q", ErrInvalidFPETemplate, template.PostProcess)
+		}
+	}
+
+	// Copy the segments so that later changes by the caller do not reach the registry
+	t.Segments = slices.Clone(template.Segments)
+	return t, nil
+}
+
+// RegisterTemplate adds a custom template. Built-in templates cannot be replaced.
+func (s *fpeServiceImpl) RegisterTemplate(template FPETemplate) error {
+	template.BuiltIn = false
+	t, err := compileFPETemplate(template)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.templates[t.Name]; exists {
+		return fmt.Errorf("%w: %s", ErrFPETemplateExists, t.Name)
+	}
+	s.templates[t.Name] = t
+	return nil
+}
+
+// ListTemplates returns the registered templates, sorted by name
+func (s *fpeServiceImpl) ListTemplates() []FPETemplate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	templates := make([]FPETemplate, 0, len(s.templates))
+	for _, t := range s.templates {
+		template := t.FPETemplate
+		template.Segments = slices.Clone(t.Segments)
+		templates = append(templates, template)
+	}
+	slices.SortFunc(templates, func(a, b FPETemplate) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return templates
+}
+
+// EncryptTemplate encrypts the segments of a value that a template selects
+func (s *fpeServiceImpl) EncryptTemplate(name, plaintext string, key []byte, tweak []byte) (string, error) {
+	return s.applyTemplate(name, plaintext, key, tweak, true)
+}
+
+// DecryptTemplate decrypts a value encrypted with EncryptTemplate
+func (s *fpeServiceImpl) DecryptTemplate(name, ciphertext string, key []byte, tweak []byte) (string, error) {
+	return s.applyTemplate(name, ciphertext, key, tweak, false)
+}
+
+// applyTemplate encrypts or decrypts the alphabet characters of a value's encrypted
+// segments as one string and puts the result back in their places
+func (s *fpeServiceImpl) applyTemplate(name, input string, key, tweak []byte, encrypt bool) (string, error) {
+	s.mu.RLock()
+	t, ok := s.templates[name]
+	s.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrFPETemplateNotFound, name)
+	}
+
+	match := t.pattern.FindStringSubmatchIndex(input)
+	if match == nil {
+		return "", fmt.Errorf("%w: does not match the %s template", ErrInvalidFPEInput, name)
+	}
+	if t.postProcess != nil {
+		if err := t.postProcess.check(input); err != nil {
+			return "", err
+		}
+	}
+
+	// Mark the bytes of the encrypted segments. Groups that did not take part in the
+	// match have no position.
+	selected := make([]bool, len(input))
+	for _, group := range t.encrypted {
+		start, end := match[2*group], match[2*group+1]
+		for i := start; i >= 0 && i < end; i++ {
+			selected[i] = true
+		}
+	}
+
+	var numerals strings.Builder
+	for i, character := range input {
+		if _, ok := t.alphabet.numerals[character]; ok && selected[i] {
+			numerals.WriteRune(character)
+		}
+	}
+
+	// A cycle-walking template encrypts or decrypts again until the value passes its
+	// check. Decryption stops at the first value that passes, which is the plaintext,
+	// because encryption only stopped at the first one after it.
+	walker, walks := t.postProcess.(fpeCycleWalker)
+	params := FPEParams{Algorithm: t.Algorithm, Alphabet: t.Alphabet}
+	current := numerals.String()
+	var value string
+	for walk := 0; ; walk++ {
+		var result string
+		var err error
+		if encrypt {
+			result, err = s.EncryptFPE(current, key, tweak, params)
+		} else {
+			result, err = s.DecryptFPE(current, key, tweak, params)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		value = substituteSelected(input, selected, t.alphabet, result)
+		if t.postProcess != nil {
+			value = t.postProcess.apply(value)
+		}
+		if !walks || walker.accepts(value) {
+			break
+		}
+		if walk == fpeMaxCycleWalk {
+			return "", fmt.Errorf("%w: cycle walking did not find a valid value", ErrInvalidFPEInput)
+		}
+		current = result
+	}
+
+	// A pattern that only accepts some characters of the alphabet in an encrypted
+	// segment would reject the ciphertext, which could then never be decrypted
+	if !t.pattern.MatchString(value) {
+		return "", fmt.Errorf("%w: the %s pattern does not accept the result", ErrInvalidFPETemplate, name)
+	}
+	return value, nil
+}
+
+// substituteSelected puts the characters of result in the places of the selected
+// alphabet characters of input
+func substituteSelected(input string, selected []bool, alphabet *fpeAlphabet, result string) string {
+	var output strings.Builder
+	output.Grow(len(input))
+	for i, character := range input {
+		if _, ok := alphabet.numerals[character]; ok && selected[i] {
+			character, size := utf8.DecodeRuneInString(result)
+			result = result[size:]
+			output.WriteRune(character)
+			continue
+		}
+		output.WriteRune(character)
+	}
+	return output.String()
+}
+
+// fpePostProcessor restores a property of a format, such as a check digit, that
+// encrypting some of a value's characters breaks
+type fpePostProcessor interface {
+	// check rejects inputs without the property, which could not be restored exactly
+	check(value string) error
+	// apply gives the value the property
+	apply(value string) string
+}
+
+// fpeCycleWalker is a post-processor that keeps a property by cycle walking instead of
+// changing the value
+type fpeCycleWalker interface {
+	fpePostProcessor
+	// accepts reports whether the value has the property
+	accepts(value string) bool
+}
+
+// fpePostProcessors are the post-processing steps templates can use
+var fpePostProcessors = map[string]fpePostProcessor{
+	FPEPostProcessLuhn:     luhnCheckDigit{},
+	FPEPostProcessLuhnWalk: luhnCycleWalk{},
+	FPEPostProcessIBAN:     ibanCheckDigits{},
+}
+
+// luhnCheckDigit treats the last digit of a value as the Luhn check digit of the digits
+// before it. Other characters are ignored.
+type luhnCheckDigit struct{}
+
+func (luhnCheckDigit) check(value string) error {
+	digits := digitPositions(value)
+	if len(digits) < 2 {
+		return fmt.Errorf("%w: a Luhn check digit needs at least 2 digits", ErrInvalidFPEInput)
+	}
+	last := digits[len(digits)-1]
+	if value[last] != luhnDigit(value, digits[:len(digits)-1]) {
+		return fmt.Errorf("%w: fails the Luhn check", ErrInvalidFPEInput)
+	}
+	return nil
+}
+
+func (luhnCheckDigit) apply(value string) string {
+	digits := digitPositions(value)
+	last := digits[len(digits)-1]
+	b := []byte(value)
+	b[last] = luhnDigit(value, digits[:len(digits)-1])
+	return string(b)
+}
+
+// luhnCycleWalk keeps every digit of a value, which must pass the Luhn check, and cycle
+// walks until the encrypted value passes it too
+type luhnCycleWalk struct{}
+
+func (luhnCycleWalk) check(value string) error {
+	return luhnCheckDigit{}.check(value)
+}
+
+func (luhnCycleWalk) apply(value string) string {
+	return value
+}
+
+func (luhnCycleWalk) accepts(value string) bool {
+	return luhnCheckDigit{}.check(value) == nil
+}
+
+// luhnDigit computes the Luhn check digit of the digits of value at positions
+func luhnDigit(value string, positions []int) byte {
+	sum := 0
+	for i := range positions {
+		d := int(value[positions[len(positions)-1-i]] - '0')
+		// Every other digit is doubled, starting with the one next to the check digit
+		if i%2 == 0 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// digitPositions returns the byte positions of the ASCII digits of a value
+func digitPositions(value string) []int {
+	var positions []int
+	for i := 0; i < len(value); i++ {
+		if value[i] >= '0' && value[i] <= '9' {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// ibanCheckDigits treats the third and fourth characters of a value, ignoring spaces, as
+// the check digits of an IBAN
+type ibanCheckDigits struct{}
+
+func (ibanCheckDigits) check(value string) error {
+	positions, ok := ibanPositions(value)
+	if !ok {
+		return fmt.Errorf("%w: not an IBAN", ErrInvalidFPEInput)
+	}
+	if ibanMod97(value, positions, value[positions[2]], value[positions[3]]) != 1 {
+		return fmt.Errorf("%w: fails the IBAN check", ErrInvalidFPEInput)
+	}
+	return nil
+}
+
+func (ibanCheckDigits) apply(value string) string {
+	positions, ok := ibanPositions(value)
+	if !ok {
+		return value
+	}
+	check := 98 - ibanMod97(value, positions, '0', '0')
+	b := []byte(value)
+	b[positions[2]] = byte('0' + check/10)
+	b[positions[3]] = byte('0' + check%10)
+	return string(b)
+}
+
+// ibanPositions returns the byte positions of the characters of an IBAN other than
+// spaces, and whether they are a country code, two check digits and an alphanumeric
+// BBAN
+func ibanPositions(value string) ([]int, bool) {
+	var positions []int
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == ' ':
+			continue
+		case c >= '0' && c <= '9', c >= 'A' && c <= 'Z':
+			positions = append(positions, i)
+		default:
+			return nil, false
+		}
+	}
+	if len(positions) < 5 {
+		return nil, false
+	}
+	for i, position := range positions[:4] {
+		c := value[position]
+		if isDigit := c >= '0' && c <= '9'; isDigit != (i >= 2) {
+			return nil, false
+		}
+	}
+	return positions, true
+}
+
+// ibanMod97 is the IBAN with the given check digits, its first four characters moved to
+// the end and its letters replaced by 10 to 35, modulo 97
+func ibanMod97(value string, positions []int, check1, check2 byte) int {
+	characters := make([]byte, 0, len(positions))
+	for _, position := range positions[4:] {
+		characters = append(characters, value[position])
+	}
+	characters = append(characters, value[positions[0]], value[positions[1]], check1, check2)
+
+	remainder := 0
+	for _, c := range characters {
+		if c >= 'A' {
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(c-'0')) % 97
+		}
+	}
+	return remainder
+}
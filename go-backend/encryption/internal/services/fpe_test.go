@@ -0,0 +1,319 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/cryptofortress/backend/encryption/internal/config"
+)
+
+const fpeAlphanumeric = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// TestFF1 checks FF1 against the NIST SP 800-38G FF1 samples
+func TestFF1(t *testing.T) {
+	service := NewFPEService(&config.Config{})
+	key128 := "2b7e151628aed2a6abf7158809cf4f3c"
+	key192 := key128 + "ef4359d8d580aa4f"
+	key256 := key192 + "7f036d6f04fc6a94"
+	cases := []struct {
+		name       string
+		key        string
+		alphabet   string
+		tweak      string
+		plaintext  string
+		ciphertext string
+	}{
+		{"Sample1", key128, fpeDigits, "", "0123456789", "2433477484"},
+		{"Sample2", key128, fpeDigits, "39383736353433323130", "0123456789", "6124200773"},
+		{"Sample3", key128, fpeAlphanumeric, "3737373770717273373737", "0123456789abcdefghi", "a9tv40mll9kdu509eum"},
+		{"Sample4", key192, fpeDigits, "", "0123456789", "2830668132"},
+		{"Sample5", key192, fpeDigits, "39383736353433323130", "0123456789", "2496655549"},
+		{"Sample6", key192, fpeAlphanumeric, "3737373770717273373737", "0123456789abcdefghi", "xbj3kv35jrawxv32ysr"},
+		{"Sample7", key256, fpeDigits, "", "0123456789", "6657667009"},
+		{"Sample8", key256, fpeDigits, "39383736353433323130", "0123456789", "1001623463"},
+		{"Sample9", key256, fpeAlphanumeric, "3737373770717273373737", "0123456789abcdefghi", "xs8a0azh2avyalyzuwd"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			params := FPEParams{Algorithm: FPEAlgorithmFF1, Alphabet: tc.alphabet}
+			ciphertext, err := service.EncryptFPE(tc.plaintext, mustHex(t, tc.key), mustHex(t, tc.tweak), params)
+			if err != nil {
+				t.Fatalf("EncryptFPE failed: %v", err)
+			}
+			if ciphertext != tc.ciphertext {
+				t.Errorf("Got ciphertext %s, want %s", ciphertext, tc.ciphertext)
+			}
+
+			plaintext, err := service.DecryptFPE(ciphertext, mustHex(t, tc.key), mustHex(t, tc.tweak), params)
+			if err != nil {
+				t.Fatalf("DecryptFPE failed: %v", err)
+			}
+			if plaintext != tc.plaintext {
+				t.Errorf("Got plaintext %s, want %s", plaintext, tc.plaintext)
+			}
+		})
+	}
+}
+
+// TestFF3 checks the FF3-1 rounds against the NIST FF3 samples, whose 64-bit tweaks are
+// split into TL and TR directly, and the tweak handling against a published FF3-1 vector
+func TestFF3(t *testing.T) {
+	key := "ef4359d8d580aa4f7f036d6f04fc6a94"
+	cases := []struct {
+		name       string
+		alphabet   string
+		tweak      string
+		plaintext  string
+		ciphertext string
+	}{
+		{"Sample1", fpeDigits, "d8e7920afa330a73", "890121234567890000", "750918814058654607"},
+		{"Sample2", fpeDigits, "9a768a92f60e12d8", "890121234567890000", "018989839189395384"},
+		{"Sample3", fpeDigits, "d8e7920afa330a73", "89012123456789000000789000000", "48598367162252569629397416226"},
+		{"Sample5", fpeAlphanumeric[:26], "9a768a92f60e12d8", "0123456789abcdefghi", "g2pk40i992fn20cjakb"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			alphabet, err := parseAlphabet(tc.alphabet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := newFF3(mustHex(t, key), len(alphabet.characters))
+			if err != nil {
+				t.Fatal(err)
+			}
+			tweak := mustHex(t, tc.tweak)
+			tl, tr := [4]byte(tweak[:4]), [4]byte(tweak[4:])
+
+			x, err := alphabet.toNumerals(tc.plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ciphertext := alphabet.toString(f.crypt(x, tl, tr, true))
+			if ciphertext != tc.ciphertext {
+				t.Errorf("Got ciphertext %s, want %s", ciphertext, tc.ciphertext)
+			}
+			y, _ := alphabet.toNumerals(ciphertext)
+			if plaintext := alphabet.toString(f.crypt(y, tl, tr, false)); plaintext != tc.plaintext {
+				t.Errorf("Got plaintext %s, want %s", plaintext, tc.plaintext)
+			}
+		})
+	}
+
+	t.Run("FF3-1", func(t *testing.T) {
+		service := NewFPEService(&config.Config{})
+		params := FPEParams{Algorithm: FPEAlgorithmFF31}
+		ciphertext, err := service.EncryptFPE("890121234567890000", mustHex(t, key), mustHex(t, "d8e7920afa330a"), params)
+		if err != nil {
+			t.Fatalf("EncryptFPE failed: %v", err)
+		}
+		if want := "477064185124354662"; ciphertext != want {
+			t.Errorf("Got ciphertext %s, want %s", ciphertext, want)
+		}
+	})
+}
+
+// TestFPELimits tests that inputs outside the limits of FF1 and FF3-1 are rejected
+func TestFPELimits(t *testing.T) {
+	service := NewFPEService(&config.Config{})
+	key := sequence(0, 16)
+	tweak := sequence(0, 7)
+	cases := []struct {
+		name   string
+		input  string
+		key    []byte
+		tweak  []byte
+		params FPEParams
+		want   error
+	}{
+		{"DomainTooSmall", "12345", key, nil, FPEParams{}, ErrFPEDomainTooSmall},
+		{"NotInAlphabet", "12345a", key, nil, FPEParams{}, ErrInvalidFPEInput},
+		{"RepeatedCharacter", "abcdef", key, nil, FPEParams{Alphabet: "abcdefa"}, ErrInvalidAlphabet},
+		{"FF3TooLong", "123456789012345678901234567890123456789012345678901234567", key, tweak, FPEParams{Algorithm: FPEAlgorithmFF31}, ErrInvalidFPEInput},
+		{"FF3TweakLength", "123456", key, sequence(0, 8), FPEParams{Algorithm: FPEAlgorithmFF31}, ErrInvalidTweak},
+		{"KeyLength", "123456", sequence(0, 20), nil, FPEParams{}, ErrInvalidFPEKey},
+		{"Algorithm", "123456", key, nil, FPEParams{Algorithm: "FF2"}, ErrUnsupportedFPEAlgorithm},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := service.EncryptFPE(tc.input, tc.key, tc.tweak, tc.params); !errors.Is(err, tc.want) {
+				t.Errorf("Expected %v, got %v", tc.want, err)
+			}
+		})
+	}
+
+	// The smallest domains that are allowed keep their length and alphabet
+	for _, params := range []FPEParams{{}, {Algorithm: FPEAlgorithmFF31, Alphabet: "αβγδεζηθικ"}} {
+		input := "123456"
+		if params.Alphabet != "" {
+			input = "αβγδεζ"
+		}
+		ciphertext, err := service.EncryptFPE(input, key, tweak, params)
+		if err != nil {
+			t.Fatalf("EncryptFPE failed: %v", err)
+		}
+		if len([]rune(ciphertext)) != 6 {
+			t.Errorf("Got ciphertext %q of another length", ciphertext)
+		}
+		if _, err := service.DecryptFPE(ciphertext, key, tweak, params); err != nil {
+			t.Errorf("DecryptFPE failed: %v", err)
+		}
+	}
+}
+
+// TestFPETemplates tests the built-in templates and custom templates for IBANs, phone
+// numbers and email addresses
+func TestFPETemplates(t *testing.T) {
+	service := NewFPEService(&config.Config{})
+	key := sequence(0, 32)
+	for _, template := range []FPETemplate{
+		{
+			Name:        "iban",
+			Pattern:     `(?P<country>[A-Z]{2})(?P<check>\d{2})(?P<bban>[ A-Z0-9]{11,40})`,
+			Segments:    []FPESegment{{Group: "bban", Action: FPESegmentEncrypt}},
+			PostProcess: FPEPostProcessIBAN,
+		},
+		{
+			Name:     "us-phone",
+			Pattern:  `\+1 \((?P<area>\d{3})\) (?P<number>\d{3}-\d{4})`,
+			Segments: []FPESegment{{Group: "area", Action: FPESegmentPreserve}, {Group: "number", Action: FPESegmentEncrypt}},
+		},
+		{
+			Name:     "email",
+			Pattern:  `(?P<local>[^@]+)@(?P<domain>[^@]+)`,
+			Alphabet: fpeAlphanumeric,
+			Segments: []FPESegment{{Group: "local", Action: FPESegmentEncrypt}, {Group: "domain", Action: FPESegmentPreserve}},
+		},
+	} {
+		if err := service.RegisterTemplate(template); err != nil {
+			t.Fatalf("RegisterTemplate %s failed: %v", template.Name, err)
+		}
+	}
+
+	cases := []struct {
+		template  string
+		plaintext string
+		kept      []string
+	}{
+		{FPETemplateCreditCard, "4111111111111111", []string{"411111"}},
+		{FPETemplateCreditCard, "4111 1111 1111 1111", []string{"4111 11", " ", " "}},
+		{FPETemplateCreditCard, "6011 0009 9013 9421 112", []string{"6011 00", "1 112"}},
+		{FPETemplateCreditCardBIN, "4111111111111111", []string{"411111"}},
+		{FPETemplateCreditCardBIN, "4222222222222", []string{"422222"}},
+		{FPETemplateSSN, "123-45-6789", []string{"-"}},
+		{"iban", "GB82 WEST 1234 5698 7654 32", []string{"GB", " WEST "}},
+		{"us-phone", "+1 (555) 123-4567", []string{"+1 (555) ", "-"}},
+		{"email", "john.doe@example.com", []string{".", "@example.com"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.template, func(t *testing.T) {
+			ciphertext, err := service.EncryptTemplate(tc.template, tc.plaintext, key, nil)
+			if err != nil {
+				t.Fatalf("EncryptTemplate failed: %v", err)
+			}
+			if ciphertext == tc.plaintext || len(ciphertext) != len(tc.plaintext) {
+				t.Errorf("Got ciphertext %q for %q", ciphertext, tc.plaintext)
+			}
+			for _, kept := range tc.kept {
+				if !strings.Contains(ciphertext, kept) {
+					t.Errorf("Expected ciphertext %q to keep %q", ciphertext, kept)
+				}
+			}
+
+			plaintext, err := service.DecryptTemplate(tc.template, ciphertext, key, nil)
+			if err != nil {
+				t.Fatalf("DecryptTemplate failed: %v", err)
+			}
+			if plaintext != tc.plaintext {
+				t.Errorf("Got plaintext %q, want %q", plaintext, tc.plaintext)
+			}
+		})
+	}
+
+	t.Run("CheckDigits", func(t *testing.T) {
+		// 16-digit cards keep their last four digits, shorter ones only their BIN
+		for _, card := range []string{"4111111111111111", "5555 5555 5555 4444", "378282246310005"} {
+			ciphertext, err := service.EncryptCreditCard(card, key)
+			if err != nil {
+				t.Fatalf("EncryptCreditCard failed: %v", err)
+			}
+			if err := (luhnCheckDigit{}).check(ciphertext); err != nil {
+				t.Errorf("Expected ciphertext %s to pass the Luhn check, got %v", ciphertext, err)
+			}
+			if ciphertext[:6] != card[:6] {
+				t.Errorf("Expected ciphertext %s to keep the BIN of %s", ciphertext, card)
+			}
+			if len(digitPositions(card)) >= 16 && ciphertext[len(ciphertext)-4:] != card[len(card)-4:] {
+				t.Errorf("Expected ciphertext %s to keep the last four digits of %s", ciphertext, card)
+			}
+
+			plaintext, err := service.DecryptCreditCard(ciphertext, key)
+			if err != nil {
+				t.Fatalf("DecryptCreditCard failed: %v", err)
+			}
+			if plaintext != card {
+				t.Errorf("Got plaintext %q, want %q", plaintext, card)
+			}
+		}
+		if _, err := service.EncryptTemplate(FPETemplateCreditCard, "4222222222222", key, nil); !errors.Is(err, ErrInvalidFPEInput) {
+			t.Errorf("Expected ErrInvalidFPEInput for a 13-digit card, got %v", err)
+		}
+		if _, err := service.EncryptCreditCard("4111111111111112", key); !errors.Is(err, ErrInvalidFPEInput) {
+			t.Errorf("Expected ErrInvalidFPEInput for a card that fails the Luhn check, got %v", err)
+		}
+
+		ciphertext, err := service.EncryptTemplate("iban", "DE89370400440532013000", key, nil)
+		if err != nil {
+			t.Fatalf("EncryptTemplate failed: %v", err)
+		}
+		if err := (ibanCheckDigits{}).check(ciphertext); err != nil {
+			t.Errorf("Expected ciphertext %s to pass the IBAN check, got %v", ciphertext, err)
+		}
+	})
+
+	t.Run("Registry", func(t *testing.T) {
+		cases := []struct {
+			name     string
+			template FPETemplate
+			want     error
+		}{
+			{"BuiltIn", FPETemplate{Name: FPETemplateSSN, Pattern: `(?P<v>\d{9})`, Segments: []FPESegment{{Group: "v", Action: FPESegmentEncrypt}}}, ErrFPETemplateExists},
+			{"Exists", FPETemplate{Name: "email", Pattern: `(?P<v>\d{9})`, Segments: []FPESegment{{Group: "v", Action: FPESegmentEncrypt}}}, ErrFPETemplateExists},
+			{"Name", FPETemplate{Name: "Email", Pattern: `(?P<v>\d{9})`, Segments: []FPESegment{{Group: "v", Action: FPESegmentEncrypt}}}, ErrInvalidFPETemplate},
+			{"Pattern", FPETemplate{Name: "bad", Pattern: `(?P<v>\d{9}`, Segments: []FPESegment{{Group: "v", Action: FPESegmentEncrypt}}}, ErrInvalidFPETemplate},
+			{"Group", FPETemplate{Name: "bad", Pattern: `(?P<v>\d{9})`, Segments: []FPESegment{{Group: "w", Action: FPESegmentEncrypt}}}, ErrInvalidFPETemplate},
+			{"NothingEncrypted", FPETemplate{Name: "bad", Pattern: `(?P<v>\d{9})`, Segments: []FPESegment{{Group: "v", Action: FPESegmentPreserve}}}, ErrInvalidFPETemplate},
+			{"PostProcess", FPETemplate{Name: "bad", Pattern: `(?P<v>\d{9})`, Segments: []FPESegment{{Group: "v", Action: FPESegmentEncrypt}}, PostProcess: "crc"}, ErrInvalidFPETemplate},
+		}
+		for _, tc := range cases {
+			if err := service.RegisterTemplate(tc.template); !errors.Is(err, tc.want) {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.want, err)
+			}
+		}
+
+		if _, err := service.EncryptTemplate("missing", "123456", key, nil); !errors.Is(err, ErrFPETemplateNotFound) {
+			t.Errorf("Expected ErrFPETemplateNotFound, got %v", err)
+		}
+		if _, err := service.EncryptTemplate(FPETemplateSSN, "123-45-678", key, nil); !errors.Is(err, ErrInvalidFPEInput) {
+			t.Errorf("Expected ErrInvalidFPEInput for a value that does not match, got %v", err)
+		}
+
+		// A pattern that accepts only some digits would reject most ciphertexts
+		narrow := FPETemplate{Name: "narrow", Pattern: `(?P<v>[0-4]{8})`, Segments: []FPESegment{{Group: "v", Action: FPESegmentEncrypt}}}
+		if err := service.RegisterTemplate(narrow); err != nil {
+			t.Fatalf("RegisterTemplate failed: %v", err)
+		}
+		if _, err := service.EncryptTemplate("narrow", "01234012", key, nil); !errors.Is(err, ErrInvalidFPETemplate) {
+			t.Errorf("Expected ErrInvalidFPETemplate, got %v", err)
+		}
+
+		templates := service.ListTemplates()
+		if len(templates) != 7 || templates[0].Name != FPETemplateCreditCard || !templates[1].BuiltIn || templates[2].BuiltIn {
+			t.Errorf("Got templates %+v", templates)
+		}
+	})
+}
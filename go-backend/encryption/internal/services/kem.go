@@ -0,0 +1,339 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/mlkem"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KEM algorithms. ML-KEM-768 and ML-KEM-1024 are the FIPS 203 parameter sets. The
+// hybrid algorithms combine ML-KEM with X25519, so their shared key stays secret as long
+// as either of the two is unbroken.
+const (
+	KEMAlgorithmMLKEM768        = "ML-KEM-768"
+	KEMAlgorithmMLKEM1024       = "ML-KEM-1024"
+	KEMAlgorithmX25519MLKEM768  = "X25519-ML-KEM-768"
+	KEMAlgorithmX25519MLKEM1024 = "X25519-ML-KEM-1024"
+)
+
+// defaultKEMAlgorithm is used for key generation when no algorithm is given
+const defaultKEMAlgorithm = KEMAlgorithmX25519MLKEM768
+
+// x25519KeySize is the size of X25519 private keys, public keys and shared secrets
+const x25519KeySize = 32
+
+// hybridKEMLabel starts the KDF info of the hybrid algorithms, separating their keys
+// from any other use of the same shared secrets
+const hybridKEMLabel = "CryptoFortress hybrid KEM v1 "
+
+var (
+	// ErrInvalidKEMKey is returned for KEM keys that cannot be parsed or do not fit the
+	// operation
+	ErrInvalidKEMKey = errors.New("invalid KEM key")
+	// ErrInvalidKEMCiphertext is returned for KEM ciphertexts of an unknown size
+	ErrInvalidKEMCiphertext = errors.New("invalid KEM ciphertext")
+)
+
+// mlkemParameters describes an ML-KEM parameter set
+type mlkemParameters struct {
+	publicKeySize  int
+	ciphertextSize int
+	newPublicKey   func(publicKey []byte) (crypto.Encapsulator, error)
+	newPrivateKey  func(seed []byte) (crypto.Decapsulator, error)
+}
+
+var mlkem768 = mlkemParameters{
+	publicKeySize:  mlkem.EncapsulationKeySize768,
+	ciphertextSize: mlkem.CiphertextSize768,
+	newPublicKey: func(publicKey []byte) (crypto.Encapsulator, error) {
+		key, err := mlkem.NewEncapsulationKey768(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	},
+	newPrivateKey: func(seed []byte) (crypto.Decapsulator, error) {
+		key, err := mlkem.NewDecapsulationKey768(seed)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	},
+}
+
+var mlkem1024 = mlkemParameters{
+	publicKeySize:  mlkem.EncapsulationKeySize1024,
+	ciphertextSize: mlkem.CiphertextSize1024,
+	newPublicKey: func(publicKey []byte) (crypto.Encapsulator, error) {
+		key, err := mlkem.NewEncapsulationKey1024(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	},
+	newPrivateKey: func(seed []byte) (crypto.Decapsulator, error) {
+		key, err := mlkem.NewDecapsulationKey1024(seed)
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	},
+}
+
+// kemAlgorithm is a KEM algorithm. Keys and ciphertexts are the ML-KEM ones, followed by
+// the X25519 ones for hybrid algorithms. Private keys hold the 64-byte ML-KEM seed
+// (d || z) rather than the expanded key.
+type kemAlgorithm struct {
+	name   string
+	mlkem  mlkemParameters
+	hybrid bool
+}
+
+var kemAlgorithms = []*kemAlgorithm{
+	{name: KEMAlgorithmMLKEM768, mlkem: mlkem768},
+	{name: KEMAlgorithmMLKEM1024, mlkem: mlkem1024},
+	{name: KEMAlgorithmX25519MLKEM768, mlkem: mlkem768, hybrid: true},
+	{name: KEMAlgorithmX25519MLKEM1024, mlkem: mlkem1024, hybrid: true},
+}
+
+// x25519Size returns the size of the X25519 part of keys and ciphertexts
+func (a *kemAlgorithm) x25519Size() int {
+	if a.hybrid {
+		return x25519KeySize
+	}
+	return 0
+}
+
+func (a *kemAlgorithm) privateKeySize() int {
+	return mlkem.SeedSize + a.x25519Size()
+}
+
+func (a *kemAlgorithm) publicKeySize() int {
+	return a.mlkem.publicKeySize + a.x25519Size()
+}
+
+func (a *kemAlgorithm) ciphertextSize() int {
+	return a.mlkem.ciphertextSize + a.x25519Size()
+}
+
+// findKEMAlgorithm returns the first algorithm that matches, or nil
+func findKEMAlgorithm(match func(*kemAlgorithm) bool) *kemAlgorithm {
+	for _, algorithm := range kemAlgorithms {
+		if match(algorithm) {
+			return algorithm
+		}
+	}
+	return nil
+}
+
+func kemAlgorithmByName(name string) *kemAlgorithm {
+	return findKEMAlgorithm(func(a *kemAlgorithm) bool { return a.name == name })
+}
+
+// kemAlgorithmByPublicKey identifies an algorithm by its public key size, which differs
+// for every algorithm
+func kemAlgorithmByPublicKey(publicKey []byte) *kemAlgorithm {
+	return findKEMAlgorithm(func(a *kemAlgorithm) bool { return a.publicKeySize() == len(publicKey) })
+}
+
+// kemAlgorithmByCiphertext identifies an algorithm by its ciphertext size, which differs
+// for every algorithm
+func kemAlgorithmByCiphertext(ciphertext []byte) *kemAlgorithm {
+	return findKEMAlgorithm(func(a *kemAlgorithm) bool { return a.ciphertextSize() == len(ciphertext) })
+}
+
+// kemPublicKey is a parsed KEM public key. x25519 is nil for ML-KEM algorithms.
+type kemPublicKey struct {
+	algorithm *kemAlgorithm
+	mlkem     crypto.Encapsulator
+	x25519    *ecdh.PublicKey
+}
+
+// kemPrivateKey is a parsed KEM private key. x25519 is nil for ML-KEM algorithms.
+type kemPrivateKey struct {
+	algorithm *kemAlgorithm
+	seed      []byte
+	mlkem     crypto.Decapsulator
+	x25519    *ecdh.PrivateKey
+}
+
+// generateKEMKey generates a private key for an algorithm
+func generateKEMKey(algorithm *kemAlgorithm) (*kemPrivateKey, error) {
+	key := make([]byte, algorithm.privateKeySize())
+	if _, err := io.ReadFull(rand.Reader, key[:mlkem.SeedSize]); err != nil {
+		return nil, err
+	}
+	if algorithm.hybrid {
+		x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		copy(key[mlkem.SeedSize:], x25519Key.Bytes())
+	}
+	return parseKEMPrivateKey(algorithm, key)
+}
+
+// parseKEMPublicKey parses a public key of any algorithm
+func parseKEMPublicKey(publicKey []byte) (*kemPublicKey, error) {
+	algorithm := kemAlgorithmByPublicKey(publicKey)
+	if algorithm == nil {
+		return nil, fmt.Errorf("%w: a %d-byte public key matches no KEM algorithm", ErrInvalidKEMKey, len(publicKey))
+	}
+
+	split := algorithm.mlkem.publicKeySize
+	mlkemKey, err := algorithm.mlkem.newPublicKey(publicKey[:split])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKEMKey, err)
+	}
+	key := &kemPublicKey{algorithm: algorithm, mlkem: mlkemKey}
+	if algorithm.hybrid {
+		if key.x25519, err = ecdh.X25519().NewPublicKey(publicKey[split:]); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKEMKey, err)
+		}
+	}
+	return key, nil
+}
+
+// parseKEMPrivateKey parses a private key of the given algorithm. ML-KEM-768 and
+// ML-KEM-1024 private keys have the same size, so the algorithm cannot be told from the
+// key alone.
+func parseKEMPrivateKey(algorithm *kemAlgorithm, privateKey []byte) (*kemPrivateKey, error) {
+	if len(privateKey) != algorithm.privateKeySize() {
+		return nil, fmt.Errorf("%w: %s private keys are %d bytes", ErrInvalidKEMKey, algorithm.name, algorithm.privateKeySize())
+	}
+
+	seed := privateKey[:mlkem.SeedSize]
+	mlkemKey, err := algorithm.mlkem.newPrivateKey(seed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKEMKey, err)
+	}
+	key := &kemPrivateKey{algorithm: algorithm, seed: seed, mlkem: mlkemKey}
+	if algorithm.hybrid {
+		if key.x25519, err = ecdh.X25519().NewPrivateKey(privateKey[mlkem.SeedSize:]); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidKEMKey, err)
+		}
+	}
+	return key, nil
+}
+
+// Bytes encodes the private key
+func (k *kemPrivateKey) Bytes() []byte {
+	key := append([]byte(nil), k.seed...)
+	if k.x25519 != nil {
+		key = append(key, k.x25519.Bytes()...)
+	}
+	return key
+}
+
+// publicKey returns the public key of the private key
+func (k *kemPrivateKey) publicKey() *kemPublicKey {
+	key := &kemPublicKey{algorithm: k.algorithm, mlkem: k.mlkem.Encapsulator()}
+	if k.x25519 != nil {
+		key.x25519 = k.x25519.PublicKey()
+	}
+	return key
+}
+
+// Bytes encodes the public key
+func (k *kemPublicKey) Bytes() []byte {
+	key := k.mlkem.Bytes()
+	if k.x25519 != nil {
+		key = append(key, k.x25519.Bytes()...)
+	}
+	return key
+}
+
+// keyID identifies the public key in envelopes encrypted to it: the first 16 bytes of
+// its SHA-256 hash, in hex
+func (k *kemPublicKey) keyID() string {
+	hash := sha256.Sum256(k.Bytes())
+	return hex.EncodeToString(hash[:16])
+}
+
+// encapsulate returns a fresh shared key and its ciphertext
+func (k *kemPublicKey) encapsulate() (sharedKey, ciphertext []byte, err error) {
+	if !k.algorithm.hybrid {
+		sharedKey, ciphertext = k.mlkem.Encapsulate()
+		return sharedKey, ciphertext, nil
+	}
+
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	return k.encapsulateHybrid(ephemeral)
+}
+
+// encapsulateHybrid encapsulates to both halves of a hybrid key. The X25519 ciphertext
+// is the ephemeral public key.
+func (k *kemPublicKey) encapsulateHybrid(ephemeral *ecdh.PrivateKey) (sharedKey, ciphertext []byte, err error) {
+	mlkemSecret, ciphertext := k.mlkem.Encapsulate()
+	defer clear(mlkemSecret)
+	x25519Secret, err := ephemeral.ECDH(k.x25519)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidKEMKey, err)
+	}
+	defer clear(x25519Secret)
+
+	ciphertext = append(ciphertext, ephemeral.PublicKey().Bytes()...)
+	sharedKey, err = combineHybridSecrets(k.algorithm, mlkemSecret, x25519Secret, ciphertext, k.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	return sharedKey, ciphertext, nil
+}
+
+// decapsulate recovers the shared key from a ciphertext. ML-KEM rejects a modified
+// ciphertext implicitly, returning an unrelated key rather than an error.
+func (k *kemPrivateKey) decapsulate(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) != k.algorithm.ciphertextSize() {
+		return nil, fmt.Errorf("%w: %s ciphertexts are %d bytes", ErrInvalidKEMCiphertext, k.algorithm.name, k.algorithm.ciphertextSize())
+	}
+
+	split := k.algorithm.mlkem.ciphertextSize
+	mlkemSecret, err := k.mlkem.Decapsulate(ciphertext[:split])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKEMCiphertext, err)
+	}
+	if !k.algorithm.hybrid {
+		return mlkemSecret, nil
+	}
+	defer clear(mlkemSecret)
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(ciphertext[split:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKEMCiphertext, err)
+	}
+	x25519Secret, err := k.x25519.ECDH(ephemeral)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKEMCiphertext, err)
+	}
+	defer clear(x25519Secret)
+
+	return combineHybridSecrets(k.algorithm, mlkemSecret, x25519Secret, ciphertext, k.publicKey().Bytes())
+}
+
+// combineHybridSecrets derives a hybrid shared key with HKDF-SHA-256 from both shared
+// secrets. The info binds the key to the algorithm, the whole ciphertext and the whole
+// public key, so the key stays secure as long as either X25519 or ML-KEM does.
+func combineHybridSecrets(algorithm *kemAlgorithm, mlkemSecret, x25519Secret, ciphertext, publicKey []byte) ([]byte, error) {
+	secret := make([]byte, 0, len(mlkemSecret)+len(x25519Secret))
+	secret = append(secret, mlkemSecret...)
+	secret = append(secret, x25519Secret...)
+	defer clear(secret)
+
+	info := make([]byte, 0, len(hybridKEMLabel)+len(algorithm.name)+1+len(ciphertext)+len(publicKey))
+	info = append(info, hybridKEMLabel...)
+	info = append(info, algorithm.name...)
+	info = append(info, 0)
+	info = append(info, ciphertext...)
+	info = append(info, publicKey...)
+	return hkdf.Key(sha256.New, secret, nil, string(info), mlkem.SharedKeySize)
+}
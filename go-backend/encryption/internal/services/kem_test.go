@@ -0,0 +1,268 @@
+package services
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"crypto/mlkem/mlkemtest"
+	"crypto/sha3"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/cryptofortress/backend/encryption/internal/config"
+)
+
+// deterministicEncapsulator encapsulates with fixed randomness, for known-answer tests
+type deterministicEncapsulator struct {
+	crypto.Encapsulator
+	random []byte
+}
+
+func (e deterministicEncapsulator) Encapsulate() (sharedKey, ciphertext []byte) {
+	var err error
+	switch key := e.Encapsulator.(type) {
+	case *mlkem.EncapsulationKey768:
+		sharedKey, ciphertext, err = mlkemtest.Encapsulate768(key, e.random)
+	case *mlkem.EncapsulationKey1024:
+		sharedKey, ciphertext, err = mlkemtest.Encapsulate1024(key, e.random)
+	default:
+		panic("unexpected encapsulation key type")
+	}
+	if err != nil {
+		panic(err)
+	}
+	return sharedKey, ciphertext
+}
+
+// sequence returns n bytes counting up from first
+func sequence(first byte, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = first + byte(i)
+	}
+	return b
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestKEMKnownAnswers checks ML-KEM and the hybrid KEMs against fixed vectors
+func TestKEMKnownAnswers(t *testing.T) {
+	// The ML-KEM-768 self-test vector of the Go FIPS 140-3 module: d = 01..20,
+	// z = 21..40 and m = 41..60
+	t.Run("MLKEM768SelfTest", func(t *testing.T) {
+		priv, err := parseKEMPrivateKey(kemAlgorithmByName(KEMAlgorithmMLKEM768), sequence(0x01, 64))
+		if err != nil {
+			t.Fatalf("parseKEMPrivateKey failed: %v", err)
+		}
+		pub := priv.publicKey()
+		pub.mlkem = deterministicEncapsulator{pub.mlkem, sequence(0x41, 32)}
+
+		sharedKey, ciphertext, err := pub.encapsulate()
+		if err != nil {
+			t.Fatalf("encapsulate failed: %v", err)
+		}
+		want := mustHex(t, "5501fc523b745f41762a188de44a59b920f430146204ee4e793732396df7aa48")
+		if !bytes.Equal(sharedKey, want) {
+			t.Errorf("Got shared key %x, want %x", sharedKey, want)
+		}
+
+		decapsulated, err := priv.decapsulate(ciphertext)
+		if err != nil {
+			t.Fatalf("decapsulate failed: %v", err)
+		}
+		if !bytes.Equal(decapsulated, want) {
+			t.Errorf("Got decapsulated key %x, want %x", decapsulated, want)
+		}
+	})
+
+	// Accumulated vectors in the style of C2SP CCTV: keys, messages and invalid
+	// ciphertexts are drawn from a SHAKE-128 stream, and the public keys, ciphertexts
+	// and shared keys are hashed together. The ML-KEM-768 hashes are the CCTV ones; the
+	// ML-KEM-1024 ones were generated with crypto/mlkem in the same way.
+	t.Run("Accumulated", func(t *testing.T) {
+		cases := []struct {
+			algorithm string
+			n         int
+			want      string
+		}{
+			{KEMAlgorithmMLKEM768, 100, "1114b1b6699ed191734fa339376afa7e285c9e6acf6ff0177d346696ce564415"},
+			{KEMAlgorithmMLKEM768, 10000, "8a518cc63da366322a8e7a818c7a0d63483cb3528d34a4cf42f35d5ad73f22fc"},
+			{KEMAlgorithmMLKEM1024, 100, "800018fec3e2723f73f1d657fe239b4d5d8782efaade297e8cd448e54cc2ac00"},
+		}
+
+		for _, tc := range cases {
+			if testing.Short() && tc.n > 100 {
+				continue
+			}
+			algorithm := kemAlgorithmByName(tc.algorithm)
+			s := sha3.NewSHAKE128()
+			o := sha3.NewSHAKE128()
+			seed := make([]byte, mlkem.SeedSize)
+			msg := make([]byte, 32)
+			invalid := make([]byte, algorithm.ciphertextSize())
+
+			for i := 0; i < tc.n; i++ {
+				s.Read(seed)
+				priv, err := parseKEMPrivateKey(algorithm, seed)
+				if err != nil {
+					t.Fatalf("parseKEMPrivateKey failed: %v", err)
+				}
+				pub := priv.publicKey()
+				o.Write(pub.Bytes())
+
+				s.Read(msg)
+				pub.mlkem = deterministicEncapsulator{pub.mlkem, msg}
+				sharedKey, ciphertext, err := pub.encapsulate()
+				if err != nil {
+					t.Fatalf("encapsulate failed: %v", err)
+				}
+				o.Write(ciphertext)
+				o.Write(sharedKey)
+
+				decapsulated, err := priv.decapsulate(ciphertext)
+				if err != nil {
+					t.Fatalf("decapsulate failed: %v", err)
+				}
+				if !bytes.Equal(decapsulated, sharedKey) {
+					t.Fatalf("%s vector %d: decapsulated key %x, want %x", tc.algorithm, i, decapsulated, sharedKey)
+				}
+
+				// Invalid ciphertexts are rejected implicitly, with a pseudorandom key
+				s.Read(invalid)
+				rejected, err := priv.decapsulate(invalid)
+				if err != nil {
+					t.Fatalf("decapsulate failed: %v", err)
+				}
+				o.Write(rejected)
+			}
+
+			sum := make([]byte, 32)
+			o.Read(sum)
+			if got := hex.EncodeToString(sum); got != tc.want {
+				t.Errorf("%s with %d vectors: got %s, want %s", tc.algorithm, tc.n, got, tc.want)
+			}
+		}
+	})
+
+	// The hybrid vectors use the ML-KEM self-test seed and message, and the X25519 keys
+	// of RFC 7748 section 6.1: Bob's key is the recipient's and Alice's the ephemeral one.
+	// The ML-KEM-768 key was checked with a separate HKDF implementation, from the
+	// self-test shared key and the RFC 7748 shared secret.
+	t.Run("Hybrid", func(t *testing.T) {
+		bob := mustHex(t, "5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+		alice, err := ecdh.X25519().NewPrivateKey(mustHex(t, "77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cases := []struct {
+			algorithm string
+			want      string
+		}{
+			{KEMAlgorithmX25519MLKEM768, "9a7002a91b20681a8fb6f83cc08c4c201bec321b09288646af814b02ef3c51d8"},
+			{KEMAlgorithmX25519MLKEM1024, "5c05a94d999317366aae20db6dad122d4b3346513b63b332d7726cd59e40f171"},
+		}
+
+		for _, tc := range cases {
+			priv, err := parseKEMPrivateKey(kemAlgorithmByName(tc.algorithm), append(sequence(0x01, 64), bob...))
+			if err != nil {
+				t.Fatalf("parseKEMPrivateKey failed: %v", err)
+			}
+			pub := priv.publicKey()
+			pub.mlkem = deterministicEncapsulator{pub.mlkem, sequence(0x41, 32)}
+
+			sharedKey, ciphertext, err := pub.encapsulateHybrid(alice)
+			if err != nil {
+				t.Fatalf("encapsulateHybrid failed: %v", err)
+			}
+			want := mustHex(t, tc.want)
+			if !bytes.Equal(sharedKey, want) {
+				t.Errorf("%s: got shared key %x, want %x", tc.algorithm, sharedKey, want)
+			}
+
+			// The X25519 ciphertext is Alice's public key
+			alicePublic := mustHex(t, "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+			if !bytes.HasSuffix(ciphertext, alicePublic) {
+				t.Errorf("%s: ciphertext does not end with the ephemeral public key", tc.algorithm)
+			}
+
+			decapsulated, err := priv.decapsulate(ciphertext)
+			if err != nil {
+				t.Fatalf("decapsulate failed: %v", err)
+			}
+			if !bytes.Equal(decapsulated, want) {
+				t.Errorf("%s: got decapsulated key %x, want %x", tc.algorithm, decapsulated, want)
+			}
+		}
+	})
+}
+
+// TestKyberEnvelope tests hybrid KEM encryption in the envelope format
+func TestKyberEnvelope(t *testing.T) {
+	service := NewEncryptionService(&config.Config{})
+	plaintext := []byte("post-quantum secret")
+
+	for _, algorithm := range []string{KEMAlgorithmX25519MLKEM768, KEMAlgorithmX25519MLKEM1024} {
+		t.Run(algorithm, func(t *testing.T) {
+			privateKey, publicKey, err := service.GenerateKyberKeyPair(algorithm)
+			if err != nil {
+				t.Fatalf("GenerateKyberKeyPair failed: %v", err)
+			}
+			ciphertext, err := service.EncryptKyber(plaintext, publicKey)
+			if err != nil {
+				t.Fatalf("EncryptKyber failed: %v", err)
+			}
+
+			envelope, _, err := ParseEnvelope(ciphertext)
+			if err != nil {
+				t.Fatalf("ParseEnvelope failed: %v", err)
+			}
+			if envelope.Algorithm != algorithm {
+				t.Errorf("Got envelope algorithm %s, want %s", envelope.Algorithm, algorithm)
+			}
+
+			decrypted, err := service.DecryptKyber(ciphertext, privateKey)
+			if err != nil {
+				t.Fatalf("DecryptKyber failed: %v", err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Errorf("Got plaintext %q, want %q", decrypted, plaintext)
+			}
+
+			// Any change to the header, KEM ciphertext or AEAD ciphertext fails decryption
+			for _, i := range []int{len(envelopeMagic) + 3, len(ciphertext) - len(plaintext) - 100, len(ciphertext) - 1} {
+				tampered := append([]byte(nil), ciphertext...)
+				tampered[i] ^= 1
+				if _, err := service.DecryptKyber(tampered, privateKey); err == nil {
+					t.Errorf("Decryption succeeded with byte %d modified", i)
+				}
+			}
+
+			otherKey, _, err := service.GenerateKyberKeyPair(algorithm)
+			if err != nil {
+				t.Fatalf("GenerateKyberKeyPair failed: %v", err)
+			}
+			if _, err := service.DecryptKyber(ciphertext, otherKey); !errors.Is(err, ErrInvalidKEMKey) {
+				t.Errorf("Expected ErrInvalidKEMKey for another key, got %v", err)
+			}
+		})
+	}
+
+	t.Run("RejectsMLKEMKeys", func(t *testing.T) {
+		_, publicKey, err := service.GenerateKyberKeyPair(KEMAlgorithmMLKEM768)
+		if err != nil {
+			t.Fatalf("GenerateKyberKeyPair failed: %v", err)
+		}
+		if _, err := service.EncryptKyber(plaintext, publicKey); !errors.Is(err, ErrInvalidKEMKey) {
+			t.Errorf("Expected ErrInvalidKEMKey, got %v", err)
+		}
+	})
+}
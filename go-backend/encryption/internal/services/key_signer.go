@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrSigningKeyNotFound is returned when the key management service does not know a signing key or version
+	ErrSigningKeyNotFound = errors.New("signing key not found")
+	// ErrInvalidDigest is returned when a digest does not match the size of the signing key's hash
+	ErrInvalidDigest = errors.New("invalid digest")
+)
+
+// keyManagementSigner signs digests by calling the key management service
+type keyManagementSigner struct {
+	keyManagementClient
+}
+
+// NewKeyManagementSigner creates a key signer that calls the key management service's
+// POST /api/v1/keymgmt/signing endpoints at baseURL
+func NewKeyManagementSigner(baseURL string, client *http.Client) KeySigner {
+	return &keyManagementSigner{
+		keyManagementClient: keyManagementClient{
+			baseURL: strings.TrimRight(baseURL, "/"),
+			client:  client,
+		},
+	}
+}
+
+// signingKeyRequest is the request body for POST /api/v1/keymgmt/signing/get
+type signingKeyRequest struct {
+	KeyID string `json:"key_id"`
+}
+
+// GetSigningKey returns the algorithm and hash of a signing key
+func (s *keyManagementSigner) GetSigningKey(ctx context.Context, keyID string) (*SigningKeyInfo, error) {
+	var resp SigningKeyInfo
+	if err := s.post(ctx, "/get", signingKeyRequest{KeyID: keyID}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// signDigestRequest is the request body for POST /api/v1/keymgmt/signing/sign
+type signDigestRequest struct {
+	KeyID  string `json:"key_id"`
+	Digest string `json:"digest"`
+}
+
+// signDigestResponse is the response body of POST /api/v1/keymgmt/signing/sign
+type signDigestResponse struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Algorithm  string `json:"algorithm"`
+	Signature  string `json:"signature"`
+}
+
+// SignDigest signs a digest with the current version of a signing key
+func (s *keyManagementSigner) SignDigest(ctx context.Context, keyID string, digest []byte) (*Signature, error) {
+	var resp signDigestResponse
+	err := s.post(ctx, "/sign", signDigestRequest{
+		KeyID:  keyID,
+		Digest: base64.StdEncoding.EncodeToString(digest),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(resp.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature from key management service: %w", err)
+	}
+	return &Signature{
+		KeyID:      resp.KeyID,
+		KeyVersion: resp.KeyVersion,
+		Algorithm:  resp.Algorithm,
+		Signature:  signature,
+	}, nil
+}
+
+// verifyDigestRequest is the request body for POST /api/v1/keymgmt/signing/verify
+type verifyDigestRequest struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Digest     string `json:"digest"`
+	Signature  string `json:"signature"`
+}
+
+// verifyDigestResponse is the response body of POST /api/v1/keymgmt/signing/verify
+type verifyDigestResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// VerifyDigest reports whether signature is a valid signature of digest by a version of
+// a signing key
+func (s *keyManagementSigner) VerifyDigest(ctx context.Context, keyID string, keyVersion int, digest, signature []byte) (bool, error) {
+	var resp verifyDigestResponse
+	err := s.post(ctx, "/verify", verifyDigestRequest{
+		KeyID:      keyID,
+		KeyVersion: keyVersion,
+		Digest:     base64.StdEncoding.EncodeToString(digest),
+		Signature:  base64.StdEncoding.EncodeToString(signature),
+	}, &resp)
+	if err != nil {
+		return false, err
+	}
+	return resp.Valid, nil
+}
+
+// post sends a request to a signing endpoint and decodes the response
+func (s *keyManagementSigner) post(ctx context.Context, path string, body, result interface{}) error {
+	err := s.keyManagementClient.post(ctx, "/signing"+path, body, result)
+	var kmErr *keyManagementError
+	if errors.As(err, &kmErr) {
+		switch kmErr.status {
+		case http.StatusNotFound:
+			return ErrSigningKeyNotFound
+		case http.StatusBadRequest:
+			return kmErr.as(ErrInvalidDigest)
+		}
+	}
+	return err
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrKeyNotFound is returned when the key management service does not know a key-encryption key or version
+	ErrKeyNotFound = errors.New("key-encryption key not found")
+	// ErrUnwrapFailed is returned when the key management service cannot unwrap a data key
+	ErrUnwrapFailed = errors.New("failed to unwrap data key")
+	// ErrKeyManagementUnavailable is returned when the key management service's KEK, signing
+	// or MAC routes cannot be called, because TLS is off or the service does not serve them
+	ErrKeyManagementUnavailable = errors.New("key management operations are unavailable")
+)
+
+// keyManagementClient calls the key management service's JSON endpoints. The key management
+// service only serves them to services with a client certificate, so a nil client, used when
+// TLS is off, fails every call with ErrKeyManagementUnavailable.
+type keyManagementClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// keyManagementError is an unsuccessful response from the key management service
+type keyManagementError struct {
+	status  int
+	message string
+	err     error // the sentinel error the response stands for, if any
+}
+
+func (e *keyManagementError) Error() string {
+	if e.err != nil {
+		return e.message
+	}
+	return fmt.Sprintf("key management service returned status %d: %s", e.status, e.message)
+}
+
+func (e *keyManagementError) Unwrap() error {
+	return e.err
+}
+
+// as returns the error as an instance of a sentinel error, with the message of the key
+// management service
+func (e *keyManagementError) as(err error) error {
+	return &keyManagementError{status: e.status, message: e.message, err: err}
+}
+
+// keyManagementWrapper wraps data keys by calling the key management service
+type keyManagementWrapper struct {
+	keyManagementClient
+}
+
+// NewKeyManagementWrapper creates a key wrapper that calls the key management service's
+// POST /api/v1/keymgmt/kek/wrap and /unwrap endpoints at baseURL
+func NewKeyManagementWrapper(baseURL string, client *http.Client) KeyWrapper {
+	return &keyManagementWrapper{
+		keyManagementClient: keyManagementClient{
+			baseURL: strings.TrimRight(baseURL, "/"),
+			client:  client,
+		},
+	}
+}
+
+// wrapKeyRequest is the request body for POST /api/v1/keymgmt/kek/wrap
+type wrapKeyRequest struct {
+	KeyID   string `json:"key_id"`
+	DataKey string `json:"data_key"`
+}
+
+// wrapKeyResponse is the response body of POST /api/v1/keymgmt/kek/wrap
+type wrapKeyResponse struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	WrappedKey string `json:"wrapped_key"`
+}
+
+// WrapKey wraps a data key with the current version of a key-encryption key
+func (w *keyManagementWrapper) WrapKey(ctx context.Context, keyID string, dataKey []byte) (*WrappedKey, error) {
+	var resp wrapKeyResponse
+	err := w.post(ctx, "/wrap", wrapKeyRequest{
+		KeyID:   keyID,
+		DataKey: base64.StdEncoding.EncodeToString(dataKey),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(resp.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped key from key management service: %w", err)
+	}
+	return &WrappedKey{
+		KeyID:      resp.KeyID,
+		KeyVersion: resp.KeyVersion,
+		WrappedKey: wrappedKey,
+	}, nil
+}
+
+// unwrapKeyRequest is the request body for POST /api/v1/keymgmt/kek/unwrap
+type unwrapKeyRequest struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	WrappedKey string `json:"wrapped_key"`
+}
+
+// unwrapKeyResponse is the response body of POST /api/v1/keymgmt/kek/unwrap
+type unwrapKeyResponse struct {
+	DataKey string `json:"data_key"`
+}
+
+// UnwrapKey unwraps a data key wrapped by a version of a key-encryption key
+func (w *keyManagementWrapper) UnwrapKey(ctx context.Context, keyID string, keyVersion int, wrappedKey []byte) ([]byte, error) {
+	var resp unwrapKeyResponse
+	err := w.post(ctx, "/unwrap", unwrapKeyRequest{
+		KeyID:      keyID,
+		KeyVersion: keyVersion,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrappedKey),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := base64.StdEncoding.DecodeString(resp.DataKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key from key management service: %w", err)
+	}
+	return dataKey, nil
+}
+
+// post sends a request to a KEK endpoint and decodes the response
+func (w *keyManagementWrapper) post(ctx context.Context, path string, body, result interface{}) error {
+	err := w.keyManagementClient.post(ctx, "/kek"+path, body, result)
+	var kmErr *keyManagementError
+	if errors.As(err, &kmErr) {
+		switch {
+		case kmErr.status == http.StatusNotFound:
+			return ErrKeyNotFound
+		case kmErr.status == http.StatusBadRequest && path == "/unwrap":
+			return ErrUnwrapFailed
+		}
+	}
+	return err
+}
+
+// post sends a request to a key management endpoint and decodes the response. Other
+// statuses than 200 are returned as a *keyManagementError.
+func (c *keyManagementClient) post(ctx context.Context, path string, body, result interface{}) error {
+	if c.client == nil {
+		return fmt.Errorf("%w: they need TLS_MODE tls or mtls", ErrKeyManagementUnavailable)
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v1/keymgmt"+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("key management request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	// Error responses are {"error": message}, but may come from a proxy instead
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &errResp) != nil || errResp.Error == "" {
+		// The key management service answers unknown keys with a JSON error, so a bare 404
+		// means it does not serve the route at all
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%w: key management service does not serve %s", ErrKeyManagementUnavailable, path)
+		}
+		return &keyManagementError{status: resp.StatusCode, message: string(bytes.TrimSpace(data))}
+	}
+	return &keyManagementError{status: resp.StatusCode, message: errResp.Error}
+}
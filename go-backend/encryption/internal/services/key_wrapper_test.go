@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestKeyManagementWrapper tests how responses of the key management service map to errors
+func TestKeyManagementWrapper(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("TLSOff", func(t *testing.T) {
+		wrapper := NewKeyManagementWrapper("http://keymgmt.invalid", nil)
+		_, err := wrapper.WrapKey(ctx, "kek-payments", sequence(0, 32))
+		if !errors.Is(err, ErrKeyManagementUnavailable) {
+			t.Errorf("Expected ErrKeyManagementUnavailable, got %v", err)
+		}
+	})
+
+	t.Run("MissingRoute", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		wrapper := NewKeyManagementWrapper(server.URL, server.Client())
+		_, err := wrapper.WrapKey(ctx, "kek-payments", sequence(0, 32))
+		if !errors.Is(err, ErrKeyManagementUnavailable) {
+			t.Errorf("Expected ErrKeyManagementUnavailable, got %v", err)
+		}
+		if errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Expected a missing route not to be reported as a missing key, got %v", err)
+		}
+	})
+
+	t.Run("UnknownKey", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"key not found"}`))
+		}))
+		defer server.Close()
+
+		wrapper := NewKeyManagementWrapper(server.URL, server.Client())
+		_, err := wrapper.UnwrapKey(ctx, "kek-payments", 1, sequence(0, 40))
+		if !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("Expected ErrKeyNotFound, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrMACKeyNotFound is returned when the key management service does not know a MAC key or version
+	ErrMACKeyNotFound = errors.New("MAC key not found")
+	// ErrInvalidTagLength is returned when a tag does not have the MAC key's tag length
+	ErrInvalidTagLength = errors.New("invalid tag length")
+)
+
+// keyManagementMACService computes and verifies MACs by calling the key management
+// service, which holds the MAC keys
+type keyManagementMACService struct {
+	keyManagementClient
+}
+
+// NewKeyManagementMACService creates a MAC service that calls the key management
+// service's POST /api/v1/keymgmt/mac/generate and /verify endpoints at baseURL
+func NewKeyManagementMACService(baseURL string, client *http.Client) MACService {
+	return &keyManagementMACService{
+		keyManagementClient: keyManagementClient{
+			baseURL: strings.TrimRight(baseURL, "/"),
+			client:  client,
+		},
+	}
+}
+
+// generateMACRequest is the request body for POST /api/v1/keymgmt/mac/generate
+type generateMACRequest struct {
+	KeyID   string `json:"key_id"`
+	Message string `json:"message"`
+}
+
+// generateMACResponse is the response body of POST /api/v1/keymgmt/mac/generate
+type generateMACResponse struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Algorithm  string `json:"algorithm"`
+	Tag        string `json:"tag"`
+}
+
+// GenerateMAC computes the tag of a message with the current version of a MAC key
+func (s *keyManagementMACService) GenerateMAC(ctx context.Context, keyID string, message []byte) (*MAC, error) {
+	var resp generateMACResponse
+	err := s.post(ctx, "/generate", generateMACRequest{
+		KeyID:   keyID,
+		Message: base64.StdEncoding.EncodeToString(message),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := base64.StdEncoding.DecodeString(resp.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag from key management service: %w", err)
+	}
+	return &MAC{
+		KeyID:      resp.KeyID,
+		KeyVersion: resp.KeyVersion,
+		Algorithm:  resp.Algorithm,
+		Tag:        tag,
+	}, nil
+}
+
+// verifyMACRequest is the request body for POST /api/v1/keymgmt/mac/verify
+type verifyMACRequest struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Message    string `json:"message"`
+	Tag        string `json:"tag"`
+}
+
+// verifyMACResponse is the response body of POST /api/v1/keymgmt/mac/verify
+type verifyMACResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// VerifyMAC reports whether tag is the tag of message under a version of a MAC key. The
+// key management service compares tags in constant time.
+func (s *keyManagementMACService) VerifyMAC(ctx context.Context, keyID string, keyVersion int, message, tag []byte) (bool, error) {
+	var resp verifyMACResponse
+	err := s.post(ctx, "/verify", verifyMACRequest{
+		KeyID:      keyID,
+		KeyVersion: keyVersion,
+		Message:    base64.StdEncoding.EncodeToString(message),
+		Tag:        base64.StdEncoding.EncodeToString(tag),
+	}, &resp)
+	if err != nil {
+		return false, err
+	}
+	return resp.Valid, nil
+}
+
+// post sends a request to a MAC endpoint and decodes the response
+func (s *keyManagementMACService) post(ctx context.Context, path string, body, result interface{}) error {
+	err := s.keyManagementClient.post(ctx, "/mac"+path, body, result)
+	var kmErr *keyManagementError
+	if errors.As(err, &kmErr) {
+		switch kmErr.status {
+		case http.StatusNotFound:
+			return ErrMACKeyNotFound
+		case http.StatusBadRequest:
+			return kmErr.as(ErrInvalidTagLength)
+		}
+	}
+	return err
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// RSA key formats. PKCS#1 holds a private or public key, PKCS#8 a private key and SPKI
+// a public key; exporting a private key as SPKI exports its public key.
+const (
+	RSAKeyFormatPKCS1 = "pkcs1"
+	RSAKeyFormatPKCS8 = "pkcs8"
+	RSAKeyFormatSPKI  = "spki"
+	RSAKeyFormatJWK   = "jwk"
+)
+
+// Encodings of the PKCS#1, PKCS#8 and SPKI formats
+const (
+	KeyEncodingPEM = "pem"
+	KeyEncodingDER = "der"
+)
+
+// minRSAKeySize is the smallest RSA modulus accepted, in bits
+const minRSAKeySize = 2048
+
+var (
+	// ErrInvalidRSAKey is returned for keys that cannot be parsed or are too weak
+	ErrInvalidRSAKey = errors.New("invalid RSA key")
+	// ErrUnsupportedKeyFormat is returned for unknown key formats and encodings
+	ErrUnsupportedKeyFormat = errors.New("unsupported key format")
+)
+
+// rsaJWK is an RSA key in JSON Web Key form (RFC 7518 section 6.3). Numbers are
+// unpadded base64url big-endian integers.
+type rsaJWK struct {
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	DP  string `json:"dp,omitempty"`
+	DQ  string `json:"dq,omitempty"`
+	QI  string `json:"qi,omitempty"`
+}
+
+// parseRSAKey parses an RSA key given as PEM (PKCS#1, PKCS#8 or SPKI), DER or JWK. The
+// private key is nil when data holds a public key.
+func parseRSAKey(data []byte) (*rsa.PrivateKey, *rsa.PublicKey, error) {
+	data = bytes.TrimSpace(data)
+
+	var key interface{}
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("{")):
+		key, err = parseRSAJWK(data)
+	case bytes.HasPrefix(data, []byte("-----BEGIN")):
+		key, err = parseRSAPEM(data)
+	default:
+		key, err = parseRSADER(data)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var priv *rsa.PrivateKey
+	var pub *rsa.PublicKey
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		priv, pub = k, &k.PublicKey
+	case *rsa.PublicKey:
+		pub = k
+	default:
+		return nil, nil, fmt.Errorf("%w: not an RSA key", ErrInvalidRSAKey)
+	}
+	if pub.N.BitLen() < minRSAKeySize {
+		return nil, nil, fmt.Errorf("%w: keys must be at least %d bits", ErrInvalidRSAKey, minRSAKeySize)
+	}
+	return priv, pub, nil
+}
+
+// parseRSAPEM parses a PEM block holding a PKCS#1, PKCS#8 or SPKI key
+func parseRSAPEM(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: malformed PEM", ErrInvalidRSAKey)
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: unsupported PEM type %q", ErrInvalidRSAKey, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRSAKey, err)
+	}
+	return key, nil
+}
+
+// parseRSADER parses a DER key, trying each format in turn
+func parseRSADER(data []byte) (interface{}, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(data); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(data); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKIXPublicKey(data); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(data); err == nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: not a PEM, DER or JWK key", ErrInvalidRSAKey)
+}
+
+// parseRSAJWK parses an RSA JWK. Private keys must include their primes.
+func parseRSAJWK(data []byte) (interface{}, error) {
+	var jwk rsaJWK
+	if err := json.Unmarshal(data, &jwk); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRSAKey, err)
+	}
+	if jwk.Kty != "RSA" {
+		return nil, fmt.Errorf("%w: JWK kty must be RSA", ErrInvalidRSAKey)
+	}
+
+	n, errN := decodeJWKInt(jwk.N)
+	e, errE := decodeJWKInt(jwk.E)
+	if errN != nil || errE != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("%w: malformed JWK n or e", ErrInvalidRSAKey)
+	}
+	pub := &rsa.PublicKey{N: n, E: int(e.Int64())}
+	if jwk.D == "" {
+		return pub, nil
+	}
+
+	if jwk.P == "" || jwk.Q == "" {
+		return nil, fmt.Errorf("%w: private JWK must include p and q", ErrInvalidRSAKey)
+	}
+	d, errD := decodeJWKInt(jwk.D)
+	p, errP := decodeJWKInt(jwk.P)
+	q, errQ := decodeJWKInt(jwk.Q)
+	if errD != nil || errP != nil || errQ != nil {
+		return nil, fmt.Errorf("%w: malformed JWK private key", ErrInvalidRSAKey)
+	}
+
+	priv := &rsa.PrivateKey{PublicKey: *pub, D: d, Primes: []*big.Int{p, q}}
+	if err := priv.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRSAKey, err)
+	}
+	priv.Precompute()
+	return priv, nil
+}
+
+// encodeRSAKey encodes a key in the given format and encoding. priv may be nil.
+func encodeRSAKey(priv *rsa.PrivateKey, pub *rsa.PublicKey, format, encoding string) ([]byte, error) {
+	if format == RSAKeyFormatJWK {
+		return encodeRSAJWK(priv, pub)
+	}
+	if encoding == "" {
+		encoding = KeyEncodingPEM
+	}
+	if encoding != KeyEncodingPEM && encoding != KeyEncodingDER {
+		return nil, fmt.Errorf("%w: encoding %q", ErrUnsupportedKeyFormat, encoding)
+	}
+
+	var der []byte
+	var pemType string
+	var err error
+	switch {
+	case format == RSAKeyFormatPKCS1 && priv != nil:
+		der, pemType = x509.MarshalPKCS1PrivateKey(priv), "RSA PRIVATE KEY"
+	case format == RSAKeyFormatPKCS1:
+		der, pemType = x509.MarshalPKCS1PublicKey(pub), "RSA PUBLIC KEY"
+	case format == RSAKeyFormatPKCS8 && priv != nil:
+		der, err = x509.MarshalPKCS8PrivateKey(priv)
+		pemType = "PRIVATE KEY"
+	case format == RSAKeyFormatPKCS8:
+		return nil, fmt.Errorf("%w: PKCS#8 holds private keys; use spki for public keys", ErrUnsupportedKeyFormat)
+	case format == RSAKeyFormatSPKI:
+		der, err = x509.MarshalPKIXPublicKey(pub)
+		pemType = "PUBLIC KEY"
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedKeyFormat, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if encoding == KeyEncodingDER {
+		return der, nil
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der}), nil
+}
+
+// encodeRSAJWK encodes a key as a JWK. Only two-prime private keys can be encoded.
+func encodeRSAJWK(priv *rsa.PrivateKey, pub *rsa.PublicKey) ([]byte, error) {
+	jwk := rsaJWK{
+		Kty: "RSA",
+		N:   encodeJWKInt(pub.N),
+		E:   encodeJWKInt(big.NewInt(int64(pub.E))),
+	}
+	if priv != nil {
+		if len(priv.Primes) != 2 {
+			return nil, fmt.Errorf("%w: multi-prime keys cannot be exported as JWK", ErrUnsupportedKeyFormat)
+		}
+		priv.Precompute()
+		jwk.D = encodeJWKInt(priv.D)
+		jwk.P = encodeJWKInt(priv.Primes[0])
+		jwk.Q = encodeJWKInt(priv.Primes[1])
+		jwk.DP = encodeJWKInt(priv.Precomputed.Dp)
+		jwk.DQ = encodeJWKInt(priv.Precomputed.Dq)
+		jwk.QI = encodeJWKInt(priv.Precomputed.Qinv)
+	}
+	return json.Marshal(jwk)
+}
+
+// decodeJWKInt decodes a base64url integer, tolerating padding
+func decodeJWKInt(value string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// encodeJWKInt encodes an integer as unpadded base64url
+func encodeJWKInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
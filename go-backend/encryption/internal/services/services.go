@@ -1,37 +1,48 @@
 package services
 
 import (
-	"github.com/cryptofortress/backend/encryption/internal/config"
+	"context"
+	"io"
 )
 
 // Services holds references to all encryption services
 type Services struct {
 	Encryption EncryptionService
 	FPE        FPEService
+	Envelope   EnvelopeService
+	Signing    SigningService
+	MAC        MACService
+	Audit      AuditRecorder
 }
 
 // EncryptionService defines the interface for encryption operations
 type EncryptionService interface {
-	// Symmetric encryption
-	EncryptAES256GCM(plaintext, key, nonce []byte) ([]byte, error)
-	DecryptAES256GCM(ciphertext, key, nonce []byte) ([]byte, error)
+	// Symmetric encryption; additionalData is authenticated but not encrypted
+	EncryptAES256GCM(plaintext, key, nonce, additionalData []byte) ([]byte, error)
+	DecryptAES256GCM(ciphertext, key, nonce, additionalData []byte) ([]byte, error)
 	
 	// Stream cipher encryption
-	EncryptChaCha20Poly1305(plaintext, key, nonce []byte) ([]byte, error)
-	DecryptChaCha20Poly1305(ciphertext, key, nonce []byte) ([]byte, error)
+	EncryptChaCha20Poly1305(plaintext, key, nonce, additionalData []byte) ([]byte, error)
+	DecryptChaCha20Poly1305(ciphertext, key, nonce, additionalData []byte) ([]byte, error)
 	
-	// Asymmetric encryption
-	EncryptRSAOAEP(plaintext, publicKey []byte) ([]byte, error)
-	DecryptRSAOAEP(ciphertext, privateKey []byte) ([]byte, error)
+	// Asymmetric encryption. Keys are PEM (PKCS#1, PKCS#8 or SPKI), DER or JWK, and hash
+	// is SHA-256 (the default), SHA-384 or SHA-512.
+	EncryptRSAOAEP(plaintext, publicKey []byte, hash string) ([]byte, error)
+	DecryptRSAOAEP(ciphertext, privateKey []byte, hash string) ([]byte, error)
 	
-	// Quantum-resistant cryptography
+	// Quantum-resistant cryptography. Keys are ML-KEM-768 or ML-KEM-1024 (FIPS 203), or
+	// hybrid X25519-ML-KEM-768 or X25519-ML-KEM-1024; the algorithm follows from the
+	// public key or ciphertext size. Encryption needs a hybrid key and returns an envelope.
 	EncryptKyber(plaintext, publicKey []byte) ([]byte, error)
 	DecryptKyber(ciphertext, privateKey []byte) ([]byte, error)
+	EncapsulateKyber(publicKey []byte) (sharedKey, ciphertext []byte, err error)
+	DecapsulateKyber(ciphertext, privateKey []byte) (sharedKey []byte, err error)
 	
 	// Key generation
 	GenerateAESKey() ([]byte, error)
 	GenerateRSAKeyPair() (privateKey, publicKey []byte, err error)
-	GenerateKyberKeyPair() (privateKey, publicKey []byte, err error)
+	GenerateKyberKeyPair(algorithm string) (privateKey, publicKey []byte, err error)
+	ConvertRSAKey(key []byte, format, encoding string) ([]byte, error)
 	
 	// HSM integration
 	EncryptWithHSM(plaintext []byte, keyID string) ([]byte, error)
@@ -43,14 +54,179 @@ type EncryptionService interface {
 
 // FPEService defines the interface for format-preserving encryption operations
 type FPEService interface {
-	// Format-preserving encryption
-	EncryptFPE(plaintext string, key []byte, tweak []byte) (string, error)
-	DecryptFPE(ciphertext string, key []byte, tweak []byte) (string, error)
+	// Format-preserving encryption with FF1 or FF3-1 (NIST SP 800-38G Rev. 1). The
+	// ciphertext has the length and alphabet of the plaintext. Keys are AES keys.
+	EncryptFPE(plaintext string, key []byte, tweak []byte, params FPEParams) (string, error)
+	DecryptFPE(ciphertext string, key []byte, tweak []byte, params FPEParams) (string, error)
 	
-	// Common data type encryption
+	// Template encryption encrypts the segments of a value that a template selects and
+	// keeps the rest, such as separators, a card's BIN or an email's domain
+	EncryptTemplate(name, plaintext string, key []byte, tweak []byte) (string, error)
+	DecryptTemplate(name, ciphertext string, key []byte, tweak []byte) (string, error)
+	
+	// Template management; the credit-card, credit-card-bin and ssn templates are built in
+	RegisterTemplate(template FPETemplate) error
+	ListTemplates() []FPETemplate
+	
+	// Common data type encryption with the built-in templates
 	EncryptCreditCard(cardNumber string, key []byte) (string, error)
 	DecryptCreditCard(encryptedCard string, key []byte) (string, error)
 	
 	EncryptSSN(ssn string, key []byte) (string, error)
 	DecryptSSN(encryptedSSN string, key []byte) (string, error)
-}
\ No newline at end of file
+}
+
+// FPEParams selects the algorithm and alphabet of format-preserving encryption.
+// Algorithm is FF1 (the default) or FF3-1. Alphabet lists the characters of the radix
+// in order and defaults to the decimal digits.
+type FPEParams struct {
+	Algorithm string
+	Alphabet  string
+}
+
+// FPETemplate describes a typed value for format-preserving encryption. Pattern must
+// match the whole value, and each segment names one of its capture groups. The
+// characters of the alphabet in all encrypted segments are encrypted together as one
+// string; everything else, including other characters in encrypted segments, is kept.
+// PostProcess, if set, restores a check digit that encryption would break.
+type FPETemplate struct {
+	Name        string       `json:"name"`
+	Pattern     string       `json:"pattern"`
+	Algorithm   string       `json:"algorithm,omitempty"`
+	Alphabet    string       `json:"alphabet,omitempty"`
+	Segments    []FPESegment `json:"segments"`
+	PostProcess string       `json:"post_process,omitempty"`
+	BuiltIn     bool         `json:"built_in"`
+}
+
+// FPESegment is a capture group of a template and whether it is encrypted or preserved
+type FPESegment struct {
+	Group  string `json:"group"`
+	Action string `json:"action"`
+}
+
+// EnvelopeService defines the interface for envelope encryption. Each message is
+// encrypted with a fresh data key, which is wrapped by a key-encryption key that never
+// leaves the key management service.
+type EnvelopeService interface {
+	// Encrypt binds the ciphertext to the associated data, which decryption must repeat
+	Encrypt(ctx context.Context, keyID, algorithm string, plaintext []byte, ad AssociatedData) (*Envelope, error)
+	
+	// Decrypt parses an envelope in the wire format and decrypts it with the algorithm
+	// and key version named in its header. The envelope is returned whenever it parses,
+	// even if decryption fails.
+	Decrypt(ctx context.Context, ciphertext []byte, ad AssociatedData) ([]byte, *Envelope, error)
+	
+	// EncryptStream encrypts src to dst as a streaming envelope: the header, then the
+	// ciphertext in segments, so that inputs of any size use constant memory
+	EncryptStream(ctx context.Context, keyID, algorithm string, dst io.Writer, src io.Reader, ad AssociatedData) (*Envelope, error)
+	
+	// DecryptStream decrypts a streaming envelope from src to dst. Plaintext is written
+	// as each segment authenticates, so if it fails, what was written must be discarded.
+	DecryptStream(ctx context.Context, dst io.Writer, src io.Reader, ad AssociatedData) (*Envelope, error)
+}
+
+// SigningService defines the interface for digital signatures with signing keys held by
+// the key management service. Signatures are detached and made over a digest computed
+// with the key's hash, so messages never leave this service.
+type SigningService interface {
+	// SignMessage and VerifyMessage hash the message as it is read, so messages of any
+	// size use constant memory
+	SignMessage(ctx context.Context, keyID string, message io.Reader) (*Signature, error)
+	VerifyMessage(ctx context.Context, keyID string, keyVersion int, message io.Reader, signature []byte) (bool, error)
+
+	// SignDigest and VerifyDigest take a digest the caller computed with the key's hash
+	SignDigest(ctx context.Context, keyID string, digest []byte) (*Signature, error)
+	VerifyDigest(ctx context.Context, keyID string, keyVersion int, digest, signature []byte) (bool, error)
+}
+
+// MACService defines the interface for message authentication codes with MAC keys held
+// by the key management service. Each key has a fixed algorithm and tag length.
+type MACService interface {
+	GenerateMAC(ctx context.Context, keyID string, message []byte) (*MAC, error)
+	VerifyMAC(ctx context.Context, keyID string, keyVersion int, message, tag []byte) (bool, error)
+}
+
+// AssociatedData is authenticated along with a message but not encrypted. Context is a
+// structured encryption context, such as a tenant or record ID, that is canonicalized
+// before use; Data is opaque. Neither is secret, and neither is stored in the envelope.
+type AssociatedData struct {
+	Context map[string]string
+	Data    []byte
+}
+
+// KeyWrapper wraps and unwraps data keys with a key-encryption key
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, keyID string, dataKey []byte) (*WrappedKey, error)
+	UnwrapKey(ctx context.Context, keyID string, keyVersion int, wrappedKey []byte) ([]byte, error)
+}
+
+// KeySigner signs and verifies digests with a signing key
+type KeySigner interface {
+	GetSigningKey(ctx context.Context, keyID string) (*SigningKeyInfo, error)
+	SignDigest(ctx context.Context, keyID string, digest []byte) (*Signature, error)
+	VerifyDigest(ctx context.Context, keyID string, keyVersion int, digest, signature []byte) (bool, error)
+}
+
+// AuditRecorder records cryptographic operations in the audit trail
+type AuditRecorder interface {
+	Record(ctx context.Context, event AuditEvent) error
+}
+
+// AuditEvent is a cryptographic operation to record. Its fields match the audit
+// service's events.
+type AuditEvent struct {
+	UserID      string
+	Action      string
+	Resource    string
+	IPAddress   string
+	UserAgent   string
+	Success     bool
+	Description string
+	Metadata    map[string]string
+}
+
+// Envelope is a message encrypted under a data key, together with the wrapped data key
+// and everything else needed to decrypt it. See envelope_format.go for the wire format.
+type Envelope struct {
+	KeyID      string
+	KeyVersion int
+	Algorithm  string
+	Streaming  bool // the ciphertext is a sequence of STREAM segments
+	WrappedKey []byte
+	Nonce      []byte // the nonce prefix for streaming envelopes
+	AADHash    []byte // SHA-256 of the canonical associated data, empty if there is none
+	Ciphertext []byte // empty for streaming envelopes, whose ciphertext follows the header
+}
+
+// WrappedKey is a data key encrypted under a version of a key-encryption key
+type WrappedKey struct {
+	KeyID      string
+	KeyVersion int
+	WrappedKey []byte
+}
+
+// SigningKeyInfo describes a signing key held by the key management service
+type SigningKeyInfo struct {
+	KeyID          string `json:"key_id"`
+	Algorithm      string `json:"algorithm"`
+	Hash           string `json:"hash"`
+	CurrentVersion int    `json:"current_version"`
+}
+
+// Signature is a detached signature by a version of a signing key
+type Signature struct {
+	KeyID      string
+	KeyVersion int
+	Algorithm  string
+	Hash       string
+	Signature  []byte
+}
+
+// MAC is a message authentication tag computed with a version of a MAC key
+type MAC struct {
+	KeyID      string
+	KeyVersion int
+	Algorithm  string
+	Tag        []byte
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/cryptofortress/backend/encryption/internal/config"
+)
+
+// signingHashes maps the hash names of signing keys to hash functions
+var signingHashes = map[string]crypto.Hash{
+	crypto.SHA256.String(): crypto.SHA256,
+	crypto.SHA384.String(): crypto.SHA384,
+	crypto.SHA512.String(): crypto.SHA512,
+}
+
+// signingServiceImpl implements the SigningService interface
+type signingServiceImpl struct {
+	config *config.Config
+	signer KeySigner
+	hashes sync.Map // key ID to crypto.Hash; a signing key's algorithm never changes
+}
+
+// NewSigningService creates a new instance of the signing service
+func NewSigningService(cfg *config.Config, signer KeySigner) SigningService {
+	return &signingServiceImpl{
+		config: cfg,
+		signer: signer,
+	}
+}
+
+// SignMessage hashes a message as it is read and signs the digest
+func (s *signingServiceImpl) SignMessage(ctx context.Context, keyID string, message io.Reader) (*Signature, error) {
+	hash, err := s.hash(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := digestMessage(hash, message)
+	if err != nil {
+		return nil, err
+	}
+	return s.signDigest(ctx, keyID, hash, digest)
+}
+
+// SignDigest signs a digest computed with the signing key's hash
+func (s *signingServiceImpl) SignDigest(ctx context.Context, keyID string, digest []byte) (*Signature, error) {
+	hash, err := s.hash(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(digest) != hash.Size() {
+		return nil, fmt.Errorf("%w: key %s signs %s digests of %d bytes", ErrInvalidDigest, keyID, hash, hash.Size())
+	}
+	return s.signDigest(ctx, keyID, hash, digest)
+}
+
+// VerifyMessage hashes a message as it is read and verifies a signature of the digest
+func (s *signingServiceImpl) VerifyMessage(ctx context.Context, keyID string, keyVersion int, message io.Reader, signature []byte) (bool, error) {
+	hash, err := s.hash(ctx, keyID)
+	if err != nil {
+		return false, err
+	}
+	digest, err := digestMessage(hash, message)
+	if err != nil {
+		return false, err
+	}
+	return s.signer.VerifyDigest(ctx, keyID, keyVersion, digest, signature)
+}
+
+// VerifyDigest verifies a signature of a digest computed with the signing key's hash
+func (s *signingServiceImpl) VerifyDigest(ctx context.Context, keyID string, keyVersion int, digest, signature []byte) (bool, error) {
+	hash, err := s.hash(ctx, keyID)
+	if err != nil {
+		return false, err
+	}
+	if len(digest) != hash.Size() {
+		return false, fmt.Errorf("%w: key %s signs %s digests of %d bytes", ErrInvalidDigest, keyID, hash, hash.Size())
+	}
+	return s.signer.VerifyDigest(ctx, keyID, keyVersion, digest, signature)
+}
+
+// signDigest signs a digest and records the hash in the signature
+func (s *signingServiceImpl) signDigest(ctx context.Context, keyID string, hash crypto.Hash, digest []byte) (*Signature, error) {
+	signature, err := s.signer.SignDigest(ctx, keyID, digest)
+	if err != nil {
+		return nil, err
+	}
+	signature.Hash = hash.String()
+	return signature, nil
+}
+
+// hash returns the hash function of a signing key, asking the key management service
+// the first time
+func (s *signingServiceImpl) hash(ctx context.Context, keyID string) (crypto.Hash, error) {
+	if hash, ok := s.hashes.Load(keyID); ok {
+		return hash.(crypto.Hash), nil
+	}
+
+	info, err := s.signer.GetSigningKey(ctx, keyID)
+	if err != nil {
+		return 0, err
+	}
+	hash, ok := signingHashes[info.Hash]
+	if !ok {
+		return 0, fmt.Errorf("signing key %s uses unsupported hash %q", keyID, info.Hash)
+	}
+	s.hashes.Store(keyID, hash)
+	return hash, nil
+}
+
+// digestMessage hashes a message in constant memory
+func digestMessage(hash crypto.Hash, message io.Reader) ([]byte, error) {
+	h := hash.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, fmt.Errorf("failed to read message: %w", err)
+	}
+	return h.Sum(nil), nil
+}
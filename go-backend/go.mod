@@ -1,6 +1,6 @@
 module github.com/cryptofortress/backend
 
-go 1.21
+go 1.27
 
 require (
 	github.com/golang-jwt/jwt/v5 v5.2.0
@@ -9,6 +9,7 @@ require (
 	github.com/crewjam/saml v0.4.11
 	golang.org/x/crypto v0.14.0
 	google.golang.org/grpc v1.59.0
+	google.golang.org/protobuf v1.31.0
 	github.com/prometheus/client_golang v1.16.0
 	github.com/gin-gonic/gin v1.9.1
 	github.com/go-playground/validator/v10 v10.15.2
@@ -16,5 +17,6 @@ require (
 	github.com/stretchr/testify v1.8.4
 	github.com/hashicorp/vault/api v1.9.2
 	github.com/aws/aws-sdk-go-v2 v1.21.0
+	gopkg.in/yaml.v3 v3.0.1
 	github.com/microsoft/kiota-go v0.0.0-20230920120005-0b89493a29c9
 )
\ No newline at end of file
@@ -5,6 +5,9 @@ import (
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 )
 
 // Config holds the configuration for the key management service
@@ -18,6 +21,31 @@ type Config struct {
 	ShamirShares      int
 	ReplicationEnabled bool
 	ReplicationRegions []string
+	AuthGRPCURL        string // address of the auth service's gRPC API, which validates access tokens
+	StepUpMaxAge       int    // in minutes
+	RateLimitAPI       ratelimit.Limit // per calling service
+	RateLimitShamir    ratelimit.Limit // secret sharing endpoints, per client IP
+	RateLimitKEK       ratelimit.Limit // data key wrapping, per client IP
+	RateLimitSigning   ratelimit.Limit // digest signing and verification, per client IP
+	RateLimitMAC       ratelimit.Limit // MAC generation and verification, per client IP
+	RateLimitCA        ratelimit.Limit // certificate issuance, per client IP
+	TrustedProxies     []string        // proxy IPs or CIDRs whose X-Forwarded-For is believed
+
+	// Service-to-service TLS and the internal CA
+	TLSMode           string // "off", "tls" or "mtls"
+	TLSTrustDomain    string
+	CARootCertFile    string
+	CARootKeyFile     string
+	CACertTTL         int               // in hours
+	CABootstrapTokens map[string]string // service name to bootstrap token
+	KeyMaterialPeers  []string          // service IDs allowed to call routes that expose key material
+	KEKPeers          []string          // service IDs allowed to wrap and unwrap data keys
+	SigningPeers      []string          // service IDs allowed to sign and verify digests
+	MACPeers          []string          // service IDs allowed to generate and verify MACs
+
+	// Graceful shutdown
+	ShutdownTimeout int // in seconds, covering the whole shutdown including the drain delay
+	ShutdownDelay   int // in seconds, between turning not ready and closing listeners
 }
 
 // Load reads configuration from environment variables
@@ -44,6 +72,114 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid REPLICATION_ENABLED: %v", err)
 	}
 	
+	stepUpMaxAge, err := strconv.Atoi(getEnv("STEP_UP_MAX_AGE", "5"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid STEP_UP_MAX_AGE: %v", err)
+	}
+	
+	rateLimitAPI, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_API", "300/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_API: %v", err)
+	}
+	
+	rateLimitShamir, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_SHAMIR", "10/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_SHAMIR: %v", err)
+	}
+	
+	rateLimitKEK, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_KEK", "6000/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_KEK: %v", err)
+	}
+	
+	rateLimitSigning, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_SIGNING", "6000/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_SIGNING: %v", err)
+	}
+	
+	rateLimitMAC, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_MAC", "6000/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_MAC: %v", err)
+	}
+	
+	rateLimitCA, err := ratelimit.ParseLimit(getEnv("RATE_LIMIT_CA", "10/m"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid RATE_LIMIT_CA: %v", err)
+	}
+	
+	shutdownTimeout, err := strconv.Atoi(getEnv("SHUTDOWN_TIMEOUT", "30")) // 30 seconds default
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %v", err)
+	}
+	
+	shutdownDelay, err := strconv.Atoi(getEnv("SHUTDOWN_DELAY", "5")) // 5 seconds default
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_DELAY: %v", err)
+	}
+	if shutdownDelay < 0 || shutdownDelay >= shutdownTimeout {
+		return nil, fmt.Errorf("SHUTDOWN_DELAY must be at least 0 and less than SHUTDOWN_TIMEOUT")
+	}
+	
+	var trustedProxies []string
+	if proxies := getEnv("TRUSTED_PROXIES", ""); proxies != "" {
+		for _, proxy := range strings.Split(proxies, ",") {
+			trustedProxies = append(trustedProxies, strings.TrimSpace(proxy))
+		}
+	}
+	
+	tlsMode, err := mtls.ParseMode(getEnv("TLS_MODE", mtls.ModeOff))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS_MODE: %v", err)
+	}
+	trustDomain := getEnv("TLS_TRUST_DOMAIN", mtls.DefaultTrustDomain)
+	
+	caCertTTL, err := strconv.Atoi(getEnv("CA_CERT_TTL", "24")) // 24 hours default
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA_CERT_TTL: %v", err)
+	}
+	
+	caRootCertFile := os.Getenv("CA_ROOT_CERT_FILE")
+	caRootKeyFile := os.Getenv("CA_ROOT_KEY_FILE")
+	if (caRootCertFile == "") != (caRootKeyFile == "") {
+		return nil, fmt.Errorf("CA_ROOT_CERT_FILE and CA_ROOT_KEY_FILE must be set together")
+	}
+	
+	// Parse bootstrap tokens, given as service=token pairs
+	bootstrapTokens := make(map[string]string)
+	if tokensStr := getEnv("CA_BOOTSTRAP_TOKENS", ""); tokensStr != "" {
+		for _, pair := range strings.Split(tokensStr, ",") {
+			service, token, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || service == "" || token == "" {
+				return nil, fmt.Errorf("invalid CA_BOOTSTRAP_TOKENS: expected service=token pairs")
+			}
+			bootstrapTokens[service] = token
+		}
+	}
+	
+	// Parse the services allowed to read key material
+	var keyMaterialPeers []string
+	for _, service := range strings.Split(getEnv("KEY_MATERIAL_PEERS", "auth"), ",") {
+		keyMaterialPeers = append(keyMaterialPeers, mtls.ServiceID(trustDomain, strings.TrimSpace(service)))
+	}
+	
+	// Parse the services allowed to wrap and unwrap data keys
+	var kekPeers []string
+	for _, service := range strings.Split(getEnv("KEK_PEERS", "encryption"), ",") {
+		kekPeers = append(kekPeers, mtls.ServiceID(trustDomain, strings.TrimSpace(service)))
+	}
+	
+	// Parse the services allowed to sign and verify digests
+	var signingPeers []string
+	for _, service := range strings.Split(getEnv("SIGNING_PEERS", "encryption"), ",") {
+		signingPeers = append(signingPeers, mtls.ServiceID(trustDomain, strings.TrimSpace(service)))
+	}
+	
+	// Parse the services allowed to generate and verify MACs
+	var macPeers []string
+	for _, service := range strings.Split(getEnv("MAC_PEERS", "encryption"), ",") {
+		macPeers = append(macPeers, mtls.ServiceID(trustDomain, strings.TrimSpace(service)))
+	}
+	
 	// Parse replication regions
 	regionsStr := getEnv("REPLICATION_REGIONS", "")
 	var regions []string
@@ -64,6 +200,29 @@ func Load() (*Config, error) {
 		ShamirShares:       shares,
 		ReplicationEnabled: replicationEnabled,
 		ReplicationRegions: regions,
+		AuthGRPCURL:        getEnv("AUTH_GRPC_URL", "localhost:9080"),
+		StepUpMaxAge:       stepUpMaxAge,
+		RateLimitAPI:       rateLimitAPI,
+		RateLimitShamir:    rateLimitShamir,
+		RateLimitKEK:       rateLimitKEK,
+		RateLimitSigning:   rateLimitSigning,
+		RateLimitMAC:       rateLimitMAC,
+		RateLimitCA:        rateLimitCA,
+		TrustedProxies:     trustedProxies,
+
+		TLSMode:           tlsMode,
+		TLSTrustDomain:    trustDomain,
+		CARootCertFile:    caRootCertFile,
+		CARootKeyFile:     caRootKeyFile,
+		CACertTTL:         caCertTTL,
+		CABootstrapTokens: bootstrapTokens,
+		KeyMaterialPeers:  keyMaterialPeers,
+		KEKPeers:          kekPeers,
+		SigningPeers:      signingPeers,
+		MACPeers:          macPeers,
+
+		ShutdownTimeout: shutdownTimeout,
+		ShutdownDelay:   shutdownDelay,
 	}, nil
 }
 
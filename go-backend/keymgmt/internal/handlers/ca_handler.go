@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/cryptofortress/backend/keymgmt/internal/services"
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// CAHandler handles certificate issuance requests from other services
+type CAHandler struct {
+	caService services.CAService
+}
+
+// NewCAHandler creates a new CA handler
+func NewCAHandler(caService services.CAService) *CAHandler {
+	return &CAHandler{
+		caService: caService,
+	}
+}
+
+// IssueCertificateRequest represents the certificate issuance request payload
+type IssueCertificateRequest struct {
+	CSR string `json:"csr" binding:"required"`
+}
+
+// IssueCertificate handles certificate issuance requests. A service renewing its
+// certificate authenticates with its current one; a service without a certificate
+// presents its bootstrap token instead.
+func (h *CAHandler) IssueCertificate(c *gin.Context) {
+	var req IssueCertificateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Issue certificate
+	issued, err := h.caService.IssueCertificate(
+		[]byte(req.CSR),
+		mtls.RequestPeerID(c.Request),
+		c.GetHeader(mtls.HeaderBootstrapToken),
+	)
+	switch {
+	case errors.Is(err, services.ErrInvalidCSR):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrIssuanceNotAuthorized):
+		log.Warn().
+			Str("peer_id", mtls.RequestPeerID(c.Request)).
+			Str("client_ip", c.ClientIP()).
+			Msg("Rejected certificate request")
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue certificate"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, issued)
+}
+
+// GetRoots returns the PEM bundle of CA roots
+func (h *CAHandler) GetRoots(c *gin.Context) {
+	c.Data(http.StatusOK, "application/x-pem-file", h.caService.RootsPEM())
+}
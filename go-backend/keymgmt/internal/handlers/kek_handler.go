@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/cryptofortress/backend/keymgmt/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// KEKHandler handles key-encryption key HTTP requests
+type KEKHandler struct {
+	kekService services.KEKService
+}
+
+// NewKEKHandler creates a new KEK handler
+func NewKEKHandler(kekService services.KEKService) *KEKHandler {
+	return &KEKHandler{
+		kekService: kekService,
+	}
+}
+
+// CreateKEKRequest represents the KEK creation request payload
+type CreateKEKRequest struct {
+	Description string `json:"description"`
+}
+
+// CreateKEK handles KEK creation requests
+func (h *KEKHandler) CreateKEK(c *gin.Context) {
+	var req CreateKEKRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Create KEK
+	info, err := h.kekService.CreateKEK(req.Description)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create key-encryption key"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, info)
+}
+
+// ListKEKs handles KEK listing requests
+func (h *KEKHandler) ListKEKs(c *gin.Context) {
+	keks, err := h.kekService.ListKEKs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list key-encryption keys"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, gin.H{"keks": keks})
+}
+
+// KEKRequest represents a request that names a KEK
+type KEKRequest struct {
+	KeyID string `json:"key_id" binding:"required"`
+}
+
+// GetKEK handles KEK lookup requests
+func (h *KEKHandler) GetKEK(c *gin.Context) {
+	var req KEKRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get KEK
+	info, err := h.kekService.GetKEK(req.KeyID)
+	if err != nil {
+		respondKEKError(c, err, "Failed to get key-encryption key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, info)
+}
+
+// RotateKEK handles KEK rotation requests. Data keys wrapped by earlier versions can
+// still be unwrapped.
+func (h *KEKHandler) RotateKEK(c *gin.Context) {
+	var req KEKRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Rotate KEK
+	info, err := h.kekService.RotateKEK(req.KeyID)
+	if err != nil {
+		respondKEKError(c, err, "Failed to rotate key-encryption key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, info)
+}
+
+// WrapKeyRequest represents the data key wrapping request payload
+type WrapKeyRequest struct {
+	KeyID   string `json:"key_id" binding:"required"`
+	DataKey string `json:"data_key" binding:"required"`
+}
+
+// WrapKeyResponse represents the data key wrapping response payload
+type WrapKeyResponse struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	WrappedKey string `json:"wrapped_key"`
+}
+
+// WrapKey handles data key wrapping requests
+func (h *KEKHandler) WrapKey(c *gin.Context) {
+	var req WrapKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode data key from base64
+	dataKey, err := base64.StdEncoding.DecodeString(req.DataKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data key format"})
+		return
+	}
+
+	// Wrap data key
+	wrapped, err := h.kekService.WrapKey(req.KeyID, dataKey)
+	if err != nil {
+		respondKEKError(c, err, "Failed to wrap data key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, WrapKeyResponse{
+		KeyID:      wrapped.KeyID,
+		KeyVersion: wrapped.KeyVersion,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped.WrappedKey),
+	})
+}
+
+// UnwrapKeyRequest represents the data key unwrapping request payload
+type UnwrapKeyRequest struct {
+	KeyID      string `json:"key_id" binding:"required"`
+	KeyVersion int    `json:"key_version" binding:"required"`
+	WrappedKey string `json:"wrapped_key" binding:"required"`
+}
+
+// UnwrapKeyResponse represents the data key unwrapping response payload
+type UnwrapKeyResponse struct {
+	DataKey string `json:"data_key"`
+}
+
+// UnwrapKey handles data key unwrapping requests
+func (h *KEKHandler) UnwrapKey(c *gin.Context) {
+	var req UnwrapKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode wrapped key from base64
+	wrappedKey, err := base64.StdEncoding.DecodeString(req.WrappedKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wrapped key format"})
+		return
+	}
+
+	// Unwrap data key
+	dataKey, err := h.kekService.UnwrapKey(req.KeyID, req.KeyVersion, wrappedKey)
+	if err != nil {
+		respondKEKError(c, err, "Failed to unwrap data key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, UnwrapKeyResponse{
+		DataKey: base64.StdEncoding.EncodeToString(dataKey),
+	})
+}
+
+// respondKEKError maps KEK service errors to HTTP responses
+func respondKEKError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrKEKNotFound), errors.Is(err, services.ErrKEKVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidDataKey), errors.Is(err, services.ErrUnwrapFailed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/cryptofortress/backend/keymgmt/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// MACHandler handles MAC key HTTP requests
+type MACHandler struct {
+	macService services.MACService
+}
+
+// NewMACHandler creates a new MAC key handler
+func NewMACHandler(macService services.MACService) *MACHandler {
+	return &MACHandler{
+		macService: macService,
+	}
+}
+
+// CreateMACKeyRequest represents the MAC key creation request payload. TagLength is in
+// bytes and defaults to the algorithm's longest tag.
+type CreateMACKeyRequest struct {
+	Algorithm   string `json:"algorithm" binding:"required"`
+	TagLength   int    `json:"tag_length"`
+	Description string `json:"description"`
+}
+
+// CreateMACKey handles MAC key creation requests
+func (h *MACHandler) CreateMACKey(c *gin.Context) {
+	var req CreateMACKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Create MAC key
+	info, err := h.macService.CreateMACKey(req.Algorithm, req.TagLength, req.Description)
+	if err != nil {
+		respondMACError(c, err, "Failed to create MAC key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, info)
+}
+
+// ListMACKeys handles MAC key listing requests
+func (h *MACHandler) ListMACKeys(c *gin.Context) {
+	keys, err := h.macService.ListMACKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list MAC keys"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, gin.H{"mac_keys": keys})
+}
+
+// MACKeyRequest represents a request that names a MAC key
+type MACKeyRequest struct {
+	KeyID string `json:"key_id" binding:"required"`
+}
+
+// GetMACKey handles MAC key lookup requests
+func (h *MACHandler) GetMACKey(c *gin.Context) {
+	var req MACKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get MAC key
+	info, err := h.macService.GetMACKey(req.KeyID)
+	if err != nil {
+		respondMACError(c, err, "Failed to get MAC key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, info)
+}
+
+// RotateMACKey handles MAC key rotation requests. Tags computed with earlier versions can
+// still be verified.
+func (h *MACHandler) RotateMACKey(c *gin.Context) {
+	var req MACKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Rotate MAC key
+	info, err := h.macService.RotateMACKey(req.KeyID)
+	if err != nil {
+		respondMACError(c, err, "Failed to rotate MAC key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, info)
+}
+
+// GenerateMACRequest represents the MAC generation request payload. Message is base64.
+type GenerateMACRequest struct {
+	KeyID   string `json:"key_id" binding:"required"`
+	Message string `json:"message"`
+}
+
+// GenerateMACResponse represents the MAC generation response payload
+type GenerateMACResponse struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Algorithm  string `json:"algorithm"`
+	Tag        string `json:"tag"`
+}
+
+// GenerateMAC handles MAC generation requests
+func (h *MACHandler) GenerateMAC(c *gin.Context) {
+	var req GenerateMACRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode message from base64
+	message, err := base64.StdEncoding.DecodeString(req.Message)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message format"})
+		return
+	}
+
+	// Generate MAC
+	mac, err := h.macService.GenerateMAC(req.KeyID, message)
+	if err != nil {
+		respondMACError(c, err, "Failed to generate MAC")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, GenerateMACResponse{
+		KeyID:      mac.KeyID,
+		KeyVersion: mac.KeyVersion,
+		Algorithm:  mac.Algorithm,
+		Tag:        base64.StdEncoding.EncodeToString(mac.Tag),
+	})
+}
+
+// VerifyMACRequest represents the MAC verification request payload. Message and Tag are
+// base64.
+type VerifyMACRequest struct {
+	KeyID      string `json:"key_id" binding:"required"`
+	KeyVersion int    `json:"key_version" binding:"required"`
+	Message    string `json:"message"`
+	Tag        string `json:"tag" binding:"required"`
+}
+
+// VerifyMACResponse represents the MAC verification response payload
+type VerifyMACResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// VerifyMAC handles MAC verification requests
+func (h *MACHandler) VerifyMAC(c *gin.Context) {
+	var req VerifyMACRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode message and tag from base64
+	message, err := base64.StdEncoding.DecodeString(req.Message)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message format"})
+		return
+	}
+	tag, err := base64.StdEncoding.DecodeString(req.Tag)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag format"})
+		return
+	}
+
+	// Verify MAC
+	valid, err := h.macService.VerifyMAC(req.KeyID, req.KeyVersion, message, tag)
+	if err != nil {
+		respondMACError(c, err, "Failed to verify MAC")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, VerifyMACResponse{Valid: valid})
+}
+
+// respondMACError maps MAC service errors to HTTP responses
+func respondMACError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrMACKeyNotFound), errors.Is(err, services.ErrMACKeyVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUnsupportedMACAlgorithm), errors.Is(err, services.ErrInvalidTagLength):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -1,28 +1,128 @@
 package handlers
 
 import (
+	"time"
+
+	"github.com/cryptofortress/backend/auth/httpauth"
+	"github.com/cryptofortress/backend/keymgmt/internal/config"
 	"github.com/cryptofortress/backend/keymgmt/internal/services"
 	"github.com/cryptofortress/backend/keymgmt/internal/middleware"
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterRoutes sets up all the routes for the key management service
-func RegisterRoutes(router *gin.Engine, services *services.Services) {
+func RegisterRoutes(router *gin.Engine, services *services.Services, cfg *config.Config, limiter *ratelimit.Limiter, authenticator *httpauth.Authenticator) {
 	// Create handlers
 	keyHandler := NewKeyHandler(services.Key)
 	rotationHandler := NewRotationHandler(services.Rotation)
 	shamirHandler := NewShamirHandler(services.Shamir)
 	replicationHandler := NewReplicationHandler(services.Replication)
+	kekHandler := NewKEKHandler(services.KEK)
+	signingHandler := NewSigningHandler(services.Signing)
+	macHandler := NewMACHandler(services.MAC)
+
+	// Operations that expose or destroy key material need a recent MFA login, checked on a
+	// token the auth service has validated
+	authenticated := authenticator.Authenticate()
+	manageKeys := authenticator.Require(PermissionManageKeys)
+	recentAuth := middleware.RequireRecentAuth(time.Duration(cfg.StepUpMaxAge) * time.Minute)
+
+	// Secret sharing endpoints handle key material, so they get a tight per-IP budget
+	shamirLimit := limiter.Limit("shamir", cfg.RateLimitShamir, ratelimit.ByIP)
+
+	// With TLS on, only the configured services may read or recover key material
+	keyMaterialPeers := func(c *gin.Context) { c.Next() }
+	if cfg.TLSMode != mtls.ModeOff {
+		keyMaterialPeers = mtls.RequirePeer(cfg.KeyMaterialPeers...)
+	}
+
+	// Certificate issuance has to be reachable without a client certificate, since
+	// a service obtains its first one with a bootstrap token
+	if services.CA != nil {
+		caHandler := NewCAHandler(services.CA)
+
+		ca := router.Group("/api/v1/keymgmt/ca")
+		// Issuance is rare, so it gets a tight per-IP budget of its own
+		ca.Use(limiter.Limit("ca", cfg.RateLimitCA, ratelimit.ByIP))
+		{
+			ca.POST("/issue", caHandler.IssueCertificate)
+			ca.GET("/roots", caHandler.GetRoots)
+		}
+	}
+
+	// Data keys are wrapped and unwrapped for every message the encryption service
+	// handles, so these routes get their own per-IP budget. Only the configured services
+	// may call them, which needs client certificates, so they do not exist with TLS off.
+	if cfg.TLSMode != mtls.ModeOff {
+		kek := router.Group("/api/v1/keymgmt/kek")
+		kek.Use(mtls.RequirePeer(cfg.KEKPeers...))
+		kek.Use(limiter.Limit("kek", cfg.RateLimitKEK, ratelimit.ByIP))
+		{
+			kek.POST("/wrap", kekHandler.WrapKey)
+			kek.POST("/unwrap", kekHandler.UnwrapKey)
+		}
+	}
+
+	// Digests are signed and verified for every document the encryption service signs,
+	// so these routes get their own per-IP budget. Only the configured services may call
+	// them, which needs client certificates, so they do not exist with TLS off.
+	if cfg.TLSMode != mtls.ModeOff {
+		signing := router.Group("/api/v1/keymgmt/signing")
+		signing.Use(mtls.RequirePeer(cfg.SigningPeers...))
+		signing.Use(limiter.Limit("signing", cfg.RateLimitSigning, ratelimit.ByIP))
+		{
+			signing.POST("/sign", signingHandler.Sign)
+			signing.POST("/verify", signingHandler.Verify)
+		}
+	}
+
+	// Webhooks and records are authenticated one message at a time, so MAC generation
+	// and verification get their own per-IP budget. Only the configured services may
+	// call them, which needs client certificates, so they do not exist with TLS off.
+	if cfg.TLSMode != mtls.ModeOff {
+		mac := router.Group("/api/v1/keymgmt/mac")
+		mac.Use(mtls.RequirePeer(cfg.MACPeers...))
+		mac.Use(limiter.Limit("mac", cfg.RateLimitMAC, ratelimit.ByIP))
+		{
+			mac.POST("/generate", macHandler.GenerateMAC)
+			mac.POST("/verify", macHandler.VerifyMAC)
+		}
+	}
 
 	// Public routes (no authentication required for demo purposes)
 	public := router.Group("/api/v1/keymgmt")
+	if cfg.TLSMode == mtls.ModeMTLS {
+		public.Use(mtls.RequirePeer())
+	}
+	public.Use(limiter.Limit("api", cfg.RateLimitAPI, ratelimit.ByPeer))
 	{
 		// Key management routes
 		public.POST("/keys/generate", keyHandler.GenerateKey)
 		public.POST("/keys/generate-pair", keyHandler.GenerateKeyPair)
 		public.POST("/keys/store", keyHandler.StoreKey)
-		public.POST("/keys/retrieve", keyHandler.RetrieveKey)
-		public.POST("/keys/delete", keyHandler.DeleteKey)
+		public.POST("/keys/retrieve", keyMaterialPeers, authenticated, recentAuth, keyHandler.RetrieveKey)
+		public.POST("/keys/delete", authenticated, middleware.RejectImpersonation(), recentAuth, keyHandler.DeleteKey)
+		
+		// Key-encryption key routes
+		public.POST("/kek/create", manageKeys, kekHandler.CreateKEK)
+		public.GET("/kek", kekHandler.ListKEKs)
+		public.POST("/kek/get", kekHandler.GetKEK)
+		public.POST("/kek/rotate", manageKeys, kekHandler.RotateKEK)
+		
+		// Signing key routes
+		public.POST("/signing/create", manageKeys, signingHandler.CreateSigningKey)
+		public.GET("/signing", signingHandler.ListSigningKeys)
+		public.POST("/signing/get", signingHandler.GetSigningKey)
+		public.POST("/signing/rotate", manageKeys, signingHandler.RotateSigningKey)
+		public.POST("/signing/public-key", signingHandler.GetPublicKey)
+		
+		// MAC key routes
+		public.POST("/mac/create", manageKeys, macHandler.CreateMACKey)
+		public.GET("/mac", macHandler.ListMACKeys)
+		public.POST("/mac/get", macHandler.GetMACKey)
+		public.POST("/mac/rotate", manageKeys, macHandler.RotateMACKey)
 		
 		// Key rotation routes
 		public.POST("/rotation/rotate", rotationHandler.RotateKey)
@@ -33,16 +133,16 @@ func RegisterRoutes(router *gin.Engine, services *services.Services) {
 		public.POST("/rotation/auto-disable", rotationHandler.DisableAutoRotation)
 		
 		// Shamir's Secret Sharing routes
-		public.POST("/shamir/split", shamirHandler.SplitSecret)
-		public.POST("/shamir/combine", shamirHandler.CombineShares)
-		public.POST("/shamir/distribute", shamirHandler.DistributeKey)
-		public.POST("/shamir/recover", shamirHandler.RecoverKey)
+		public.POST("/shamir/split", shamirLimit, shamirHandler.SplitSecret)
+		public.POST("/shamir/combine", shamirLimit, shamirHandler.CombineShares)
+		public.POST("/shamir/distribute", shamirLimit, shamirHandler.DistributeKey)
+		public.POST("/shamir/recover", keyMaterialPeers, shamirLimit, authenticated, recentAuth, shamirHandler.RecoverKey)
 		
 		// Key replication routes
 		public.POST("/replication/replicate", replicationHandler.ReplicateKey)
 		public.POST("/replication/enable", replicationHandler.EnableCrossRegionReplication)
 		public.POST("/replication/disable", replicationHandler.DisableCrossRegionReplication)
-		public.POST("/replication/backup", replicationHandler.BackupKey)
+		public.POST("/replication/backup", keyMaterialPeers, replicationHandler.BackupKey)
 		public.POST("/replication/restore", replicationHandler.RestoreKey)
 	}
 
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+
+	"github.com/cryptofortress/backend/keymgmt/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionManageKeys is required to create and rotate keys
+const PermissionManageKeys = "manage:keys"
+
+// SigningHandler handles signing key HTTP requests
+type SigningHandler struct {
+	signingService services.SigningService
+}
+
+// NewSigningHandler creates a new signing key handler
+func NewSigningHandler(signingService services.SigningService) *SigningHandler {
+	return &SigningHandler{
+		signingService: signingService,
+	}
+}
+
+// CreateSigningKeyRequest represents the signing key creation request payload
+type CreateSigningKeyRequest struct {
+	Algorithm   string `json:"algorithm" binding:"required"`
+	Description string `json:"description"`
+}
+
+// CreateSigningKey handles signing key creation requests
+func (h *SigningHandler) CreateSigningKey(c *gin.Context) {
+	var req CreateSigningKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Create signing key
+	info, err := h.signingService.CreateSigningKey(req.Algorithm, req.Description)
+	if err != nil {
+		respondSigningError(c, err, "Failed to create signing key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, info)
+}
+
+// ListSigningKeys handles signing key listing requests
+func (h *SigningHandler) ListSigningKeys(c *gin.Context) {
+	keys, err := h.signingService.ListSigningKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list signing keys"})
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, gin.H{"signing_keys": keys})
+}
+
+// SigningKeyRequest represents a request that names a signing key
+type SigningKeyRequest struct {
+	KeyID string `json:"key_id" binding:"required"`
+}
+
+// GetSigningKey handles signing key lookup requests
+func (h *SigningHandler) GetSigningKey(c *gin.Context) {
+	var req SigningKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get signing key
+	info, err := h.signingService.GetSigningKey(req.KeyID)
+	if err != nil {
+		respondSigningError(c, err, "Failed to get signing key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, info)
+}
+
+// RotateSigningKey handles signing key rotation requests. Signatures made by earlier
+// versions can still be verified.
+func (h *SigningHandler) RotateSigningKey(c *gin.Context) {
+	var req SigningKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Rotate signing key
+	info, err := h.signingService.RotateSigningKey(req.KeyID)
+	if err != nil {
+		respondSigningError(c, err, "Failed to rotate signing key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, info)
+}
+
+// GetPublicKeyRequest represents the public key request payload. KeyVersion defaults to
+// the current version.
+type GetPublicKeyRequest struct {
+	KeyID      string `json:"key_id" binding:"required"`
+	KeyVersion int    `json:"key_version"`
+}
+
+// GetPublicKey handles public key requests
+func (h *SigningHandler) GetPublicKey(c *gin.Context) {
+	var req GetPublicKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get public key
+	publicKey, err := h.signingService.GetPublicKey(req.KeyID, req.KeyVersion)
+	if err != nil {
+		respondSigningError(c, err, "Failed to get public key")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, publicKey)
+}
+
+// SignDigestRequest represents the digest signing request payload
+type SignDigestRequest struct {
+	KeyID  string `json:"key_id" binding:"required"`
+	Digest string `json:"digest" binding:"required"`
+}
+
+// SignDigestResponse represents the digest signing response payload
+type SignDigestResponse struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Algorithm  string `json:"algorithm"`
+	Signature  string `json:"signature"`
+}
+
+// Sign handles digest signing requests
+func (h *SigningHandler) Sign(c *gin.Context) {
+	var req SignDigestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode digest from base64
+	digest, err := base64.StdEncoding.DecodeString(req.Digest)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid digest format"})
+		return
+	}
+
+	// Sign digest
+	signature, err := h.signingService.Sign(req.KeyID, digest)
+	if err != nil {
+		respondSigningError(c, err, "Failed to sign digest")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, SignDigestResponse{
+		KeyID:      signature.KeyID,
+		KeyVersion: signature.KeyVersion,
+		Algorithm:  signature.Algorithm,
+		Signature:  base64.StdEncoding.EncodeToString(signature.Signature),
+	})
+}
+
+// VerifyDigestRequest represents the digest signature verification request payload
+type VerifyDigestRequest struct {
+	KeyID      string `json:"key_id" binding:"required"`
+	KeyVersion int    `json:"key_version" binding:"required"`
+	Digest     string `json:"digest" binding:"required"`
+	Signature  string `json:"signature" binding:"required"`
+}
+
+// VerifyDigestResponse represents the digest signature verification response payload
+type VerifyDigestResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// Verify handles digest signature verification requests
+func (h *SigningHandler) Verify(c *gin.Context) {
+	var req VerifyDigestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Decode digest and signature from base64
+	digest, err := base64.StdEncoding.DecodeString(req.Digest)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid digest format"})
+		return
+	}
+	signature, err := base64.StdEncoding.DecodeString(req.Signature)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signature format"})
+		return
+	}
+
+	// Verify signature
+	valid, err := h.signingService.Verify(req.KeyID, req.KeyVersion, digest, signature)
+	if err != nil {
+		respondSigningError(c, err, "Failed to verify signature")
+		return
+	}
+
+	// Return response
+	c.JSON(http.StatusOK, VerifyDigestResponse{Valid: valid})
+}
+
+// respondSigningError maps signing service errors to HTTP responses
+func respondSigningError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrSigningKeyNotFound), errors.Is(err, services.ErrSigningKeyVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUnsupportedSigningAlgorithm), errors.Is(err, services.ErrInvalidDigest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
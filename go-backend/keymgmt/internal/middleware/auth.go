@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// RejectImpersonation creates a middleware that blocks requests made with impersonation tokens,
+// which carry an RFC 8693 "act" claim. It must run after the httpauth authentication middleware.
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if actorID := c.GetString("actorID"); actorID != "" {
+			log.Warn().
+				Str("actor_id", actorID).
+				Str("user_id", c.GetString("userID")).
+				Str("path", c.Request.URL.Path).
+				Msg("Impersonation token rejected")
+			c.JSON(http.StatusForbidden, gin.H{"error": "Operation not permitted with an impersonation token"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cryptofortress/backend/auth/httpauth"
+	"github.com/cryptofortress/backend/pkg/stepup"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// RequireRecentAuth creates a middleware that demands an auth service access token showing
+// multi-factor authentication within the last maxAge. Impersonation tokens are refused.
+// It must run after the httpauth authentication middleware.
+func RequireRecentAuth(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info, ok := httpauth.TokenInfo(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		if info.GetActorId() != "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Operation not permitted with an impersonation token"})
+			c.Abort()
+			return
+		}
+
+		if reason := stepup.Check(info.GetAcr(), info.GetAuthTime(), maxAge); reason != "" {
+			log.Info().
+				Str("user_id", info.GetUserId()).
+				Str("path", c.Request.URL.Path).
+				Str("reason", reason).
+				Msg("Step-up authentication required")
+			stepup.Abort(c, reason, maxAge)
+			return
+		}
+
+		c.Next()
+	}
+}
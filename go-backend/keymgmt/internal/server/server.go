@@ -2,77 +2,177 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/cryptofortress/backend/auth/httpauth"
+	"github.com/cryptofortress/backend/auth/proto/authpb"
 	"github.com/cryptofortress/backend/keymgmt/internal/config"
 	"github.com/cryptofortress/backend/keymgmt/internal/handlers"
 	"github.com/cryptofortress/backend/keymgmt/internal/middleware"
 	"github.com/cryptofortress/backend/keymgmt/internal/services"
+	"github.com/cryptofortress/backend/pkg/lifecycle"
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/cryptofortress/backend/pkg/ratelimit"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 // Server represents the key management service server
 type Server struct {
-	config   *config.Config
-	router   *gin.Engine
-	services *services.Services
+	config     *config.Config
+	router     *gin.Engine
+	httpServer *http.Server
+	authConn   *grpc.ClientConn
+	services   *services.Services
+	certs      *mtls.CertManager // nil when TLS is off
+	readiness  *lifecycle.Readiness
+	workers    *lifecycle.Workers
 }
 
 // New creates a new key management server instance
-func New(cfg *config.Config) *Server {
+func New(cfg *config.Config) (*Server, error) {
 	// Initialize services
 	keyService := services.NewKeyService(cfg)
 	rotationService := services.NewRotationService(cfg)
 	shamirService := services.NewShamirService(cfg)
 	replicationService := services.NewReplicationService(cfg)
+	kekService := services.NewKEKService(cfg)
+	signingService := services.NewSigningService(cfg)
+	macService := services.NewMACService(cfg)
+	
+	// The internal CA issues the certificates of every service, including this one
+	var (
+		caService services.CAService
+		certs     *mtls.CertManager
+	)
+	if cfg.TLSMode != mtls.ModeOff {
+		var err error
+		if caService, err = services.NewCAService(cfg); err != nil {
+			return nil, err
+		}
+		roots, err := mtls.ParseRoots(caService.RootsPEM())
+		if err != nil {
+			return nil, err
+		}
+		certs = mtls.NewCertManager(mtls.ServiceID(cfg.TLSTrustDomain, mtls.ServiceKeyManagement), roots)
+	}
+	
+	// Access tokens are validated by the auth service, which knows about disabled
+	// accounts and revoked sessions
+	authCreds := insecure.NewCredentials()
+	if certs != nil {
+		authCreds = credentials.NewTLS(certs.ClientTLSConfig(mtls.ServiceID(cfg.TLSTrustDomain, mtls.ServiceAuth)))
+	}
+	authConn, err := grpc.Dial(cfg.AuthGRPCURL, grpc.WithTransportCredentials(authCreds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up auth service client: %w", err)
+	}
+	authenticator := httpauth.New(authpb.NewAuthServiceClient(authConn))
 	
 	services := &services.Services{
 		Key:         keyService,
 		Rotation:    rotationService,
 		Shamir:      shamirService,
 		Replication: replicationService,
+		CA:          caService,
+		KEK:         kekService,
+		Signing:     signingService,
+		MAC:         macService,
 	}
 	
 	// Create router
 	router := gin.New()
+	// Client IPs, which most rate limits are keyed on, are only taken from X-Forwarded-For
+	// when a trusted proxy sent it
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	// The same proxies set the scheme and host that DPoP proofs are checked against
+	trustForwarded, err := httpauth.TrustForwardedHeaders(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	router.Use(trustForwarded)
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logging())
 	
+	// Rate limits are kept in memory, so each instance enforces its own
+	limiter := ratelimit.New(ratelimit.NewMemoryStore())
+	
 	// Register routes
-	handlers.RegisterRoutes(router, services)
+	handlers.RegisterRoutes(router, services, cfg, limiter, authenticator)
+	
+	// Readiness turns false as soon as shutdown begins, so load balancers stop sending traffic
+	readiness := lifecycle.NewReadiness()
+	router.GET("/ready", readiness.Handler())
 	
 	return &Server{
-		config:   cfg,
-		router:   router,
-		services: services,
-	}
+		config: cfg,
+		router: router,
+		httpServer: &http.Server{
+			Addr:    ":" + cfg.Port,
+			Handler: router,
+		},
+		authConn:  authConn,
+		services:  services,
+		certs:     certs,
+		readiness: readiness,
+		workers:   lifecycle.NewWorkers(),
+	}, nil
 }
 
-// Start begins serving requests
+// Start begins serving requests and returns once the server is stopped
 func (s *Server) Start() error {
-	server := &http.Server{
-		Addr:    ":" + s.config.Port,
-		Handler: s.router,
+	// Rotate keys whose automatic rotation is due
+	s.workers.Every("rotation", time.Minute, s.rotateDueKeys)
+	
+	if s.certs == nil {
+		return lifecycle.ServeError(s.httpServer.ListenAndServe())
 	}
 	
-	// Graceful shutdown
-	go func() {
-		<-context.Background().Done()
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		
-		if err := server.Shutdown(ctx); err != nil {
-			log.Error().Err(err).Msg("Server shutdown failed")
+	// This service's own certificate comes straight from the local CA
+	issuer := mtls.IssuerFunc(func(_ context.Context, csrPEM []byte) ([]byte, []byte, error) {
+		issued, err := s.services.CA.IssueCertificate(csrPEM, s.certs.ID(), "")
+		if err != nil {
+			return nil, nil, err
 		}
-	}()
+		return []byte(issued.Certificate), []byte(issued.Roots), nil
+	})
+	if err := s.certs.Start(s.workers.Context(), issuer); err != nil {
+		return lifecycle.ServeError(err)
+	}
 	
-	return server.ListenAndServe()
+	s.httpServer.TLSConfig = s.certs.ServerTLSConfig(tls.VerifyClientCertIfGiven)
+	return lifecycle.ServeError(s.httpServer.ListenAndServeTLS("", ""))
+}
+
+// rotateDueKeys performs due automatic key rotations
+func (s *Server) rotateDueKeys(ctx context.Context) {
+	rotated, err := s.services.Rotation.RotateDueKeys(ctx)
+	if errors.Is(err, context.Canceled) {
+		log.Info().Msg("Key rotation interrupted by shutdown, remaining keys stay due")
+	} else if err != nil {
+		log.Error().Err(err).Msg("Failed to rotate due keys")
+	}
+	if rotated > 0 {
+		log.Info().Int("count", rotated).Msg("Rotated due keys")
+	}
 }
 
-// Stop gracefully shuts down the server
-func (s *Server) Stop() error {
-	// Cleanup resources if needed
-	return nil
-}
\ No newline at end of file
+// Stop gracefully shuts down the server: it turns not ready, waits for load balancers
+// to notice, drains open connections and then stops the background workers
+func (s *Server) Stop(ctx context.Context) error {
+	s.readiness.Drain(ctx, time.Duration(s.config.ShutdownDelay)*time.Second)
+	
+	// Workers stop even if draining times out, with a timeout of their own, so the shutdown
+	// error reports everything
+	httpErr := s.httpServer.Shutdown(ctx)
+	return errors.Join(httpErr, s.authConn.Close(), s.workers.StopWithin(lifecycle.WorkerStopTimeout))
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/cryptofortress/backend/keymgmt/internal/config"
+	"github.com/cryptofortress/backend/pkg/mtls"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrInvalidCSR is returned when a certificate signing request is malformed or asks for more than a service identity
+	ErrInvalidCSR = errors.New("invalid certificate signing request")
+	// ErrIssuanceNotAuthorized is returned when the caller may not obtain a certificate for the requested identity
+	ErrIssuanceNotAuthorized = errors.New("not authorized to obtain a certificate for this identity")
+)
+
+// caRootValidity is the lifetime of a generated root certificate
+const caRootValidity = 10 * 365 * 24 * time.Hour
+
+// caServiceImpl implements the CAService interface
+type caServiceImpl struct {
+	config   *config.Config
+	root     *x509.Certificate
+	rootKey  crypto.Signer
+	rootsPEM []byte
+	// In a real implementation, the root key would live in an HSM or Vault
+}
+
+// NewCAService creates the internal CA from the configured root certificate and key,
+// or from a freshly generated root when none is configured
+func NewCAService(cfg *config.Config) (CAService, error) {
+	var (
+		root    *x509.Certificate
+		rootKey crypto.Signer
+		err     error
+	)
+	if cfg.CARootCertFile != "" {
+		root, rootKey, err = loadCARoot(cfg.CARootCertFile, cfg.CARootKeyFile)
+	} else {
+		log.Warn().Msg("No CA root configured, generating an ephemeral root; service certificates will not survive a restart")
+		root, rootKey, err = generateCARoot(cfg.TLSTrustDomain)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &caServiceImpl{
+		config:   cfg,
+		root:     root,
+		rootKey:  rootKey,
+		rootsPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw}),
+	}, nil
+}
+
+// IssueCertificate signs a certificate signing request for a service identity
+func (s *caServiceImpl) IssueCertificate(csrPEM []byte, peerID, bootstrapToken string) (*IssuedCertificate, error) {
+	block, _ := pem.Decode(csrPEM)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("%w: expected a PEM CERTIFICATE REQUEST", ErrInvalidCSR)
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+
+	// Service certificates carry exactly one SPIFFE ID and nothing else
+	if len(csr.URIs) != 1 || len(csr.DNSNames) > 0 || len(csr.IPAddresses) > 0 || len(csr.EmailAddresses) > 0 {
+		return nil, fmt.Errorf("%w: must name exactly one service ID and no other subject alternative names", ErrInvalidCSR)
+	}
+	serviceID := csr.URIs[0].String()
+	trustDomain, service, err := mtls.ParseServiceID(serviceID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCSR, err)
+	}
+	if trustDomain != s.config.TLSTrustDomain {
+		return nil, fmt.Errorf("%w: trust domain %s is not %s", ErrInvalidCSR, trustDomain, s.config.TLSTrustDomain)
+	}
+
+	if !s.authorized(serviceID, service, peerID, bootstrapToken) {
+		log.Warn().
+			Str("service_id", serviceID).
+			Str("peer_id", peerID).
+			Msg("Certificate issuance denied")
+		return nil, ErrIssuanceNotAuthorized
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	notAfter := now.Add(time.Duration(s.config.CACertTTL) * time.Hour)
+	if notAfter.After(s.root.NotAfter) {
+		notAfter = s.root.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: service},
+		URIs:         csr.URIs,
+		NotBefore:    now.Add(-time.Minute), // tolerate small clock skew
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, s.root, csr.PublicKey, s.rootKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign certificate: %w", err)
+	}
+
+	log.Info().
+		Str("service_id", serviceID).
+		Str("serial", serial.Text(16)).
+		Time("expires_at", notAfter).
+		Bool("renewal", peerID == serviceID).
+		Msg("Service certificate issued")
+
+	return &IssuedCertificate{
+		ServiceID:    serviceID,
+		SerialNumber: serial.Text(16),
+		Certificate:  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		Roots:        string(s.rootsPEM),
+		NotBefore:    template.NotBefore,
+		NotAfter:     notAfter,
+	}, nil
+}
+
+// authorized reports whether the caller may obtain a certificate for the service: either
+// it renews its own identity, or it presents the service's bootstrap token
+func (s *caServiceImpl) authorized(serviceID, service, peerID, bootstrapToken string) bool {
+	if peerID != "" && peerID == serviceID {
+		return true
+	}
+	expected := s.config.CABootstrapTokens[service]
+	return expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(bootstrapToken)) == 1
+}
+
+// RootsPEM returns the PEM bundle of CA roots
+func (s *caServiceImpl) RootsPEM() []byte {
+	return s.rootsPEM
+}
+
+// generateCARoot creates a self-signed root restricted to the trust domain's service IDs
+func generateCARoot(trustDomain string) (*x509.Certificate, crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "CryptoFortress Internal CA", Organization: []string{"CryptoFortress"}},
+		NotBefore:             now.Add(-time.Minute),
+		NotAfter:              now.Add(caRootValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		PermittedURIDomains:   []string{trustDomain},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+
+	root, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return root, key, nil
+}
+
+// loadCARoot reads a PEM root certificate and its PKCS#8 or EC private key
+func loadCARoot(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, nil, errors.New("CA certificate file does not contain a PEM certificate")
+	}
+	root, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CA certificate: %w", err)
+	}
+	if !root.IsCA {
+		return nil, nil, errors.New("CA certificate is not a CA")
+	}
+
+	keyPEM, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA key: %w", err)
+	}
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, errors.New("CA key file does not contain a PEM key")
+	}
+
+	var key crypto.Signer
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		var parsed interface{}
+		if parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+			var ok bool
+			if key, ok = parsed.(crypto.Signer); !ok {
+				err = errors.New("unsupported key type")
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported PEM block %s", block.Type)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CA key: %w", err)
+	}
+
+	// Make sure the key belongs to the certificate before signing anything with it
+	if pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !pub.Equal(root.PublicKey) {
+		return nil, nil, errors.New("CA key does not match the CA certificate")
+	}
+	return root, key, nil
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cryptofortress/backend/keymgmt/internal/config"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrKEKNotFound is returned when a key-encryption key does not exist
+	ErrKEKNotFound = errors.New("key-encryption key not found")
+	// ErrKEKVersionNotFound is returned when a key-encryption key has no such version
+	ErrKEKVersionNotFound = errors.New("key-encryption key version not found")
+	// ErrInvalidDataKey is returned when a data key to wrap has an unsupported length
+	ErrInvalidDataKey = errors.New("data key must be 16, 24 or 32 bytes")
+	// ErrUnwrapFailed is returned when a wrapped key was not produced by the given KEK version or was modified
+	ErrUnwrapFailed = errors.New("failed to unwrap data key")
+)
+
+// kekAlgorithm is the algorithm KEKs use to wrap data keys
+const kekAlgorithm = "AES-256-GCM"
+
+// kek is a key-encryption key with all of its versions
+type kek struct {
+	info     KEKInfo
+	versions map[int][]byte
+}
+
+// kekServiceImpl implements the KEKService interface
+type kekServiceImpl struct {
+	config *config.Config
+	mu     sync.RWMutex
+	keks   map[string]*kek
+	// In a real implementation, KEK material would live in an HSM or Vault and
+	// wrapping would happen there
+}
+
+// NewKEKService creates a new instance of the KEK service
+func NewKEKService(cfg *config.Config) KEKService {
+	return &kekServiceImpl{
+		config: cfg,
+		keks:   make(map[string]*kek),
+	}
+}
+
+// CreateKEK creates a key-encryption key with fresh material as version 1
+func (s *kekServiceImpl) CreateKEK(description string) (*KEKInfo, error) {
+	material, err := newKEKMaterial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	k := &kek{
+		info: KEKInfo{
+			KeyID:          uuid.New().String(),
+			Algorithm:      kekAlgorithm,
+			Description:    description,
+			CurrentVersion: 1,
+			CreatedAt:      now,
+			RotatedAt:      now,
+		},
+		versions: map[int][]byte{1: material},
+	}
+
+	s.mu.Lock()
+	s.keks[k.info.KeyID] = k
+	s.mu.Unlock()
+
+	info := k.info
+	return &info, nil
+}
+
+// GetKEK returns the description of a key-encryption key
+func (s *kekServiceImpl) GetKEK(keyID string) (*KEKInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.keks[keyID]
+	if !ok {
+		return nil, ErrKEKNotFound
+	}
+	info := k.info
+	return &info, nil
+}
+
+// ListKEKs lists all key-encryption keys
+func (s *kekServiceImpl) ListKEKs() ([]KEKInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keks := make([]KEKInfo, 0, len(s.keks))
+	for _, k := range s.keks {
+		keks = append(keks, k.info)
+	}
+	return keks, nil
+}
+
+// RotateKEK adds a new version of a key-encryption key and makes it current
+func (s *kekServiceImpl) RotateKEK(keyID string) (*KEKInfo, error) {
+	material, err := newKEKMaterial()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keks[keyID]
+	if !ok {
+		return nil, ErrKEKNotFound
+	}
+	k.info.CurrentVersion++
+	k.info.RotatedAt = time.Now()
+	k.versions[k.info.CurrentVersion] = material
+
+	info := k.info
+	return &info, nil
+}
+
+// WrapKey encrypts a data key with the current version of a KEK
+func (s *kekServiceImpl) WrapKey(keyID string, dataKey []byte) (*WrappedKey, error) {
+	switch len(dataKey) {
+	case 16, 24, 32:
+	default:
+		return nil, ErrInvalidDataKey
+	}
+
+	s.mu.RLock()
+	k, ok := s.keks[keyID]
+	var (
+		version  int
+		material []byte
+	)
+	if ok {
+		version = k.info.CurrentVersion
+		material = k.versions[version]
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrKEKNotFound
+	}
+
+	aead, err := newKEKCipher(material)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	// The nonce is prepended, and the KEK ID and version are bound as associated data
+	return &WrappedKey{
+		KeyID:      keyID,
+		KeyVersion: version,
+		WrappedKey: aead.Seal(nonce, nonce, dataKey, kekAssociatedData(keyID, version)),
+	}, nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey
+func (s *kekServiceImpl) UnwrapKey(keyID string, version int, wrappedKey []byte) ([]byte, error) {
+	s.mu.RLock()
+	k, ok := s.keks[keyID]
+	var material []byte
+	if ok {
+		material = k.versions[version]
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrKEKNotFound
+	}
+	if material == nil {
+		return nil, ErrKEKVersionNotFound
+	}
+
+	aead, err := newKEKCipher(material)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrappedKey) < aead.NonceSize() {
+		return nil, ErrUnwrapFailed
+	}
+
+	nonce, ciphertext := wrappedKey[:aead.NonceSize()], wrappedKey[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, ciphertext, kekAssociatedData(keyID, version))
+	if err != nil {
+		return nil, ErrUnwrapFailed
+	}
+	return dataKey, nil
+}
+
+// newKEKMaterial generates the material for a KEK version
+func newKEKMaterial() ([]byte, error) {
+	material := make([]byte, 32) // 256 bits
+	if _, err := rand.Read(material); err != nil {
+		return nil, fmt.Errorf("failed to generate key material: %w", err)
+	}
+	return material, nil
+}
+
+// newKEKCipher creates the AEAD for a KEK version
+func newKEKCipher(material []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(material)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// kekAssociatedData binds a wrapped key to the KEK version that wrapped it
+func kekAssociatedData(keyID string, version int) []byte {
+	return []byte(keyID + ":" + strconv.Itoa(version))
+}
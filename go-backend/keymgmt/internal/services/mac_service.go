@@ -0,0 +1,278 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math/bits"
+	"sync"
+	"time"
+
+	"github.com/cryptofortress/backend/keymgmt/internal/config"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrMACKeyNotFound is returned when a MAC key does not exist
+	ErrMACKeyNotFound = errors.New("MAC key not found")
+	// ErrMACKeyVersionNotFound is returned when a MAC key has no such version
+	ErrMACKeyVersionNotFound = errors.New("MAC key version not found")
+	// ErrUnsupportedMACAlgorithm is returned for unknown MAC algorithms
+	ErrUnsupportedMACAlgorithm = errors.New("unsupported MAC algorithm")
+	// ErrInvalidTagLength is returned for tag lengths a MAC key's policy does not allow
+	ErrInvalidTagLength = errors.New("invalid tag length")
+)
+
+// minMACTagLength is the shortest tag, in bytes, a MAC key may be created with
+const minMACTagLength = 16
+
+// macAlgorithm is a MAC algorithm with its key size and longest tag, in bytes
+type macAlgorithm struct {
+	keySize      int
+	maxTagLength int
+	mac          func(key, message []byte, tagLength int) []byte
+}
+
+// macAlgorithms are the supported MAC algorithms. HMAC tags are truncated to the key's
+// tag length (SP 800-107), while KMAC takes the tag length as its output length, so a
+// shorter KMAC tag is not a prefix of a longer one (SP 800-185).
+var macAlgorithms = map[string]macAlgorithm{
+	"HMAC-SHA256": hmacAlgorithm(sha256.New, sha256.Size),
+	"HMAC-SHA384": hmacAlgorithm(sha512.New384, sha512.Size384),
+	"HMAC-SHA512": hmacAlgorithm(sha512.New, sha512.Size),
+	"KMAC128": {
+		keySize:      32,
+		maxTagLength: 32,
+		mac: func(key, message []byte, tagLength int) []byte {
+			return kmac(sha3.NewCSHAKE128, 168, key, message, nil, tagLength)
+		},
+	},
+	"KMAC256": {
+		keySize:      32,
+		maxTagLength: 64,
+		mac: func(key, message []byte, tagLength int) []byte {
+			return kmac(sha3.NewCSHAKE256, 136, key, message, nil, tagLength)
+		},
+	},
+}
+
+// hmacAlgorithm is HMAC with a key as long as the hash output
+func hmacAlgorithm(h func() hash.Hash, size int) macAlgorithm {
+	return macAlgorithm{
+		keySize:      size,
+		maxTagLength: size,
+		mac: func(key, message []byte, tagLength int) []byte {
+			m := hmac.New(h, key)
+			m.Write(message)
+			return m.Sum(nil)[:tagLength]
+		},
+	}
+}
+
+// kmac computes KMAC128 or KMAC256 (SP 800-185 section 4) with an output of length
+// bytes. rate is the cSHAKE rate in bytes, which the key is padded to.
+func kmac(newCSHAKE func(N, S []byte) *sha3.SHAKE, rate int, key, message, customization []byte, length int) []byte {
+	h := newCSHAKE([]byte("KMAC"), customization)
+	h.Write(bytepad(encodeString(key), rate))
+	h.Write(message)
+	h.Write(rightEncode(uint64(length) * 8))
+	tag := make([]byte, length)
+	h.Read(tag)
+	return tag
+}
+
+// leftEncode encodes x as its length in bytes followed by its big-endian bytes
+func leftEncode(x uint64) []byte {
+	n := max(1, (bits.Len64(x)+7)/8)
+	return append([]byte{byte(n)}, binary.BigEndian.AppendUint64(nil, x)[8-n:]...)
+}
+
+// rightEncode encodes x as its big-endian bytes followed by their length
+func rightEncode(x uint64) []byte {
+	n := max(1, (bits.Len64(x)+7)/8)
+	return append(binary.BigEndian.AppendUint64(nil, x)[8-n:], byte(n))
+}
+
+// encodeString prefixes s with its length in bits
+func encodeString(s []byte) []byte {
+	return append(leftEncode(uint64(len(s))*8), s...)
+}
+
+// bytepad prefixes x with w and pads it with zeros to a multiple of w bytes
+func bytepad(x []byte, w int) []byte {
+	b := append(leftEncode(uint64(w)), x...)
+	if r := len(b) % w; r != 0 {
+		b = append(b, make([]byte, w-r)...)
+	}
+	return b
+}
+
+// macKey is a MAC key with all of its versions
+type macKey struct {
+	info     MACKeyInfo
+	versions map[int][]byte
+}
+
+// macServiceImpl implements the MACService interface
+type macServiceImpl struct {
+	config *config.Config
+	mu     sync.RWMutex
+	keys   map[string]*macKey
+	// In a real implementation, MAC keys would live in an HSM or Vault
+}
+
+// NewMACService creates a new instance of the MAC service
+func NewMACService(cfg *config.Config) MACService {
+	return &macServiceImpl{
+		config: cfg,
+		keys:   make(map[string]*macKey),
+	}
+}
+
+// CreateMACKey creates a MAC key with fresh material as version 1. A tag length of 0
+// selects the algorithm's longest tag.
+func (s *macServiceImpl) CreateMACKey(algorithm string, tagLength int, description string) (*MACKeyInfo, error) {
+	alg, ok := macAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMACAlgorithm, algorithm)
+	}
+	if tagLength == 0 {
+		tagLength = alg.maxTagLength
+	}
+	if tagLength < minMACTagLength || tagLength > alg.maxTagLength {
+		return nil, fmt.Errorf("%w: %s tags are %d to %d bytes", ErrInvalidTagLength, algorithm, minMACTagLength, alg.maxTagLength)
+	}
+
+	key := make([]byte, alg.keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate MAC key: %w", err)
+	}
+
+	now := time.Now()
+	k := &macKey{
+		info: MACKeyInfo{
+			KeyID:          uuid.New().String(),
+			Algorithm:      algorithm,
+			TagLength:      tagLength,
+			Description:    description,
+			CurrentVersion: 1,
+			CreatedAt:      now,
+			RotatedAt:      now,
+		},
+		versions: map[int][]byte{1: key},
+	}
+
+	s.mu.Lock()
+	s.keys[k.info.KeyID] = k
+	s.mu.Unlock()
+
+	info := k.info
+	return &info, nil
+}
+
+// GetMACKey returns the description of a MAC key
+func (s *macServiceImpl) GetMACKey(keyID string) (*MACKeyInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.keys[keyID]
+	if !ok {
+		return nil, ErrMACKeyNotFound
+	}
+	info := k.info
+	return &info, nil
+}
+
+// ListMACKeys lists all MAC keys
+func (s *macServiceImpl) ListMACKeys() ([]MACKeyInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]MACKeyInfo, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k.info)
+	}
+	return keys, nil
+}
+
+// RotateMACKey adds fresh material as the current version of a MAC key. The algorithm
+// and tag length stay the same.
+func (s *macServiceImpl) RotateMACKey(keyID string) (*MACKeyInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k, ok := s.keys[keyID]
+	if !ok {
+		return nil, ErrMACKeyNotFound
+	}
+	key := make([]byte, macAlgorithms[k.info.Algorithm].keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate MAC key: %w", err)
+	}
+
+	k.info.CurrentVersion++
+	k.info.RotatedAt = time.Now()
+	k.versions[k.info.CurrentVersion] = key
+
+	info := k.info
+	return &info, nil
+}
+
+// GenerateMAC computes the tag of a message with the current version of a MAC key
+func (s *macServiceImpl) GenerateMAC(keyID string, message []byte) (*MAC, error) {
+	info, version, key, err := s.version(keyID, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &MAC{
+		KeyID:      keyID,
+		KeyVersion: version,
+		Algorithm:  info.Algorithm,
+		Tag:        macAlgorithms[info.Algorithm].mac(key, message, info.TagLength),
+	}, nil
+}
+
+// VerifyMAC reports whether tag is the tag of message under a version of a MAC key. The
+// comparison takes constant time, and tags of any length other than the key's are
+// rejected, so a truncated tag never verifies.
+func (s *macServiceImpl) VerifyMAC(keyID string, version int, message, tag []byte) (bool, error) {
+	if version == 0 {
+		return false, ErrMACKeyVersionNotFound
+	}
+	info, _, key, err := s.version(keyID, version)
+	if err != nil {
+		return false, err
+	}
+	if len(tag) != info.TagLength {
+		return false, fmt.Errorf("%w: key %s has %d-byte tags", ErrInvalidTagLength, keyID, info.TagLength)
+	}
+
+	expected := macAlgorithms[info.Algorithm].mac(key, message, info.TagLength)
+	return hmac.Equal(expected, tag), nil
+}
+
+// version returns a MAC key's description, and the number and material of one of its
+// versions. Version 0 means the current version.
+func (s *macServiceImpl) version(keyID string, version int) (MACKeyInfo, int, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.keys[keyID]
+	if !ok {
+		return MACKeyInfo{}, 0, nil, ErrMACKeyNotFound
+	}
+	if version == 0 {
+		version = k.info.CurrentVersion
+	}
+	key, ok := k.versions[version]
+	if !ok {
+		return MACKeyInfo{}, 0, nil, ErrMACKeyVersionNotFound
+	}
+	return k.info, version, key, nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha3"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/cryptofortress/backend/keymgmt/internal/config"
+)
+
+// sequence returns n bytes counting up from first
+func sequence(first byte, n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = first + byte(i)
+	}
+	return b
+}
+
+// TestKMAC checks KMAC against the NIST SP 800-185 KMAC samples
+func TestKMAC(t *testing.T) {
+	key := sequence(0x40, 32)
+	tagged := []byte("My Tagged Application")
+	cases := []struct {
+		name          string
+		newCSHAKE     func(N, S []byte) *sha3.SHAKE
+		rate          int
+		message       []byte
+		customization []byte
+		want          string
+	}{
+		{"Sample1", sha3.NewCSHAKE128, 168, sequence(0, 4), nil, "e5780b0d3ea6f7d3a429c5706aa43a00fadbd7d49628839e3187243f456ee14e"},
+		{"Sample2", sha3.NewCSHAKE128, 168, sequence(0, 4), tagged, "3b1fba963cd8b0b59e8c1a6d71888b7143651af8ba0a7070c0979e2811324aa5"},
+		{"Sample3", sha3.NewCSHAKE128, 168, sequence(0, 200), tagged, "1f5b4e6cca02209e0dcb5ca635b89a15e271ecc760071dfd805faa38f9729230"},
+		{"Sample4", sha3.NewCSHAKE256, 136, sequence(0, 4), tagged, "20c570c31346f703c9ac36c61c03cb64c3970d0cfc787e9b79599d273a68d2f7f69d4cc3de9d104a351689f27cf6f5951f0103f33f4f24871024d9c27773a8dd"},
+		{"Sample5", sha3.NewCSHAKE256, 136, sequence(0, 200), nil, "75358cf39e41494e949707927cee0af20a3ff553904c86b08f21cc414bcfd691589d27cf5e15369cbbff8b9a4c2eb17800855d0235ff635da82533ec6b759b69"},
+		{"Sample6", sha3.NewCSHAKE256, 136, sequence(0, 200), tagged, "b58618f71f92e1d56c1b8c55ddd7cd188b97b4ca4d99831eb2699a837da2e4d970fbacfde50033aea585f1a2708510c32d07880801bd182898fe476876fc8965"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			want, _ := hex.DecodeString(tc.want)
+			got := kmac(tc.newCSHAKE, tc.rate, key, tc.message, tc.customization, len(want))
+			if !bytes.Equal(got, want) {
+				t.Errorf("Got %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+// TestMACTagPolicy tests that MAC keys only produce and accept tags of their length
+func TestMACTagPolicy(t *testing.T) {
+	service := NewMACService(&config.Config{})
+	message := []byte("webhook payload")
+
+	t.Run("Truncated", func(t *testing.T) {
+		info, err := service.CreateMACKey("HMAC-SHA256", 16, "")
+		if err != nil {
+			t.Fatalf("CreateMACKey failed: %v", err)
+		}
+		mac, err := service.GenerateMAC(info.KeyID, message)
+		if err != nil {
+			t.Fatalf("GenerateMAC failed: %v", err)
+		}
+		if len(mac.Tag) != 16 {
+			t.Errorf("Expected a 16-byte tag, got %d bytes", len(mac.Tag))
+		}
+
+		valid, err := service.VerifyMAC(info.KeyID, mac.KeyVersion, message, mac.Tag)
+		if err != nil || !valid {
+			t.Errorf("Expected the tag to verify, got %v, %v", valid, err)
+		}
+		valid, err = service.VerifyMAC(info.KeyID, mac.KeyVersion, []byte("forged payload"), mac.Tag)
+		if err != nil || valid {
+			t.Errorf("Expected a forged message to fail, got %v, %v", valid, err)
+		}
+		if _, err := service.VerifyMAC(info.KeyID, mac.KeyVersion, message, mac.Tag[:12]); !errors.Is(err, ErrInvalidTagLength) {
+			t.Errorf("Expected ErrInvalidTagLength for a shorter tag, got %v", err)
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		for _, tc := range []struct {
+			algorithm string
+			tagLength int
+		}{
+			{"HMAC-SHA256", 8},
+			{"HMAC-SHA256", 33},
+			{"KMAC128", 33},
+		} {
+			if _, err := service.CreateMACKey(tc.algorithm, tc.tagLength, ""); !errors.Is(err, ErrInvalidTagLength) {
+				t.Errorf("Expected ErrInvalidTagLength for %s with %d-byte tags, got %v", tc.algorithm, tc.tagLength, err)
+			}
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		info, err := service.CreateMACKey("KMAC256", 0, "")
+		if err != nil {
+			t.Fatalf("CreateMACKey failed: %v", err)
+		}
+		mac, err := service.GenerateMAC(info.KeyID, message)
+		if err != nil {
+			t.Fatalf("GenerateMAC failed: %v", err)
+		}
+		if _, err := service.RotateMACKey(info.KeyID); err != nil {
+			t.Fatalf("RotateMACKey failed: %v", err)
+		}
+
+		valid, err := service.VerifyMAC(info.KeyID, mac.KeyVersion, message, mac.Tag)
+		if err != nil || !valid {
+			t.Errorf("Expected a tag of the earlier version to verify, got %v, %v", valid, err)
+		}
+		valid, err = service.VerifyMAC(info.KeyID, mac.KeyVersion+1, message, mac.Tag)
+		if err != nil || valid {
+			t.Errorf("Expected the tag to fail under the new version, got %v, %v", valid, err)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"time"
 
 	"github.com/cryptofortress/backend/keymgmt/internal/config"
@@ -12,6 +13,10 @@ type Services struct {
 	Rotation    RotationService
 	Shamir      ShamirService
 	Replication ReplicationService
+	CA          CAService
+	KEK         KEKService
+	Signing     SigningService
+	MAC         MACService
 }
 
 // KeyService defines the interface for key management operations
@@ -43,6 +48,10 @@ type RotationService interface {
 	EnableAutoRotation(keyID string, period time.Duration) error
 	DisableAutoRotation(keyID string) error
 	ListAutoRotations() ([]RotationInfo, error)
+	
+	// RotateDueKeys rotates every key whose automatic rotation is due. It checks ctx
+	// between keys, so a cancelled run leaves the remaining keys due for the next one.
+	RotateDueKeys(ctx context.Context) (rotated int, err error)
 }
 
 // ShamirService defines the interface for Shamir's Secret Sharing operations
@@ -69,6 +78,140 @@ type ReplicationService interface {
 	ListBackups() ([]BackupInfo, error)
 }
 
+// CAService defines the interface for the internal certificate authority that issues
+// service certificates for mutual TLS
+type CAService interface {
+	// IssueCertificate signs a PEM certificate signing request for a service identity.
+	// The caller must either hold a certificate for that identity already, identified by
+	// peerID, or present the service's bootstrap token.
+	IssueCertificate(csrPEM []byte, peerID, bootstrapToken string) (*IssuedCertificate, error)
+	
+	// RootsPEM returns the PEM bundle of CA roots services should trust
+	RootsPEM() []byte
+}
+
+// KEKService defines the interface for key-encryption keys, which wrap the data keys
+// used for envelope encryption. KEK material never leaves this service.
+type KEKService interface {
+	// KEK lifecycle
+	CreateKEK(description string) (*KEKInfo, error)
+	GetKEK(keyID string) (*KEKInfo, error)
+	ListKEKs() ([]KEKInfo, error)
+	RotateKEK(keyID string) (*KEKInfo, error)
+	
+	// Data key wrapping. Keys are wrapped with the current version of the KEK, and older
+	// versions stay available for unwrapping.
+	WrapKey(keyID string, dataKey []byte) (*WrappedKey, error)
+	UnwrapKey(keyID string, version int, wrappedKey []byte) ([]byte, error)
+}
+
+// SigningService defines the interface for asymmetric signing keys. Private keys never
+// leave this service; callers hash their messages and send the digest.
+type SigningService interface {
+	// Signing key lifecycle
+	CreateSigningKey(algorithm, description string) (*SigningKeyInfo, error)
+	GetSigningKey(keyID string) (*SigningKeyInfo, error)
+	ListSigningKeys() ([]SigningKeyInfo, error)
+	RotateSigningKey(keyID string) (*SigningKeyInfo, error)
+	GetPublicKey(keyID string, version int) (*SigningPublicKey, error)
+	
+	// Digest signing. Digests are signed with the current version of the key, and older
+	// versions stay available for verification.
+	Sign(keyID string, digest []byte) (*Signature, error)
+	Verify(keyID string, version int, digest, signature []byte) (bool, error)
+}
+
+// MACService defines the interface for message authentication keys. Key material never
+// leaves this service; callers send the message.
+type MACService interface {
+	// MAC key lifecycle. Each key has a fixed tag length, which tags must match.
+	CreateMACKey(algorithm string, tagLength int, description string) (*MACKeyInfo, error)
+	GetMACKey(keyID string) (*MACKeyInfo, error)
+	ListMACKeys() ([]MACKeyInfo, error)
+	RotateMACKey(keyID string) (*MACKeyInfo, error)
+	
+	// Tags are computed with the current version of the key, and older versions stay
+	// available for verification
+	GenerateMAC(keyID string, message []byte) (*MAC, error)
+	VerifyMAC(keyID string, version int, message, tag []byte) (bool, error)
+}
+
+// KEKInfo describes a key-encryption key without its material
+type KEKInfo struct {
+	KeyID          string    `json:"key_id"`
+	Algorithm      string    `json:"algorithm"`
+	Description    string    `json:"description,omitempty"`
+	CurrentVersion int       `json:"current_version"`
+	CreatedAt      time.Time `json:"created_at"`
+	RotatedAt      time.Time `json:"rotated_at"`
+}
+
+// WrappedKey is a data key encrypted under a version of a KEK
+type WrappedKey struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// SigningKeyInfo describes a signing key without its material. Hash is the hash of the
+// digests the key signs.
+type SigningKeyInfo struct {
+	KeyID          string    `json:"key_id"`
+	Algorithm      string    `json:"algorithm"`
+	Hash           string    `json:"hash"`
+	Description    string    `json:"description,omitempty"`
+	CurrentVersion int       `json:"current_version"`
+	CreatedAt      time.Time `json:"created_at"`
+	RotatedAt      time.Time `json:"rotated_at"`
+}
+
+// SigningPublicKey is the SPKI PEM public key of a signing key version
+type SigningPublicKey struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Algorithm  string `json:"algorithm"`
+	Hash       string `json:"hash"`
+	PublicKey  string `json:"public_key"`
+}
+
+// Signature is a signature made by a version of a signing key
+type Signature struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Algorithm  string `json:"algorithm"`
+	Signature  []byte `json:"signature"`
+}
+
+// MACKeyInfo describes a MAC key without its material. TagLength is the length of its
+// tags in bytes.
+type MACKeyInfo struct {
+	KeyID          string    `json:"key_id"`
+	Algorithm      string    `json:"algorithm"`
+	TagLength      int       `json:"tag_length"`
+	Description    string    `json:"description,omitempty"`
+	CurrentVersion int       `json:"current_version"`
+	CreatedAt      time.Time `json:"created_at"`
+	RotatedAt      time.Time `json:"rotated_at"`
+}
+
+// MAC is a message authentication tag computed with a version of a MAC key
+type MAC struct {
+	KeyID      string `json:"key_id"`
+	KeyVersion int    `json:"key_version"`
+	Algorithm  string `json:"algorithm"`
+	Tag        []byte `json:"tag"`
+}
+
+// IssuedCertificate is a service certificate issued by the internal CA
+type IssuedCertificate struct {
+	ServiceID    string    `json:"service_id"`
+	SerialNumber string    `json:"serial_number"`
+	Certificate  string    `json:"certificate"`
+	Roots        string    `json:"roots"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+}
+
 // KeyMetadata represents metadata associated with a key
 type KeyMetadata struct {
 	Algorithm   string            `json:"algorithm"`
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/mldsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cryptofortress/backend/keymgmt/internal/config"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrSigningKeyNotFound is returned when a signing key does not exist
+	ErrSigningKeyNotFound = errors.New("signing key not found")
+	// ErrSigningKeyVersionNotFound is returned when a signing key has no such version
+	ErrSigningKeyVersionNotFound = errors.New("signing key version not found")
+	// ErrUnsupportedSigningAlgorithm is returned for unknown signing algorithms
+	ErrUnsupportedSigningAlgorithm = errors.New("unsupported signing algorithm")
+	// ErrInvalidDigest is returned when a digest does not match the size of the key's hash
+	ErrInvalidDigest = errors.New("invalid digest")
+)
+
+// mldsaDigestContext is the ML-DSA context string of digest signatures. It keeps them
+// apart from signatures over messages that happen to be as long as a digest.
+const mldsaDigestContext = "CryptoFortress SHA-512 digest"
+
+// signingAlgorithm is a signature algorithm together with the hash of the digests it
+// signs
+type signingAlgorithm struct {
+	hash     crypto.Hash
+	opts     crypto.SignerOpts
+	generate func() (crypto.Signer, error)
+}
+
+// signingAlgorithms are the supported signing algorithms. Ed25519 signs digests as
+// Ed25519ph (RFC 8032), and ML-DSA (FIPS 204) signs the digest as its message.
+var signingAlgorithms = map[string]signingAlgorithm{
+	"Ed25519": {
+		hash: crypto.SHA512,
+		opts: &ed25519.Options{Hash: crypto.SHA512},
+		generate: func() (crypto.Signer, error) {
+			_, key, err := ed25519.GenerateKey(rand.Reader)
+			return key, err
+		},
+	},
+	"ECDSA-P256": {
+		hash:     crypto.SHA256,
+		opts:     crypto.SHA256,
+		generate: func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P256(), rand.Reader) },
+	},
+	"ECDSA-P384": {
+		hash:     crypto.SHA384,
+		opts:     crypto.SHA384,
+		generate: func() (crypto.Signer, error) { return ecdsa.GenerateKey(elliptic.P384(), rand.Reader) },
+	},
+	"RSA-PSS-2048": rsaPSSAlgorithm(2048),
+	"RSA-PSS-3072": rsaPSSAlgorithm(3072),
+	"RSA-PSS-4096": rsaPSSAlgorithm(4096),
+	"ML-DSA-44":    mldsaAlgorithm(mldsa.MLDSA44()),
+	"ML-DSA-65":    mldsaAlgorithm(mldsa.MLDSA65()),
+	"ML-DSA-87":    mldsaAlgorithm(mldsa.MLDSA87()),
+}
+
+// rsaPSSAlgorithm signs SHA-256 digests with RSA-PSS, using a salt as long as the hash
+func rsaPSSAlgorithm(bits int) signingAlgorithm {
+	return signingAlgorithm{
+		hash: crypto.SHA256,
+		opts: &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256},
+		generate: func() (crypto.Signer, error) {
+			return rsa.GenerateKey(rand.Reader, bits)
+		},
+	}
+}
+
+// mldsaAlgorithm signs SHA-512 digests with ML-DSA
+func mldsaAlgorithm(params mldsa.Parameters) signingAlgorithm {
+	return signingAlgorithm{
+		hash: crypto.SHA512,
+		opts: &mldsa.Options{Context: mldsaDigestContext},
+		generate: func() (crypto.Signer, error) {
+			return mldsa.GenerateKey(params)
+		},
+	}
+}
+
+// signingKey is an asymmetric signing key with all of its versions
+type signingKey struct {
+	info     SigningKeyInfo
+	versions map[int]crypto.Signer
+}
+
+// signingServiceImpl implements the SigningService interface
+type signingServiceImpl struct {
+	config *config.Config
+	mu     sync.RWMutex
+	keys   map[string]*signingKey
+	// In a real implementation, private keys would live in an HSM or Vault and signing
+	// would happen there
+}
+
+// NewSigningService creates a new instance of the signing service
+func NewSigningService(cfg *config.Config) SigningService {
+	return &signingServiceImpl{
+		config: cfg,
+		keys:   make(map[string]*signingKey),
+	}
+}
+
+// CreateSigningKey creates a signing key with a fresh key pair as version 1
+func (s *signingServiceImpl) CreateSigningKey(algorithm, description string) (*SigningKeyInfo, error) {
+	alg, ok := signingAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedSigningAlgorithm, algorithm)
+	}
+	signer, err := alg.generate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	now := time.Now()
+	k := &signingKey{
+		info: SigningKeyInfo{
+			KeyID:          uuid.New().String(),
+			Algorithm:      algorithm,
+			Hash:           alg.hash.String(),
+			Description:    description,
+			CurrentVersion: 1,
+			CreatedAt:      now,
+			RotatedAt:      now,
+		},
+		versions: map[int]crypto.Signer{1: signer},
+	}
+
+	s.mu.Lock()
+	s.keys[k.info.KeyID] = k
+	s.mu.Unlock()
+
+	info := k.info
+	return &info, nil
+}
+
+// GetSigningKey returns the description of a signing key
+func (s *signingServiceImpl) GetSigningKey(keyID string) (*SigningKeyInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.keys[keyID]
+	if !ok {
+		return nil, ErrSigningKeyNotFound
+	}
+	info := k.info
+	return &info, nil
+}
+
+// ListSigningKeys lists all signing keys
+func (s *signingServiceImpl) ListSigningKeys() ([]SigningKeyInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keys := make([]SigningKeyInfo, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k.info)
+	}
+	return keys, nil
+}
+
+// RotateSigningKey adds a new key pair as the current version of a signing key. The
+// algorithm stays the same.
+func (s *signingServiceImpl) RotateSigningKey(keyID string) (*SigningKeyInfo, error) {
+	s.mu.RLock()
+	k, ok := s.keys[keyID]
+	var algorithm string
+	if ok {
+		algorithm = k.info.Algorithm
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrSigningKeyNotFound
+	}
+
+	// Generating RSA keys is slow, so it happens outside the lock
+	signer, err := signingAlgorithms[algorithm].generate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k.info.CurrentVersion++
+	k.info.RotatedAt = time.Now()
+	k.versions[k.info.CurrentVersion] = signer
+
+	info := k.info
+	return &info, nil
+}
+
+// GetPublicKey returns a version of a signing key's public key as SPKI PEM. Version 0
+// means the current version.
+func (s *signingServiceImpl) GetPublicKey(keyID string, version int) (*SigningPublicKey, error) {
+	k, version, signer, err := s.version(keyID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	return &SigningPublicKey{
+		KeyID:      keyID,
+		KeyVersion: version,
+		Algorithm:  k.Algorithm,
+		Hash:       k.Hash,
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	}, nil
+}
+
+// Sign signs a digest with the current version of a signing key
+func (s *signingServiceImpl) Sign(keyID string, digest []byte) (*Signature, error) {
+	k, version, signer, err := s.version(keyID, 0)
+	if err != nil {
+		return nil, err
+	}
+	alg := signingAlgorithms[k.Algorithm]
+	if len(digest) != alg.hash.Size() {
+		return nil, fmt.Errorf("%w: %s keys sign %s digests of %d bytes", ErrInvalidDigest, k.Algorithm, k.Hash, alg.hash.Size())
+	}
+
+	signature, err := signer.Sign(rand.Reader, digest, alg.opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{
+		KeyID:      keyID,
+		KeyVersion: version,
+		Algorithm:  k.Algorithm,
+		Signature:  signature,
+	}, nil
+}
+
+// Verify reports whether signature is a valid signature of digest by a version of a
+// signing key
+func (s *signingServiceImpl) Verify(keyID string, version int, digest, signature []byte) (bool, error) {
+	if version == 0 {
+		return false, ErrSigningKeyVersionNotFound
+	}
+	k, _, signer, err := s.version(keyID, version)
+	if err != nil {
+		return false, err
+	}
+	alg := signingAlgorithms[k.Algorithm]
+	if len(digest) != alg.hash.Size() {
+		return false, fmt.Errorf("%w: %s keys sign %s digests of %d bytes", ErrInvalidDigest, k.Algorithm, k.Hash, alg.hash.Size())
+	}
+
+	switch pub := signer.Public().(type) {
+	case ed25519.PublicKey:
+		return ed25519.VerifyWithOptions(pub, digest, signature, alg.opts.(*ed25519.Options)) == nil, nil
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(pub, digest, signature), nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPSS(pub, alg.hash, digest, signature, alg.opts.(*rsa.PSSOptions)) == nil, nil
+	case *mldsa.PublicKey:
+		return mldsa.Verify(pub, digest, signature, alg.opts.(*mldsa.Options)) == nil, nil
+	}
+	return false, fmt.Errorf("%w: %s", ErrUnsupportedSigningAlgorithm, k.Algorithm)
+}
+
+// version returns a signing key's description, and the number and signer of one of its
+// versions. Version 0 means the current version.
+func (s *signingServiceImpl) version(keyID string, version int) (SigningKeyInfo, int, crypto.Signer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	k, ok := s.keys[keyID]
+	if !ok {
+		return SigningKeyInfo{}, 0, nil, ErrSigningKeyNotFound
+	}
+	if version == 0 {
+		version = k.info.CurrentVersion
+	}
+	signer, ok := k.versions[version]
+	if !ok {
+		return SigningKeyInfo{}, 0, nil, ErrSigningKeyVersionNotFound
+	}
+	return k.info, version, signer, nil
+}
@@ -0,0 +1,70 @@
+// Package lifecycle runs a service until it receives SIGINT or SIGTERM and then shuts
+// it down gracefully: readiness turns false, open connections drain and background
+// workers finish their current run.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Service is a server that can be started and gracefully stopped
+type Service interface {
+	// Start serves requests until the service is stopped
+	Start() error
+
+	// Stop drains the service, giving up when ctx is done
+	Stop(ctx context.Context) error
+}
+
+// Run starts the service and stops it on SIGINT or SIGTERM, allowing it timeout to
+// drain. A second signal during shutdown ends the process immediately.
+func Run(service Service, timeout time.Duration) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	started := make(chan error, 1)
+	go func() {
+		started <- service.Start()
+	}()
+
+	select {
+	case err := <-started:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Restore default signal handling so a second signal kills the process
+	stop()
+	log.Info().Dur("timeout", timeout).Msg("Shutdown signal received, draining")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := service.Stop(shutdownCtx); err != nil {
+		return fmt.Errorf("graceful shutdown failed: %w", err)
+	}
+
+	// Start returns once its listeners are closed
+	if err := <-started; err != nil {
+		return err
+	}
+	log.Info().Msg("Shutdown complete")
+	return nil
+}
+
+// ServeError maps the errors a server returns because it was stopped to nil
+func ServeError(err error) error {
+	if errors.Is(err, http.ErrServerClosed) || errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
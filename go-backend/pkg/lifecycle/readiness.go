@@ -0,0 +1,49 @@
+package lifecycle
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Readiness reports whether a service should receive traffic. It starts out ready and
+// turns not ready for good once draining begins; liveness is reported separately by
+// /health.
+type Readiness struct {
+	draining atomic.Bool
+}
+
+// NewReadiness creates a ready service state
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+// Drain marks the service as not ready and then waits delay, or until ctx is done, so
+// load balancers stop routing to it before its connections are closed
+func (r *Readiness) Drain(ctx context.Context, delay time.Duration) {
+	r.draining.Store(true)
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(delay):
+	}
+}
+
+// Ready reports whether the service is accepting new traffic
+func (r *Readiness) Ready() bool {
+	return !r.draining.Load()
+}
+
+// Handler serves the readiness probe: 200 while ready, 503 while draining
+func (r *Readiness) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !r.Ready() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
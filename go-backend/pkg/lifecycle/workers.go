@@ -0,0 +1,89 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Workers runs a service's background tasks and stops them together. Tasks receive a
+// context that is cancelled on Stop; a run in progress is allowed to finish, and tasks
+// that process batches should check the context between items so they can stop at a
+// consistent point.
+type Workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWorkers creates an empty worker group
+func NewWorkers() *Workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Workers{ctx: ctx, cancel: cancel}
+}
+
+// Context returns the context that is cancelled when the workers stop
+func (w *Workers) Context() context.Context {
+	return w.ctx
+}
+
+// Go runs task in the background until it returns
+func (w *Workers) Go(name string, task func(ctx context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		task(w.ctx)
+		log.Debug().Str("worker", name).Msg("Worker stopped")
+	}()
+}
+
+// Every runs task each interval until the workers stop. The first run happens one
+// interval after the call.
+func (w *Workers) Every(name string, interval time.Duration, task func(ctx context.Context)) {
+	w.Go(name, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				task(ctx)
+			}
+		}
+	})
+}
+
+// WorkerStopTimeout bounds how long StopWithin waits for the workers
+const WorkerStopTimeout = 10 * time.Second
+
+// StopWithin stops the workers like Stop, giving up after timeout. Servers stop their
+// workers last, with a budget of their own, so a drain that used up the shutdown context
+// does not cut short a run in progress.
+func (w *Workers) StopWithin(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return w.Stop(ctx)
+}
+
+// Stop cancels the workers and waits for them to return, giving up when ctx is done
+func (w *Workers) Stop(ctx context.Context) error {
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background workers did not finish: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,266 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// retryInterval is how long to wait after a failed issuance before trying again
+const retryInterval = 10 * time.Second
+
+// CertManager holds a service's certificate and trusted roots and renews the certificate
+// before it expires. TLS configurations it returns always use the current certificate.
+type CertManager struct {
+	id string
+
+	mu    sync.RWMutex
+	cert  *tls.Certificate
+	roots *x509.CertPool
+}
+
+// NewCertManager creates a manager for the given service ID, trusting the given roots
+// until the CA sends its own
+func NewCertManager(id string, roots *x509.CertPool) *CertManager {
+	return &CertManager{id: id, roots: roots}
+}
+
+// ID returns the SPIFFE ID of the service
+func (m *CertManager) ID() string {
+	return m.id
+}
+
+// Start obtains the first certificate, retrying until it succeeds or ctx is done, and
+// then keeps renewing it in the background at two thirds of its lifetime
+func (m *CertManager) Start(ctx context.Context, issuer Issuer) error {
+	for {
+		err := m.renew(ctx, issuer)
+		if err == nil {
+			break
+		}
+		log.Warn().Err(err).Str("service_id", m.id).Msg("Failed to obtain service certificate, retrying")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryInterval):
+		}
+	}
+
+	go m.renewLoop(ctx, issuer)
+	return nil
+}
+
+// renewLoop renews the certificate until ctx is done
+func (m *CertManager) renewLoop(ctx context.Context, issuer Issuer) {
+	for {
+		wait := m.renewIn()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		if err := m.renew(ctx, issuer); err != nil {
+			log.Error().Err(err).Str("service_id", m.id).Msg("Failed to renew service certificate")
+		}
+	}
+}
+
+// renewIn returns how long to wait before the next renewal
+func (m *CertManager) renewIn() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	leaf := m.cert.Leaf
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	wait := time.Until(leaf.NotBefore.Add(lifetime * 2 / 3))
+	if wait < retryInterval {
+		// Renewal is overdue, most likely because the last attempt failed
+		return retryInterval
+	}
+	return wait
+}
+
+// renew issues a certificate for a fresh key and swaps it in
+func (m *CertManager) renew(ctx context.Context, issuer Issuer) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	uri, err := url.Parse(m.id)
+	if err != nil {
+		return fmt.Errorf("invalid service ID: %w", err)
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: m.id},
+		URIs:    []*url.URL{uri},
+	}, key)
+	if err != nil {
+		return fmt.Errorf("failed to create certificate request: %w", err)
+	}
+
+	chainPEM, rootsPEM, err := issuer.Issue(ctx, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}))
+	if err != nil {
+		return err
+	}
+
+	chain, err := parseCertificateChain(chainPEM)
+	if err != nil {
+		return err
+	}
+	if PeerID(chain[0]) != m.id {
+		return fmt.Errorf("issued certificate is for %q, not %q", PeerID(chain[0]), m.id)
+	}
+
+	cert := &tls.Certificate{PrivateKey: key, Leaf: chain[0]}
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+
+	var roots *x509.CertPool
+	if len(rootsPEM) > 0 {
+		if roots, err = ParseRoots(rootsPEM); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.cert = cert
+	if roots != nil {
+		m.roots = roots
+	}
+	m.mu.Unlock()
+
+	log.Info().
+		Str("service_id", m.id).
+		Str("serial", chain[0].SerialNumber.Text(16)).
+		Time("expires_at", chain[0].NotAfter).
+		Msg("Service certificate issued")
+	return nil
+}
+
+// current returns the current certificate and roots
+func (m *CertManager) current() (*tls.Certificate, *x509.CertPool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.cert, m.roots
+}
+
+// ServerTLSConfig returns a server configuration that presents the current certificate
+// and verifies client certificates against the current roots
+func (m *CertManager) ServerTLSConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	getCertificate := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		cert, _ := m.current()
+		if cert == nil {
+			return nil, errors.New("no service certificate yet")
+		}
+		return cert, nil
+	}
+
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: getCertificate,
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, err := getCertificate(hello)
+			if err != nil {
+				return nil, err
+			}
+			_, roots := m.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2", "http/1.1"},
+				Certificates: []tls.Certificate{*cert},
+				ClientAuth:   clientAuth,
+				ClientCAs:    roots,
+			}, nil
+		},
+	}
+}
+
+// ClientTLSConfig returns a client configuration that presents the current certificate,
+// if there is one, and accepts only servers with one of the given SPIFFE IDs
+func (m *CertManager) ClientTLSConfig(serverIDs ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert, _ := m.current(); cert != nil {
+				return cert, nil
+			}
+			return &tls.Certificate{}, nil
+		},
+		// Service certificates carry SPIFFE IDs rather than host names, so the standard
+		// verification is replaced by VerifyPeerCertificate against the current roots
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			id, err := m.verify(rawCerts, x509.ExtKeyUsageServerAuth)
+			if err != nil {
+				return err
+			}
+			for _, serverID := range serverIDs {
+				if id == serverID {
+					return nil
+				}
+			}
+			return fmt.Errorf("unexpected server identity %q", id)
+		},
+	}
+}
+
+// HTTPClient returns an HTTP client that uses ClientTLSConfig
+func (m *CertManager) HTTPClient(timeout time.Duration, serverIDs ...string) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = m.ClientTLSConfig(serverIDs...)
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// verify checks a peer's certificate chain against the current roots and returns its SPIFFE ID
+func (m *CertManager) verify(rawCerts [][]byte, usage x509.ExtKeyUsage) (string, error) {
+	if len(rawCerts) == 0 {
+		return "", errors.New("peer presented no certificate")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid peer certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, roots := m.current()
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}); err != nil {
+		return "", fmt.Errorf("untrusted peer certificate: %w", err)
+	}
+
+	id := PeerID(certs[0])
+	if id == "" {
+		return "", errors.New("peer certificate has no SPIFFE ID")
+	}
+	return id, nil
+}
@@ -0,0 +1,92 @@
+package mtls
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// HeaderBootstrapToken carries the token that authorizes a service's first certificate
+const HeaderBootstrapToken = "X-Bootstrap-Token"
+
+// Issuer signs certificate signing requests
+type Issuer interface {
+	// Issue signs a PEM certificate signing request and returns the PEM certificate
+	// chain, leaf first, and the PEM bundle of current CA roots
+	Issue(ctx context.Context, csrPEM []byte) (chainPEM, rootsPEM []byte, err error)
+}
+
+// IssuerFunc adapts a function to the Issuer interface
+type IssuerFunc func(ctx context.Context, csrPEM []byte) (chainPEM, rootsPEM []byte, err error)
+
+// Issue calls f
+func (f IssuerFunc) Issue(ctx context.Context, csrPEM []byte) ([]byte, []byte, error) {
+	return f(ctx, csrPEM)
+}
+
+// IssueRequest is the payload of the key management service's certificate endpoint
+type IssueRequest struct {
+	CSR string `json:"csr"`
+}
+
+// IssueResponse is the response of the key management service's certificate endpoint
+type IssueResponse struct {
+	Certificate string `json:"certificate"`
+	Roots       string `json:"roots"`
+}
+
+// httpIssuer requests certificates from the key management service's CA
+type httpIssuer struct {
+	url            string
+	bootstrapToken string
+	client         *http.Client
+}
+
+// NewHTTPIssuer creates an issuer that calls the CA at caURL, the base URL of the key
+// management service. The bootstrap token authorizes the first certificate; renewals
+// are authorized by the current certificate, which the client presents.
+func NewHTTPIssuer(caURL, bootstrapToken string, client *http.Client) Issuer {
+	return &httpIssuer{
+		url:            strings.TrimRight(caURL, "/") + "/api/v1/keymgmt/ca/issue",
+		bootstrapToken: bootstrapToken,
+		client:         client,
+	}
+}
+
+// Issue sends the signing request to the CA
+func (i *httpIssuer) Issue(ctx context.Context, csrPEM []byte) ([]byte, []byte, error) {
+	body, err := json.Marshal(IssueRequest{CSR: string(csrPEM)})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if i.bootstrapToken != "" {
+		req.Header.Set(HeaderBootstrapToken, i.bootstrapToken)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("certificate request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, nil, fmt.Errorf("certificate request failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+
+	var issued IssueResponse
+	if err := json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+		return nil, nil, fmt.Errorf("invalid certificate response: %w", err)
+	}
+	return []byte(issued.Certificate), []byte(issued.Roots), nil
+}
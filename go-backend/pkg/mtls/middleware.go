@@ -0,0 +1,52 @@
+package mtls
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestPeerID returns the SPIFFE ID of the verified client certificate of a request,
+// or "" if the client presented none
+func RequestPeerID(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return PeerID(r.TLS.VerifiedChains[0][0])
+}
+
+// RequirePeer returns middleware that only admits clients with a verified certificate.
+// When IDs are given, the certificate must carry one of them. The peer's ID is stored
+// in the context as "peerID".
+func RequirePeer(ids ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		peerID := RequestPeerID(c.Request)
+		if peerID == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Client certificate required"})
+			return
+		}
+
+		if len(ids) > 0 && !contains(ids, peerID) {
+			log.Warn().
+				Str("peer_id", peerID).
+				Str("path", c.Request.URL.Path).
+				Msg("Peer not authorized for route")
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Peer not authorized"})
+			return
+		}
+
+		c.Set("peerID", peerID)
+		c.Next()
+	}
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
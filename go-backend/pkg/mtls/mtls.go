@@ -0,0 +1,111 @@
+// Package mtls provides service-to-service TLS for the gin and gRPC servers. Services
+// are identified by SPIFFE-style URI SANs (spiffe://<trust domain>/service/<name>) in
+// certificates issued by the internal CA in the key management service.
+package mtls
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLS modes
+const (
+	ModeOff  = "off"  // plain HTTP
+	ModeTLS  = "tls"  // TLS, with client certificates verified when presented
+	ModeMTLS = "mtls" // TLS, with a client certificate required on API routes
+)
+
+// Service names used in service IDs
+const (
+	ServiceAuth          = "auth"
+	ServiceEncryption    = "encryption"
+	ServiceKeyManagement = "keymgmt"
+	ServiceAudit         = "audit"
+)
+
+// DefaultTrustDomain is the trust domain of service identities
+const DefaultTrustDomain = "cryptofortress.local"
+
+// ParseMode validates a TLS mode
+func ParseMode(mode string) (string, error) {
+	switch mode {
+	case ModeOff, ModeTLS, ModeMTLS:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown TLS mode %q", mode)
+}
+
+// ServiceID returns the SPIFFE ID of a service
+func ServiceID(trustDomain, service string) string {
+	return "spiffe://" + trustDomain + "/service/" + service
+}
+
+// ParseServiceID returns the trust domain and service name of a SPIFFE service ID
+func ParseServiceID(id string) (trustDomain, service string, err error) {
+	rest, ok := strings.CutPrefix(id, "spiffe://")
+	if !ok {
+		return "", "", fmt.Errorf("%q is not a SPIFFE ID", id)
+	}
+	trustDomain, path, ok := strings.Cut(rest, "/")
+	service, isService := strings.CutPrefix(path, "service/")
+	if !ok || !isService || trustDomain == "" || service == "" || strings.Contains(service, "/") {
+		return "", "", fmt.Errorf("%q is not a service ID", id)
+	}
+	return trustDomain, service, nil
+}
+
+// PeerID returns the SPIFFE ID of a certificate, or "" if it has none
+func PeerID(cert *x509.Certificate) string {
+	for _, uri := range cert.URIs {
+		if uri.Scheme == "spiffe" {
+			return uri.String()
+		}
+	}
+	return ""
+}
+
+// LoadRoots reads a PEM bundle of trusted root certificates
+func LoadRoots(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA roots: %w", err)
+	}
+	return ParseRoots(data)
+}
+
+// ParseRoots parses a PEM bundle of trusted root certificates
+func ParseRoots(data []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no certificates found in CA roots")
+	}
+	return pool, nil
+}
+
+// parseCertificateChain parses a PEM certificate chain, leaf first
+func parseCertificateChain(data []byte) ([]*x509.Certificate, error) {
+	var chain []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate: %w", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return chain, nil
+}
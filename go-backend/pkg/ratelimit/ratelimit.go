@@ -0,0 +1,160 @@
+// Package ratelimit provides token-bucket request rate limiting shared by the gin services.
+// Buckets live in a Store, which is in-memory by default and can be swapped for a shared
+// backend when a service runs as several instances.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token-bucket limit: Requests tokens are added every Period, up to Burst.
+// The zero Limit disables rate limiting.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+// Disabled reports whether the limit lets every request through
+func (l Limit) Disabled() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// rate returns the refill rate in tokens per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// burst returns the bucket capacity, which defaults to Requests
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Requests
+}
+
+// String formats the limit in the form accepted by ParseLimit
+func (l Limit) String() string {
+	if l.Disabled() {
+		return "off"
+	}
+	s := strconv.Itoa(l.Requests) + "/" + formatPeriod(l.Period)
+	if l.Burst > 0 && l.Burst != l.Requests {
+		s += ":" + strconv.Itoa(l.Burst)
+	}
+	return s
+}
+
+// ParseLimit parses a limit of the form "requests/period[:burst]", for example "10/m",
+// "600/h" or "5/10s:20". The period is s, m, h or a Go duration. "off" disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" || s == "0" {
+		return Limit{}, nil
+	}
+
+	spec, burstStr, hasBurst := strings.Cut(s, ":")
+	requestsStr, periodStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must look like requests/period", s)
+	}
+
+	requests, err := strconv.Atoi(requestsStr)
+	if err != nil || requests <= 0 {
+		return Limit{}, fmt.Errorf("limit %q has an invalid request count", s)
+	}
+
+	period, err := parsePeriod(periodStr)
+	if err != nil {
+		return Limit{}, fmt.Errorf("limit %q has an invalid period: %v", s, err)
+	}
+
+	limit := Limit{Requests: requests, Period: period}
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burstStr); err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("limit %q has an invalid burst", s)
+		}
+	}
+	return limit, nil
+}
+
+// parsePeriod parses a period unit or a Go duration
+func parsePeriod(s string) (time.Duration, error) {
+	switch s {
+	case "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	}
+	period, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if period <= 0 {
+		return 0, fmt.Errorf("period must be positive")
+	}
+	return period, nil
+}
+
+// formatPeriod formats a period as a unit where possible
+func formatPeriod(d time.Duration) string {
+	switch d {
+	case time.Second:
+		return "s"
+	case time.Minute:
+		return "m"
+	case time.Hour:
+		return "h"
+	}
+	return d.String()
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed    bool
+	Remaining  int           // whole tokens left in the bucket
+	Reset      time.Duration // time until the bucket is full again
+	RetryAfter time.Duration // time until the next token, when not allowed
+}
+
+// Store holds token buckets. Implementations must be safe for concurrent use.
+type Store interface {
+	// Take removes one token from the bucket identified by key
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// refill computes a bucket's state after taking a token at now. It is shared by the
+// in-memory store and mirrored by the script of the Redis store.
+func refill(tokens float64, updated, now time.Time, limit Limit) (float64, Result) {
+	rate := limit.rate()
+	burst := float64(limit.burst())
+
+	if elapsed := now.Sub(updated).Seconds(); elapsed > 0 {
+		tokens += elapsed * rate
+	}
+	if tokens > burst {
+		tokens = burst
+	}
+
+	result := Result{}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+	result.Remaining = int(tokens)
+	result.Reset = secondsToDuration((burst - tokens) / rate)
+	return tokens, result
+}
+
+// secondsToDuration converts fractional seconds to a duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
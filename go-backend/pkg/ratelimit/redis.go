@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Scripter runs a Lua script on a Redis server. It is satisfied by an adapter around
+// any Redis client, for example with go-redis:
+//
+//	ratelimit.ScripterFunc(func(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+//		return rdb.Eval(ctx, script, keys, args...).Result()
+//	})
+type Scripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// ScripterFunc adapts a function to the Scripter interface
+type ScripterFunc func(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+
+// Eval calls f
+func (f ScripterFunc) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return f(ctx, script, keys, args...)
+}
+
+// takeScript atomically refills and takes a token from a bucket stored as a hash. It uses
+// the Redis server clock so that instances with skewed clocks share one consistent bucket.
+// The bucket expires once it would be full again, since a missing bucket starts full.
+const takeScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or burst
+local updated = tonumber(state[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - updated) * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil((burst - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`
+
+// RedisStore keeps token buckets in Redis so that every instance of a service shares them
+type RedisStore struct {
+	client Scripter
+	prefix string
+}
+
+// NewRedisStore creates a store that keeps buckets in Redis under the given key prefix
+func NewRedisStore(client Scripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Take removes one token from the bucket identified by key
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	reply, err := s.client.Eval(ctx, takeScript, []string{s.prefix + key},
+		strconv.FormatFloat(limit.rate(), 'g', -1, 64), limit.burst())
+	if err != nil {
+		return Result{}, fmt.Errorf("rate limit script failed: %w", err)
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+	allowed, ok := values[0].(int64)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+	tokensStr, ok := values[1].(string)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected rate limit script reply %v", reply)
+	}
+
+	rate := limit.rate()
+	result := Result{
+		Allowed:   allowed == 1,
+		Remaining: int(tokens),
+		Reset:     secondsToDuration((float64(limit.burst()) - tokens) / rate),
+	}
+	if !result.Allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / rate)
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+)
+
+// Reader decrypts a stream written by Writer. Each segment's plaintext is returned only
+// after the segment authenticates, but a stream is only known to be complete once Read
+// returns io.EOF. If Read fails first, everything read so far must be discarded.
+type Reader struct {
+	src        io.Reader
+	segments   segmenter
+	ciphertext []byte // one full segment and one byte beyond it
+	buffered   int    // bytes of ciphertext read ahead from the next segment
+	plaintext  []byte // decrypted plaintext not yet returned
+	output     []byte
+	final      bool
+	err        error
+}
+
+// NewReader returns a Reader that decrypts src with the aead, nonce prefix, associated
+// data and segment size the stream was written with
+func NewReader(src io.Reader, aead cipher.AEAD, noncePrefix, additionalData []byte, segmentSize int) (*Reader, error) {
+	segments, err := newSegmenter(aead, noncePrefix, additionalData, segmentSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		src:        src,
+		segments:   segments,
+		ciphertext: make([]byte, segmentSize+aead.Overhead()+1),
+		output:     make([]byte, 0, segmentSize),
+	}, nil
+}
+
+// Read returns decrypted plaintext. It returns io.EOF only after the final segment has
+// authenticated.
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.final {
+			return 0, io.EOF
+		}
+		r.err = r.open()
+	}
+
+	n := copy(p, r.plaintext)
+	r.plaintext = r.plaintext[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next segment
+func (r *Reader) open() error {
+	// Reading one byte beyond a full segment tells whether this segment is the final one
+	segmentEnd := len(r.ciphertext) - 1
+	n, err := io.ReadFull(r.src, r.ciphertext[r.buffered:])
+	r.buffered += n
+
+	final := false
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+		segmentEnd = r.buffered
+	default:
+		return err
+	}
+
+	nonce, err := r.segments.next(final)
+	if err != nil {
+		return err
+	}
+	r.output, err = r.segments.aead.Open(r.output[:0], nonce, r.ciphertext[:segmentEnd], r.segments.additionalData)
+	if err != nil {
+		return ErrAuthentication
+	}
+	r.plaintext = r.output
+
+	if final {
+		r.final = true
+	} else {
+		r.ciphertext[0] = r.ciphertext[segmentEnd]
+		r.buffered = 1
+	}
+	return nil
+}
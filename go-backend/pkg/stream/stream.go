@@ -0,0 +1,78 @@
+// Package stream encrypts data of any length with an AEAD in fixed-size segments, using
+// the STREAM construction. Each segment's nonce is a random per-stream prefix followed by
+// the segment's index and a flag marking the final segment. A reader therefore only
+// accepts segments in their original order and detects a stream that was truncated,
+// reordered or extended, while holding a single segment in memory.
+package stream
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// NonceOverhead is the number of bytes at the end of each nonce taken by the segment
+// index and final-segment flag. The nonce prefix fills the rest.
+const NonceOverhead = 5
+
+// DefaultSegmentSize is the plaintext size of each segment, except the final one,
+// unless another size is chosen
+const DefaultSegmentSize = 64 * 1024
+
+var (
+	// ErrAuthentication is returned when a segment fails authentication. The stream was
+	// modified, truncated or reordered, or the key or associated data is wrong.
+	ErrAuthentication = errors.New("stream: segment authentication failed")
+	// ErrTooManySegments is returned when a stream would need more segments than the
+	// segment index can count
+	ErrTooManySegments = errors.New("stream: too many segments")
+	// ErrClosed is returned when writing to a closed Writer
+	ErrClosed = errors.New("stream: writer is closed")
+)
+
+// segmenter derives segment nonces and counts segments
+type segmenter struct {
+	aead           cipher.AEAD
+	nonce          []byte // prefix, then the index and final flag of the current segment
+	additionalData []byte
+	segmentSize    int
+	index          uint32
+}
+
+// newSegmenter checks the stream parameters
+func newSegmenter(aead cipher.AEAD, noncePrefix, additionalData []byte, segmentSize int) (segmenter, error) {
+	if len(noncePrefix) != aead.NonceSize()-NonceOverhead {
+		return segmenter{}, fmt.Errorf("stream: nonce prefix must be %d bytes", aead.NonceSize()-NonceOverhead)
+	}
+	if segmentSize <= 0 {
+		return segmenter{}, errors.New("stream: segment size must be positive")
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	copy(nonce, noncePrefix)
+	return segmenter{
+		aead:           aead,
+		nonce:          nonce,
+		additionalData: additionalData,
+		segmentSize:    segmentSize,
+	}, nil
+}
+
+// next returns the nonce of the next segment and advances the index. Only the final
+// segment may use the last index.
+func (s *segmenter) next(final bool) ([]byte, error) {
+	if !final && s.index == math.MaxUint32 {
+		return nil, ErrTooManySegments
+	}
+
+	suffix := s.nonce[len(s.nonce)-NonceOverhead:]
+	binary.BigEndian.PutUint32(suffix, s.index)
+	suffix[4] = 0
+	if final {
+		suffix[4] = 1
+	}
+	s.index++
+	return s.nonce, nil
+}
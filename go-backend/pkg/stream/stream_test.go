@@ -0,0 +1,224 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+// testSegmentSize keeps streams short while still spanning several segments
+const testSegmentSize = 16
+
+var testAdditionalData = []byte("stream test")
+
+// newTestAEAD returns AES-256-GCM with a random key and a random nonce prefix
+func newTestAEAD(t *testing.T) (cipher.AEAD, []byte) {
+	t.Helper()
+	key := make([]byte, 32)
+	rand.Read(key)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create GCM: %v", err)
+	}
+	prefix := make([]byte, aead.NonceSize()-NonceOverhead)
+	rand.Read(prefix)
+	return aead, prefix
+}
+
+// encrypt writes plaintext through a Writer in chunks of the given size
+func encrypt(t *testing.T, aead cipher.AEAD, prefix, plaintext []byte, chunk int) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := NewWriter(&out, aead, prefix, testAdditionalData, testSegmentSize)
+	if err != nil {
+		t.Fatalf("Failed to create writer: %v", err)
+	}
+	for len(plaintext) > 0 {
+		n := min(chunk, len(plaintext))
+		if _, err := w.Write(plaintext[:n]); err != nil {
+			t.Fatalf("Failed to write: %v", err)
+		}
+		plaintext = plaintext[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	return out.Bytes()
+}
+
+// decrypt reads a whole stream
+func decrypt(t *testing.T, aead cipher.AEAD, prefix, ciphertext, additionalData []byte) ([]byte, error) {
+	t.Helper()
+	r, err := NewReader(iotest.HalfReader(bytes.NewReader(ciphertext)), aead, prefix, additionalData, testSegmentSize)
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	return io.ReadAll(r)
+}
+
+// sealSegment seals one segment by hand, with any index and final flag
+func sealSegment(aead cipher.AEAD, prefix, plaintext []byte, index uint32, final bool) []byte {
+	nonce := append(bytes.Clone(prefix), make([]byte, NonceOverhead)...)
+	binary.BigEndian.PutUint32(nonce[len(prefix):], index)
+	if final {
+		nonce[len(nonce)-1] = 1
+	}
+	return aead.Seal(nil, nonce, plaintext, testAdditionalData)
+}
+
+// TestStream tests the STREAM construction's round trip and its rejection of modified streams
+func TestStream(t *testing.T) {
+	aead, prefix := newTestAEAD(t)
+	sealedSize := testSegmentSize + aead.Overhead()
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		sizes := []int{1, testSegmentSize - 1, testSegmentSize, testSegmentSize + 1, 3 * testSegmentSize, 3*testSegmentSize + 7}
+		for _, size := range sizes {
+			plaintext := make([]byte, size)
+			rand.Read(plaintext)
+
+			for _, chunk := range []int{1, 5, testSegmentSize, size} {
+				ciphertext := encrypt(t, aead, prefix, plaintext, chunk)
+
+				segments := (size + testSegmentSize - 1) / testSegmentSize
+				if expected := size + segments*aead.Overhead(); len(ciphertext) != expected {
+					t.Errorf("%d bytes in chunks of %d: expected %d bytes of ciphertext, got %d", size, chunk, expected, len(ciphertext))
+				}
+
+				decrypted, err := decrypt(t, aead, prefix, ciphertext, testAdditionalData)
+				if err != nil {
+					t.Fatalf("%d bytes in chunks of %d: failed to decrypt: %v", size, chunk, err)
+				}
+				if !bytes.Equal(decrypted, plaintext) {
+					t.Errorf("%d bytes in chunks of %d: round trip mismatch", size, chunk)
+				}
+			}
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		// An empty stream is a single, empty final segment
+		ciphertext := encrypt(t, aead, prefix, nil, 1)
+		if len(ciphertext) != aead.Overhead() {
+			t.Fatalf("Expected %d bytes of ciphertext, got %d", aead.Overhead(), len(ciphertext))
+		}
+		decrypted, err := decrypt(t, aead, prefix, ciphertext, testAdditionalData)
+		if err != nil {
+			t.Fatalf("Failed to decrypt: %v", err)
+		}
+		if len(decrypted) != 0 {
+			t.Errorf("Expected no plaintext, got %d bytes", len(decrypted))
+		}
+
+		// Without even the final segment the stream is truncated
+		if _, err := decrypt(t, aead, prefix, nil, testAdditionalData); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("Expected ErrAuthentication for no ciphertext, got %v", err)
+		}
+	})
+
+	plaintext := make([]byte, 3*testSegmentSize+5)
+	rand.Read(plaintext)
+	ciphertext := encrypt(t, aead, prefix, plaintext, len(plaintext))
+
+	t.Run("DroppedFinalSegment", func(t *testing.T) {
+		truncated := ciphertext[:3*sealedSize]
+		decrypted, err := decrypt(t, aead, prefix, truncated, testAdditionalData)
+		if !errors.Is(err, ErrAuthentication) {
+			t.Fatalf("Expected ErrAuthentication, got %v", err)
+		}
+		if len(decrypted) > 2*testSegmentSize {
+			t.Errorf("Reader returned %d bytes, beyond the segments before the last full one", len(decrypted))
+		}
+
+		// Cutting inside a segment is caught too
+		if _, err := decrypt(t, aead, prefix, ciphertext[:len(ciphertext)-1], testAdditionalData); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("Expected ErrAuthentication for a cut final segment, got %v", err)
+		}
+	})
+
+	t.Run("SwappedSegments", func(t *testing.T) {
+		swapped := bytes.Clone(ciphertext)
+		copy(swapped[:sealedSize], ciphertext[sealedSize:2*sealedSize])
+		copy(swapped[sealedSize:2*sealedSize], ciphertext[:sealedSize])
+		if _, err := decrypt(t, aead, prefix, swapped, testAdditionalData); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("Expected ErrAuthentication, got %v", err)
+		}
+	})
+
+	t.Run("FlippedFinalFlag", func(t *testing.T) {
+		first := plaintext[:testSegmentSize]
+		second := plaintext[testSegmentSize : 2*testSegmentSize]
+
+		// A full segment sealed as final cannot be followed by more segments
+		early := append(sealSegment(aead, prefix, first, 0, true), sealSegment(aead, prefix, second, 1, true)...)
+		if _, err := decrypt(t, aead, prefix, early, testAdditionalData); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("Expected ErrAuthentication for an early final segment, got %v", err)
+		}
+
+		// A last segment not sealed as final means the stream was cut
+		missing := append(sealSegment(aead, prefix, first, 0, false), sealSegment(aead, prefix, second[:5], 1, false)...)
+		if _, err := decrypt(t, aead, prefix, missing, testAdditionalData); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("Expected ErrAuthentication for a missing final flag, got %v", err)
+		}
+
+		// The same segments with the right flags decrypt
+		valid := append(sealSegment(aead, prefix, first, 0, false), sealSegment(aead, prefix, second[:5], 1, true)...)
+		decrypted, err := decrypt(t, aead, prefix, valid, testAdditionalData)
+		if err != nil {
+			t.Fatalf("Failed to decrypt hand-sealed stream: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext[:testSegmentSize+5]) {
+			t.Error("Hand-sealed stream round trip mismatch")
+		}
+	})
+
+	t.Run("Modified", func(t *testing.T) {
+		extended := append(bytes.Clone(ciphertext), sealSegment(aead, prefix, []byte("more"), 4, true)...)
+		if _, err := decrypt(t, aead, prefix, extended, testAdditionalData); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("Expected ErrAuthentication for an extended stream, got %v", err)
+		}
+
+		flipped := bytes.Clone(ciphertext)
+		flipped[sealedSize+3] ^= 0x80
+		if _, err := decrypt(t, aead, prefix, flipped, testAdditionalData); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("Expected ErrAuthentication for a flipped bit, got %v", err)
+		}
+
+		if _, err := decrypt(t, aead, prefix, ciphertext, []byte("other")); !errors.Is(err, ErrAuthentication) {
+			t.Errorf("Expected ErrAuthentication for the wrong associated data, got %v", err)
+		}
+	})
+
+	t.Run("Parameters", func(t *testing.T) {
+		if _, err := NewWriter(io.Discard, aead, prefix[1:], nil, testSegmentSize); err == nil {
+			t.Error("Expected an error for a short nonce prefix")
+		}
+		if _, err := NewReader(bytes.NewReader(nil), aead, prefix, nil, 0); err == nil {
+			t.Error("Expected an error for a zero segment size")
+		}
+
+		w, err := NewWriter(io.Discard, aead, prefix, nil, testSegmentSize)
+		if err != nil {
+			t.Fatalf("Failed to create writer: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Failed to close writer: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Errorf("Expected a second Close to succeed, got %v", err)
+		}
+		if _, err := w.Write([]byte("late")); !errors.Is(err, ErrClosed) {
+			t.Errorf("Expected ErrClosed writing after Close, got %v", err)
+		}
+	})
+}
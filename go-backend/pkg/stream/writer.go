@@ -0,0 +1,90 @@
+package stream
+
+import (
+	"crypto/cipher"
+	"io"
+)
+
+// Writer encrypts what is written to it and writes the sealed segments to an underlying
+// writer. Close must be called to write the final segment; without it the stream does
+// not decrypt.
+type Writer struct {
+	dst        io.Writer
+	segments   segmenter
+	plaintext  []byte // buffered plaintext of the current segment
+	ciphertext []byte
+	err        error
+}
+
+// NewWriter returns a Writer that encrypts to dst with aead. noncePrefix must be
+// aead.NonceSize()-NonceOverhead random bytes and must never be reused with the same
+// key. additionalData is authenticated with every segment.
+func NewWriter(dst io.Writer, aead cipher.AEAD, noncePrefix, additionalData []byte, segmentSize int) (*Writer, error) {
+	segments, err := newSegmenter(aead, noncePrefix, additionalData, segmentSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{
+		dst:        dst,
+		segments:   segments,
+		plaintext:  make([]byte, 0, segmentSize),
+		ciphertext: make([]byte, 0, segmentSize+aead.Overhead()),
+	}, nil
+}
+
+// Write encrypts p. Segments are written to the underlying writer as they fill.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	n := 0
+	for len(p) > 0 {
+		// A full segment is only sealed once more data arrives, because the final
+		// segment must be sealed as final
+		if len(w.plaintext) == cap(w.plaintext) {
+			if err := w.seal(false); err != nil {
+				w.err = err
+				return n, err
+			}
+		}
+
+		copied := copy(w.plaintext[len(w.plaintext):cap(w.plaintext)], p)
+		w.plaintext = w.plaintext[:len(w.plaintext)+copied]
+		p = p[copied:]
+		n += copied
+	}
+	return n, nil
+}
+
+// Close writes the final segment. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		if w.err == ErrClosed {
+			return nil
+		}
+		return w.err
+	}
+
+	if err := w.seal(true); err != nil {
+		w.err = err
+		return err
+	}
+	w.err = ErrClosed
+	return nil
+}
+
+// seal encrypts the buffered plaintext as the next segment and writes it
+func (w *Writer) seal(final bool) error {
+	nonce, err := w.segments.next(final)
+	if err != nil {
+		return err
+	}
+
+	w.ciphertext = w.segments.aead.Seal(w.ciphertext[:0], nonce, w.plaintext, w.segments.additionalData)
+	clear(w.plaintext)
+	w.plaintext = w.plaintext[:0]
+
+	_, err = w.dst.Write(w.ciphertext)
+	return err
+}
@@ -40,7 +40,8 @@ def calculate_realtime_risk():
             risk_factors.get('access_frequency', 0.5),
             risk_factors.get('geographical_risk', 0.5),
             risk_factors.get('time_based_risk', 0.5),
-            risk_factors.get('device_trust_score', 0.5)
+            # Device trust lowers the risk, so it enters the score inverted
+            1 - risk_factors.get('device_trust_score', 0.5)
         ]).reshape(1, -1)
         
         # In a real implementation, you would: